@@ -0,0 +1,112 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+)
+
+// ==== Admin CLI ====
+//
+//	api-server user add <username>      — создать пользователя
+//	api-server user passwd <username>   — сбросить пароль (и снять блокировку)
+//
+// Пароль читается из stdin (одна строка), чтобы не попадать в историю shell:
+//
+//	echo 'secret-password' | api-server user add alice
+
+func runUserCommand(cfg Config, args []string) int {
+	fs := flag.NewFlagSet("user", flag.ContinueOnError)
+	usersFile := fs.String("file", cfg.UsersFile, "путь к JSON файлу пользователей")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "usage: api-server user [-file path] add|passwd <username>")
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	if fs.NArg() != 2 {
+		fs.Usage()
+		return 2
+	}
+
+	store, err := newJSONUserStore(*usersFile)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+
+	cmd, username := fs.Arg(0), fs.Arg(1)
+	switch cmd {
+	case "add":
+		err = addUser(store, username, os.Stdin)
+	case "passwd":
+		err = resetPassword(store, username, os.Stdin)
+	default:
+		fs.Usage()
+		return 2
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "user %s: %v\n", cmd, err)
+		return 1
+	}
+	fmt.Printf("user %s: ok (%s)\n", cmd, username)
+	return 0
+}
+
+func addUser(store UserStore, username string, in io.Reader) error {
+	if err := validateUsername(username); err != nil {
+		return err
+	}
+	if _, err := store.Get(username); err == nil {
+		return ErrUserExists
+	} else if !errors.Is(err, ErrUserNotFound) {
+		return err
+	}
+
+	hash, err := readPasswordHash(in)
+	if err != nil {
+		return err
+	}
+	now := time.Now()
+	return store.Put(&User{
+		Username:     username,
+		PasswordHash: hash,
+		CreatedAt:    now,
+		UpdatedAt:    now,
+	})
+}
+
+func resetPassword(store UserStore, username string, in io.Reader) error {
+	u, err := store.Get(username)
+	if err != nil {
+		return err
+	}
+	hash, err := readPasswordHash(in)
+	if err != nil {
+		return err
+	}
+	u.PasswordHash = hash
+	u.FailedLogins = 0
+	u.LockedUntil = time.Time{}
+	u.UpdatedAt = time.Now()
+	return store.Put(u)
+}
+
+func readPasswordHash(in io.Reader) (string, error) {
+	fmt.Fprint(os.Stderr, "password: ")
+	line, err := bufio.NewReader(in).ReadString('\n')
+	if err != nil && !(errors.Is(err, io.EOF) && line != "") {
+		return "", errors.New("password required on stdin")
+	}
+	password := strings.TrimRight(line, "\r\n")
+	if err := validatePassword(password); err != nil {
+		return "", err
+	}
+	return hashPassword(password)
+}
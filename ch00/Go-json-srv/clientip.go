@@ -0,0 +1,196 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+)
+
+// ==== IP клиента за доверенными прокси ====
+//
+// Заголовкам X-Forwarded-For / Forwarded / X-Real-IP верим только если
+// запрос пришёл от доверенного прокси (TrustedProxies). Цепочку идём справа
+// налево: правые записи добавили наши прокси, левые мог подставить клиент.
+
+type ctxKey int
+
+const (
+	ctxClientIP ctxKey = iota
+	ctxRequestID
+	ctxClaims
+)
+
+// ipNets — список подсетей (доверенные прокси, доступ к /metrics).
+type ipNets []*net.IPNet
+
+func parseIPNets(cidrs []string) (ipNets, error) {
+	var nets ipNets
+	for _, c := range cidrs {
+		// Одиночный IP допускаем без маски
+		if !strings.Contains(c, "/") {
+			if ip := net.ParseIP(c); ip != nil && ip.To4() != nil {
+				c += "/32"
+			} else {
+				c += "/128"
+			}
+		}
+		_, n, err := net.ParseCIDR(c)
+		if err != nil {
+			return nil, fmt.Errorf("cidr %q: %w", c, err)
+		}
+		nets = append(nets, n)
+	}
+	return nets, nil
+}
+
+func (nets ipNets) contains(ip net.IP) bool {
+	for _, n := range nets {
+		if n.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+type ipResolver struct {
+	trusted ipNets
+}
+
+func newIPResolver(cidrs []string) (*ipResolver, error) {
+	trusted, err := parseIPNets(cidrs)
+	if err != nil {
+		return nil, fmt.Errorf("trusted proxies: %w", err)
+	}
+	return &ipResolver{trusted: trusted}, nil
+}
+
+func (res *ipResolver) isTrusted(ip net.IP) bool { return res.trusted.contains(ip) }
+
+// resolve определяет IP клиента для запроса.
+func (res *ipResolver) resolve(r *http.Request) string {
+	peer := parseIP(r.RemoteAddr)
+	if peer == nil {
+		host, _, _ := net.SplitHostPort(r.RemoteAddr)
+		return host
+	}
+	if !res.isTrusted(peer) {
+		return peer.String()
+	}
+
+	// RFC 7239 приоритетнее, X-Forwarded-For — де-факто стандарт nginx
+	var hops []string
+	if fwd := r.Header.Values("Forwarded"); len(fwd) > 0 {
+		hops = parseForwardedFor(fwd)
+	} else if xff := r.Header.Values("X-Forwarded-For"); len(xff) > 0 {
+		for _, v := range xff {
+			for _, p := range strings.Split(v, ",") {
+				hops = append(hops, strings.TrimSpace(p))
+			}
+		}
+	}
+
+	if len(hops) > 0 {
+		for i := len(hops) - 1; i >= 0; i-- {
+			ip := parseIP(hops[i])
+			if ip == nil {
+				// "unknown", обфусцированный идентификатор или мусор:
+				// левее доверять нечему
+				return peer.String()
+			}
+			if !res.isTrusted(ip) || i == 0 {
+				return ip.String()
+			}
+		}
+	}
+
+	// Прокси без X-Forwarded-For (nginx: proxy_set_header X-Real-IP $remote_addr)
+	if ip := parseIP(r.Header.Get("X-Real-IP")); ip != nil {
+		return ip.String()
+	}
+	return peer.String()
+}
+
+// parseIP понимает "1.2.3.4", "1.2.3.4:80", "[::1]:80", "::1".
+func parseIP(s string) net.IP {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return nil
+	}
+	if ip := net.ParseIP(s); ip != nil {
+		return ip
+	}
+	if host, _, err := net.SplitHostPort(s); err == nil {
+		return net.ParseIP(host)
+	}
+	return net.ParseIP(strings.Trim(s, "[]"))
+}
+
+// parseForwardedFor достаёт параметры for= из заголовков Forwarded (RFC 7239)
+// в порядке хопов: for=192.0.2.60;proto=http, for="[2001:db8::17]:4711"
+func parseForwardedFor(values []string) []string {
+	var hops []string
+	for _, v := range values {
+		for _, elem := range splitQuoted(v, ',') {
+			hop := ""
+			for _, pair := range splitQuoted(elem, ';') {
+				k, val, ok := strings.Cut(strings.TrimSpace(pair), "=")
+				if !ok || !strings.EqualFold(strings.TrimSpace(k), "for") {
+					continue
+				}
+				hop = strings.Trim(strings.TrimSpace(val), `"`)
+			}
+			// Элемент без for= — тоже хоп, который мы не можем проверить
+			hops = append(hops, hop)
+		}
+	}
+	return hops
+}
+
+// splitQuoted режет строку по sep, не трогая разделители внутри кавычек.
+func splitQuoted(s string, sep byte) []string {
+	var parts []string
+	inQuotes := false
+	start := 0
+	for i := 0; i < len(s); i++ {
+		switch s[i] {
+		case '"':
+			inQuotes = !inQuotes
+		case '\\':
+			if inQuotes {
+				i++
+			}
+		case sep:
+			if !inQuotes {
+				parts = append(parts, s[start:i])
+				start = i + 1
+			}
+		}
+	}
+	return append(parts, s[start:])
+}
+
+// realIP — определяет IP один раз на запрос и кладёт в контекст,
+// чтобы rateLimit, requestLogger и хэндлеры видели одно и то же значение.
+func realIP(res *ipResolver) middleware {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			ctx := context.WithValue(r.Context(), ctxClientIP, res.resolve(r))
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
+}
+
+// clientIP — IP клиента, определённый middleware realIP.
+// Если middleware не подключён — только RemoteAddr, заголовкам не верим.
+func clientIP(r *http.Request) string {
+	if ip, ok := r.Context().Value(ctxClientIP).(string); ok {
+		return ip
+	}
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
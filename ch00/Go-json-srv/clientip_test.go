@@ -0,0 +1,154 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+)
+
+func TestIPResolverResolve(t *testing.T) {
+	res, err := newIPResolver([]string{"10.0.0.0/8", "2001:db8:ffff::1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name    string
+		remote  string
+		headers map[string][]string
+		want    string
+	}{
+		{"прямой клиент", "203.0.113.7:5000", nil, "203.0.113.7"},
+		{"недоверенный пир: XFF игнорируется", "203.0.113.7:5000",
+			map[string][]string{"X-Forwarded-For": {"198.51.100.1"}}, "203.0.113.7"},
+		{"недоверенный пир: Forwarded игнорируется", "203.0.113.7:5000",
+			map[string][]string{"Forwarded": {"for=198.51.100.1"}}, "203.0.113.7"},
+		{"недоверенный пир: X-Real-IP игнорируется", "203.0.113.7:5000",
+			map[string][]string{"X-Real-IP": {"198.51.100.1"}}, "203.0.113.7"},
+		{"недоверенный IPv6 пир", "[2001:db8::1]:443",
+			map[string][]string{"X-Forwarded-For": {"198.51.100.1"}}, "2001:db8::1"},
+
+		{"XFF от доверенного прокси", "10.0.0.1:80",
+			map[string][]string{"X-Forwarded-For": {"198.51.100.1"}}, "198.51.100.1"},
+		{"подделанный левый XFF", "10.0.0.1:80",
+			map[string][]string{"X-Forwarded-For": {"1.1.1.1, 198.51.100.1"}}, "198.51.100.1"},
+		{"цепочка доверенных прокси", "10.0.0.1:80",
+			map[string][]string{"X-Forwarded-For": {"6.6.6.6, 198.51.100.1, 10.0.0.5, 10.0.0.2"}}, "198.51.100.1"},
+		{"несколько заголовков XFF", "10.0.0.1:80",
+			map[string][]string{"X-Forwarded-For": {"6.6.6.6, 7.7.7.7", "198.51.100.1, 10.0.0.2"}}, "198.51.100.1"},
+		{"несколько заголовков XFF: подделка в первом", "10.0.0.1:80",
+			map[string][]string{"X-Forwarded-For": {"6.6.6.6", "198.51.100.1"}}, "198.51.100.1"},
+		{"все хопы доверенные — берём самый левый", "10.0.0.1:80",
+			map[string][]string{"X-Forwarded-For": {"10.0.0.9, 10.0.0.2"}}, "10.0.0.9"},
+		{"мусор в XFF — левее не доверяем", "10.0.0.1:80",
+			map[string][]string{"X-Forwarded-For": {"198.51.100.1, garbage, 10.0.0.2"}}, "10.0.0.1"},
+		{"XFF с портом", "10.0.0.1:80",
+			map[string][]string{"X-Forwarded-For": {"198.51.100.1:4711"}}, "198.51.100.1"},
+
+		{"Forwarded", "10.0.0.1:80",
+			map[string][]string{"Forwarded": {"for=198.51.100.1;proto=https"}}, "198.51.100.1"},
+		{"Forwarded: кавычки и IPv6 с портом", "10.0.0.1:80",
+			map[string][]string{"Forwarded": {`for="[2001:db8::17]:4711";proto=https`}}, "2001:db8::17"},
+		{"Forwarded: IPv6 без порта", "10.0.0.1:80",
+			map[string][]string{"Forwarded": {`For="[2001:db8::17]"`}}, "2001:db8::17"},
+		{"Forwarded: подделка слева", "10.0.0.1:80",
+			map[string][]string{"Forwarded": {`for=6.6.6.6, for="198.51.100.1", for=10.0.0.2`}}, "198.51.100.1"},
+		{"Forwarded: запятая в кавычках не режет элемент", "10.0.0.1:80",
+			map[string][]string{"Forwarded": {`for=198.51.100.1;ext="a,b"`}}, "198.51.100.1"},
+		{"Forwarded: доверенный IPv6 прокси", "10.0.0.1:80",
+			map[string][]string{"Forwarded": {`for=198.51.100.1, for="[2001:db8:ffff::1]"`}}, "198.51.100.1"},
+		{"Forwarded: несколько заголовков", "10.0.0.1:80",
+			map[string][]string{"Forwarded": {"for=6.6.6.6", "for=198.51.100.1"}}, "198.51.100.1"},
+		{"Forwarded: обфусцированный хоп", "10.0.0.1:80",
+			map[string][]string{"Forwarded": {"for=198.51.100.1, for=_hidden"}}, "10.0.0.1"},
+		{"Forwarded: unknown", "10.0.0.1:80",
+			map[string][]string{"Forwarded": {"for=unknown"}}, "10.0.0.1"},
+		{"Forwarded: элемент без for=", "10.0.0.1:80",
+			map[string][]string{"Forwarded": {"for=198.51.100.1, proto=https"}}, "10.0.0.1"},
+		{"Forwarded приоритетнее XFF", "10.0.0.1:80",
+			map[string][]string{"Forwarded": {"for=198.51.100.1"}, "X-Forwarded-For": {"198.51.100.2"}}, "198.51.100.1"},
+
+		{"X-Real-IP от доверенного прокси", "10.0.0.1:80",
+			map[string][]string{"X-Real-IP": {"198.51.100.1"}}, "198.51.100.1"},
+		{"X-Real-IP с мусором", "10.0.0.1:80",
+			map[string][]string{"X-Real-IP": {"garbage"}}, "10.0.0.1"},
+		{"доверенный прокси без заголовков", "10.0.0.1:80", nil, "10.0.0.1"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodGet, "/", nil)
+			r.RemoteAddr = tt.remote
+			for k, vs := range tt.headers {
+				for _, v := range vs {
+					r.Header.Add(k, v)
+				}
+			}
+			if got := res.resolve(r); got != tt.want {
+				t.Errorf("resolve = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseForwardedFor(t *testing.T) {
+	tests := []struct {
+		in   []string
+		want []string
+	}{
+		{[]string{"for=192.0.2.60;proto=http;by=203.0.113.43"}, []string{"192.0.2.60"}},
+		{[]string{`for="_gazonk"`}, []string{"_gazonk"}},
+		{[]string{`For="[2001:db8:cafe::17]:4711"`}, []string{"[2001:db8:cafe::17]:4711"}},
+		{[]string{"for=192.0.2.43, for=198.51.100.17"}, []string{"192.0.2.43", "198.51.100.17"}},
+		{[]string{"for=192.0.2.43", "for=198.51.100.17"}, []string{"192.0.2.43", "198.51.100.17"}},
+		{[]string{`for=192.0.2.43;ext="x;y,z"`}, []string{"192.0.2.43"}},
+		{[]string{"proto=https"}, []string{""}},
+	}
+	for _, tt := range tests {
+		if got := parseForwardedFor(tt.in); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("parseForwardedFor(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestParseIPNets(t *testing.T) {
+	nets, err := parseIPNets([]string{"10.0.0.0/8", "192.0.2.1", "::1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for ip, want := range map[string]bool{
+		"10.1.2.3":  true,
+		"192.0.2.1": true,
+		"192.0.2.2": false,
+		"::1":       true,
+		"::2":       false,
+	} {
+		if got := nets.contains(parseIP(ip)); got != want {
+			t.Errorf("contains(%s) = %v, want %v", ip, got, want)
+		}
+	}
+	if _, err := parseIPNets([]string{"10.0.0.0/33"}); err == nil {
+		t.Error("неверная маска принята")
+	}
+}
+
+func TestClientIPWithoutMiddleware(t *testing.T) {
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+	r.RemoteAddr = "203.0.113.7:5000"
+	r.Header.Set("X-Forwarded-For", "198.51.100.1")
+	if got := clientIP(r); got != "203.0.113.7" {
+		t.Errorf("clientIP = %q", got)
+	}
+
+	res, err := newIPResolver([]string{"203.0.113.0/24"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got string
+	realIP(res)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = clientIP(r)
+	})).ServeHTTP(httptest.NewRecorder(), r)
+	if got != "198.51.100.1" {
+		t.Errorf("clientIP после realIP = %q", got)
+	}
+}
@@ -0,0 +1,657 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"net/url"
+	"os"
+	"os/signal"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"syscall"
+	"time"
+)
+
+// ==== Конфигурация ====
+//
+// Слои (каждый следующий перекрывает предыдущий):
+//
+//	1. константы в main.go (дефолты)
+//	2. JSON файл: -config path или API_CONFIG
+//	3. переменные окружения API_<KEY>  (API_RATE_LIMIT_MAX=500)
+//	4. флаги командной строки -<key>    (-rate-limit-max=500)
+//
+// Ключи в файле совпадают с выводом -print-config, поэтому его можно
+// использовать как шаблон конфига. SIGHUP перечитывает все слои и
+// применяет rate limit и CORS/Origin без перезапуска.
+
+const configEnvPrefix = "API_"
+
+type Config struct {
+	Addr                     string
+	AllowedOrigins           []string
+	TrustedProxies           []string
+	MaxHeaderBytes           int
+	MaxBodyBytes             int64
+	ReadHeaderTimeout        time.Duration
+	ReadBodyTimeout          time.Duration
+	WriteTimeout             time.Duration
+	IdleTimeout              time.Duration
+	ShutdownTimeout          time.Duration
+	ShutdownDrainDelay       time.Duration
+	HealthTimeout            time.Duration
+	RateLimitMax             int
+	RateLimitWindow          time.Duration
+	RateLimitAlgorithm       string
+	RateLimitRoutes          map[string]RateLimitPolicy
+	RateLimitJanitorInterval time.Duration
+	SessionStore             string
+	SessionDir               string
+	SessionTTL               time.Duration
+	SessionGCInterval        time.Duration
+	UsersFile                string
+	UploadDir                string
+	UploadQuota              int64
+	MaxUploadMB              int64
+	MetricsAllowed           []string
+	JWTAlg                   string
+	JWTSecret                string
+	JWTKeyFile               string
+	JWTIssuer                string
+	JWTAudience              string
+	JWTAccessTTL             time.Duration
+	JWTRefreshTTL            time.Duration
+	TLSCertFile              string
+	TLSKeyFile               string
+	TLSSelfSigned            bool
+	TLSRedirectAddr          string
+	HSTSMaxAge               time.Duration
+}
+
+// tlsEnabled — сервер сам терминирует TLS (иначе это делает Nginx).
+func (c Config) tlsEnabled() bool { return c.TLSCertFile != "" || c.TLSSelfSigned }
+
+// defaultConfig — конфиг из констант, нижний слой.
+func defaultConfig() Config {
+	return Config{
+		Addr:                     APIAddr,
+		AllowedOrigins:           splitCSV(AllowedOrigins),
+		TrustedProxies:           splitCSV(TrustedProxies),
+		MaxHeaderBytes:           MaxHeaderBytes,
+		MaxBodyBytes:             MaxBodyBytes,
+		ReadHeaderTimeout:        ReadHeaderTimeout,
+		ReadBodyTimeout:          ReadBodyTimeout,
+		WriteTimeout:             WriteTimeout,
+		IdleTimeout:              IdleTimeout,
+		ShutdownTimeout:          ShutdownTimeout,
+		ShutdownDrainDelay:       ShutdownDrainDelay,
+		HealthTimeout:            HealthTimeout,
+		RateLimitMax:             RateLimitMaxRequests,
+		RateLimitWindow:          RateLimitWindow,
+		RateLimitAlgorithm:       RateLimitAlgorithm,
+		RateLimitRoutes:          cloneRoutes(RateLimitRoutes),
+		RateLimitJanitorInterval: RateLimitJanitor,
+		SessionStore:             SessionStoreKind,
+		SessionDir:               SessionDir,
+		SessionTTL:               SessionMaxAge * time.Second,
+		SessionGCInterval:        SessionGCInterval,
+		UsersFile:                UsersFile,
+		UploadDir:                UploadDir,
+		UploadQuota:              UploadQuotaPerUser,
+		MaxUploadMB:              MaxUploadFileMB,
+		MetricsAllowed:           splitCSV(MetricsAllowed),
+		JWTAlg:                   JWTAlgorithm,
+		JWTIssuer:                JWTIssuer,
+		JWTAudience:              JWTAudience,
+		JWTAccessTTL:             JWTAccessTTL,
+		JWTRefreshTTL:            JWTRefreshTTL,
+		HSTSMaxAge:               HSTSMaxAge,
+	}
+}
+
+// ==== Описание настроек ====
+
+// setting — одна настройка: ключ в файле, env и флаг выводятся из key.
+type setting struct {
+	key    string // rate_limit_max → API_RATE_LIMIT_MAX, -rate-limit-max
+	usage  string
+	secret bool // маскируется в -print-config
+	isBool bool // флаг без значения: -tls-self-signed
+	get    func(*Config) any
+	set    func(*Config, string) error
+}
+
+func (s setting) env() string  { return configEnvPrefix + strings.ToUpper(s.key) }
+func (s setting) flag() string { return strings.ReplaceAll(s.key, "_", "-") }
+
+func strSetting(key, usage string, f func(*Config) *string) setting {
+	return setting{key: key, usage: usage,
+		get: func(c *Config) any { return *f(c) },
+		set: func(c *Config, v string) error { *f(c) = v; return nil },
+	}
+}
+
+// secretSetting — строка, которая не выводится в -print-config.
+func secretSetting(key, usage string, f func(*Config) *string) setting {
+	s := strSetting(key, usage, f)
+	s.secret = true
+	return s
+}
+
+func csvSetting(key, usage string, f func(*Config) *[]string) setting {
+	return setting{key: key, usage: usage,
+		get: func(c *Config) any { return *f(c) },
+		set: func(c *Config, v string) error { *f(c) = splitCSV(v); return nil },
+	}
+}
+
+func intSetting(key, usage string, f func(*Config) *int) setting {
+	return setting{key: key, usage: usage,
+		get: func(c *Config) any { return *f(c) },
+		set: func(c *Config, v string) error {
+			n, err := strconv.Atoi(v)
+			if err != nil {
+				return err
+			}
+			*f(c) = n
+			return nil
+		},
+	}
+}
+
+func int64Setting(key, usage string, f func(*Config) *int64) setting {
+	return setting{key: key, usage: usage,
+		get: func(c *Config) any { return *f(c) },
+		set: func(c *Config, v string) error {
+			n, err := strconv.ParseInt(v, 10, 64)
+			if err != nil {
+				return err
+			}
+			*f(c) = n
+			return nil
+		},
+	}
+}
+
+func boolSetting(key, usage string, f func(*Config) *bool) setting {
+	return setting{key: key, usage: usage, isBool: true,
+		get: func(c *Config) any { return *f(c) },
+		set: func(c *Config, v string) error {
+			b, err := strconv.ParseBool(v)
+			if err != nil {
+				return err
+			}
+			*f(c) = b
+			return nil
+		},
+	}
+}
+
+func durSetting(key, usage string, f func(*Config) *time.Duration) setting {
+	return setting{key: key, usage: usage,
+		get: func(c *Config) any { return f(c).String() },
+		set: func(c *Config, v string) error {
+			d, err := time.ParseDuration(v)
+			if err != nil {
+				return err
+			}
+			*f(c) = d
+			return nil
+		},
+	}
+}
+
+var settings = []setting{
+	strSetting("addr", "адрес HTTP сервера", func(c *Config) *string { return &c.Addr }),
+	csvSetting("allowed_origins", "разрешённые Origin (CSV), перезагружается по SIGHUP", func(c *Config) *[]string { return &c.AllowedOrigins }),
+	csvSetting("trusted_proxies", "CIDR доверенных прокси (CSV)", func(c *Config) *[]string { return &c.TrustedProxies }),
+	intSetting("max_header_bytes", "лимит заголовков", func(c *Config) *int { return &c.MaxHeaderBytes }),
+	int64Setting("max_body_bytes", "лимит тела запроса", func(c *Config) *int64 { return &c.MaxBodyBytes }),
+	durSetting("read_header_timeout", "таймаут чтения заголовков", func(c *Config) *time.Duration { return &c.ReadHeaderTimeout }),
+	durSetting("read_body_timeout", "таймаут чтения тела", func(c *Config) *time.Duration { return &c.ReadBodyTimeout }),
+	durSetting("write_timeout", "таймаут записи ответа", func(c *Config) *time.Duration { return &c.WriteTimeout }),
+	durSetting("idle_timeout", "таймаут keep-alive", func(c *Config) *time.Duration { return &c.IdleTimeout }),
+	durSetting("shutdown_timeout", "таймаут graceful shutdown", func(c *Config) *time.Duration { return &c.ShutdownTimeout }),
+	durSetting("shutdown_drain_delay", "пауза между /readyz 503 и Shutdown (не меньше периода проб LB)", func(c *Config) *time.Duration { return &c.ShutdownDrainDelay }),
+	intSetting("rate_limit_max", "запросов за окно на IP, перезагружается по SIGHUP", func(c *Config) *int { return &c.RateLimitMax }),
+	durSetting("health_timeout", "таймаут одной проверки /readyz", func(c *Config) *time.Duration { return &c.HealthTimeout }),
+	durSetting("rate_limit_window", "окно rate limit", func(c *Config) *time.Duration { return &c.RateLimitWindow }),
+	strSetting("rate_limit_algorithm", "token-bucket | sliding-window", func(c *Config) *string { return &c.RateLimitAlgorithm }),
+	{
+		key:   "rate_limit_routes",
+		usage: `лимиты маршрутов: "/api/login=10/1m,/healthz=600/1m/token-bucket"`,
+		get:   func(c *Config) any { return routesToJSON(c.RateLimitRoutes) },
+		set: func(c *Config, v string) error {
+			routes, err := parseRoutes(v)
+			if err != nil {
+				return err
+			}
+			c.RateLimitRoutes = routes
+			return nil
+		},
+	},
+	durSetting("rate_limit_janitor_interval", "как часто чистить неактивные IP", func(c *Config) *time.Duration { return &c.RateLimitJanitorInterval }),
+	strSetting("session_store", "memory | file", func(c *Config) *string { return &c.SessionStore }),
+	strSetting("session_dir", "каталог file-хранилища сессий", func(c *Config) *string { return &c.SessionDir }),
+	durSetting("session_ttl", "время жизни сессии", func(c *Config) *time.Duration { return &c.SessionTTL }),
+	durSetting("session_gc_interval", "как часто чистить истёкшие сессии", func(c *Config) *time.Duration { return &c.SessionGCInterval }),
+	strSetting("users_file", "JSON файл пользователей", func(c *Config) *string { return &c.UsersFile }),
+	strSetting("upload_dir", "каталог загрузок", func(c *Config) *string { return &c.UploadDir }),
+	int64Setting("upload_quota", "квота загрузок на пользователя (байт)", func(c *Config) *int64 { return &c.UploadQuota }),
+	csvSetting("metrics_allowed", "CIDR, кому доступен /metrics (CSV)", func(c *Config) *[]string { return &c.MetricsAllowed }),
+	strSetting("jwt_alg", `Bearer режим: "" (выключен) | HS256 | EdDSA`, func(c *Config) *string { return &c.JWTAlg }),
+	secretSetting("jwt_secret", "ключ HS256, минимум 32 байта", func(c *Config) *string { return &c.JWTSecret }),
+	strSetting("jwt_key_file", "Ed25519 ключ PKCS#8 PEM для EdDSA (пусто — временный)", func(c *Config) *string { return &c.JWTKeyFile }),
+	strSetting("jwt_issuer", "iss в токенах", func(c *Config) *string { return &c.JWTIssuer }),
+	strSetting("jwt_audience", "aud в токенах", func(c *Config) *string { return &c.JWTAudience }),
+	durSetting("jwt_access_ttl", "время жизни access token", func(c *Config) *time.Duration { return &c.JWTAccessTTL }),
+	durSetting("jwt_refresh_ttl", "время жизни refresh token", func(c *Config) *time.Duration { return &c.JWTRefreshTTL }),
+	strSetting("tls_cert_file", "сертификат PEM: сервер сам слушает HTTPS (перечитывается по SIGHUP)", func(c *Config) *string { return &c.TLSCertFile }),
+	strSetting("tls_key_file", "ключ PEM к tls_cert_file", func(c *Config) *string { return &c.TLSKeyFile }),
+	boolSetting("tls_self_signed", "HTTPS с временным самоподписанным сертификатом (dev)", func(c *Config) *bool { return &c.TLSSelfSigned }),
+	strSetting("tls_redirect_addr", `HTTP listener с редиректом на HTTPS, например ":80"`, func(c *Config) *string { return &c.TLSRedirectAddr }),
+	durSetting("hsts_max_age", "Strict-Transport-Security max-age в TLS режиме (0 — без HSTS)", func(c *Config) *time.Duration { return &c.HSTSMaxAge }),
+	int64Setting("max_upload_mb", "лимит одного файла (MB)", func(c *Config) *int64 { return &c.MaxUploadMB }),
+}
+
+// ==== Источники ====
+
+// configSource — откуда собирать конфиг; хранится, чтобы перечитать по SIGHUP.
+type configSource struct {
+	path  string            // JSON файл
+	flags map[string]string // key → значение из командной строки
+	print bool              // -print-config
+}
+
+// parseConfigFlags разбирает флаги сервера.
+func parseConfigFlags(args []string) (*configSource, error) {
+	src := &configSource{path: os.Getenv(configEnvPrefix + "CONFIG"), flags: map[string]string{}}
+
+	fs := flag.NewFlagSet("api-server", flag.ContinueOnError)
+	fs.StringVar(&src.path, "config", src.path, "JSON файл конфигурации (или "+configEnvPrefix+"CONFIG)")
+	fs.BoolVar(&src.print, "print-config", false, "вывести итоговый конфиг (секреты скрыты) и выйти")
+	for _, s := range settings {
+		key := s.key
+		set := func(v string) error {
+			src.flags[key] = v
+			return nil
+		}
+		if s.isBool {
+			fs.BoolFunc(s.flag(), s.usage+" (env "+s.env()+")", set)
+		} else {
+			fs.Func(s.flag(), s.usage+" (env "+s.env()+")", set)
+		}
+	}
+	if err := fs.Parse(args); err != nil {
+		return nil, err
+	}
+	if fs.NArg() > 0 {
+		return nil, fmt.Errorf("unexpected arguments: %v", fs.Args())
+	}
+	return src, nil
+}
+
+// load собирает конфиг по слоям и валидирует.
+func (src *configSource) load() (Config, error) {
+	cfg := defaultConfig()
+
+	if src.path != "" {
+		if err := applyConfigFile(&cfg, src.path); err != nil {
+			return Config{}, err
+		}
+	}
+
+	for _, s := range settings {
+		if v, ok := os.LookupEnv(s.env()); ok {
+			if err := s.set(&cfg, strings.TrimSpace(v)); err != nil {
+				return Config{}, fmt.Errorf("env %s: %w", s.env(), err)
+			}
+		}
+	}
+
+	for _, s := range settings {
+		if v, ok := src.flags[s.key]; ok {
+			if err := s.set(&cfg, v); err != nil {
+				return Config{}, fmt.Errorf("flag -%s: %w", s.flag(), err)
+			}
+		}
+	}
+
+	if err := cfg.validate(); err != nil {
+		return Config{}, err
+	}
+	return cfg, nil
+}
+
+func applyConfigFile(cfg *Config, path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("config file: %w", err)
+	}
+	var raw map[string]json.RawMessage
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return fmt.Errorf("config file %s: %w", path, err)
+	}
+
+	known := make(map[string]setting, len(settings))
+	for _, s := range settings {
+		known[s.key] = s
+	}
+	for key, val := range raw {
+		s, ok := known[key]
+		if !ok {
+			return fmt.Errorf("config file %s: unknown key %q", path, key)
+		}
+		if s.key == "rate_limit_routes" && len(val) > 0 && val[0] == '{' {
+			routes, err := routesFromJSON(val)
+			if err != nil {
+				return fmt.Errorf("config file %s: %s: %w", path, key, err)
+			}
+			cfg.RateLimitRoutes = routes
+			continue
+		}
+		str, err := jsonScalar(val)
+		if err != nil {
+			return fmt.Errorf("config file %s: %s: %w", path, key, err)
+		}
+		if err := s.set(cfg, str); err != nil {
+			return fmt.Errorf("config file %s: %s: %w", path, key, err)
+		}
+	}
+	return nil
+}
+
+// jsonScalar превращает JSON значение в строку для setting.set:
+// "x" → x, 42 → 42, ["a","b"] → a,b
+func jsonScalar(raw json.RawMessage) (string, error) {
+	var s string
+	if err := json.Unmarshal(raw, &s); err == nil {
+		return s, nil
+	}
+	var list []string
+	if err := json.Unmarshal(raw, &list); err == nil {
+		return strings.Join(list, ","), nil
+	}
+	var n json.Number
+	if err := json.Unmarshal(raw, &n); err == nil {
+		return n.String(), nil
+	}
+	return "", errors.New("expected string, number or array of strings")
+}
+
+// ==== Маршруты rate limit ====
+
+type routePolicyJSON struct {
+	Algorithm string `json:"algorithm,omitempty"`
+	Limit     int    `json:"limit"`
+	Window    string `json:"window"`
+}
+
+func routesFromJSON(raw json.RawMessage) (map[string]RateLimitPolicy, error) {
+	var in map[string]routePolicyJSON
+	if err := json.Unmarshal(raw, &in); err != nil {
+		return nil, err
+	}
+	out := make(map[string]RateLimitPolicy, len(in))
+	for path, p := range in {
+		w, err := time.ParseDuration(p.Window)
+		if err != nil {
+			return nil, fmt.Errorf("%s: window: %w", path, err)
+		}
+		out[path] = RateLimitPolicy{Algorithm: p.Algorithm, Limit: p.Limit, Window: w}
+	}
+	return out, nil
+}
+
+func routesToJSON(routes map[string]RateLimitPolicy) map[string]routePolicyJSON {
+	out := make(map[string]routePolicyJSON, len(routes))
+	for path, p := range routes {
+		out[path] = routePolicyJSON{Algorithm: p.Algorithm, Limit: p.Limit, Window: p.Window.String()}
+	}
+	return out
+}
+
+// parseRoutes: "/api/login=10/1m,/healthz=600/1m/token-bucket"
+func parseRoutes(s string) (map[string]RateLimitPolicy, error) {
+	out := make(map[string]RateLimitPolicy)
+	for _, item := range splitCSV(s) {
+		path, spec, ok := strings.Cut(item, "=")
+		if !ok {
+			return nil, fmt.Errorf("route %q: expected path=limit/window[/algorithm]", item)
+		}
+		parts := strings.Split(spec, "/")
+		if len(parts) < 2 || len(parts) > 3 {
+			return nil, fmt.Errorf("route %q: expected path=limit/window[/algorithm]", item)
+		}
+		limit, err := strconv.Atoi(parts[0])
+		if err != nil {
+			return nil, fmt.Errorf("route %q: limit: %w", item, err)
+		}
+		window, err := time.ParseDuration(parts[1])
+		if err != nil {
+			return nil, fmt.Errorf("route %q: window: %w", item, err)
+		}
+		p := RateLimitPolicy{Limit: limit, Window: window}
+		if len(parts) == 3 {
+			p.Algorithm = parts[2]
+		}
+		out[strings.TrimSpace(path)] = p
+	}
+	return out, nil
+}
+
+func cloneRoutes(in map[string]RateLimitPolicy) map[string]RateLimitPolicy {
+	out := make(map[string]RateLimitPolicy, len(in))
+	for k, v := range in {
+		out[k] = v
+	}
+	return out
+}
+
+// ==== Валидация ====
+
+func (c Config) validate() error {
+	var errs []error
+	check := func(ok bool, format string, args ...any) {
+		if !ok {
+			errs = append(errs, fmt.Errorf(format, args...))
+		}
+	}
+
+	check(c.Addr != "", "addr: required")
+	check(len(c.AllowedOrigins) > 0, "allowed_origins: at least one origin required")
+	for _, o := range c.AllowedOrigins {
+		u, err := url.Parse(o)
+		check(err == nil && (u.Scheme == "https" || u.Scheme == "http") && u.Host != "" && u.Path == "",
+			"allowed_origins: %q must be scheme://host[:port]", o)
+	}
+	if _, err := parseIPNets(c.TrustedProxies); err != nil {
+		errs = append(errs, fmt.Errorf("trusted_proxies: %w", err))
+	}
+	if _, err := parseIPNets(c.MetricsAllowed); err != nil {
+		errs = append(errs, fmt.Errorf("metrics_allowed: %w", err))
+	}
+
+	check(c.MaxHeaderBytes > 0, "max_header_bytes: must be > 0")
+	check(c.MaxBodyBytes > 0, "max_body_bytes: must be > 0")
+	for name, d := range map[string]time.Duration{
+		"read_header_timeout":         c.ReadHeaderTimeout,
+		"read_body_timeout":           c.ReadBodyTimeout,
+		"write_timeout":               c.WriteTimeout,
+		"idle_timeout":                c.IdleTimeout,
+		"shutdown_timeout":            c.ShutdownTimeout,
+		"health_timeout":              c.HealthTimeout,
+		"rate_limit_window":           c.RateLimitWindow,
+		"rate_limit_janitor_interval": c.RateLimitJanitorInterval,
+		"session_ttl":                 c.SessionTTL,
+		"session_gc_interval":         c.SessionGCInterval,
+	} {
+		check(d > 0, "%s: must be > 0", name)
+	}
+	check(c.ShutdownDrainDelay >= 0, "shutdown_drain_delay: must be >= 0")
+
+	check(c.RateLimitMax > 0, "rate_limit_max: must be > 0")
+	check(c.RateLimitAlgorithm == AlgoTokenBucket || c.RateLimitAlgorithm == AlgoSlidingWindow,
+		"rate_limit_algorithm: unknown %q", c.RateLimitAlgorithm)
+	for path, p := range c.RateLimitRoutes {
+		check(strings.HasPrefix(path, "/"), "rate_limit_routes: %q must start with /", path)
+		check(p.Limit > 0 && p.Window > 0, "rate_limit_routes: %s: limit and window must be > 0", path)
+		check(p.Algorithm == "" || p.Algorithm == AlgoTokenBucket || p.Algorithm == AlgoSlidingWindow,
+			"rate_limit_routes: %s: unknown algorithm %q", path, p.Algorithm)
+	}
+
+	check(c.SessionStore == "memory" || c.SessionStore == "file", "session_store: unknown %q", c.SessionStore)
+	check(c.SessionStore != "file" || c.SessionDir != "", "session_dir: required for file store")
+	check(c.UsersFile != "", "users_file: required")
+	check(c.UploadDir != "", "upload_dir: required")
+	check(c.UploadQuota > 0, "upload_quota: must be > 0")
+	check(c.MaxUploadMB > 0, "max_upload_mb: must be > 0")
+
+	check((c.TLSCertFile == "") == (c.TLSKeyFile == ""), "tls_cert_file, tls_key_file: set both or neither")
+	check(!(c.TLSSelfSigned && c.TLSCertFile != ""), "tls_self_signed: conflicts with tls_cert_file")
+	check(c.TLSRedirectAddr == "" || c.tlsEnabled(), "tls_redirect_addr: requires TLS mode")
+	check(c.TLSRedirectAddr == "" || c.TLSRedirectAddr != c.Addr, "tls_redirect_addr: must differ from addr")
+	check(c.HSTSMaxAge >= 0, "hsts_max_age: must be >= 0")
+
+	switch c.JWTAlg {
+	case "":
+	case JWTAlgHS256:
+		check(len(c.JWTSecret) >= 32, "jwt_secret: at least 32 bytes required for HS256")
+	case JWTAlgEdDSA:
+		check(c.JWTSecret == "", "jwt_secret: not used with EdDSA, set jwt_key_file")
+	default:
+		errs = append(errs, fmt.Errorf("jwt_alg: unknown %q", c.JWTAlg))
+	}
+	if c.JWTAlg != "" {
+		check(c.JWTIssuer != "", "jwt_issuer: required")
+		check(c.JWTAudience != "", "jwt_audience: required")
+		check(c.JWTAccessTTL > 0 && c.JWTAccessTTL <= time.Hour, "jwt_access_ttl: must be in (0, 1h]")
+		check(c.JWTRefreshTTL > c.JWTAccessTTL, "jwt_refresh_ttl: must be longer than jwt_access_ttl")
+	}
+
+	if len(errs) > 0 {
+		return fmt.Errorf("invalid config:\n%w", errors.Join(errs...))
+	}
+	return nil
+}
+
+// ==== -print-config ====
+
+func printConfig(w io.Writer, c Config) error {
+	out := make(map[string]any, len(settings))
+	for _, s := range settings {
+		v := s.get(&c)
+		if s.secret {
+			v = maskSecret(fmt.Sprint(v))
+		}
+		out[s.key] = v
+	}
+	// encoding/json выводит ключи map по алфавиту
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(out)
+}
+
+func maskSecret(v string) string {
+	if v == "" {
+		return ""
+	}
+	return "***"
+}
+
+// ==== Перезагрузка по SIGHUP ====
+
+// originPolicy — список разрешённых Origin, который можно заменить на лету.
+type originPolicy struct {
+	v atomic.Pointer[[]string]
+}
+
+func newOriginPolicy(origins []string) *originPolicy {
+	p := &originPolicy{}
+	p.set(origins)
+	return p
+}
+
+func (p *originPolicy) set(origins []string) { p.v.Store(&origins) }
+func (p *originPolicy) list() []string       { return *p.v.Load() }
+
+func (p *originPolicy) contains(origin string) bool {
+	for _, o := range p.list() {
+		if o == origin {
+			return true
+		}
+	}
+	return false
+}
+
+// liveRateLimiter — routeRateLimiter, который можно заменить на лету.
+type liveRateLimiter struct {
+	v atomic.Pointer[routeRateLimiter]
+}
+
+func newLiveRateLimiter(rl *routeRateLimiter) *liveRateLimiter {
+	l := &liveRateLimiter{}
+	l.v.Store(rl)
+	return l
+}
+
+func (l *liveRateLimiter) forPath(path string) (string, RateLimiter) {
+	return l.v.Load().forPath(path)
+}
+
+// swap ставит новый лимитер. Счётчики начинаются заново; старый лимитер
+// только останавливает janitor — запросы в полёте дорабатывают на нём.
+func (l *liveRateLimiter) swap(rl *routeRateLimiter) {
+	l.v.Swap(rl).Close()
+}
+
+func (l *liveRateLimiter) Close() { l.v.Load().Close() }
+
+// watchReload перечитывает конфиг по SIGHUP. Применяются только rate limit
+// и CORS/Origin; остальное (адрес, таймауты, хранилища) требует рестарта.
+// Невалидный конфиг отклоняется целиком, старые настройки остаются.
+// TLS сертификат (certs != nil) перечитывается независимо от конфига.
+func watchReload(src *configSource, origins *originPolicy, rl *liveRateLimiter, certs *certReloader) {
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+	for range hup {
+		if certs != nil {
+			if err := certs.reload(); err != nil {
+				log.Printf("certificate reload failed, keeping old: %v", err)
+			} else if certs.certFile != "" {
+				log.Printf("certificate reloaded: %s", certs.certFile)
+			}
+		}
+
+		cfg, err := src.load()
+		if err != nil {
+			log.Printf("config reload rejected: %v", err)
+			continue
+		}
+		newRL, err := newRouteRateLimiter(cfg)
+		if err != nil {
+			log.Printf("config reload rejected: %v", err)
+			continue
+		}
+		rl.swap(newRL)
+		origins.set(cfg.AllowedOrigins)
+		log.Printf("config reloaded: origins=%v rate_limit=%d/%v routes=%d",
+			cfg.AllowedOrigins, cfg.RateLimitMax, cfg.RateLimitWindow, len(cfg.RateLimitRoutes))
+	}
+}
+
+func splitCSV(s string) []string {
+	var out []string
+	for _, p := range strings.Split(s, ",") {
+		p = strings.TrimSpace(p)
+		if p != "" {
+			out = append(out, p)
+		}
+	}
+	return out
+}
@@ -0,0 +1,7 @@
+module go-json-srv
+
+go 1.25.1
+
+require golang.org/x/crypto v0.43.0
+
+require golang.org/x/sys v0.37.0 // indirect
@@ -0,0 +1,203 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// ==== Health checks ====
+//
+//	/livez  — процесс жив и обслуживает HTTP (зависимости не проверяются,
+//	          иначе оркестратор перезапустит сервер из-за упавшего диска)
+//	/readyz — можно слать трафик: все зарегистрированные проверки прошли
+//	          и сервер не останавливается
+//
+// Версия задаётся при сборке: go build -ldflags "-X main.version=1.4.2"
+
+var version = "dev"
+
+// HealthCheck — проверка компонента; должна уважать ctx (таймаут).
+type HealthCheck func(ctx context.Context) error
+
+type namedCheck struct {
+	name    string
+	timeout time.Duration
+	check   HealthCheck
+}
+
+type healthRegistry struct {
+	mu       sync.RWMutex
+	checks   []namedCheck
+	timeout  time.Duration // по умолчанию для register
+	started  time.Time
+	draining atomic.Bool
+}
+
+func newHealthRegistry(timeout time.Duration) *healthRegistry {
+	return &healthRegistry{timeout: timeout, started: time.Now()}
+}
+
+// register добавляет проверку с таймаутом по умолчанию.
+func (h *healthRegistry) register(name string, check HealthCheck) {
+	h.registerTimeout(name, h.timeout, check)
+}
+
+func (h *healthRegistry) registerTimeout(name string, timeout time.Duration, check HealthCheck) {
+	h.mu.Lock()
+	h.checks = append(h.checks, namedCheck{name: name, timeout: timeout, check: check})
+	h.mu.Unlock()
+}
+
+// drain — с этого момента /readyz отвечает 503 (вызывается по SIGTERM
+// до srv.Shutdown, чтобы Nginx/LB сняли трафик).
+func (h *healthRegistry) drain() { h.draining.Store(true) }
+
+type checkResult struct {
+	Status    string  `json:"status"` // ok | fail
+	LatencyMS float64 `json:"latency_ms"`
+	Error     string  `json:"error,omitempty"`
+}
+
+type healthReport struct {
+	Status  string                 `json:"status"` // ok | fail | draining
+	Version string                 `json:"version"`
+	Uptime  string                 `json:"uptime"`
+	Checks  map[string]checkResult `json:"checks,omitempty"`
+}
+
+// run выполняет все проверки параллельно, каждую со своим таймаутом.
+func (h *healthRegistry) run(ctx context.Context) (map[string]checkResult, bool) {
+	h.mu.RLock()
+	checks := append([]namedCheck(nil), h.checks...)
+	h.mu.RUnlock()
+
+	results := make(map[string]checkResult, len(checks))
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	healthy := true
+
+	for _, c := range checks {
+		wg.Add(1)
+		go func(c namedCheck) {
+			defer wg.Done()
+			res := runCheck(ctx, c)
+			mu.Lock()
+			results[c.name] = res
+			if res.Status != "ok" {
+				healthy = false
+			}
+			mu.Unlock()
+		}(c)
+	}
+	wg.Wait()
+	return results, healthy
+}
+
+// runCheck не ждёт зависшую проверку дольше таймаута, даже если она
+// игнорирует ctx: горутина доработает сама, результат отбрасывается.
+func runCheck(ctx context.Context, c namedCheck) checkResult {
+	ctx, cancel := context.WithTimeout(ctx, c.timeout)
+	defer cancel()
+
+	start := time.Now()
+	done := make(chan error, 1)
+	go func() {
+		defer func() {
+			if rec := recover(); rec != nil {
+				done <- fmt.Errorf("panic: %v", rec)
+			}
+		}()
+		done <- c.check(ctx)
+	}()
+
+	var err error
+	select {
+	case err = <-done:
+	case <-ctx.Done():
+		err = fmt.Errorf("timeout after %v", c.timeout)
+	}
+
+	res := checkResult{Status: "ok", LatencyMS: float64(time.Since(start).Microseconds()) / 1000}
+	if err != nil {
+		res.Status = "fail"
+		res.Error = err.Error()
+	}
+	return res
+}
+
+func (h *healthRegistry) report(status string, checks map[string]checkResult) healthReport {
+	return healthReport{
+		Status:  status,
+		Version: version,
+		Uptime:  time.Since(h.started).Truncate(time.Second).String(),
+		Checks:  checks,
+	}
+}
+
+func livezHandler(h *healthRegistry) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet && r.Method != http.MethodHead {
+			writeJSON(w, http.StatusMethodNotAllowed, "method not allowed")
+			return
+		}
+		w.Header().Set("Cache-Control", "no-store")
+		writeJSON(w, http.StatusOK, h.report("ok", nil))
+	}
+}
+
+func readyzHandler(h *healthRegistry) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet && r.Method != http.MethodHead {
+			writeJSON(w, http.StatusMethodNotAllowed, "method not allowed")
+			return
+		}
+		w.Header().Set("Cache-Control", "no-store")
+
+		// При остановке проверки не гоняем: ответ нужен быстро и однозначно
+		if h.draining.Load() {
+			writeHealth(w, http.StatusServiceUnavailable, h.report("draining", nil))
+			return
+		}
+
+		checks, ok := h.run(r.Context())
+		if !ok {
+			writeHealth(w, http.StatusServiceUnavailable, h.report("fail", checks))
+			return
+		}
+		writeJSON(w, http.StatusOK, h.report("ok", checks))
+	}
+}
+
+// writeHealth — 503 с тем же телом, что и 200: writeJSON для ошибок
+// сворачивает data в строку, а LB/оператору нужен список проверок.
+func writeHealth(w http.ResponseWriter, status int, rep healthReport) {
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.WriteHeader(status)
+	resp := jsonResponse{Status: "error", Data: rep, Error: fmt.Sprintf("%d: %s", status, rep.Status)}
+	_ = encodeJSON(w, resp)
+}
+
+// dirWritable — каталог существует и в него можно писать (диск не
+// переполнен, не перемонтирован read-only).
+func dirWritable(dir string) error {
+	fi, err := os.Stat(dir)
+	if err != nil {
+		return err
+	}
+	if !fi.IsDir() {
+		return errors.New(dir + ": not a directory")
+	}
+	f, err := os.CreateTemp(dir, ".healthcheck-*")
+	if err != nil {
+		return err
+	}
+	name := f.Name()
+	f.Close()
+	return os.Remove(name)
+}
@@ -0,0 +1,478 @@
+package main
+
+import (
+	"context"
+	"crypto/ed25519"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ==== JWT (Bearer) ====
+//
+// Второй режим входа для не-браузерных клиентов (мобильные, CLI, сервисы):
+//
+//	POST /api/login {"username","password","mode":"bearer"}
+//	  → access_token (JWT, JWTAccessTTL) + refresh_token (случайный, JWTRefreshTTL)
+//	POST /api/token/refresh {"refresh_token"} → новая пара, старый refresh сгорает
+//	POST /api/token/revoke  {"refresh_token"} → отзыв всей цепочки
+//
+// Access token не хранится на сервере и живёт до exp — поэтому короткий.
+// Refresh token одноразовый: повторное предъявление уже использованного
+// токена значит, что его украли, и отзывается вся цепочка (family).
+// Реализация на stdlib: HS256 (crypto/hmac) и EdDSA (crypto/ed25519).
+
+const (
+	JWTAlgHS256 = "HS256"
+	JWTAlgEdDSA = "EdDSA"
+
+	jwtLeeway = 30 * time.Second // допуск расхождения часов для exp/nbf
+)
+
+var (
+	ErrInvalidToken   = errors.New("invalid token")
+	ErrTokenExpired   = errors.New("token expired")
+	ErrInvalidRefresh = errors.New("invalid refresh token")
+	ErrRefreshReused  = errors.New("refresh token reused")
+)
+
+// Claims — полезная нагрузка access token.
+type Claims struct {
+	Issuer    string `json:"iss"`
+	Audience  string `json:"aud"`
+	Subject   string `json:"sub"` // username
+	IssuedAt  int64  `json:"iat"`
+	NotBefore int64  `json:"nbf"`
+	ExpiresAt int64  `json:"exp"`
+	ID        string `json:"jti"`
+	SessionID string `json:"sid"` // family refresh токенов = "сессия" входа
+}
+
+type jwtHeader struct {
+	Alg string `json:"alg"`
+	Typ string `json:"typ"`
+}
+
+var b64 = base64.RawURLEncoding
+
+// jwtIssuer подписывает и проверяет access token одним алгоритмом.
+// Алгоритм берётся из конфига, а не из заголовка токена: "alg":"none" и
+// подмена EdDSA → HS256 отклоняются.
+type jwtIssuer struct {
+	alg       string
+	secret    []byte             // HS256
+	priv      ed25519.PrivateKey // EdDSA
+	pub       ed25519.PublicKey
+	issuer    string
+	audience  string
+	accessTTL time.Duration
+}
+
+func newJWTIssuer(cfg Config) (*jwtIssuer, error) {
+	j := &jwtIssuer{alg: cfg.JWTAlg, issuer: cfg.JWTIssuer, audience: cfg.JWTAudience, accessTTL: cfg.JWTAccessTTL}
+	switch cfg.JWTAlg {
+	case JWTAlgHS256:
+		j.secret = []byte(cfg.JWTSecret)
+	case JWTAlgEdDSA:
+		if cfg.JWTKeyFile == "" {
+			// Dev-режим: ключ живёт до рестарта, все токены после него недействительны
+			_, priv, err := ed25519.GenerateKey(rand.Reader)
+			if err != nil {
+				return nil, err
+			}
+			slog.Warn("jwt: jwt_key_file not set, using ephemeral Ed25519 key")
+			j.priv = priv
+		} else {
+			priv, err := loadEd25519Key(cfg.JWTKeyFile)
+			if err != nil {
+				return nil, err
+			}
+			j.priv = priv
+		}
+		j.pub = j.priv.Public().(ed25519.PublicKey)
+	default:
+		return nil, fmt.Errorf("jwt: unknown algorithm %q", cfg.JWTAlg)
+	}
+	return j, nil
+}
+
+// loadEd25519Key читает PKCS#8 PEM (openssl genpkey -algorithm ed25519).
+func loadEd25519Key(path string) (ed25519.PrivateKey, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("jwt key: %w", err)
+	}
+	block, _ := pem.Decode(data)
+	if block == nil || block.Type != "PRIVATE KEY" {
+		return nil, fmt.Errorf("jwt key %s: expected PEM \"PRIVATE KEY\"", path)
+	}
+	key, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("jwt key %s: %w", path, err)
+	}
+	priv, ok := key.(ed25519.PrivateKey)
+	if !ok {
+		return nil, fmt.Errorf("jwt key %s: not an Ed25519 key", path)
+	}
+	return priv, nil
+}
+
+func (j *jwtIssuer) signature(signingInput []byte) []byte {
+	if j.alg == JWTAlgHS256 {
+		mac := hmac.New(sha256.New, j.secret)
+		mac.Write(signingInput)
+		return mac.Sum(nil)
+	}
+	return ed25519.Sign(j.priv, signingInput)
+}
+
+// issue создаёт access token для пользователя в рамках сессии sid.
+func (j *jwtIssuer) issue(username, sid string) (string, *Claims, error) {
+	jti, err := randomToken(16)
+	if err != nil {
+		return "", nil, err
+	}
+	now := time.Now()
+	claims := &Claims{
+		Issuer:    j.issuer,
+		Audience:  j.audience,
+		Subject:   username,
+		IssuedAt:  now.Unix(),
+		NotBefore: now.Unix(),
+		ExpiresAt: now.Add(j.accessTTL).Unix(),
+		ID:        jti,
+		SessionID: sid,
+	}
+
+	header, _ := json.Marshal(jwtHeader{Alg: j.alg, Typ: "JWT"})
+	payload, err := json.Marshal(claims)
+	if err != nil {
+		return "", nil, err
+	}
+	signingInput := b64.EncodeToString(header) + "." + b64.EncodeToString(payload)
+	return signingInput + "." + b64.EncodeToString(j.signature([]byte(signingInput))), claims, nil
+}
+
+// verify проверяет подпись, алгоритм, издателя, аудиторию и сроки.
+func (j *jwtIssuer) verify(token string) (*Claims, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return nil, ErrInvalidToken
+	}
+
+	headerJSON, err := b64.DecodeString(parts[0])
+	if err != nil {
+		return nil, ErrInvalidToken
+	}
+	var header jwtHeader
+	if err := json.Unmarshal(headerJSON, &header); err != nil || header.Alg != j.alg {
+		return nil, ErrInvalidToken
+	}
+
+	sig, err := b64.DecodeString(parts[2])
+	if err != nil {
+		return nil, ErrInvalidToken
+	}
+	signingInput := []byte(parts[0] + "." + parts[1])
+	switch j.alg {
+	case JWTAlgHS256:
+		if !hmac.Equal(sig, j.signature(signingInput)) {
+			return nil, ErrInvalidToken
+		}
+	case JWTAlgEdDSA:
+		if !ed25519.Verify(j.pub, signingInput, sig) {
+			return nil, ErrInvalidToken
+		}
+	}
+
+	payload, err := b64.DecodeString(parts[1])
+	if err != nil {
+		return nil, ErrInvalidToken
+	}
+	var claims Claims
+	if err := json.Unmarshal(payload, &claims); err != nil {
+		return nil, ErrInvalidToken
+	}
+	if claims.Issuer != j.issuer || claims.Audience != j.audience || claims.Subject == "" || claims.ExpiresAt == 0 {
+		return nil, ErrInvalidToken
+	}
+
+	now := time.Now()
+	if now.After(time.Unix(claims.ExpiresAt, 0).Add(jwtLeeway)) {
+		return nil, ErrTokenExpired
+	}
+	if now.Add(jwtLeeway).Before(time.Unix(claims.NotBefore, 0)) {
+		return nil, ErrInvalidToken
+	}
+	return &claims, nil
+}
+
+// ==== Refresh tokens ====
+
+type refreshToken struct {
+	Username  string
+	Family    string // = Claims.SessionID
+	ExpiresAt time.Time
+	Used      bool
+}
+
+// refreshStore хранит refresh токены в памяти (по sha256, не в открытом
+// виде). После рестарта клиенты входят заново.
+type refreshStore struct {
+	mu     sync.Mutex
+	tokens map[string]*refreshToken // sha256(token) → запись
+	ttl    time.Duration
+	stop   chan struct{}
+	once   sync.Once
+}
+
+func newRefreshStore(ttl, gcInterval time.Duration) *refreshStore {
+	s := &refreshStore{tokens: make(map[string]*refreshToken), ttl: ttl, stop: make(chan struct{})}
+	go runJanitor(gcInterval, s.stop, s.gc)
+	return s
+}
+
+func hashRefresh(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// issue выдаёт новый refresh token в цепочке family ("" — новая цепочка).
+func (s *refreshStore) issue(username, family string) (token, fam string, err error) {
+	if token, err = randomToken(32); err != nil {
+		return "", "", err
+	}
+	if family == "" {
+		if family, err = randomToken(16); err != nil {
+			return "", "", err
+		}
+	}
+	s.mu.Lock()
+	s.tokens[hashRefresh(token)] = &refreshToken{
+		Username:  username,
+		Family:    family,
+		ExpiresAt: time.Now().Add(s.ttl),
+	}
+	s.mu.Unlock()
+	return token, family, nil
+}
+
+// use помечает токен использованным и возвращает его запись. Повторное
+// использование отзывает всю цепочку.
+func (s *refreshStore) use(token string) (*refreshToken, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	rt, ok := s.tokens[hashRefresh(token)]
+	if !ok || time.Now().After(rt.ExpiresAt) {
+		return nil, ErrInvalidRefresh
+	}
+	if rt.Used {
+		s.revokeFamilyLocked(rt.Family)
+		return nil, ErrRefreshReused
+	}
+	rt.Used = true
+	return rt, nil
+}
+
+// familyOf — цепочка токена (для отзыва); "" если токен неизвестен.
+func (s *refreshStore) familyOf(token string) string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if rt, ok := s.tokens[hashRefresh(token)]; ok {
+		return rt.Family
+	}
+	return ""
+}
+
+func (s *refreshStore) revokeFamily(family string) {
+	s.mu.Lock()
+	s.revokeFamilyLocked(family)
+	s.mu.Unlock()
+}
+
+func (s *refreshStore) revokeFamilyLocked(family string) {
+	for h, rt := range s.tokens {
+		if rt.Family == family {
+			delete(s.tokens, h)
+		}
+	}
+}
+
+// gc удаляет истёкшие токены (использованные держим до exp — для
+// обнаружения повторного предъявления).
+func (s *refreshStore) gc() {
+	now := time.Now()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for h, rt := range s.tokens {
+		if now.After(rt.ExpiresAt) {
+			delete(s.tokens, h)
+		}
+	}
+}
+
+func (s *refreshStore) Close() {
+	s.once.Do(func() { close(s.stop) })
+}
+
+// ==== Выдача пары токенов ====
+
+// bearerAuthn — всё, что нужно для режима Bearer; nil, если JWT выключен.
+type bearerAuthn struct {
+	jwt     *jwtIssuer
+	refresh *refreshStore
+}
+
+type tokenResponse struct {
+	TokenType        string `json:"token_type"`
+	AccessToken      string `json:"access_token"`
+	ExpiresIn        int    `json:"expires_in"`
+	RefreshToken     string `json:"refresh_token"`
+	RefreshExpiresIn int    `json:"refresh_expires_in"`
+}
+
+// issuePair выдаёт access + refresh в цепочке family ("" — новый вход).
+func (b *bearerAuthn) issuePair(username, family string) (*tokenResponse, error) {
+	refresh, family, err := b.refresh.issue(username, family)
+	if err != nil {
+		return nil, err
+	}
+	access, _, err := b.jwt.issue(username, family)
+	if err != nil {
+		return nil, err
+	}
+	return &tokenResponse{
+		TokenType:        "Bearer",
+		AccessToken:      access,
+		ExpiresIn:        int(b.jwt.accessTTL / time.Second),
+		RefreshToken:     refresh,
+		RefreshExpiresIn: int(b.refresh.ttl / time.Second),
+	}, nil
+}
+
+func decodeRefreshRequest(w http.ResponseWriter, r *http.Request) (string, bool) {
+	if r.Method != http.MethodPost {
+		writeJSON(w, http.StatusMethodNotAllowed, "method not allowed")
+		return "", false
+	}
+	var req struct {
+		RefreshToken string `json:"refresh_token"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.RefreshToken == "" {
+		writeJSON(w, http.StatusBadRequest, "refresh_token required")
+		return "", false
+	}
+	return req.RefreshToken, true
+}
+
+// refreshHandler — ротация: старый refresh сгорает, выдаётся новая пара.
+func refreshHandler(b *bearerAuthn, users UserStore) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		token, ok := decodeRefreshRequest(w, r)
+		if !ok {
+			return
+		}
+
+		rt, err := b.refresh.use(token)
+		if err != nil {
+			if errors.Is(err, ErrRefreshReused) {
+				slog.Warn("refresh token reuse, family revoked",
+					"request_id", requestIDFrom(r), "ip", clientIP(r))
+			}
+			writeJSON(w, http.StatusUnauthorized, "invalid refresh token")
+			return
+		}
+
+		// Заблокированный/удалённый пользователь не продлевает доступ
+		user, err := users.Get(rt.Username)
+		if err != nil || user.LockedUntil.After(time.Now()) {
+			b.refresh.revokeFamily(rt.Family)
+			writeJSON(w, http.StatusUnauthorized, "invalid refresh token")
+			return
+		}
+
+		pair, err := b.issuePair(user.Username, rt.Family)
+		if err != nil {
+			writeJSON(w, http.StatusInternalServerError, "token generation failed")
+			return
+		}
+		w.Header().Set("Cache-Control", "no-store")
+		writeJSON(w, http.StatusOK, pair)
+	}
+}
+
+// revokeHandler — выход для Bearer клиентов. Как в RFC 7009, ответ 200
+// и для неизвестного токена.
+func revokeHandler(b *bearerAuthn) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		token, ok := decodeRefreshRequest(w, r)
+		if !ok {
+			return
+		}
+		if family := b.refresh.familyOf(token); family != "" {
+			b.refresh.revokeFamily(family)
+		}
+		writeJSON(w, http.StatusOK, map[string]string{"status": "revoked"})
+	}
+}
+
+// ==== Middleware ====
+
+// bearerAuth проверяет "Authorization: Bearer <jwt>" и кладёт Claims в
+// контекст. Запросы без заголовка, с другой схемой (Basic и т.п.) или при
+// выключенном JWT проходят дальше без изменений (cookie-режим; CSRF смотрит
+// только на проверенные Claims). Испорченный или невалидный Bearer токен —
+// 401, без отката на cookie.
+func bearerAuth(b *bearerAuthn) middleware {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			authz := r.Header.Get("Authorization")
+			scheme, token, _ := strings.Cut(authz, " ")
+			if b == nil || !strings.EqualFold(scheme, "Bearer") {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			token = strings.TrimSpace(token)
+			if token == "" {
+				w.Header().Set("WWW-Authenticate", `Bearer error="invalid_request"`)
+				writeJSON(w, http.StatusUnauthorized, "malformed bearer token")
+				return
+			}
+
+			claims, err := b.jwt.verify(token)
+			if err != nil {
+				desc := "invalid token"
+				if errors.Is(err, ErrTokenExpired) {
+					desc = "token expired"
+				}
+				w.Header().Set("WWW-Authenticate", `Bearer error="invalid_token", error_description="`+desc+`"`)
+				writeJSON(w, http.StatusUnauthorized, desc)
+				return
+			}
+
+			ctx := context.WithValue(r.Context(), ctxClaims, claims)
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
+}
+
+// claimsFrom — Claims, если запрос аутентифицирован Bearer токеном.
+func claimsFrom(r *http.Request) (*Claims, bool) {
+	c, ok := r.Context().Value(ctxClaims).(*Claims)
+	return c, ok
+}
@@ -0,0 +1,320 @@
+package main
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+const testJWTSecret = "0123456789abcdef0123456789abcdef"
+
+func newTestIssuer(t *testing.T, alg string) *jwtIssuer {
+	t.Helper()
+	cfg := Config{
+		JWTAlg:       alg,
+		JWTIssuer:    "api-server",
+		JWTAudience:  "api",
+		JWTAccessTTL: 15 * time.Minute,
+	}
+	if alg == JWTAlgHS256 {
+		cfg.JWTSecret = testJWTSecret
+	}
+	j, err := newJWTIssuer(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return j
+}
+
+// rawToken — JWT с произвольным заголовком и claims; sign == nil — подпись issuer'а.
+func rawToken(t *testing.T, j *jwtIssuer, header, claims any, sign func([]byte) []byte) string {
+	t.Helper()
+	h, err := json.Marshal(header)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c, err := json.Marshal(claims)
+	if err != nil {
+		t.Fatal(err)
+	}
+	input := b64.EncodeToString(h) + "." + b64.EncodeToString(c)
+	if sign == nil {
+		sign = j.signature
+	}
+	return input + "." + b64.EncodeToString(sign([]byte(input)))
+}
+
+// validClaims — claims, которые issuer примет; тест портит одно поле.
+func validClaims(j *jwtIssuer) Claims {
+	now := time.Now()
+	return Claims{
+		Issuer:    j.issuer,
+		Audience:  j.audience,
+		Subject:   "alice",
+		IssuedAt:  now.Unix(),
+		NotBefore: now.Unix(),
+		ExpiresAt: now.Add(time.Minute).Unix(),
+		ID:        "jti",
+		SessionID: "sid",
+	}
+}
+
+func TestJWTIssueVerifyRoundTrip(t *testing.T) {
+	for _, alg := range []string{JWTAlgHS256, JWTAlgEdDSA} {
+		t.Run(alg, func(t *testing.T) {
+			j := newTestIssuer(t, alg)
+			token, issued, err := j.issue("alice", "family-1")
+			if err != nil {
+				t.Fatal(err)
+			}
+			got, err := j.verify(token)
+			if err != nil {
+				t.Fatalf("verify: %v", err)
+			}
+			if *got != *issued || got.Subject != "alice" || got.SessionID != "family-1" || got.Audience != "api" {
+				t.Errorf("claims %+v, want %+v", got, issued)
+			}
+		})
+	}
+}
+
+func TestJWTVerifyRejects(t *testing.T) {
+	hs := newTestIssuer(t, JWTAlgHS256)
+	ed := newTestIssuer(t, JWTAlgEdDSA)
+	otherHS := &jwtIssuer{alg: JWTAlgHS256, secret: []byte(strings.Repeat("x", 32))}
+	now := time.Now()
+
+	hsHeader := jwtHeader{Alg: JWTAlgHS256, Typ: "JWT"}
+	edHeader := jwtHeader{Alg: JWTAlgEdDSA, Typ: "JWT"}
+	noSig := func([]byte) []byte { return nil }
+	// HS256 с публичным ключом EdDSA в роли секрета — классическая подмена алгоритма
+	hmacWithPub := func(input []byte) []byte {
+		mac := hmac.New(sha256.New, ed.pub)
+		mac.Write(input)
+		return mac.Sum(nil)
+	}
+	with := func(j *jwtIssuer, mutate func(*Claims)) Claims {
+		c := validClaims(j)
+		mutate(&c)
+		return c
+	}
+
+	tests := []struct {
+		name  string
+		j     *jwtIssuer
+		token string
+		want  error
+	}{
+		{"alg none HS256", hs, rawToken(t, hs, jwtHeader{Alg: "none", Typ: "JWT"}, validClaims(hs), noSig), ErrInvalidToken},
+		{"alg none EdDSA", ed, rawToken(t, ed, jwtHeader{Alg: "none", Typ: "JWT"}, validClaims(ed), noSig), ErrInvalidToken},
+		{"alg none с подписью", hs, rawToken(t, hs, jwtHeader{Alg: "none"}, validClaims(hs), nil), ErrInvalidToken},
+		{"HS256 подписан публичным ключом EdDSA", ed, rawToken(t, ed, hsHeader, validClaims(ed), hmacWithPub), ErrInvalidToken},
+		{"EdDSA в заголовке для HS256", hs, rawToken(t, hs, edHeader, validClaims(hs), nil), ErrInvalidToken},
+		{"чужой секрет", hs, rawToken(t, hs, hsHeader, validClaims(hs), otherHS.signature), ErrInvalidToken},
+		{"испорченная подпись", hs, rawToken(t, hs, hsHeader, validClaims(hs), nil) + "x", ErrInvalidToken},
+		{"две части", hs, "a.b", ErrInvalidToken},
+		{"мусор", hs, "!!!.???.***", ErrInvalidToken},
+		{"чужой iss", hs, rawToken(t, hs, hsHeader, with(hs, func(c *Claims) { c.Issuer = "evil" }), nil), ErrInvalidToken},
+		{"чужой aud", hs, rawToken(t, hs, hsHeader, with(hs, func(c *Claims) { c.Audience = "billing" }), nil), ErrInvalidToken},
+		{"без aud", hs, rawToken(t, hs, hsHeader, with(hs, func(c *Claims) { c.Audience = "" }), nil), ErrInvalidToken},
+		{"без sub", hs, rawToken(t, hs, hsHeader, with(hs, func(c *Claims) { c.Subject = "" }), nil), ErrInvalidToken},
+		{"без exp", hs, rawToken(t, hs, hsHeader, with(hs, func(c *Claims) { c.ExpiresAt = 0 }), nil), ErrInvalidToken},
+		{"истёк за пределами допуска", hs, rawToken(t, hs, hsHeader, with(hs, func(c *Claims) {
+			c.ExpiresAt = now.Add(-jwtLeeway - 5*time.Second).Unix()
+		}), nil), ErrTokenExpired},
+		{"nbf в будущем за пределами допуска", hs, rawToken(t, hs, hsHeader, with(hs, func(c *Claims) {
+			c.NotBefore = now.Add(jwtLeeway + 5*time.Second).Unix()
+		}), nil), ErrInvalidToken},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := tt.j.verify(tt.token); !errors.Is(err, tt.want) {
+				t.Errorf("err = %v, want %v", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestJWTVerifyClockSkew(t *testing.T) {
+	j := newTestIssuer(t, JWTAlgHS256)
+	header := jwtHeader{Alg: JWTAlgHS256, Typ: "JWT"}
+	now := time.Now()
+
+	// Часы клиента/другого узла расходятся в пределах jwtLeeway — токен принимается
+	expired := validClaims(j)
+	expired.ExpiresAt = now.Add(-jwtLeeway / 2).Unix()
+	if _, err := j.verify(rawToken(t, j, header, expired, nil)); err != nil {
+		t.Errorf("exp чуть в прошлом: %v", err)
+	}
+	early := validClaims(j)
+	early.NotBefore = now.Add(jwtLeeway / 2).Unix()
+	if _, err := j.verify(rawToken(t, j, header, early, nil)); err != nil {
+		t.Errorf("nbf чуть в будущем: %v", err)
+	}
+}
+
+func newTestRefreshStore(t *testing.T) *refreshStore {
+	t.Helper()
+	s := newRefreshStore(time.Hour, time.Hour)
+	t.Cleanup(s.Close)
+	return s
+}
+
+func TestRefreshRotationAndReuse(t *testing.T) {
+	s := newTestRefreshStore(t)
+
+	first, family, err := s.issue("alice", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	rt, err := s.use(first)
+	if err != nil || rt.Username != "alice" || rt.Family != family {
+		t.Fatalf("use: %+v, %v", rt, err)
+	}
+	second, fam2, err := s.issue("alice", family)
+	if err != nil || fam2 != family {
+		t.Fatalf("issue в той же цепочке: %q, %v", fam2, err)
+	}
+	other, _, err := s.issue("alice", "") // другой вход — другая цепочка
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Повтор использованного токена — кража: отзывается вся цепочка
+	if _, err := s.use(first); !errors.Is(err, ErrRefreshReused) {
+		t.Fatalf("повтор: %v, want ErrRefreshReused", err)
+	}
+	if _, err := s.use(second); !errors.Is(err, ErrInvalidRefresh) {
+		t.Errorf("токен отозванной цепочки: %v, want ErrInvalidRefresh", err)
+	}
+	if _, err := s.use(other); err != nil {
+		t.Errorf("чужая цепочка задета: %v", err)
+	}
+	if _, err := s.use("unknown"); !errors.Is(err, ErrInvalidRefresh) {
+		t.Errorf("неизвестный токен: %v", err)
+	}
+}
+
+func TestRefreshExpiredAndGC(t *testing.T) {
+	s := newTestRefreshStore(t)
+	token, _, err := s.issue("alice", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.mu.Lock()
+	s.tokens[hashRefresh(token)].ExpiresAt = time.Now().Add(-time.Second)
+	s.mu.Unlock()
+
+	if _, err := s.use(token); !errors.Is(err, ErrInvalidRefresh) {
+		t.Errorf("истёкший токен: %v", err)
+	}
+	s.gc()
+	if s.familyOf(token) != "" {
+		t.Error("gc не удалил истёкший токен")
+	}
+}
+
+func TestRefreshHandlerReuseRevokesFamily(t *testing.T) {
+	users, err := newJSONUserStore(t.TempDir() + "/users.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := users.Put(&User{Username: "alice"}); err != nil {
+		t.Fatal(err)
+	}
+	b := &bearerAuthn{jwt: newTestIssuer(t, JWTAlgHS256), refresh: newTestRefreshStore(t)}
+	h := refreshHandler(b, users)
+
+	refresh := func(token string) (*httptest.ResponseRecorder, tokenResponse) {
+		rec := httptest.NewRecorder()
+		body := strings.NewReader(`{"refresh_token":"` + token + `"}`)
+		h.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/api/token/refresh", body))
+		var resp struct {
+			Data tokenResponse `json:"data"`
+		}
+		_ = json.Unmarshal(rec.Body.Bytes(), &resp)
+		return rec, resp.Data
+	}
+
+	pair, err := b.issuePair("alice", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	rec, next := refresh(pair.RefreshToken)
+	if rec.Code != http.StatusOK || next.RefreshToken == "" || next.AccessToken == "" {
+		t.Fatalf("ротация: %d %s", rec.Code, rec.Body)
+	}
+	if _, err := b.jwt.verify(next.AccessToken); err != nil {
+		t.Errorf("новый access token: %v", err)
+	}
+
+	if rec, _ := refresh(pair.RefreshToken); rec.Code != http.StatusUnauthorized {
+		t.Fatalf("повтор старого refresh: %d, want 401", rec.Code)
+	}
+	if rec, _ := refresh(next.RefreshToken); rec.Code != http.StatusUnauthorized {
+		t.Errorf("новый refresh после кражи: %d, want 401 (цепочка отозвана)", rec.Code)
+	}
+}
+
+func TestBearerAuthMiddleware(t *testing.T) {
+	b := &bearerAuthn{jwt: newTestIssuer(t, JWTAlgHS256)}
+	valid, _, err := b.jwt.issue("alice", "sid")
+	if err != nil {
+		t.Fatal(err)
+	}
+	expiredClaims := validClaims(b.jwt)
+	expiredClaims.ExpiresAt = time.Now().Add(-time.Hour).Unix()
+	expired := rawToken(t, b.jwt, jwtHeader{Alg: JWTAlgHS256, Typ: "JWT"}, expiredClaims, nil)
+
+	tests := []struct {
+		name     string
+		b        *bearerAuthn
+		authz    string
+		wantCode int
+		wantUser string // "" — Claims в контексте нет
+		wantAuth string // начало WWW-Authenticate
+	}{
+		{"без заголовка", b, "", http.StatusOK, "", ""},
+		{"Basic проходит", b, "Basic YWxpY2U6cHc=", http.StatusOK, "", ""},
+		{"другая схема проходит", b, "Token abc", http.StatusOK, "", ""},
+		{"JWT выключен — Bearer проходит", nil, "Bearer " + valid, http.StatusOK, "", ""},
+		{"валидный", b, "Bearer " + valid, http.StatusOK, "alice", ""},
+		{"схема без учёта регистра", b, "bearer " + valid, http.StatusOK, "alice", ""},
+		{"пустой Bearer", b, "Bearer ", http.StatusUnauthorized, "", `Bearer error="invalid_request"`},
+		{"Bearer без пробела", b, "Bearer", http.StatusUnauthorized, "", `Bearer error="invalid_request"`},
+		{"испорченный", b, "Bearer abc.def", http.StatusUnauthorized, "", `Bearer error="invalid_token"`},
+		{"истёкший", b, "Bearer " + expired, http.StatusUnauthorized, "", `Bearer error="invalid_token", error_description="token expired"`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var gotUser string
+			next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if c, ok := claimsFrom(r); ok {
+					gotUser = c.Subject
+				}
+				if got := r.Header.Get("Authorization"); got != tt.authz {
+					t.Errorf("Authorization изменён: %q", got)
+				}
+			})
+			req := httptest.NewRequest(http.MethodGet, "/api/data", nil)
+			if tt.authz != "" {
+				req.Header.Set("Authorization", tt.authz)
+			}
+			rec := httptest.NewRecorder()
+			bearerAuth(tt.b)(next).ServeHTTP(rec, req)
+
+			if rec.Code != tt.wantCode || gotUser != tt.wantUser {
+				t.Errorf("код %d, user %q; want %d, %q", rec.Code, gotUser, tt.wantCode, tt.wantUser)
+			}
+			if got := rec.Header().Get("WWW-Authenticate"); !strings.HasPrefix(got, tt.wantAuth) || (tt.wantAuth == "") != (got == "") {
+				t.Errorf("WWW-Authenticate %q, want %q", got, tt.wantAuth)
+			}
+		})
+	}
+}
@@ -6,16 +6,17 @@ import (
 	"encoding/hex"
 	"encoding/json"
 	"errors"
+	"flag"
 	"fmt"
+	"io"
 	"log"
-	"net"
+	"log/slog"
 	"net/http"
 	"net/url"
 	"os"
 	"os/signal"
-	"path/filepath"
-	"strings"
-	"sync"
+	"runtime/debug"
+	"strconv"
 	"syscall"
 	"time"
 )
@@ -24,113 +25,73 @@ import (
 
 const (
 	// Сетевые настройки (Nginx проксирует на 127.0.0.1:8080)
-	APIAddr           = "127.0.0.1:8080" // Только localhost для безопасности
-	ReadHeaderTimeout = 5 * time.Second
-	ReadBodyTimeout   = 30 * time.Second // Больше для JSON
-	WriteTimeout      = 60 * time.Second // JSON может быть медленнее
-	IdleTimeout       = 5 * time.Minute  // Дольше для keep-alive
-	MaxHeaderBytes    = 1 << 20          // 1MB заголовков
-	MaxBodyBytes      = 10 << 20         // 10MB JSON
+	APIAddr            = "127.0.0.1:8080" // Только localhost для безопасности
+	ReadHeaderTimeout  = 5 * time.Second
+	ReadBodyTimeout    = 30 * time.Second // Больше для JSON
+	WriteTimeout       = 60 * time.Second // JSON может быть медленнее
+	IdleTimeout        = 5 * time.Minute  // Дольше для keep-alive
+	MaxHeaderBytes     = 1 << 20          // 1MB заголовков
+	MaxBodyBytes       = 10 << 20         // 10MB JSON
+	ShutdownTimeout    = 30 * time.Second
+	ShutdownDrainDelay = 10 * time.Second // /readyz 503 → Shutdown: не меньше периода проб LB
+	HealthTimeout      = 2 * time.Second  // на одну проверку /readyz
+
+	// TLS режим без Nginx (tls_cert_file / tls_self_signed)
+	HSTSMaxAge = 365 * 24 * time.Hour
 
 	// Безопасность (Nginx обрабатывает Host validation)
 	AllowedOrigins       = "https://example.com,https://app.example.com" // Ваши фронтенды
+	TrustedProxies       = "127.0.0.1/32,::1/128"                        // Nginx; X-Forwarded-* от остальных игнорируются
+	MetricsAllowed       = "127.0.0.1/32,::1/128"                        // кому доступен /metrics
 	RateLimitMaxRequests = 200                                           // Больше для API
 	RateLimitWindow      = 1 * time.Minute
+	RateLimitAlgorithm   = AlgoSlidingWindow // token-bucket | sliding-window
+	RateLimitJanitor     = 1 * time.Minute   // как часто удалять неактивные IP
 	SessionTokenLength   = 32
 	SessionMaxAge        = 24 * 3600 // 24 часа
 	MaxUploadFileMB      = 10
 
+	// Сессии
+	SessionCookieName = "session"
+	SessionStoreKind  = "memory"        // memory | file
+	SessionDir        = "data/sessions" // для file-хранилища
+	SessionGCInterval = 10 * time.Minute
+
+	// Пользователи и вход
+	UsersFile            = "data/users.json"
+	PasswordMinLength    = 8
+	PasswordArgonTime    = 3         // argon2id: проходов
+	PasswordArgonMemory  = 64 * 1024 // argon2id: KiB (64 MiB)
+	PasswordArgonThreads = 4         // argon2id: потоков
+	LoginMaxFailures     = 5         // ошибок подряд до блокировки
+	LoginLockout         = 15 * time.Minute
+
+	// Загрузки
+	UploadDir          = "data/uploads"
+	UploadQuotaPerUser = 50 << 20 // 50MB на пользователя
+	MaxImageDimension  = 8192     // px по любой стороне
+	MaxImagePixels     = 40 << 20 // ~40 Мпикс (защита от decompression bomb)
+
+	// JWT (Bearer режим для не-браузерных клиентов)
+	JWTAlgorithm  = ""           // "" — выключен | HS256 | EdDSA
+	JWTIssuer     = "api-server" // iss в токенах
+	JWTAudience   = "api"        // aud в токенах: токен другого сервиса с тем же ключом не подойдёт
+	JWTAccessTTL  = 15 * time.Minute
+	JWTRefreshTTL = 30 * 24 * time.Hour
+
 	// JSON API настройки
 	JSONIndent     = false          // false = компактный JSON
 	CSRFHeaderName = "X-CSRF-Token" // Для API клиентов
 )
 
-// ==== Конфигурация ====
-
-type Config struct {
-	Addr              string
-	AllowedOrigins    []string
-	MaxHeaderBytes    int
-	MaxBodyBytes      int64
-	ReadHeaderTimeout time.Duration
-	ReadBodyTimeout   time.Duration
-	WriteTimeout      time.Duration
-	IdleTimeout       time.Duration
-	RateLimitMax      int
-	RateLimitWindow   time.Duration
-}
-
-func LoadConfig() Config {
-	return Config{
-		Addr:              APIAddr,
-		AllowedOrigins:    splitCSV(AllowedOrigins),
-		MaxHeaderBytes:    MaxHeaderBytes,
-		MaxBodyBytes:      MaxBodyBytes,
-		ReadHeaderTimeout: ReadHeaderTimeout,
-		ReadBodyTimeout:   ReadBodyTimeout,
-		WriteTimeout:      WriteTimeout,
-		IdleTimeout:       IdleTimeout,
-		RateLimitMax:      RateLimitMaxRequests,
-		RateLimitWindow:   RateLimitWindow,
-	}
-}
-
-func splitCSV(s string) []string {
-	var out []string
-	for _, p := range strings.Split(s, ",") {
-		p = strings.TrimSpace(p)
-		if p != "" {
-			out = append(out, p)
-		}
-	}
-	return out
-}
-
-// ==== Rate Limiter ====
-
-type rateLimiter struct {
-	requests map[string][]time.Time
-	mu       sync.RWMutex
-	max      int
-	window   time.Duration
-}
-
-func newRateLimiter(max int, window time.Duration) *rateLimiter {
-	return &rateLimiter{
-		requests: make(map[string][]time.Time),
-		max:      max,
-		window:   window,
-	}
-}
-
-func (rl *rateLimiter) allow(ip string) bool {
-	rl.mu.Lock()
-	defer rl.mu.Unlock()
-
-	now := time.Now()
-	reqs := rl.requests[ip]
-
-	// Очистка старых записей
-	reqs = filterRecent(reqs, now, rl.window)
-
-	if len(reqs) >= rl.max {
-		return false
-	}
-
-	reqs = append(reqs, now)
-	rl.requests[ip] = reqs
-	return true
-}
-
-func filterRecent(times []time.Time, now time.Time, window time.Duration) []time.Time {
-	var recent []time.Time
-	cutoff := now.Add(-window)
-	for _, t := range times {
-		if t.After(cutoff) {
-			recent = append(recent, t)
-		}
-	}
-	return recent
+// RateLimitRoutes — лимиты отдельных маршрутов поверх RateLimitMaxRequests.
+// Ключ: точный путь или префикс с "/" на конце.
+var RateLimitRoutes = map[string]RateLimitPolicy{
+	"/api/login":  {Algorithm: AlgoSlidingWindow, Limit: 10, Window: time.Minute}, // перебор паролей
+	"/api/token/": {Algorithm: AlgoSlidingWindow, Limit: 30, Window: time.Minute},
+	"/healthz":    {Algorithm: AlgoTokenBucket, Limit: 600, Window: time.Minute}, // частые пробы LB
+	"/livez":      {Algorithm: AlgoTokenBucket, Limit: 600, Window: time.Minute},
+	"/readyz":     {Algorithm: AlgoTokenBucket, Limit: 600, Window: time.Minute},
 }
 
 // ==== JSON Response Helper ====
@@ -150,7 +111,10 @@ func writeJSON(w http.ResponseWriter, status int, data interface{}) error {
 		resp.Status = "error"
 		resp.Error = fmt.Sprintf("%d: %v", status, data)
 	}
+	return encodeJSON(w, resp)
+}
 
+func encodeJSON(w io.Writer, resp jsonResponse) error {
 	enc := json.NewEncoder(w)
 	enc.SetIndent("", "")
 	if JSONIndent {
@@ -161,27 +125,6 @@ func writeJSON(w http.ResponseWriter, status int, data interface{}) error {
 
 // ==== Утилиты ====
 
-func clientIP(r *http.Request) string {
-	// Nginx передает X-Real-IP или X-Forwarded-For
-	realIP := r.Header.Get("X-Real-IP")
-	if realIP != "" {
-		return realIP
-	}
-
-	xff := r.Header.Get("X-Forwarded-For")
-	if xff != "" {
-		// Берем первый IP (клиент)
-		parts := strings.Split(xff, ",")
-		if len(parts) > 0 {
-			return strings.TrimSpace(parts[0])
-		}
-	}
-
-	// Fallback
-	host, _, _ := net.SplitHostPort(r.RemoteAddr)
-	return host
-}
-
 func randomToken(n int) (string, error) {
 	b := make([]byte, n)
 	if _, err := rand.Read(b); err != nil {
@@ -213,13 +156,32 @@ func isStateChanging(method string) bool {
 		method == http.MethodPatch || method == http.MethodDelete
 }
 
+// csrfExemptPaths — cookie-сессии ещё нет или она не используется, токен
+// проверить не с чем. Origin проверяется, если он есть: без Origin приходят
+// только не-браузерные клиенты, для них CSRF не существует.
+var csrfExemptPaths = map[string]bool{
+	"/api/login":         true,
+	"/api/token/refresh": true,
+	"/api/token/revoke":  true,
+}
+
 // ==== Middleware ====
 
 type middleware func(http.Handler) http.Handler
 
-func secureHeaders() middleware {
+// secureHeaders: HSTS только для ответов по TLS (за Nginx его ставит Nginx;
+// по plain HTTP браузер заголовок всё равно игнорирует).
+func secureHeaders(hstsMaxAge time.Duration) middleware {
+	hsts := ""
+	if hstsMaxAge > 0 {
+		hsts = "max-age=" + strconv.Itoa(int(hstsMaxAge/time.Second))
+	}
+
 	return func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if hsts != "" && r.TLS != nil {
+				w.Header().Set("Strict-Transport-Security", hsts)
+			}
 			// API-focused security headers
 			w.Header().Set("X-Content-Type-Options", "nosniff")
 			w.Header().Set("X-Frame-Options", "DENY")
@@ -241,17 +203,14 @@ func limitBody(max int64) middleware {
 	}
 }
 
-func corsStrict(allowedOrigins []string) middleware {
-	origins := make(map[string]struct{}, len(allowedOrigins))
-	for _, o := range allowedOrigins {
-		origins[o] = struct{}{}
-	}
-
+// corsStrict и csrfGuard читают origins на каждый запрос — список
+// меняется по SIGHUP без перезапуска.
+func corsStrict(origins *originPolicy) middleware {
 	return func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 			origin := r.Header.Get("Origin")
 			if origin != "" {
-				if _, ok := origins[origin]; ok {
+				if origins.contains(origin) {
 					w.Header().Set("Access-Control-Allow-Origin", origin)
 					w.Header().Set("Vary", "Origin")
 					w.Header().Set("Access-Control-Allow-Methods",
@@ -274,7 +233,7 @@ func corsStrict(allowedOrigins []string) middleware {
 	}
 }
 
-func csrfGuard(allowedOrigins []string) middleware {
+func csrfGuard(origins *originPolicy, store SessionStore) middleware {
 	return func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 			if !isStateChanging(r.Method) {
@@ -282,11 +241,26 @@ func csrfGuard(allowedOrigins []string) middleware {
 				return
 			}
 
+			// Bearer токен браузер сам не подставит — подделывать нечего
+			if _, ok := claimsFrom(r); ok {
+				next.ServeHTTP(w, r)
+				return
+			}
+
 			// API CSRF: проверяем Origin + CSRF токен
 			origin := r.Header.Get("Origin")
 			csrfToken := r.Header.Get(CSRFHeaderName)
 
-			if origin == "" || !validateOrigin(allowedOrigins, origin) {
+			if csrfExemptPaths[r.URL.Path] {
+				if origin != "" && !validateOrigin(origins.list(), origin) {
+					writeJSON(w, http.StatusForbidden, "invalid origin")
+					return
+				}
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			if origin == "" || !validateOrigin(origins.list(), origin) {
 				writeJSON(w, http.StatusForbidden, "invalid origin")
 				return
 			}
@@ -296,55 +270,63 @@ func csrfGuard(allowedOrigins []string) middleware {
 				return
 			}
 
-			// В реальности: валидация токена против сессии
-			// sessionToken := getSessionToken(r)
-			// if !validateCSRF(sessionToken, csrfToken) { ... }
+			// Токен должен совпасть с тем, что привязан к сессии из cookie
+			sess, err := sessionFromRequest(store, r)
+			if err != nil {
+				if !errors.Is(err, ErrSessionNotFound) {
+					slog.Error("session lookup", "request_id", requestIDFrom(r), "err", err)
+				}
+				writeJSON(w, http.StatusForbidden, "invalid session")
+				return
+			}
+			if !validCSRF(sess, csrfToken) {
+				writeJSON(w, http.StatusForbidden, "invalid CSRF token")
+				return
+			}
 
 			next.ServeHTTP(w, r)
 		})
 	}
 }
 
-func recoverer(next http.Handler) http.Handler {
-	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		defer func() {
-			if rec := recover(); rec != nil {
-				log.Printf("panic: %v", rec)
-				writeJSON(w, http.StatusInternalServerError, "internal error")
-			}
-		}()
-		next.ServeHTTP(w, r)
-	})
-}
-
-type responseWriter struct {
-	http.ResponseWriter
-	status int
-}
-
-func (rw *responseWriter) WriteHeader(code int) {
-	rw.status = code
-	rw.ResponseWriter.WriteHeader(code)
-}
-
-func requestLogger(next http.Handler) http.Handler {
-	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		start := time.Now()
-		rw := &responseWriter{ResponseWriter: w, status: http.StatusOK}
-		ip := clientIP(r)
-
-		next.ServeHTTP(rw, r)
-
-		log.Printf("%s %s %d %s ip=%s size=%d dur=%v",
-			r.Method, r.URL.Path, rw.status, r.Proto, ip,
-			r.ContentLength, time.Since(start))
-	})
+func recoverer(m *metrics) middleware {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			defer func() {
+				if rec := recover(); rec != nil {
+					if rec == http.ErrAbortHandler {
+						panic(rec)
+					}
+					m.panicked()
+					slog.Error("panic",
+						"request_id", requestIDFrom(r),
+						"method", r.Method,
+						"path", r.URL.Path,
+						"panic", fmt.Sprint(rec),
+						"stack", string(debug.Stack()),
+					)
+					writeJSON(w, http.StatusInternalServerError, "internal error")
+				}
+			}()
+			next.ServeHTTP(w, r)
+		})
+	}
 }
 
-func rateLimit(rl *rateLimiter) middleware {
+func rateLimit(rl *liveRateLimiter, m *metrics) middleware {
 	return func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-			if !rl.allow(clientIP(r)) {
+			route, limiter := rl.forPath(r.URL.Path)
+			res := limiter.Allow(clientIP(r))
+
+			h := w.Header()
+			h.Set("X-RateLimit-Limit", strconv.Itoa(res.Limit))
+			h.Set("X-RateLimit-Remaining", strconv.Itoa(res.Remaining))
+			h.Set("X-RateLimit-Reset", strconv.Itoa(ceilSeconds(res.Reset)))
+
+			if !res.Allowed {
+				h.Set("Retry-After", strconv.Itoa(ceilSeconds(res.RetryAfter)))
+				m.rateLimitRejected(route)
 				writeJSON(w, http.StatusTooManyRequests, "rate limited")
 				return
 			}
@@ -353,87 +335,148 @@ func rateLimit(rl *rateLimiter) middleware {
 	}
 }
 
-// ==== Хэндлеры ====
-
-func healthHandler(w http.ResponseWriter, r *http.Request) {
-	writeJSON(w, http.StatusOK, map[string]string{"status": "ok", "version": "1.0"})
-}
-
-func loginHandler(w http.ResponseWriter, r *http.Request) {
-	// Парсим JSON credentials
-	var creds struct {
-		Username string `json:"username"`
-		Password string `json:"password"`
-	}
-	if err := json.NewDecoder(r.Body).Decode(&creds); err != nil {
-		writeJSON(w, http.StatusBadRequest, "invalid JSON")
-		return
-	}
-
-	// В реальности: валидация credentials
-	token, err := randomToken(SessionTokenLength / 2)
-	if err != nil {
-		writeJSON(w, http.StatusInternalServerError, "token generation failed")
-		return
+// ceilSeconds — целые секунды вверх (для Retry-After минимум 1).
+func ceilSeconds(d time.Duration) int {
+	s := int((d + time.Second - 1) / time.Second)
+	if s < 1 {
+		return 1
 	}
-
-	// CSRF токен для клиента
-	csrfToken, _ := randomToken(16)
-
-	http.SetCookie(w, &http.Cookie{
-		Name:     "session",
-		Value:    token,
-		Path:     "/",
-		MaxAge:   SessionMaxAge,
-		HttpOnly: true,
-		SameSite: http.SameSiteLaxMode,
-	})
-
-	writeJSON(w, http.StatusOK, map[string]interface{}{
-		"status":     "ok",
-		"csrf_token": csrfToken,
-		// В реальности: JWT или session ID
-	})
+	return s
 }
 
-func uploadHandler(maxMB int64) http.HandlerFunc {
+// ==== Хэндлеры ====
+
+// loginHandler: cookie-сессия (по умолчанию) или пара JWT ("mode":"bearer",
+// если bearer != nil).
+func loginHandler(users UserStore, store SessionStore, ttl time.Duration, bearer *bearerAuthn) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
-		// CSRF уже проверен middleware'ом
+		if r.Method != http.MethodPost {
+			writeJSON(w, http.StatusMethodNotAllowed, "method not allowed")
+			return
+		}
 
-		if err := r.ParseMultipartForm(maxMB << 20); err != nil {
-			writeJSON(w, http.StatusBadRequest, "invalid multipart form")
+		// Парсим JSON credentials
+		var creds struct {
+			Username string `json:"username"`
+			Password string `json:"password"`
+			Mode     string `json:"mode"` // "" | cookie | bearer
+		}
+		if err := json.NewDecoder(r.Body).Decode(&creds); err != nil {
+			writeJSON(w, http.StatusBadRequest, "invalid JSON")
+			return
+		}
+		switch creds.Mode {
+		case "", "cookie":
+		case "bearer":
+			if bearer == nil {
+				writeJSON(w, http.StatusBadRequest, "bearer mode disabled")
+				return
+			}
+		default:
+			writeJSON(w, http.StatusBadRequest, "unknown mode")
 			return
 		}
 
-		file, header, err := r.FormFile("file")
+		// Проверка credentials (с блокировкой после LoginMaxFailures ошибок)
+		user, err := authenticate(users, creds.Username, creds.Password)
 		if err != nil {
-			writeJSON(w, http.StatusBadRequest, "file required")
+			switch {
+			case errors.Is(err, ErrAccountLocked):
+				log.Printf("login: account locked user=%q ip=%s", creds.Username, clientIP(r))
+			case errors.Is(err, ErrInvalidCredentials):
+				log.Printf("login: invalid credentials user=%q ip=%s", creds.Username, clientIP(r))
+			default:
+				log.Printf("login: %v", err)
+				writeJSON(w, http.StatusInternalServerError, "user store failed")
+				return
+			}
+			// Одинаковый ответ: не раскрываем, существует ли пользователь и заблокирован ли он
+			writeJSON(w, http.StatusUnauthorized, "invalid credentials")
 			return
 		}
-		defer file.Close()
 
-		// Безопасная валидация
-		name := filepath.Base(header.Filename)
-		if name == "." || strings.Contains(name, "..") || strings.ContainsAny(name, "/\\") {
-			writeJSON(w, http.StatusBadRequest, "invalid filename")
+		if creds.Mode == "bearer" {
+			pair, err := bearer.issuePair(user.Username, "")
+			if err != nil {
+				writeJSON(w, http.StatusInternalServerError, "token generation failed")
+				return
+			}
+			w.Header().Set("Cache-Control", "no-store")
+			writeJSON(w, http.StatusOK, pair)
 			return
 		}
 
-		ext := strings.ToLower(filepath.Ext(name))
-		allowedExts := map[string]bool{".png": true, ".jpg": true, ".jpeg": true, ".gif": true}
-		if !allowedExts[ext] {
-			writeJSON(w, http.StatusUnsupportedMediaType, "unsupported file type")
+		// Старую сессию (если была) отзываем — защита от session fixation
+		if old, err := sessionFromRequest(store, r); err == nil {
+			_ = store.Delete(old.ID)
+		}
+
+		// Новая сессия + привязанный к ней CSRF токен
+		sess, err := newSession(user.Username, ttl)
+		if err != nil {
+			writeJSON(w, http.StatusInternalServerError, "token generation failed")
+			return
+		}
+		if err := store.Save(sess); err != nil {
+			log.Printf("session save: %v", err)
+			writeJSON(w, http.StatusInternalServerError, "session store failed")
 			return
 		}
 
+		http.SetCookie(w, &http.Cookie{
+			Name:     SessionCookieName,
+			Value:    sess.ID,
+			Path:     "/",
+			MaxAge:   int(ttl / time.Second),
+			HttpOnly: true,
+			Secure:   r.TLS != nil,
+			SameSite: http.SameSiteLaxMode,
+		})
+
 		writeJSON(w, http.StatusOK, map[string]interface{}{
-			"status":   "uploaded",
-			"filename": name,
-			"size":     header.Size,
+			"status":     "ok",
+			"csrf_token": sess.CSRFToken,
 		})
 	}
 }
 
+func logoutHandler(store SessionStore, bearer *bearerAuthn) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			writeJSON(w, http.StatusMethodNotAllowed, "method not allowed")
+			return
+		}
+
+		// Bearer: отзываем refresh токены этого входа; access доживёт до exp
+		if claims, ok := claimsFrom(r); ok {
+			bearer.refresh.revokeFamily(claims.SessionID)
+			writeJSON(w, http.StatusOK, map[string]string{"status": "logged out"})
+			return
+		}
+
+		// CSRF уже проверен middleware'ом, значит сессия существует
+		if sess, err := sessionFromRequest(store, r); err == nil {
+			if err := store.Delete(sess.ID); err != nil {
+				log.Printf("session delete: %v", err)
+				writeJSON(w, http.StatusInternalServerError, "session store failed")
+				return
+			}
+		}
+
+		http.SetCookie(w, &http.Cookie{
+			Name:     SessionCookieName,
+			Value:    "",
+			Path:     "/",
+			MaxAge:   -1,
+			HttpOnly: true,
+			Secure:   r.TLS != nil,
+			SameSite: http.SameSiteLaxMode,
+		})
+
+		writeJSON(w, http.StatusOK, map[string]string{"status": "logged out"})
+	}
+}
+
 // ==== Сборка ====
 
 func chain(h http.Handler, m ...middleware) http.Handler {
@@ -446,23 +489,126 @@ func chain(h http.Handler, m ...middleware) http.Handler {
 // ==== Main ====
 
 func main() {
-	cfg := LoadConfig()
-	rl := newRateLimiter(cfg.RateLimitMax, cfg.RateLimitWindow)
+	// Admin CLI: api-server user add|passwd <username>
+	// Конфиг — из файла API_CONFIG и env, флаги у подкоманды свои.
+	if len(os.Args) > 1 && os.Args[1] == "user" {
+		cfg, err := (&configSource{path: os.Getenv(configEnvPrefix + "CONFIG")}).load()
+		if err != nil {
+			log.Fatal(err)
+		}
+		os.Exit(runUserCommand(cfg, os.Args[2:]))
+	}
+
+	setupLogger()
+
+	src, err := parseConfigFlags(os.Args[1:])
+	if err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			os.Exit(0)
+		}
+		os.Exit(2)
+	}
+	cfg, err := src.load()
+	if err != nil {
+		log.Fatal(err)
+	}
+	if src.print {
+		if err := printConfig(os.Stdout, cfg); err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
+
+	ips, err := newIPResolver(cfg.TrustedProxies)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	routeRL, err := newRouteRateLimiter(cfg)
+	if err != nil {
+		log.Fatal(err)
+	}
+	rl := newLiveRateLimiter(routeRL)
+	defer rl.Close()
+
+	var certs *certReloader
+	if cfg.tlsEnabled() {
+		if certs, err = newCertReloader(cfg); err != nil {
+			log.Fatal(err)
+		}
+	}
+
+	origins := newOriginPolicy(cfg.AllowedOrigins)
+	go watchReload(src, origins, rl, certs)
+
+	sessions, err := newSessionStore(cfg)
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer sessions.Close()
+
+	users, err := newJSONUserStore(cfg.UsersFile)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	uploads, err := newUploadStore(cfg.UploadDir, cfg.UploadQuota)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	metricsAllowed, err := parseIPNets(cfg.MetricsAllowed)
+	if err != nil {
+		log.Fatal(err)
+	}
+	m := newMetrics()
+
+	var bearer *bearerAuthn
+	if cfg.JWTAlg != "" {
+		issuer, err := newJWTIssuer(cfg)
+		if err != nil {
+			log.Fatal(err)
+		}
+		refresh := newRefreshStore(cfg.JWTRefreshTTL, cfg.SessionGCInterval)
+		defer refresh.Close()
+		bearer = &bearerAuthn{jwt: issuer, refresh: refresh}
+	}
+
+	health := newHealthRegistry(cfg.HealthTimeout)
+	health.register("sessions", sessions.Ping)
+	health.register("users", users.Ping)
+	health.register("uploads", uploads.Ping)
+	if certs != nil {
+		health.register("tls_cert", certs.Ping)
+	}
 
 	mux := http.NewServeMux()
-	mux.HandleFunc("/healthz", healthHandler)
-	mux.HandleFunc("/api/login", loginHandler)
-	mux.Handle("/api/upload", uploadHandler(MaxUploadFileMB))
+	mux.Handle("/livez", livezHandler(health))
+	mux.Handle("/readyz", readyzHandler(health))
+	mux.Handle("/healthz", readyzHandler(health)) // старый адрес для LB
+	mux.Handle("/api/login", loginHandler(users, sessions, cfg.SessionTTL, bearer))
+	mux.Handle("/api/logout", logoutHandler(sessions, bearer))
+	if bearer != nil {
+		mux.Handle("/api/token/refresh", refreshHandler(bearer, users))
+		mux.Handle("/api/token/revoke", revokeHandler(bearer))
+	}
+	mux.Handle("/api/upload", uploadHandler(uploads, sessions, cfg.MaxUploadMB))
+	mux.Handle("/api/files/", filesHandler(uploads, sessions))
+	mux.Handle("/metrics", metricsHandler(m, metricsAllowed))
 
 	// API-only middleware stack
 	handler := chain(
 		mux,
+		realIP(ips),
+		requestID,
 		requestLogger,
-		recoverer,
-		rateLimit(rl),
-		secureHeaders(),
-		csrfGuard(cfg.AllowedOrigins),
-		corsStrict(cfg.AllowedOrigins),
+		instrument(m, mux),
+		recoverer(m),
+		rateLimit(rl, m),
+		secureHeaders(cfg.HSTSMaxAge),
+		bearerAuth(bearer),
+		csrfGuard(origins, sessions),
+		corsStrict(origins),
 		limitBody(cfg.MaxBodyBytes),
 	)
 
@@ -475,6 +621,24 @@ func main() {
 		MaxHeaderBytes:    cfg.MaxHeaderBytes,
 	}
 
+	var redirect *http.Server
+	if certs != nil {
+		srv.TLSConfig = certs.tlsConfig()
+		srv.Protocols = new(http.Protocols)
+		srv.Protocols.SetHTTP1(true)
+		srv.Protocols.SetHTTP2(true)
+
+		if cfg.TLSRedirectAddr != "" {
+			redirect = newRedirectServer(cfg)
+			go func() {
+				slog.Info("HTTP → HTTPS redirect starting", "addr", cfg.TLSRedirectAddr)
+				if err := redirect.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+					log.Fatal(err)
+				}
+			}()
+		}
+	}
+
 	// Graceful shutdown
 	idleConnsClosed := make(chan struct{})
 	go func() {
@@ -482,18 +646,36 @@ func main() {
 		signal.Notify(sigint, os.Interrupt, syscall.SIGTERM)
 		<-sigint
 
-		log.Println("shutting down...")
-		ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+		// Сначала readiness → 503. LB замечает это только на следующей пробе,
+		// а Shutdown сразу закрывает listener — ждём хотя бы период проб, всё
+		// это время новые запросы ещё обслуживаются. Повторный сигнал — без ожидания.
+		health.drain()
+		slog.Info("draining", "delay", cfg.ShutdownDrainDelay.String())
+		select {
+		case <-time.After(cfg.ShutdownDrainDelay):
+		case <-sigint:
+		}
+		slog.Info("shutting down", "timeout", cfg.ShutdownTimeout.String())
+		ctx, cancel := context.WithTimeout(context.Background(), cfg.ShutdownTimeout)
 		defer cancel()
 
+		if redirect != nil {
+			_ = redirect.Shutdown(ctx)
+		}
 		if err := srv.Shutdown(ctx); err != nil {
 			log.Printf("shutdown error: %v", err)
 		}
 		close(idleConnsClosed)
 	}()
 
-	log.Printf("JSON API starting on %s", cfg.Addr)
-	if err := srv.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+	slog.Info("JSON API starting", "addr", cfg.Addr, "tls", certs != nil, "version", version)
+	if certs != nil {
+		// Сертификат приходит из TLSConfig.GetCertificate
+		err = srv.ListenAndServeTLS("", "")
+	} else {
+		err = srv.ListenAndServe()
+	}
+	if err != nil && !errors.Is(err, http.ErrServerClosed) {
 		log.Fatal(err)
 	}
 	<-idleConnsClosed
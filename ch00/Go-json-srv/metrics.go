@@ -0,0 +1,214 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// ==== Метрики (Prometheus text format 0.0.4) ====
+//
+// Без client_golang: несколько счётчиков и гистограмм проще написать руками.
+// route — pattern из ServeMux ("/api/files/"), а не сырой путь, чтобы
+// число рядов не росло от ID в URL.
+
+var latencyBuckets = []float64{0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10}
+
+type requestKey struct {
+	route, method, code string
+}
+
+type histogram struct {
+	counts []uint64 // по latencyBuckets, не накопительные
+	sum    float64
+	count  uint64
+}
+
+func (h *histogram) observe(v float64) {
+	for i, b := range latencyBuckets {
+		if v <= b {
+			h.counts[i]++
+			break
+		}
+	}
+	h.sum += v
+	h.count++
+}
+
+type metrics struct {
+	mu          sync.Mutex
+	requests    map[requestKey]uint64
+	durations   map[string]*histogram // route
+	rateLimited map[string]uint64     // route лимитера
+	inFlight    atomic.Int64
+	panics      atomic.Uint64
+	start       time.Time
+}
+
+func newMetrics() *metrics {
+	return &metrics{
+		requests:    make(map[requestKey]uint64),
+		durations:   make(map[string]*histogram),
+		rateLimited: make(map[string]uint64),
+		start:       time.Now(),
+	}
+}
+
+func (m *metrics) observeRequest(route, method string, code int, d time.Duration) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.requests[requestKey{route, method, strconv.Itoa(code)}]++
+	h, ok := m.durations[route]
+	if !ok {
+		h = &histogram{counts: make([]uint64, len(latencyBuckets))}
+		m.durations[route] = h
+	}
+	h.observe(d.Seconds())
+}
+
+func (m *metrics) rateLimitRejected(route string) {
+	m.mu.Lock()
+	m.rateLimited[route]++
+	m.mu.Unlock()
+}
+
+func (m *metrics) panicked() { m.panics.Add(1) }
+
+// knownMethod — метод как label; остальное в "other", иначе клиент
+// может наплодить рядов произвольными методами.
+func knownMethod(method string) string {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodPost, http.MethodPut,
+		http.MethodPatch, http.MethodDelete, http.MethodOptions:
+		return method
+	}
+	return "other"
+}
+
+// instrument — считает запросы и латентность по маршрутам mux.
+func instrument(m *metrics, mux *http.ServeMux) middleware {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			_, route := mux.Handler(r)
+			if route == "" {
+				route = "unmatched"
+			}
+
+			start := time.Now()
+			rw := newResponseWriter(w)
+			m.inFlight.Add(1)
+			defer func() {
+				m.inFlight.Add(-1)
+				m.observeRequest(route, knownMethod(r.Method), rw.status, time.Since(start))
+			}()
+			next.ServeHTTP(rw, r)
+		})
+	}
+}
+
+// ==== /metrics ====
+
+func (m *metrics) writeTo(w io.Writer) error {
+	bw := bufio.NewWriter(w)
+
+	m.mu.Lock()
+	reqKeys := make([]requestKey, 0, len(m.requests))
+	for k := range m.requests {
+		reqKeys = append(reqKeys, k)
+	}
+	sort.Slice(reqKeys, func(i, j int) bool {
+		a, b := reqKeys[i], reqKeys[j]
+		if a.route != b.route {
+			return a.route < b.route
+		}
+		if a.method != b.method {
+			return a.method < b.method
+		}
+		return a.code < b.code
+	})
+
+	header(bw, "http_requests_total", "counter", "HTTP requests by route, method and status code.")
+	for _, k := range reqKeys {
+		fmt.Fprintf(bw, "http_requests_total{route=%s,method=%s,code=%s} %d\n",
+			quoteLabel(k.route), quoteLabel(k.method), quoteLabel(k.code), m.requests[k])
+	}
+
+	header(bw, "http_request_duration_seconds", "histogram", "HTTP request latency by route.")
+	for _, route := range sortedKeys(m.durations) {
+		h := m.durations[route]
+		var cum uint64
+		for i, b := range latencyBuckets {
+			cum += h.counts[i]
+			fmt.Fprintf(bw, "http_request_duration_seconds_bucket{route=%s,le=%q} %d\n",
+				quoteLabel(route), strconv.FormatFloat(b, 'g', -1, 64), cum)
+		}
+		fmt.Fprintf(bw, "http_request_duration_seconds_bucket{route=%s,le=\"+Inf\"} %d\n", quoteLabel(route), h.count)
+		fmt.Fprintf(bw, "http_request_duration_seconds_sum{route=%s} %s\n", quoteLabel(route), strconv.FormatFloat(h.sum, 'g', -1, 64))
+		fmt.Fprintf(bw, "http_request_duration_seconds_count{route=%s} %d\n", quoteLabel(route), h.count)
+	}
+
+	header(bw, "http_rate_limited_total", "counter", "Requests rejected by the rate limiter, by limiter route.")
+	for _, route := range sortedKeys(m.rateLimited) {
+		fmt.Fprintf(bw, "http_rate_limited_total{route=%s} %d\n", quoteLabel(route), m.rateLimited[route])
+	}
+	m.mu.Unlock()
+
+	header(bw, "http_requests_in_flight", "gauge", "HTTP requests currently being served.")
+	fmt.Fprintf(bw, "http_requests_in_flight %d\n", m.inFlight.Load())
+
+	header(bw, "http_panics_total", "counter", "Panics recovered in HTTP handlers.")
+	fmt.Fprintf(bw, "http_panics_total %d\n", m.panics.Load())
+
+	header(bw, "process_start_time_seconds", "gauge", "Start time of the process since unix epoch in seconds.")
+	fmt.Fprintf(bw, "process_start_time_seconds %d\n", m.start.Unix())
+
+	return bw.Flush()
+}
+
+func header(w io.Writer, name, typ, help string) {
+	fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s %s\n", name, help, name, typ)
+}
+
+// quoteLabel — значение label в кавычках; экранируются \, " и перевод строки.
+func quoteLabel(v string) string {
+	v = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`).Replace(v)
+	return `"` + v + `"`
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// metricsHandler отдаёт метрики только адресам из allowed (по умолчанию
+// localhost): Prometheus ходит напрямую, мимо Nginx.
+func metricsHandler(m *metrics, allowed ipNets) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet && r.Method != http.MethodHead {
+			writeJSON(w, http.StatusMethodNotAllowed, "method not allowed")
+			return
+		}
+		ip := parseIP(clientIP(r))
+		if ip == nil || !allowed.contains(ip) {
+			writeJSON(w, http.StatusForbidden, "forbidden")
+			return
+		}
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+		w.Header().Set("Cache-Control", "no-store")
+		if r.Method == http.MethodHead {
+			return
+		}
+		_ = m.writeTo(w) // ошибка = клиент отвалился, ответить уже некуда
+	}
+}
@@ -0,0 +1,297 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ==== Rate Limiter ====
+
+// RateLimitPolicy — лимит для маршрута: Limit запросов за Window.
+type RateLimitPolicy struct {
+	Algorithm string        // token-bucket | sliding-window
+	Limit     int           // запросов за окно (для token-bucket — ёмкость ведра)
+	Window    time.Duration // окно (для token-bucket — время полного пополнения)
+}
+
+// RateLimitResult — решение лимитера + данные для X-RateLimit-* заголовков.
+type RateLimitResult struct {
+	Allowed    bool
+	Limit      int
+	Remaining  int
+	Reset      time.Duration // через сколько лимит полностью восстановится
+	RetryAfter time.Duration // когда можно повторить (только если !Allowed)
+}
+
+// RateLimiter — общий интерфейс алгоритмов ограничения.
+type RateLimiter interface {
+	Allow(key string) RateLimitResult
+	Close()
+}
+
+const (
+	AlgoTokenBucket   = "token-bucket"
+	AlgoSlidingWindow = "sliding-window"
+)
+
+func newRateLimiter(p RateLimitPolicy, janitorInterval time.Duration) (RateLimiter, error) {
+	if p.Limit <= 0 || p.Window <= 0 {
+		return nil, fmt.Errorf("rate limit: invalid policy limit=%d window=%v", p.Limit, p.Window)
+	}
+	switch p.Algorithm {
+	case AlgoTokenBucket:
+		return newTokenBucketLimiter(p.Limit, p.Window, janitorInterval), nil
+	case "", AlgoSlidingWindow:
+		return newSlidingWindowLimiter(p.Limit, p.Window, janitorInterval), nil
+	default:
+		return nil, fmt.Errorf("rate limit: unknown algorithm %q", p.Algorithm)
+	}
+}
+
+// ==== Token bucket ====
+
+type bucket struct {
+	tokens float64
+	last   time.Time
+}
+
+// tokenBucketLimiter — ведро на capacity токенов, пополняется равномерно
+// (capacity за window). Допускает короткие всплески до capacity.
+type tokenBucketLimiter struct {
+	mu       sync.Mutex
+	buckets  map[string]*bucket
+	capacity float64
+	rate     float64 // токенов в секунду
+	window   time.Duration
+	now      func() time.Time // подменяется в тестах
+	stop     chan struct{}
+	once     sync.Once
+}
+
+func newTokenBucketLimiter(capacity int, window time.Duration, janitorInterval time.Duration) *tokenBucketLimiter {
+	l := &tokenBucketLimiter{
+		buckets:  make(map[string]*bucket),
+		capacity: float64(capacity),
+		rate:     float64(capacity) / window.Seconds(),
+		window:   window,
+		now:      time.Now,
+		stop:     make(chan struct{}),
+	}
+	go runJanitor(janitorInterval, l.stop, l.evictIdle)
+	return l
+}
+
+func (l *tokenBucketLimiter) Allow(key string) RateLimitResult {
+	now := l.now()
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	b, ok := l.buckets[key]
+	if !ok {
+		b = &bucket{tokens: l.capacity, last: now}
+		l.buckets[key] = b
+	}
+
+	// Пополнение с момента последнего запроса
+	b.tokens = math.Min(l.capacity, b.tokens+now.Sub(b.last).Seconds()*l.rate)
+	b.last = now
+
+	res := RateLimitResult{Limit: int(l.capacity)}
+	if b.tokens >= 1 {
+		b.tokens--
+		res.Allowed = true
+	} else {
+		res.RetryAfter = secondsToDuration((1 - b.tokens) / l.rate)
+	}
+	res.Remaining = int(b.tokens)
+	res.Reset = secondsToDuration((l.capacity - b.tokens) / l.rate)
+	return res
+}
+
+// evictIdle удаляет вёдра, которые уже полностью пополнились: они
+// ничем не отличаются от нового ключа, хранить их незачем.
+func (l *tokenBucketLimiter) evictIdle() {
+	now := l.now()
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	for key, b := range l.buckets {
+		if now.Sub(b.last) >= l.window {
+			delete(l.buckets, key)
+		}
+	}
+}
+
+func (l *tokenBucketLimiter) Close() {
+	l.once.Do(func() { close(l.stop) })
+}
+
+// ==== Sliding window counter ====
+
+type windowCounter struct {
+	start time.Time // начало текущего окна
+	cur   int
+	prev  int
+}
+
+// slidingWindowLimiter — два счётчика (текущее и предыдущее окно) на ключ,
+// предыдущее учитывается пропорционально перекрытию. O(1) памяти на IP
+// вместо []time.Time на каждый запрос.
+type slidingWindowLimiter struct {
+	mu       sync.Mutex
+	counters map[string]*windowCounter
+	limit    int
+	window   time.Duration
+	now      func() time.Time // подменяется в тестах
+	stop     chan struct{}
+	once     sync.Once
+}
+
+func newSlidingWindowLimiter(limit int, window time.Duration, janitorInterval time.Duration) *slidingWindowLimiter {
+	l := &slidingWindowLimiter{
+		counters: make(map[string]*windowCounter),
+		limit:    limit,
+		window:   window,
+		now:      time.Now,
+		stop:     make(chan struct{}),
+	}
+	go runJanitor(janitorInterval, l.stop, l.evictIdle)
+	return l
+}
+
+func (l *slidingWindowLimiter) Allow(key string) RateLimitResult {
+	now := l.now()
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	c, ok := l.counters[key]
+	if !ok {
+		c = &windowCounter{start: now.Truncate(l.window)}
+		l.counters[key] = c
+	}
+
+	// Сдвиг окон
+	if elapsed := now.Sub(c.start); elapsed >= l.window {
+		if elapsed >= 2*l.window {
+			c.prev = 0
+		} else {
+			c.prev = c.cur
+		}
+		c.cur = 0
+		c.start = now.Truncate(l.window)
+	}
+
+	elapsed := now.Sub(c.start)
+	weight := 1 - float64(elapsed)/float64(l.window)
+	estimate := float64(c.prev)*weight + float64(c.cur)
+
+	res := RateLimitResult{Limit: l.limit, Reset: l.window - elapsed}
+	if estimate+1 <= float64(l.limit) {
+		c.cur++
+		estimate++
+		res.Allowed = true
+	} else {
+		res.RetryAfter = l.retryAfter(c, elapsed)
+	}
+	res.Remaining = max(0, l.limit-int(math.Ceil(estimate)))
+	return res
+}
+
+// retryAfter — через сколько вклад предыдущего окна уменьшится настолько,
+// что следующий запрос пройдёт.
+func (l *slidingWindowLimiter) retryAfter(c *windowCounter, elapsed time.Duration) time.Duration {
+	remaining := l.window - elapsed
+	if c.prev == 0 || c.cur+1 > l.limit {
+		return remaining
+	}
+	// prev*(1 - t/window) + cur + 1 <= limit  =>  t >= window*(1 - (limit-cur-1)/prev)
+	need := time.Duration(float64(l.window) * (1 - float64(l.limit-c.cur-1)/float64(c.prev)))
+	if wait := need - elapsed; wait > 0 && wait < remaining {
+		return wait
+	}
+	return remaining
+}
+
+// evictIdle удаляет ключи без запросов два окна подряд.
+func (l *slidingWindowLimiter) evictIdle() {
+	now := l.now()
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	for key, c := range l.counters {
+		if now.Sub(c.start) >= 2*l.window {
+			delete(l.counters, key)
+		}
+	}
+}
+
+func (l *slidingWindowLimiter) Close() {
+	l.once.Do(func() { close(l.stop) })
+}
+
+// ==== Лимиты по маршрутам ====
+
+type routeLimiter struct {
+	pattern string // "/api/login" — точное совпадение, "/api/" — префикс
+	limiter RateLimiter
+}
+
+// routeRateLimiter выбирает лимитер по пути (самый длинный подходящий
+// pattern, как в http.ServeMux), иначе — лимитер по умолчанию.
+type routeRateLimiter struct {
+	def    RateLimiter
+	routes []routeLimiter
+}
+
+func newRouteRateLimiter(cfg Config) (*routeRateLimiter, error) {
+	def, err := newRateLimiter(RateLimitPolicy{
+		Algorithm: cfg.RateLimitAlgorithm,
+		Limit:     cfg.RateLimitMax,
+		Window:    cfg.RateLimitWindow,
+	}, cfg.RateLimitJanitorInterval)
+	if err != nil {
+		return nil, err
+	}
+
+	rl := &routeRateLimiter{def: def}
+	for pattern, p := range cfg.RateLimitRoutes {
+		if p.Algorithm == "" {
+			p.Algorithm = cfg.RateLimitAlgorithm
+		}
+		l, err := newRateLimiter(p, cfg.RateLimitJanitorInterval)
+		if err != nil {
+			rl.Close()
+			return nil, fmt.Errorf("route %s: %w", pattern, err)
+		}
+		rl.routes = append(rl.routes, routeLimiter{pattern: pattern, limiter: l})
+	}
+	sort.Slice(rl.routes, func(i, j int) bool {
+		return len(rl.routes[i].pattern) > len(rl.routes[j].pattern)
+	})
+	return rl, nil
+}
+
+// forPath возвращает имя маршрута (для логов/метрик) и его лимитер.
+func (rl *routeRateLimiter) forPath(path string) (string, RateLimiter) {
+	for _, r := range rl.routes {
+		if path == r.pattern || (strings.HasSuffix(r.pattern, "/") && strings.HasPrefix(path, r.pattern)) {
+			return r.pattern, r.limiter
+		}
+	}
+	return "default", rl.def
+}
+
+func (rl *routeRateLimiter) Close() {
+	rl.def.Close()
+	for _, r := range rl.routes {
+		r.limiter.Close()
+	}
+}
+
+func secondsToDuration(s float64) time.Duration {
+	return time.Duration(s * float64(time.Second))
+}
@@ -0,0 +1,309 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeClock — часы для лимитеров, двигаются только вручную.
+type fakeClock struct {
+	mu sync.Mutex
+	t  time.Time
+}
+
+func (c *fakeClock) now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.t
+}
+
+func (c *fakeClock) advance(d time.Duration) {
+	c.mu.Lock()
+	c.t = c.t.Add(d)
+	c.mu.Unlock()
+}
+
+// testEpoch кратен окнам тестов: Truncate(window) не сдвигает начало окна.
+var testEpoch = time.Unix(1_000_000, 0)
+
+type allowStep struct {
+	advance    time.Duration
+	key        string
+	allowed    bool
+	remaining  int
+	retryAfter time.Duration
+}
+
+func runSteps(t *testing.T, l RateLimiter, clock *fakeClock, steps []allowStep) {
+	t.Helper()
+	for i, s := range steps {
+		clock.advance(s.advance)
+		key := s.key
+		if key == "" {
+			key = "a"
+		}
+		res := l.Allow(key)
+		if res.Allowed != s.allowed || res.Remaining != s.remaining || res.RetryAfter != s.retryAfter {
+			t.Errorf("шаг %d (+%v, %s): allowed %v remaining %d retry %v; want %v %d %v",
+				i, s.advance, key, res.Allowed, res.Remaining, res.RetryAfter, s.allowed, s.remaining, s.retryAfter)
+		}
+	}
+}
+
+func newTestTokenBucket(capacity int, window time.Duration) (*tokenBucketLimiter, *fakeClock) {
+	clock := &fakeClock{t: testEpoch}
+	l := newTokenBucketLimiter(capacity, window, 0)
+	l.now = clock.now
+	return l, clock
+}
+
+func newTestSlidingWindow(limit int, window time.Duration) (*slidingWindowLimiter, *fakeClock) {
+	clock := &fakeClock{t: testEpoch}
+	l := newSlidingWindowLimiter(limit, window, 0)
+	l.now = clock.now
+	return l, clock
+}
+
+func TestTokenBucketLimiter(t *testing.T) {
+	// 3 токена, пополнение 1 токен/с
+	l, clock := newTestTokenBucket(3, 3*time.Second)
+	defer l.Close()
+
+	runSteps(t, l, clock, []allowStep{
+		{0, "", true, 2, 0},
+		{0, "", true, 1, 0},
+		{0, "", true, 0, 0},
+		{0, "", false, 0, time.Second}, // всплеск исчерпан
+		{0, "b", true, 2, 0},           // у другого ключа своё ведро
+		{500 * time.Millisecond, "", false, 0, 500 * time.Millisecond},
+		{500 * time.Millisecond, "", true, 0, 0},
+		{10 * time.Second, "", true, 2, 0}, // пополнение не выше ёмкости
+	})
+
+	clock.advance(time.Hour)
+	if res := l.Allow("a"); res.Limit != 3 || res.Reset != time.Second {
+		t.Errorf("Limit %d Reset %v, want 3 и 1s", res.Limit, res.Reset)
+	}
+}
+
+func TestSlidingWindowLimiter(t *testing.T) {
+	l, clock := newTestSlidingWindow(4, 10*time.Second)
+	defer l.Close()
+
+	runSteps(t, l, clock, []allowStep{
+		{0, "", true, 3, 0},
+		{0, "", true, 2, 0},
+		{0, "", true, 1, 0},
+		{0, "", true, 0, 0},
+		{0, "", false, 0, 10 * time.Second}, // предыдущего окна нет — ждать конца текущего
+		{0, "b", true, 3, 0},
+		// Новое окно: вклад предыдущего 4*1.0 — отказ, пока он не упадёт до 3
+		{10 * time.Second, "", false, 0, 2500 * time.Millisecond},
+		{2500 * time.Millisecond, "", true, 0, 0},  // 4*0.75 + 0 + 1 = 4
+		{0, "", false, 0, 2500 * time.Millisecond}, // 3 + 1 + 1 > 4: ждать ещё четверть окна
+		{20 * time.Second, "", true, 3, 0},         // два окна тишины — счёт с нуля
+		{0, "", true, 2, 0},
+	})
+
+	clock.advance(4 * time.Second) // окно началось на 30s, сейчас 36.5s
+	if res := l.Allow("a"); res.Limit != 4 || res.Reset != 3500*time.Millisecond {
+		t.Errorf("Limit %d Reset %v, want 4 и 3.5s", res.Limit, res.Reset)
+	}
+}
+
+func TestTokenBucketEvictIdle(t *testing.T) {
+	l, clock := newTestTokenBucket(3, 3*time.Second)
+	defer l.Close()
+
+	l.Allow("a")
+	clock.advance(2 * time.Second)
+	l.Allow("b")
+	clock.advance(time.Second) // "a" пополнилось полностью, "b" — нет
+
+	l.evictIdle()
+	if _, ok := l.buckets["a"]; ok {
+		t.Error("полное ведро не удалено")
+	}
+	if _, ok := l.buckets["b"]; !ok {
+		t.Error("неполное ведро удалено")
+	}
+}
+
+func TestSlidingWindowEvictIdle(t *testing.T) {
+	l, clock := newTestSlidingWindow(4, 10*time.Second)
+	defer l.Close()
+
+	l.Allow("a")
+	clock.advance(19 * time.Second) // предыдущее окно ещё влияет на оценку
+	l.evictIdle()
+	if _, ok := l.counters["a"]; !ok {
+		t.Fatal("ключ удалён, пока предыдущее окно учитывается")
+	}
+	clock.advance(time.Second)
+	l.evictIdle()
+	if _, ok := l.counters["a"]; ok {
+		t.Error("ключ без запросов два окна не удалён")
+	}
+}
+
+func TestRateLimiterJanitor(t *testing.T) {
+	for _, algo := range []string{AlgoTokenBucket, AlgoSlidingWindow} {
+		t.Run(algo, func(t *testing.T) {
+			l, err := newRateLimiter(RateLimitPolicy{Algorithm: algo, Limit: 5, Window: 10 * time.Millisecond}, 5*time.Millisecond)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer l.Close()
+			l.Allow("a")
+
+			size := func() int {
+				switch l := l.(type) {
+				case *tokenBucketLimiter:
+					l.mu.Lock()
+					defer l.mu.Unlock()
+					return len(l.buckets)
+				case *slidingWindowLimiter:
+					l.mu.Lock()
+					defer l.mu.Unlock()
+					return len(l.counters)
+				}
+				t.Fatalf("неожиданный тип %T", l)
+				return 0
+			}
+			deadline := time.Now().Add(5 * time.Second)
+			for size() != 0 {
+				if time.Now().After(deadline) {
+					t.Fatal("janitor не удалил простаивающий ключ")
+				}
+				time.Sleep(5 * time.Millisecond)
+			}
+		})
+	}
+}
+
+func TestNewRateLimiterRejectsBadPolicy(t *testing.T) {
+	for _, p := range []RateLimitPolicy{
+		{Algorithm: AlgoTokenBucket, Limit: 0, Window: time.Second},
+		{Algorithm: AlgoSlidingWindow, Limit: 1, Window: 0},
+		{Algorithm: "leaky-bucket", Limit: 1, Window: time.Second},
+	} {
+		if l, err := newRateLimiter(p, 0); err == nil {
+			l.Close()
+			t.Errorf("политика %+v принята", p)
+		}
+	}
+}
+
+func newTestRouteLimiter(t *testing.T) *routeRateLimiter {
+	t.Helper()
+	rl, err := newRouteRateLimiter(Config{
+		RateLimitAlgorithm: AlgoSlidingWindow,
+		RateLimitMax:       100,
+		RateLimitWindow:    time.Minute,
+		RateLimitRoutes: map[string]RateLimitPolicy{
+			"/api/login":  {Algorithm: AlgoSlidingWindow, Limit: 2, Window: time.Minute},
+			"/api/":       {Algorithm: AlgoTokenBucket, Limit: 50, Window: time.Minute},
+			"/api/token/": {Limit: 30, Window: time.Minute}, // алгоритм по умолчанию
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(rl.Close)
+	return rl
+}
+
+func TestRouteRateLimiterSelectsPolicy(t *testing.T) {
+	rl := newTestRouteLimiter(t)
+
+	tests := []struct {
+		path      string
+		wantRoute string
+		wantLimit int
+	}{
+		{"/api/login", "/api/login", 2},
+		{"/api/login/extra", "/api/", 50}, // без "/" в конце — только точное совпадение
+		{"/api/token/refresh", "/api/token/", 30},
+		{"/api/token/", "/api/token/", 30},
+		{"/api/token", "/api/", 50},
+		{"/api/data", "/api/", 50},
+		{"/api", "default", 100},
+		{"/healthz", "default", 100},
+	}
+	for _, tt := range tests {
+		route, l := rl.forPath(tt.path)
+		if route != tt.wantRoute {
+			t.Errorf("forPath(%q) = %q, want %q", tt.path, route, tt.wantRoute)
+			continue
+		}
+		if res := l.Allow("probe-" + tt.path); res.Limit != tt.wantLimit {
+			t.Errorf("%s: limit %d, want %d", tt.path, res.Limit, tt.wantLimit)
+		}
+	}
+
+	if _, l := rl.forPath("/api/token/refresh"); func() bool { _, ok := l.(*slidingWindowLimiter); return !ok }() {
+		t.Errorf("маршрут без алгоритма не унаследовал %s: %T", AlgoSlidingWindow, l)
+	}
+	if _, l := rl.forPath("/api/data"); func() bool { _, ok := l.(*tokenBucketLimiter); return !ok }() {
+		t.Errorf("алгоритм маршрута не применён: %T", l)
+	}
+}
+
+func TestNewRouteRateLimiterRejectsBadRoute(t *testing.T) {
+	_, err := newRouteRateLimiter(Config{
+		RateLimitAlgorithm: AlgoSlidingWindow,
+		RateLimitMax:       100,
+		RateLimitWindow:    time.Minute,
+		RateLimitRoutes:    map[string]RateLimitPolicy{"/api/login": {Limit: 0, Window: time.Minute}},
+	})
+	if err == nil {
+		t.Error("маршрут с limit=0 принят")
+	}
+}
+
+func TestRateLimitMiddleware(t *testing.T) {
+	rl := newLiveRateLimiter(newTestRouteLimiter(t))
+	h := rateLimit(rl, newMetrics())(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+
+	do := func(path, ip string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, path, nil)
+		req.RemoteAddr = ip + ":5000"
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, req)
+		return rec
+	}
+
+	for i := 0; i < 2; i++ {
+		if rec := do("/api/login", "203.0.113.1"); rec.Code != http.StatusOK {
+			t.Fatalf("запрос %d: %d", i, rec.Code)
+		}
+	}
+	rec := do("/api/login", "203.0.113.1")
+	if rec.Code != http.StatusTooManyRequests {
+		t.Fatalf("третий вход: %d, want 429", rec.Code)
+	}
+	for k, want := range map[string]string{
+		"X-RateLimit-Limit":     "2",
+		"X-RateLimit-Remaining": "0",
+	} {
+		if got := rec.Header().Get(k); got != want {
+			t.Errorf("%s: %q, want %q", k, got, want)
+		}
+	}
+	// Часы настоящие: до конца окна осталось от 1 до 60 секунд
+	if n, err := strconv.Atoi(rec.Header().Get("Retry-After")); err != nil || n < 1 || n > 60 {
+		t.Errorf("Retry-After %q", rec.Header().Get("Retry-After"))
+	}
+
+	// Лимит маршрута не задевает другие маршруты и другие IP
+	if rec := do("/api/data", "203.0.113.1"); rec.Code != http.StatusOK || rec.Header().Get("X-RateLimit-Limit") != "50" {
+		t.Errorf("/api/data: %d, limit %q", rec.Code, rec.Header().Get("X-RateLimit-Limit"))
+	}
+	if rec := do("/api/login", "203.0.113.2"); rec.Code != http.StatusOK {
+		t.Errorf("другой IP: %d", rec.Code)
+	}
+}
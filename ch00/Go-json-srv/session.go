@@ -0,0 +1,297 @@
+package main
+
+import (
+	"context"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ==== Сессии ====
+
+// ErrSessionNotFound — сессии нет или она истекла.
+var ErrSessionNotFound = errors.New("session not found")
+
+// Session — серверная сессия: cookie хранит только ID, CSRF токен привязан к ней.
+type Session struct {
+	ID        string    `json:"id"`
+	Username  string    `json:"username"`
+	CSRFToken string    `json:"csrf_token"`
+	CreatedAt time.Time `json:"created_at"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+func (s *Session) expired(now time.Time) bool {
+	return !now.Before(s.ExpiresAt)
+}
+
+// SessionStore — подключаемое хранилище сессий (memory, file, позже Redis).
+type SessionStore interface {
+	Save(s *Session) error
+	Get(id string) (*Session, error) // ErrSessionNotFound, если нет или истекла
+	Delete(id string) error
+	Ping(ctx context.Context) error // health check
+	Close() error
+}
+
+// newSession создаёт сессию со случайными ID и CSRF токеном.
+func newSession(username string, ttl time.Duration) (*Session, error) {
+	id, err := randomToken(SessionTokenLength)
+	if err != nil {
+		return nil, err
+	}
+	csrfToken, err := randomToken(SessionTokenLength)
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	return &Session{
+		ID:        id,
+		Username:  username,
+		CSRFToken: csrfToken,
+		CreatedAt: now,
+		ExpiresAt: now.Add(ttl),
+	}, nil
+}
+
+// newSessionStore выбирает реализацию по конфигу.
+func newSessionStore(cfg Config) (SessionStore, error) {
+	switch cfg.SessionStore {
+	case "", "memory":
+		return newMemorySessionStore(cfg.SessionGCInterval), nil
+	case "file":
+		return newFileSessionStore(cfg.SessionDir, cfg.SessionGCInterval)
+	default:
+		return nil, fmt.Errorf("unknown session store %q", cfg.SessionStore)
+	}
+}
+
+// sessionFromRequest достаёт сессию по cookie.
+func sessionFromRequest(store SessionStore, r *http.Request) (*Session, error) {
+	c, err := r.Cookie(SessionCookieName)
+	if err != nil || c.Value == "" {
+		return nil, ErrSessionNotFound
+	}
+	return store.Get(c.Value)
+}
+
+// validCSRF — сравнение за постоянное время (без утечки по таймингу).
+func validCSRF(s *Session, token string) bool {
+	if s == nil || s.CSRFToken == "" || token == "" {
+		return false
+	}
+	return subtle.ConstantTimeCompare([]byte(s.CSRFToken), []byte(token)) == 1
+}
+
+// ==== In-memory хранилище ====
+
+type memorySessionStore struct {
+	mu       sync.RWMutex
+	sessions map[string]*Session
+	stop     chan struct{}
+	once     sync.Once
+}
+
+func newMemorySessionStore(gcInterval time.Duration) *memorySessionStore {
+	s := &memorySessionStore{
+		sessions: make(map[string]*Session),
+		stop:     make(chan struct{}),
+	}
+	go runJanitor(gcInterval, s.stop, s.gc)
+	return s
+}
+
+func (s *memorySessionStore) Save(sess *Session) error {
+	cp := *sess
+	s.mu.Lock()
+	s.sessions[sess.ID] = &cp
+	s.mu.Unlock()
+	return nil
+}
+
+func (s *memorySessionStore) Get(id string) (*Session, error) {
+	s.mu.RLock()
+	sess, ok := s.sessions[id]
+	s.mu.RUnlock()
+	if !ok || sess.expired(time.Now()) {
+		return nil, ErrSessionNotFound
+	}
+	cp := *sess
+	return &cp, nil
+}
+
+func (s *memorySessionStore) Delete(id string) error {
+	s.mu.Lock()
+	delete(s.sessions, id)
+	s.mu.Unlock()
+	return nil
+}
+
+func (s *memorySessionStore) Ping(ctx context.Context) error { return nil }
+
+func (s *memorySessionStore) Close() error {
+	s.once.Do(func() { close(s.stop) })
+	return nil
+}
+
+// gc удаляет истёкшие сессии, чтобы map не рос бесконечно.
+func (s *memorySessionStore) gc() {
+	now := time.Now()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for id, sess := range s.sessions {
+		if sess.expired(now) {
+			delete(s.sessions, id)
+		}
+	}
+}
+
+// ==== Файловое хранилище ====
+
+// fileSessionStore хранит каждую сессию в отдельном JSON файле.
+// Имя файла — SHA-256 от ID: сам токен на диске не светится и не влияет на путь.
+type fileSessionStore struct {
+	dir  string
+	mu   sync.Mutex
+	stop chan struct{}
+	once sync.Once
+}
+
+func newFileSessionStore(dir string, gcInterval time.Duration) (*fileSessionStore, error) {
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return nil, fmt.Errorf("session dir: %w", err)
+	}
+	s := &fileSessionStore{dir: dir, stop: make(chan struct{})}
+	go runJanitor(gcInterval, s.stop, s.gc)
+	return s, nil
+}
+
+func (s *fileSessionStore) path(id string) string {
+	sum := sha256.Sum256([]byte(id))
+	return filepath.Join(s.dir, hex.EncodeToString(sum[:])+".json")
+}
+
+func (s *fileSessionStore) Save(sess *Session) error {
+	data, err := json.Marshal(sess)
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	// Атомарная запись: temp файл + rename
+	tmp, err := os.CreateTemp(s.dir, ".session-*")
+	if err != nil {
+		return err
+	}
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	return os.Rename(tmp.Name(), s.path(sess.ID))
+}
+
+func (s *fileSessionStore) Get(id string) (*Session, error) {
+	if id == "" {
+		return nil, ErrSessionNotFound
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	data, err := os.ReadFile(s.path(id))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, ErrSessionNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var sess Session
+	if err := json.Unmarshal(data, &sess); err != nil {
+		return nil, err
+	}
+	if sess.ID != id {
+		return nil, ErrSessionNotFound
+	}
+	if sess.expired(time.Now()) {
+		os.Remove(s.path(id))
+		return nil, ErrSessionNotFound
+	}
+	return &sess, nil
+}
+
+func (s *fileSessionStore) Delete(id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := os.Remove(s.path(id)); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	return nil
+}
+
+// Ping — каталог сессий доступен на запись.
+func (s *fileSessionStore) Ping(ctx context.Context) error { return dirWritable(s.dir) }
+
+func (s *fileSessionStore) Close() error {
+	s.once.Do(func() { close(s.stop) })
+	return nil
+}
+
+func (s *fileSessionStore) gc() {
+	entries, err := os.ReadDir(s.dir)
+	if err != nil {
+		log.Printf("session gc: %v", err)
+		return
+	}
+
+	now := time.Now()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, e := range entries {
+		if e.IsDir() || !strings.HasSuffix(e.Name(), ".json") {
+			continue
+		}
+		p := filepath.Join(s.dir, e.Name())
+		data, err := os.ReadFile(p)
+		if err != nil {
+			continue
+		}
+		var sess Session
+		if err := json.Unmarshal(data, &sess); err != nil || sess.expired(now) {
+			os.Remove(p)
+		}
+	}
+}
+
+// runJanitor периодически вызывает fn, пока не закрыт stop.
+func runJanitor(interval time.Duration, stop <-chan struct{}, fn func()) {
+	if interval <= 0 {
+		return
+	}
+	t := time.NewTicker(interval)
+	defer t.Stop()
+	for {
+		select {
+		case <-t.C:
+			fn()
+		case <-stop:
+			return
+		}
+	}
+}
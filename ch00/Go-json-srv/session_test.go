@@ -0,0 +1,283 @@
+package main
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+)
+
+const testOrigin = "https://app.example.com"
+
+func saveSession(t *testing.T, store SessionStore, username string, ttl time.Duration) *Session {
+	t.Helper()
+	sess, err := newSession(username, ttl)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := store.Save(sess); err != nil {
+		t.Fatal(err)
+	}
+	return sess
+}
+
+func newTestSessionStores(t *testing.T) map[string]SessionStore {
+	t.Helper()
+	file, err := newFileSessionStore(t.TempDir(), 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	mem := newMemorySessionStore(0)
+	t.Cleanup(func() { _ = file.Close(); _ = mem.Close() })
+	return map[string]SessionStore{"memory": mem, "file": file}
+}
+
+func TestCSRFTokenBoundToSession(t *testing.T) {
+	for kind, store := range newTestSessionStores(t) {
+		t.Run(kind, func(t *testing.T) {
+			a := saveSession(t, store, "alice", time.Hour)
+			b := saveSession(t, store, "bob", time.Hour)
+			expired := saveSession(t, store, "carol", -time.Second)
+
+			tests := []struct {
+				name     string
+				method   string
+				origin   string
+				cookie   string
+				token    string
+				wantCode int
+			}{
+				{"своя сессия A", http.MethodPost, testOrigin, a.ID, a.CSRFToken, http.StatusOK},
+				{"своя сессия B", http.MethodPost, testOrigin, b.ID, b.CSRFToken, http.StatusOK},
+				{"токен A на сессии B", http.MethodPost, testOrigin, b.ID, a.CSRFToken, http.StatusForbidden},
+				{"токен B на сессии A", http.MethodDelete, testOrigin, a.ID, b.CSRFToken, http.StatusForbidden},
+				{"ID сессии вместо токена", http.MethodPost, testOrigin, a.ID, a.ID, http.StatusForbidden},
+				{"токен без cookie", http.MethodPost, testOrigin, "", a.CSRFToken, http.StatusForbidden},
+				{"неизвестная сессия", http.MethodPost, testOrigin, "forged", a.CSRFToken, http.StatusForbidden},
+				{"истёкшая сессия", http.MethodPost, testOrigin, expired.ID, expired.CSRFToken, http.StatusForbidden},
+				{"без токена", http.MethodPost, testOrigin, a.ID, "", http.StatusForbidden},
+				{"чужой Origin", http.MethodPost, "https://evil.example.com", a.ID, a.CSRFToken, http.StatusForbidden},
+				{"без Origin", http.MethodPost, "", a.ID, a.CSRFToken, http.StatusForbidden},
+				{"GET не проверяется", http.MethodGet, "", "", "", http.StatusOK},
+			}
+			guard := csrfGuard(newOriginPolicy([]string{testOrigin}), store)
+			for _, tt := range tests {
+				t.Run(tt.name, func(t *testing.T) {
+					req := httptest.NewRequest(tt.method, "/api/data", nil)
+					if tt.origin != "" {
+						req.Header.Set("Origin", tt.origin)
+					}
+					if tt.cookie != "" {
+						req.AddCookie(&http.Cookie{Name: SessionCookieName, Value: tt.cookie})
+					}
+					if tt.token != "" {
+						req.Header.Set(CSRFHeaderName, tt.token)
+					}
+					rec := httptest.NewRecorder()
+					guard(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})).ServeHTTP(rec, req)
+					if rec.Code != tt.wantCode {
+						t.Errorf("код %d, want %d: %s", rec.Code, tt.wantCode, rec.Body)
+					}
+				})
+			}
+		})
+	}
+}
+
+func TestValidCSRF(t *testing.T) {
+	sess := &Session{CSRFToken: "token"}
+	for _, tt := range []struct {
+		sess  *Session
+		token string
+		want  bool
+	}{
+		{sess, "token", true},
+		{sess, "token2", false},
+		{sess, "tok", false},
+		{sess, "", false},
+		{&Session{}, "", false}, // пустой токен сессии не совпадает с пустым заголовком
+		{nil, "token", false},
+	} {
+		if got := validCSRF(tt.sess, tt.token); got != tt.want {
+			t.Errorf("validCSRF(%+v, %q) = %v, want %v", tt.sess, tt.token, got, tt.want)
+		}
+	}
+}
+
+func listSessionDir(t *testing.T, dir string) []string {
+	t.Helper()
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	for _, e := range entries {
+		names = append(names, e.Name())
+	}
+	sort.Strings(names)
+	return names
+}
+
+func TestFileSessionStoreRoundTrip(t *testing.T) {
+	dir := t.TempDir()
+	store, err := newFileSessionStore(dir, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer store.Close()
+
+	sess := saveSession(t, store, "alice", time.Hour)
+	got, err := store.Get(sess.ID)
+	if err != nil {
+		t.Fatalf("Get: %v", err)
+	}
+	if got.ID != sess.ID || got.Username != "alice" || got.CSRFToken != sess.CSRFToken ||
+		!got.CreatedAt.Equal(sess.CreatedAt) || !got.ExpiresAt.Equal(sess.ExpiresAt) {
+		t.Errorf("Get %+v, want %+v", got, sess)
+	}
+
+	// На диске — один файл с именем sha256(ID), временных файлов нет, токена в имени нет
+	names := listSessionDir(t, dir)
+	if len(names) != 1 || names[0] != filepath.Base(store.path(sess.ID)) {
+		t.Fatalf("файлы %v", names)
+	}
+	if strings.Contains(names[0], sess.ID) {
+		t.Error("ID сессии в имени файла")
+	}
+	if fi, err := os.Stat(store.path(sess.ID)); err != nil || fi.Mode().Perm()&0o077 != 0 {
+		t.Errorf("права файла сессии: %v, %v", fi.Mode(), err)
+	}
+
+	// Повторный Save заменяет файл целиком
+	sess.Username = "alice2"
+	if err := store.Save(sess); err != nil {
+		t.Fatal(err)
+	}
+	if got, err := store.Get(sess.ID); err != nil || got.Username != "alice2" {
+		t.Errorf("после перезаписи: %+v, %v", got, err)
+	}
+	if names := listSessionDir(t, dir); len(names) != 1 {
+		t.Errorf("после перезаписи файлы %v", names)
+	}
+
+	if err := store.Delete(sess.ID); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := store.Get(sess.ID); !errors.Is(err, ErrSessionNotFound) {
+		t.Errorf("после Delete: %v", err)
+	}
+	if err := store.Delete(sess.ID); err != nil {
+		t.Errorf("повторный Delete: %v", err)
+	}
+	if _, err := store.Get(""); !errors.Is(err, ErrSessionNotFound) {
+		t.Errorf("пустой ID: %v", err)
+	}
+}
+
+func TestFileSessionStoreExpiry(t *testing.T) {
+	dir := t.TempDir()
+	store, err := newFileSessionStore(dir, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer store.Close()
+
+	sess := saveSession(t, store, "alice", -time.Second)
+	if _, err := store.Get(sess.ID); !errors.Is(err, ErrSessionNotFound) {
+		t.Fatalf("истёкшая сессия: %v", err)
+	}
+	if _, err := os.Stat(store.path(sess.ID)); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("файл истёкшей сессии не удалён: %v", err)
+	}
+}
+
+func TestFileSessionStoreRejectsSwappedFile(t *testing.T) {
+	store, err := newFileSessionStore(t.TempDir(), 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer store.Close()
+
+	// Файл чужой сессии под именем нашей — ID внутри не совпадает
+	victim := saveSession(t, store, "alice", time.Hour)
+	data, err := os.ReadFile(store.path(victim.ID))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(store.path("forged"), data, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := store.Get("forged"); !errors.Is(err, ErrSessionNotFound) {
+		t.Errorf("подменённый файл: %v", err)
+	}
+}
+
+func TestFileSessionStoreJanitor(t *testing.T) {
+	dir := t.TempDir()
+	store, err := newFileSessionStore(dir, 10*time.Millisecond)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer store.Close()
+
+	live := saveSession(t, store, "alice", time.Hour)
+	dead := saveSession(t, store, "bob", -time.Second)
+	garbage := filepath.Join(dir, "garbage.json")
+	other := filepath.Join(dir, "notes.txt")
+	for _, p := range []string{garbage, other} {
+		if err := os.WriteFile(p, []byte("{"), 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		_, errDead := os.Stat(store.path(dead.ID))
+		_, errGarbage := os.Stat(garbage)
+		if errors.Is(errDead, os.ErrNotExist) && errors.Is(errGarbage, os.ErrNotExist) {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("janitor не удалил истёкшие файлы: %v", listSessionDir(t, dir))
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	if _, err := store.Get(live.ID); err != nil {
+		t.Errorf("живая сессия: %v", err)
+	}
+	if _, err := os.Stat(other); err != nil {
+		t.Errorf("чужой файл удалён: %v", err)
+	}
+}
+
+func TestMemorySessionStoreGC(t *testing.T) {
+	store := newMemorySessionStore(0)
+	defer store.Close()
+
+	live := saveSession(t, store, "alice", time.Hour)
+	dead := saveSession(t, store, "bob", -time.Second)
+	if _, err := store.Get(dead.ID); !errors.Is(err, ErrSessionNotFound) {
+		t.Errorf("истёкшая сессия: %v", err)
+	}
+
+	store.gc()
+	store.mu.RLock()
+	_, deadLeft := store.sessions[dead.ID]
+	_, liveLeft := store.sessions[live.ID]
+	store.mu.RUnlock()
+	if deadLeft || !liveLeft {
+		t.Errorf("после gc: dead %v, live %v", deadLeft, liveLeft)
+	}
+
+	// Save хранит копию: изменения вызывающего не протекают в хранилище
+	live.Username = "mallory"
+	if got, _ := store.Get(live.ID); got.Username != "alice" {
+		t.Errorf("хранилище разделяет структуру с вызывающим: %q", got.Username)
+	}
+}
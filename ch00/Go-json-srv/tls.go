@@ -0,0 +1,180 @@
+package main
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log/slog"
+	"math/big"
+	"net"
+	"net/http"
+	"sync/atomic"
+	"time"
+)
+
+// ==== Native TLS (без Nginx) ====
+//
+// Для маленьких установок и тестов: сервер сам терминирует TLS.
+//
+//	tls_cert_file + tls_key_file — сертификат из файлов, SIGHUP перечитывает
+//	tls_self_signed=true         — временный самоподписанный (только dev)
+//	tls_redirect_addr=":80"      — отдельный HTTP listener с редиректом на HTTPS
+//
+// HTTP/2 включается вместе с TLS. В режиме за Nginx (по умолчанию) ничего
+// из этого не используется.
+
+// certReloader отдаёт текущий сертификат через tls.Config.GetCertificate,
+// новые соединения после reload получают новый сертификат.
+type certReloader struct {
+	certFile, keyFile string // пусто — самоподписанный
+	cert              atomic.Pointer[tls.Certificate]
+}
+
+func newCertReloader(cfg Config) (*certReloader, error) {
+	c := &certReloader{certFile: cfg.TLSCertFile, keyFile: cfg.TLSKeyFile}
+	if c.certFile == "" {
+		cert, err := selfSignedCert(cfg.Addr)
+		if err != nil {
+			return nil, err
+		}
+		slog.Warn("tls: using self-signed certificate, do not use in production",
+			"sha256", certFingerprint(cert))
+		c.cert.Store(cert)
+		return c, nil
+	}
+	if err := c.reload(); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// reload перечитывает файлы. При ошибке остаётся старый сертификат.
+func (c *certReloader) reload() error {
+	if c.certFile == "" {
+		return nil
+	}
+	cert, err := tls.LoadX509KeyPair(c.certFile, c.keyFile)
+	if err != nil {
+		return fmt.Errorf("tls: %w", err)
+	}
+	c.cert.Store(&cert)
+	return nil
+}
+
+func (c *certReloader) getCertificate(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+	return c.cert.Load(), nil
+}
+
+// Ping — health check: сертификат ещё действует.
+func (c *certReloader) Ping(ctx context.Context) error {
+	leaf := c.cert.Load().Leaf
+	if leaf == nil {
+		return errors.New("no certificate")
+	}
+	if time.Now().After(leaf.NotAfter) {
+		return fmt.Errorf("certificate expired at %s", leaf.NotAfter.Format(time.RFC3339))
+	}
+	return nil
+}
+
+func (c *certReloader) tlsConfig() *tls.Config {
+	return &tls.Config{
+		MinVersion:     tls.VersionTLS12,
+		GetCertificate: c.getCertificate,
+	}
+}
+
+// selfSignedCert — ECDSA P-256 на 30 дней для localhost и хоста из addr.
+func selfSignedCert(addr string) (*tls.Certificate, error) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nil, err
+	}
+	serial, err := rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 128))
+	if err != nil {
+		return nil, err
+	}
+
+	tmpl := &x509.Certificate{
+		SerialNumber:          serial,
+		Subject:               pkix.Name{CommonName: "api-server dev"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(30 * 24 * time.Hour),
+		KeyUsage:              x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+		BasicConstraintsValid: true,
+		DNSNames:              []string{"localhost"},
+		IPAddresses:           []net.IP{net.IPv4(127, 0, 0, 1), net.IPv6loopback},
+	}
+	if host, _, err := net.SplitHostPort(addr); err == nil && host != "" {
+		if ip := net.ParseIP(host); ip != nil {
+			if !ip.IsUnspecified() && !ip.IsLoopback() {
+				tmpl.IPAddresses = append(tmpl.IPAddresses, ip)
+			}
+		} else if host != "localhost" {
+			tmpl.DNSNames = append(tmpl.DNSNames, host)
+		}
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	if err != nil {
+		return nil, err
+	}
+	leaf, err := x509.ParseCertificate(der)
+	if err != nil {
+		return nil, err
+	}
+	return &tls.Certificate{Certificate: [][]byte{der}, PrivateKey: key, Leaf: leaf}, nil
+}
+
+func certFingerprint(cert *tls.Certificate) string {
+	sum := sha256.Sum256(cert.Certificate[0])
+	return hex.EncodeToString(sum[:])
+}
+
+// ==== HTTP → HTTPS ====
+
+// redirectHandler отправляет на тот же путь по HTTPS. Хост берём из
+// запроса, порт — из адреса TLS listener'а (443 не пишем).
+func redirectHandler(tlsAddr string) http.HandlerFunc {
+	_, port, _ := net.SplitHostPort(tlsAddr)
+	return func(w http.ResponseWriter, r *http.Request) {
+		host := r.Host
+		if h, _, err := net.SplitHostPort(r.Host); err == nil {
+			host = h
+		}
+		if host == "" {
+			http.Error(w, "host required", http.StatusBadRequest)
+			return
+		}
+		if port != "" && port != "443" {
+			host = net.JoinHostPort(host, port)
+		}
+
+		// 301 меняет POST на GET, 308 сохраняет метод и тело
+		code := http.StatusPermanentRedirect
+		if r.Method == http.MethodGet || r.Method == http.MethodHead {
+			code = http.StatusMovedPermanently
+		}
+		http.Redirect(w, r, "https://"+host+r.URL.RequestURI(), code)
+	}
+}
+
+func newRedirectServer(cfg Config) *http.Server {
+	return &http.Server{
+		Addr:              cfg.TLSRedirectAddr,
+		Handler:           redirectHandler(cfg.Addr),
+		ReadHeaderTimeout: cfg.ReadHeaderTimeout,
+		WriteTimeout:      cfg.WriteTimeout,
+		IdleTimeout:       cfg.IdleTimeout,
+		MaxHeaderBytes:    cfg.MaxHeaderBytes,
+	}
+}
@@ -0,0 +1,492 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"image"
+	_ "image/gif" // регистрация декодеров для image.DecodeConfig
+	_ "image/jpeg"
+	_ "image/png"
+	"io"
+	"log"
+	"mime"
+	"net/http"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ==== Загрузки (content-addressed хранилище) ====
+//
+// Файл сохраняется под именем SHA-256 содержимого: одинаковые загрузки
+// не дублируются на диске. Рядом лежит <id>.json с метаданными и списком
+// пользователей-владельцев. Квота считается по пользователю: каждый «платит»
+// за то, что загрузил сам, даже если блоб общий. Владелец — пользователь, а не
+// сессия: после выхода или истечения сессии файлы и квота не теряются.
+
+var (
+	ErrFileNotFound  = errors.New("file not found")
+	ErrQuotaExceeded = errors.New("upload quota exceeded")
+)
+
+// allowedUploadTypes — MIME по magic bytes → допустимые расширения.
+var allowedUploadTypes = map[string][]string{
+	"image/png":  {".png"},
+	"image/jpeg": {".jpg", ".jpeg"},
+	"image/gif":  {".gif"},
+}
+
+var fileIDRe = regexp.MustCompile(`^[0-9a-f]{64}$`)
+
+// StoredFile — метаданные загруженного файла.
+type StoredFile struct {
+	ID          string    `json:"id"`
+	Filename    string    `json:"filename"`
+	ContentType string    `json:"content_type"`
+	Size        int64     `json:"size"`
+	Width       int       `json:"width"`
+	Height      int       `json:"height"`
+	CreatedAt   time.Time `json:"created_at"`
+	Owners      []string  `json:"owners,omitempty"` // ключи пользователей (ownerKey), наружу не отдаём
+}
+
+// public — копия без списка владельцев для JSON ответов.
+func (f StoredFile) public() StoredFile {
+	f.Owners = nil
+	return f
+}
+
+type uploadStore struct {
+	dir   string
+	quota int64
+
+	mu    sync.Mutex
+	usage map[string]int64 // ownerKey → байт
+}
+
+func newUploadStore(dir string, quota int64) (*uploadStore, error) {
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return nil, fmt.Errorf("upload dir: %w", err)
+	}
+	s := &uploadStore{dir: dir, quota: quota, usage: make(map[string]int64)}
+
+	// Восстанавливаем счётчики квот по метаданным
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	for _, e := range entries {
+		id, ok := strings.CutSuffix(e.Name(), ".json")
+		if !ok || !fileIDRe.MatchString(id) {
+			continue
+		}
+		meta, err := s.readMeta(id)
+		if err != nil {
+			log.Printf("upload meta %s: %v", id, err)
+			continue
+		}
+		for _, owner := range meta.Owners {
+			s.usage[owner] += meta.Size
+		}
+	}
+	return s, nil
+}
+
+// ownerKey — идентификатор владельца на диске (само имя пользователя не храним).
+func ownerKey(username string) string {
+	sum := sha256.Sum256([]byte("user:" + username))
+	return hex.EncodeToString(sum[:16])
+}
+
+// uploadOwner — владелец загрузок: пользователь cookie-сессии или Bearer
+// токена. Один и тот же при любом способе входа и на любом устройстве.
+func uploadOwner(sessions SessionStore, r *http.Request) (string, bool) {
+	if claims, ok := claimsFrom(r); ok {
+		return ownerKey(claims.Subject), true
+	}
+	sess, err := sessionFromRequest(sessions, r)
+	if err != nil || sess.Username == "" {
+		return "", false
+	}
+	return ownerKey(sess.Username), true
+}
+
+// Ping — каталог загрузок доступен на запись.
+func (s *uploadStore) Ping(ctx context.Context) error { return dirWritable(s.dir) }
+
+func (s *uploadStore) blobPath(id string) string { return filepath.Join(s.dir, id) }
+func (s *uploadStore) metaPath(id string) string { return filepath.Join(s.dir, id+".json") }
+
+func (s *uploadStore) readMeta(id string) (*StoredFile, error) {
+	data, err := os.ReadFile(s.metaPath(id))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, ErrFileNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	var meta StoredFile
+	if err := json.Unmarshal(data, &meta); err != nil {
+		return nil, err
+	}
+	return &meta, nil
+}
+
+func (s *uploadStore) writeMeta(meta *StoredFile) error {
+	data, err := json.Marshal(meta)
+	if err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(s.dir, ".meta-*")
+	if err != nil {
+		return err
+	}
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	return os.Rename(tmp.Name(), s.metaPath(meta.ID))
+}
+
+// remaining — сколько байт ещё может загрузить пользователь.
+func (s *uploadStore) remaining(owner string) int64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return max(0, s.quota-s.usage[owner])
+}
+
+// uploadError — ошибка валидации загрузки с HTTP статусом.
+type uploadError struct {
+	status int
+	msg    string
+}
+
+func (e *uploadError) Error() string { return e.msg }
+
+// save потоково пишет файл во временный файл, параллельно считая SHA-256,
+// проверяет тип по magic bytes и размеры изображения, затем переименовывает
+// его в <sha256>. Не более limit байт.
+func (s *uploadStore) save(owner, filename string, src io.Reader, limit int64) (*StoredFile, error) {
+	ext := strings.ToLower(filepath.Ext(filename))
+
+	// Magic bytes: первые 512 байт (столько смотрит DetectContentType)
+	head := make([]byte, 512)
+	n, err := io.ReadFull(src, head)
+	if err != nil && !errors.Is(err, io.ErrUnexpectedEOF) {
+		if errors.Is(err, io.EOF) {
+			return nil, &uploadError{http.StatusBadRequest, "empty file"}
+		}
+		return nil, err
+	}
+	head = head[:n]
+
+	contentType := http.DetectContentType(head)
+	exts, ok := allowedUploadTypes[contentType]
+	if !ok {
+		return nil, &uploadError{http.StatusUnsupportedMediaType, "unsupported file type"}
+	}
+	if !slices.Contains(exts, ext) {
+		return nil, &uploadError{http.StatusUnsupportedMediaType, "file extension does not match content"}
+	}
+
+	tmp, err := os.CreateTemp(s.dir, ".upload-*")
+	if err != nil {
+		return nil, err
+	}
+	tmpName := tmp.Name()
+	defer os.Remove(tmpName) // после Rename — no-op
+
+	h := sha256.New()
+	// +1 байт: отличаем «ровно limit» от «больше limit»
+	body := io.MultiReader(bytes.NewReader(head), src)
+	size, err := io.Copy(io.MultiWriter(tmp, h), io.LimitReader(body, limit+1))
+	if err != nil {
+		tmp.Close()
+		return nil, err
+	}
+	if size > limit {
+		tmp.Close()
+		return nil, &uploadError{http.StatusRequestEntityTooLarge, "file too large or quota exceeded"}
+	}
+
+	// Размеры изображения — только заголовок, без полного декодирования
+	if _, err := tmp.Seek(0, io.SeekStart); err != nil {
+		tmp.Close()
+		return nil, err
+	}
+	cfg, format, err := image.DecodeConfig(tmp)
+	if cerr := tmp.Close(); cerr != nil && err == nil {
+		err = cerr
+	}
+	if err != nil || "image/"+format != contentType {
+		return nil, &uploadError{http.StatusUnsupportedMediaType, "corrupted image"}
+	}
+	if cfg.Width <= 0 || cfg.Height <= 0 ||
+		cfg.Width > MaxImageDimension || cfg.Height > MaxImageDimension ||
+		int64(cfg.Width)*int64(cfg.Height) > MaxImagePixels {
+		return nil, &uploadError{http.StatusUnprocessableEntity,
+			fmt.Sprintf("image dimensions %dx%d exceed limit", cfg.Width, cfg.Height)}
+	}
+
+	id := hex.EncodeToString(h.Sum(nil))
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	// Квота перепроверяется под локом: параллельные загрузки одного пользователя
+	if s.usage[owner]+size > s.quota {
+		return nil, ErrQuotaExceeded
+	}
+
+	meta, err := s.readMeta(id)
+	switch {
+	case errors.Is(err, ErrFileNotFound):
+		if err := os.Rename(tmpName, s.blobPath(id)); err != nil {
+			return nil, err
+		}
+		meta = &StoredFile{
+			ID:          id,
+			Filename:    filepath.Base(filename),
+			ContentType: contentType,
+			Size:        size,
+			Width:       cfg.Width,
+			Height:      cfg.Height,
+			CreatedAt:   time.Now().UTC(),
+		}
+	case err != nil:
+		return nil, err
+	case slices.Contains(meta.Owners, owner):
+		// Повторная загрузка того же файла тем же пользователем — ничего не меняем
+		return meta, nil
+	}
+
+	meta.Owners = append(meta.Owners, owner)
+	if err := s.writeMeta(meta); err != nil {
+		return nil, err
+	}
+	s.usage[owner] += size
+	return meta, nil
+}
+
+// get возвращает метаданные, если файл принадлежит owner.
+// Чужой файл неотличим от несуществующего.
+func (s *uploadStore) get(owner, id string) (*StoredFile, error) {
+	if !fileIDRe.MatchString(id) {
+		return nil, ErrFileNotFound
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	meta, err := s.readMeta(id)
+	if err != nil {
+		return nil, err
+	}
+	if !slices.Contains(meta.Owners, owner) {
+		return nil, ErrFileNotFound
+	}
+	return meta, nil
+}
+
+// delete снимает владение; блоб удаляется, когда владельцев не осталось.
+func (s *uploadStore) delete(owner, id string) (*StoredFile, error) {
+	if !fileIDRe.MatchString(id) {
+		return nil, ErrFileNotFound
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	meta, err := s.readMeta(id)
+	if err != nil {
+		return nil, err
+	}
+	i := slices.Index(meta.Owners, owner)
+	if i < 0 {
+		return nil, ErrFileNotFound
+	}
+	meta.Owners = slices.Delete(meta.Owners, i, i+1)
+
+	if len(meta.Owners) == 0 {
+		if err := os.Remove(s.metaPath(id)); err != nil {
+			return nil, err
+		}
+		if err := os.Remove(s.blobPath(id)); err != nil && !errors.Is(err, os.ErrNotExist) {
+			log.Printf("upload blob remove %s: %v", id, err)
+		}
+	} else if err := s.writeMeta(meta); err != nil {
+		return nil, err
+	}
+
+	s.usage[owner] -= meta.Size
+	if s.usage[owner] <= 0 {
+		delete(s.usage, owner)
+	}
+	return meta, nil
+}
+
+// ==== Хэндлеры загрузок ====
+
+func uploadHandler(uploads *uploadStore, sessions SessionStore, maxMB int64) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			writeJSON(w, http.StatusMethodNotAllowed, "method not allowed")
+			return
+		}
+
+		// CSRF уже проверен middleware'ом (или запрос с Bearer токеном)
+		owner, ok := uploadOwner(sessions, r)
+		if !ok {
+			writeJSON(w, http.StatusUnauthorized, "session required")
+			return
+		}
+
+		// Потоковое чтение multipart: файл не буферизуется целиком
+		mr, err := r.MultipartReader()
+		if err != nil {
+			writeJSON(w, http.StatusBadRequest, "invalid multipart form")
+			return
+		}
+
+		for {
+			part, err := mr.NextPart()
+			if errors.Is(err, io.EOF) {
+				writeJSON(w, http.StatusBadRequest, "file required")
+				return
+			}
+			if err != nil {
+				writeJSON(w, http.StatusBadRequest, "invalid multipart form")
+				return
+			}
+			if part.FormName() != "file" {
+				part.Close()
+				continue
+			}
+
+			// Безопасная валидация
+			name := filepath.Base(part.FileName())
+			if name == "." || strings.Contains(name, "..") || strings.ContainsAny(name, "/\\") {
+				writeJSON(w, http.StatusBadRequest, "invalid filename")
+				return
+			}
+
+			limit := min(maxMB<<20, uploads.remaining(owner))
+			if limit == 0 {
+				writeJSON(w, http.StatusRequestEntityTooLarge, "upload quota exceeded")
+				return
+			}
+
+			meta, err := uploads.save(owner, name, part, limit)
+			if err != nil {
+				var ue *uploadError
+				switch {
+				case errors.As(err, &ue):
+					writeJSON(w, ue.status, ue.msg)
+				case errors.Is(err, ErrQuotaExceeded):
+					writeJSON(w, http.StatusRequestEntityTooLarge, "upload quota exceeded")
+				default:
+					var mbe *http.MaxBytesError
+					if errors.As(err, &mbe) {
+						writeJSON(w, http.StatusRequestEntityTooLarge, "request body too large")
+						return
+					}
+					log.Printf("upload save: %v", err)
+					writeJSON(w, http.StatusInternalServerError, "upload failed")
+				}
+				return
+			}
+
+			writeJSON(w, http.StatusOK, map[string]interface{}{
+				"status":       "uploaded",
+				"id":           meta.ID,
+				"filename":     name,
+				"size":         meta.Size,
+				"content_type": meta.ContentType,
+				"width":        meta.Width,
+				"height":       meta.Height,
+			})
+			return
+		}
+	}
+}
+
+// filesHandler — GET/DELETE /api/files/{id}.
+// GET отдаёт содержимое (метаданные в X-File-* заголовках), GET ?meta=1 — JSON.
+func filesHandler(uploads *uploadStore, sessions SessionStore) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id := strings.TrimPrefix(r.URL.Path, "/api/files/")
+
+		owner, ok := uploadOwner(sessions, r)
+		if !ok {
+			writeJSON(w, http.StatusUnauthorized, "session required")
+			return
+		}
+
+		switch r.Method {
+		case http.MethodGet, http.MethodHead:
+			meta, err := uploads.get(owner, id)
+			if err != nil {
+				writeFileError(w, err)
+				return
+			}
+			if r.URL.Query().Get("meta") == "1" {
+				writeJSON(w, http.StatusOK, meta.public())
+				return
+			}
+
+			f, err := os.Open(uploads.blobPath(meta.ID))
+			if err != nil {
+				writeFileError(w, err)
+				return
+			}
+			defer f.Close()
+
+			h := w.Header()
+			h.Set("Content-Type", meta.ContentType)
+			// RFC 6266: кавычки/; экранируются, не-ASCII — через filename*=utf-8''
+			h.Set("Content-Disposition", mime.FormatMediaType("inline", map[string]string{"filename": meta.Filename}))
+			h.Set("ETag", `"`+meta.ID+`"`)
+			h.Set("Cache-Control", "private, max-age=31536000, immutable")
+			h.Set("X-File-ID", meta.ID)
+			h.Set("X-File-Width", fmt.Sprint(meta.Width))
+			h.Set("X-File-Height", fmt.Sprint(meta.Height))
+			http.ServeContent(w, r, "", meta.CreatedAt, f)
+
+		case http.MethodDelete:
+			meta, err := uploads.delete(owner, id)
+			if err != nil {
+				writeFileError(w, err)
+				return
+			}
+			writeJSON(w, http.StatusOK, map[string]interface{}{
+				"status": "deleted",
+				"file":   meta.public(),
+			})
+
+		default:
+			w.Header().Set("Allow", "GET, HEAD, DELETE")
+			writeJSON(w, http.StatusMethodNotAllowed, "method not allowed")
+		}
+	}
+}
+
+func writeFileError(w http.ResponseWriter, err error) {
+	if errors.Is(err, ErrFileNotFound) || errors.Is(err, os.ErrNotExist) {
+		writeJSON(w, http.StatusNotFound, "file not found")
+		return
+	}
+	log.Printf("files: %v", err)
+	writeJSON(w, http.StatusInternalServerError, "file store failed")
+}
@@ -0,0 +1,163 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"image"
+	"image/png"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+// fileIDOf — id, под которым uploadStore сохранит data.
+func fileIDOf(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+func testPNG(t *testing.T, w, h int) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, image.NewGray(image.Rect(0, 0, w, h))); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+type uploadEnv struct {
+	uploads  *uploadStore
+	sessions SessionStore
+}
+
+func newUploadEnv(t *testing.T, quota int64) *uploadEnv {
+	t.Helper()
+	uploads, err := newUploadStore(t.TempDir(), quota)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sessions := newMemorySessionStore(0)
+	t.Cleanup(func() { _ = sessions.Close() })
+	return &uploadEnv{uploads: uploads, sessions: sessions}
+}
+
+func (e *uploadEnv) upload(t *testing.T, sess *Session, filename string, data []byte) *httptest.ResponseRecorder {
+	t.Helper()
+	var body bytes.Buffer
+	mw := multipart.NewWriter(&body)
+	fw, err := mw.CreateFormFile("file", filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, _ = fw.Write(data)
+	_ = mw.Close()
+
+	req := httptest.NewRequest(http.MethodPost, "/api/upload", &body)
+	req.Header.Set("Content-Type", mw.FormDataContentType())
+	req.AddCookie(&http.Cookie{Name: SessionCookieName, Value: sess.ID})
+	rec := httptest.NewRecorder()
+	uploadHandler(e.uploads, e.sessions, 1)(rec, req)
+	return rec
+}
+
+func (e *uploadEnv) file(sess *Session, method, id string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, "/api/files/"+id, nil)
+	req.AddCookie(&http.Cookie{Name: SessionCookieName, Value: sess.ID})
+	rec := httptest.NewRecorder()
+	filesHandler(e.uploads, e.sessions)(rec, req)
+	return rec
+}
+
+func TestUploadsOwnedByUserNotSession(t *testing.T) {
+	env := newUploadEnv(t, 1<<20)
+	data := testPNG(t, 4, 3)
+	id := fileIDOf(data)
+
+	first := saveSession(t, env.sessions, "alice", time.Hour)
+	if rec := env.upload(t, first, "a.png", data); rec.Code != http.StatusOK {
+		t.Fatalf("загрузка: %d %s", rec.Code, rec.Body)
+	}
+
+	// Выход: сессия удалена, файл остаётся за пользователем
+	if err := env.sessions.Delete(first.ID); err != nil {
+		t.Fatal(err)
+	}
+	if rec := env.file(first, http.MethodGet, id); rec.Code != http.StatusUnauthorized {
+		t.Errorf("удалённая сессия: %d, want 401", rec.Code)
+	}
+	second := saveSession(t, env.sessions, "alice", time.Hour)
+	if rec := env.file(second, http.MethodGet, id); rec.Code != http.StatusOK || !bytes.Equal(rec.Body.Bytes(), data) {
+		t.Fatalf("новая сессия того же пользователя: %d", rec.Code)
+	}
+
+	// Тот же пользователь по Bearer — те же файлы
+	req := httptest.NewRequest(http.MethodGet, "/api/files/"+id, nil)
+	req = req.WithContext(context.WithValue(req.Context(), ctxClaims, &Claims{Subject: "alice", SessionID: "sid"}))
+	rec := httptest.NewRecorder()
+	filesHandler(env.uploads, env.sessions)(rec, req)
+	if rec.Code != http.StatusOK {
+		t.Errorf("Bearer того же пользователя: %d", rec.Code)
+	}
+
+	bob := saveSession(t, env.sessions, "bob", time.Hour)
+	if rec := env.file(bob, http.MethodGet, id); rec.Code != http.StatusNotFound {
+		t.Errorf("чужой пользователь: %d, want 404", rec.Code)
+	}
+
+	// Квота тоже за пользователем: повторная загрузка не списывает байты дважды
+	if rec := env.upload(t, second, "a.png", data); rec.Code != http.StatusOK {
+		t.Fatalf("повторная загрузка: %d", rec.Code)
+	}
+	if got := env.uploads.remaining(ownerKey("alice")); got != 1<<20-int64(len(data)) {
+		t.Errorf("остаток квоты %d", got)
+	}
+
+	if rec := env.file(second, http.MethodDelete, id); rec.Code != http.StatusOK {
+		t.Fatalf("удаление: %d", rec.Code)
+	}
+	if got := env.uploads.remaining(ownerKey("alice")); got != 1<<20 {
+		t.Errorf("квота после удаления %d", got)
+	}
+}
+
+func TestUploadQuotaSurvivesRestart(t *testing.T) {
+	env := newUploadEnv(t, 1<<20)
+	data := testPNG(t, 2, 2)
+	sess := saveSession(t, env.sessions, "alice", time.Hour)
+	if rec := env.upload(t, sess, "a.png", data); rec.Code != http.StatusOK {
+		t.Fatalf("загрузка: %d %s", rec.Code, rec.Body)
+	}
+
+	restarted, err := newUploadStore(env.uploads.dir, 1<<20)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := restarted.remaining(ownerKey("alice")); got != 1<<20-int64(len(data)) {
+		t.Errorf("квота после рестарта %d", got)
+	}
+}
+
+func TestFilesContentDisposition(t *testing.T) {
+	env := newUploadEnv(t, 1<<20)
+	data := testPNG(t, 2, 2)
+	sess := saveSession(t, env.sessions, "alice", time.Hour)
+
+	for _, name := range []string{"plain.png", `кот "мурзик".png`, "a;b=c.png"} {
+		if rec := env.upload(t, sess, name, data); rec.Code != http.StatusOK {
+			t.Fatalf("%s: %d %s", name, rec.Code, rec.Body)
+		}
+		rec := env.file(sess, http.MethodGet, fileIDOf(data))
+		disp, params, err := mime.ParseMediaType(rec.Header().Get("Content-Disposition"))
+		if err != nil || disp != "inline" || params["filename"] != name {
+			t.Errorf("%s: Content-Disposition %q → %q %v (%v)", name, rec.Header().Get("Content-Disposition"), disp, params, err)
+		}
+		if rec := env.file(sess, http.MethodDelete, fileIDOf(data)); rec.Code != http.StatusOK {
+			t.Fatalf("удаление: %d", rec.Code)
+		}
+	}
+}
@@ -0,0 +1,312 @@
+package main
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/crypto/argon2"
+)
+
+// ==== Пользователи ====
+
+var (
+	ErrUserNotFound       = errors.New("user not found")
+	ErrUserExists         = errors.New("user already exists")
+	ErrInvalidCredentials = errors.New("invalid credentials")
+	ErrAccountLocked      = errors.New("account locked")
+)
+
+// User — учётная запись. Пароль хранится только в виде хэша.
+type User struct {
+	Username     string    `json:"username"`
+	PasswordHash string    `json:"password_hash"`
+	FailedLogins int       `json:"failed_logins"`
+	LockedUntil  time.Time `json:"locked_until,omitempty"`
+	CreatedAt    time.Time `json:"created_at"`
+	UpdatedAt    time.Time `json:"updated_at"`
+}
+
+// UserStore — подключаемое хранилище пользователей (JSON файл, позже БД).
+type UserStore interface {
+	Get(username string) (*User, error) // ErrUserNotFound, если нет
+	Put(u *User) error
+	// Update — атомарное чтение-изменение-запись: fn получает копию записи,
+	// изменения сохраняются, только если fn вернула nil.
+	Update(username string, fn func(u *User) error) error
+}
+
+var usernameRe = regexp.MustCompile(`^[a-zA-Z0-9._-]{3,64}$`)
+
+func validateUsername(username string) error {
+	if !usernameRe.MatchString(username) {
+		return errors.New("username: 3-64 символа [a-zA-Z0-9._-]")
+	}
+	return nil
+}
+
+func validatePassword(password string) error {
+	if len(password) < PasswordMinLength {
+		return fmt.Errorf("password: минимум %d символов", PasswordMinLength)
+	}
+	if len(password) > 1024 {
+		return errors.New("password: слишком длинный")
+	}
+	return nil
+}
+
+// ==== Хэширование паролей ====
+//
+// argon2id, формат PHC: $argon2id$v=19$m=<KiB>,t=<проходы>,p=<потоки>$<salt b64>$<hash b64>
+// Параметры берутся из записи, поэтому их можно усиливать без миграции:
+// старые хэши проверяются со своими m/t/p.
+
+const (
+	passwordHashAlgo = "argon2id"
+	passwordSaltLen  = 16
+	passwordKeyLen   = 32
+)
+
+func hashPassword(password string) (string, error) {
+	salt := make([]byte, passwordSaltLen)
+	if _, err := rand.Read(salt); err != nil {
+		return "", err
+	}
+	key := argon2.IDKey([]byte(password), salt,
+		PasswordArgonTime, PasswordArgonMemory, PasswordArgonThreads, passwordKeyLen)
+	return fmt.Sprintf("$%s$v=%d$m=%d,t=%d,p=%d$%s$%s",
+		passwordHashAlgo, argon2.Version,
+		PasswordArgonMemory, PasswordArgonTime, PasswordArgonThreads,
+		base64.RawStdEncoding.EncodeToString(salt),
+		base64.RawStdEncoding.EncodeToString(key),
+	), nil
+}
+
+func verifyPassword(encoded, password string) bool {
+	// "", "argon2id", "v=19", "m=..,t=..,p=..", salt, hash
+	parts := strings.Split(encoded, "$")
+	if len(parts) != 6 || parts[0] != "" || parts[1] != passwordHashAlgo {
+		return false
+	}
+	var version int
+	if _, err := fmt.Sscanf(parts[2], "v=%d", &version); err != nil || version != argon2.Version {
+		return false
+	}
+	var memory, passes uint32
+	var threads uint8
+	if _, err := fmt.Sscanf(parts[3], "m=%d,t=%d,p=%d", &memory, &passes, &threads); err != nil {
+		return false
+	}
+	if passes == 0 || threads == 0 || memory < 8*uint32(threads) || memory > maxArgonMemory {
+		return false
+	}
+	salt, err := base64.RawStdEncoding.DecodeString(parts[4])
+	if err != nil {
+		return false
+	}
+	want, err := base64.RawStdEncoding.DecodeString(parts[5])
+	if err != nil || len(want) == 0 {
+		return false
+	}
+	got := argon2.IDKey([]byte(password), salt, passes, memory, threads, uint32(len(want)))
+	return subtle.ConstantTimeCompare(got, want) == 1
+}
+
+// maxArgonMemory — потолок m из записи (KiB): испорченный users.json
+// не должен заставить сервер выделить гигабайты на одну проверку.
+const maxArgonMemory = 1 << 20
+
+// dummyHash — для несуществующих пользователей тратим то же время на проверку,
+// чтобы по таймингу нельзя было перебирать логины.
+var dummyHash = sync.OnceValue(func() string {
+	h, _ := hashPassword("dummy-password")
+	return h
+})
+
+// ==== Аутентификация с блокировкой ====
+
+// authenticate проверяет пароль и ведёт счётчик неудачных попыток.
+// После LoginMaxFailures ошибок подряд аккаунт блокируется на LoginLockout.
+// Хэш проверяется вне блокировки хранилища (это медленно), а счётчик
+// меняется через Update: параллельные неудачные попытки не теряются.
+func authenticate(store UserStore, username, password string) (*User, error) {
+	u, err := store.Get(username)
+	if errors.Is(err, ErrUserNotFound) {
+		verifyPassword(dummyHash(), password)
+		return nil, ErrInvalidCredentials
+	}
+	if err != nil {
+		return nil, err
+	}
+	if time.Now().Before(u.LockedUntil) {
+		return nil, ErrAccountLocked
+	}
+
+	if !verifyPassword(u.PasswordHash, password) {
+		err := store.Update(username, func(u *User) error {
+			now := time.Now()
+			if now.Before(u.LockedUntil) {
+				// Заблокирован параллельной попыткой — счётчик уже сброшен
+				return nil
+			}
+			u.FailedLogins++
+			if u.FailedLogins >= LoginMaxFailures {
+				u.LockedUntil = now.Add(LoginLockout)
+				u.FailedLogins = 0
+			}
+			u.UpdatedAt = now
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+		return nil, ErrInvalidCredentials
+	}
+
+	if u.FailedLogins != 0 || !u.LockedUntil.IsZero() {
+		err := store.Update(username, func(cur *User) error {
+			now := time.Now()
+			if now.Before(cur.LockedUntil) {
+				// Пока проверяли пароль, параллельные ошибки заблокировали аккаунт
+				return ErrAccountLocked
+			}
+			cur.FailedLogins = 0
+			cur.LockedUntil = time.Time{}
+			cur.UpdatedAt = now
+			*u = *cur
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+	return u, nil
+}
+
+// ==== JSON файл ====
+
+// jsonUserStore хранит всех пользователей в одном JSON файле.
+// Файл перечитывается на каждую операцию: admin CLI может менять его,
+// пока сервер работает, и сервер сразу увидит изменения.
+type jsonUserStore struct {
+	path string
+	mu   sync.Mutex
+}
+
+func newJSONUserStore(path string) (*jsonUserStore, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return nil, fmt.Errorf("users dir: %w", err)
+	}
+	return &jsonUserStore{path: path}, nil
+}
+
+func (s *jsonUserStore) load() (map[string]*User, error) {
+	users := make(map[string]*User)
+	data, err := os.ReadFile(s.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return users, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var list []*User
+	if err := json.Unmarshal(data, &list); err != nil {
+		return nil, fmt.Errorf("users file %s: %w", s.path, err)
+	}
+	for _, u := range list {
+		users[u.Username] = u
+	}
+	return users, nil
+}
+
+// Ping — файл пользователей читается и разбирается.
+func (s *jsonUserStore) Ping(ctx context.Context) error {
+	_, err := s.load()
+	return err
+}
+
+func (s *jsonUserStore) save(users map[string]*User) error {
+	list := make([]*User, 0, len(users))
+	for _, u := range users {
+		list = append(list, u)
+	}
+	data, err := json.MarshalIndent(list, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(s.path), ".users-*")
+	if err != nil {
+		return err
+	}
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	return os.Rename(tmp.Name(), s.path)
+}
+
+func (s *jsonUserStore) Get(username string) (*User, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	users, err := s.load()
+	if err != nil {
+		return nil, err
+	}
+	u, ok := users[username]
+	if !ok {
+		return nil, ErrUserNotFound
+	}
+	return u, nil
+}
+
+func (s *jsonUserStore) Put(u *User) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	users, err := s.load()
+	if err != nil {
+		return err
+	}
+	cp := *u
+	users[u.Username] = &cp
+	return s.save(users)
+}
+
+// Update держит s.mu на всё чтение-изменение-запись: между load и save
+// никто другой (в этом процессе) файл не перепишет.
+func (s *jsonUserStore) Update(username string, fn func(u *User) error) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	users, err := s.load()
+	if err != nil {
+		return err
+	}
+	u, ok := users[username]
+	if !ok {
+		return ErrUserNotFound
+	}
+	cp := *u
+	if err := fn(&cp); err != nil {
+		return err
+	}
+	users[username] = &cp
+	return s.save(users)
+}
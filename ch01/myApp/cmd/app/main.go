@@ -7,6 +7,7 @@ import (
 	"crypto/rand"
 	"crypto/sha256"
 	"errors"
+	"fmt"
 	"net/http"
 	"os"
 	"os/signal"
@@ -14,7 +15,9 @@ import (
 
 	"myApp/internal/app"
 	"myApp/internal/core"
+	"myApp/internal/lifecycle"
 	"myApp/internal/storage"
+	"myApp/migrations"
 
 	"golang.org/x/crypto/pbkdf2"
 )
@@ -23,6 +26,17 @@ func main() {
 	// Загружаем конфиг (из .env, переменных окружения или файла)
 	cfg := core.Load()
 
+	// Подкоманда миграций: app migrate up|down|status|redo
+	if len(os.Args) > 1 && os.Args[1] == "migrate" {
+		if err := core.InitLog(cfg); err != nil {
+			_, _ = fmt.Fprintf(os.Stderr, "Ошибка логов: %v\n", err)
+			os.Exit(1)
+		}
+		code := runMigrate(cfg, os.Args[2:])
+		core.Close()
+		os.Exit(code)
+	}
+
 	// Логируем старт с параметрами окружения
 	core.LogInfo("Приложение запущено", map[string]interface{}{
 		"env":    cfg.Env,    // режим: dev / prod
@@ -31,59 +45,110 @@ func main() {
 		"app":    cfg.AppName,
 	})
 
-	// Инициализируем ежедневный лог-файл (по дате)
-	core.InitDailyLog()
+	// Менеджер жизненного цикла: компоненты останавливаются в обратном порядке
+	// регистрации, все вместе — не дольше SHUTDOWN_TIMEOUT
+	lc := lifecycle.New(cfg.ShutdownTimeout)
 
-	// Подключаем базу данных (sqlx.DB)
-	db, err := storage.NewDB()
-	if err != nil {
-		core.LogError("Ошибка БД", map[string]interface{}{"error": err})
+	// fail — ошибка запуска: останавливаем уже запущенное и выходим с кодом 1
+	fail := func(msg string, err error) {
+		core.LogError(msg, map[string]interface{}{"error": err.Error()})
+		_ = lc.Shutdown()
 		os.Exit(1)
 	}
 
-	// Запускаем миграции, если есть (обновление структуры БД)
-	migrations := storage.NewMigrations(db)
-	if err := migrations.RunMigrations(); err != nil {
-		core.LogError("Ошибка миграций", map[string]interface{}{"error": err})
-		os.Exit(1)
+	// Логи в LOG_DIR: новый файл в полночь и по размеру, старые — в .gz
+	// и удаляются через LOG_MAX_AGE_DAYS. Закрываются последними
+	if err := core.InitLog(cfg); err != nil {
+		fail("Ошибка логов", err)
+	}
+	lc.Register("logger", func(context.Context) error {
+		core.Close()
+		return nil
+	})
+
+	// Подключаем базу данных (sqlx.DB)
+	db, err := storage.NewDB(cfg)
+	if err != nil {
+		fail("Ошибка БД", err)
+	}
+	lc.Register("db", func(context.Context) error { return storage.Close(db) })
+
+	// Применяем новые миграции при старте, если включено DB_AUTO_MIGRATE
+	// (в проде обычно отдельным шагом деплоя: app migrate up)
+	if cfg.AutoMigrate {
+		src, err := migrations.For(cfg.DBDriver)
+		if err == nil {
+			err = storage.NewMigrations(db, src).RunMigrations()
+		}
+		if err != nil {
+			fail("Ошибка миграций", err)
+		}
 	}
 
 	// Генерируем или производим derivation CSRF-ключа (32 байта)
 	// Используется для защиты форм и сессий
 	csrfKey := deriveSecureKey(cfg.CSRFKey)
 
+	// Очередь уведомлений (SMTP или лог) — фоновые воркеры с повторами.
+	// Останавливается после HTTP-сервера: досылает то, что успели поставить запросы;
+	// не успевшие до дедлайна остаются pending и уйдут после перезапуска
+	notifier := startNotifier(cfg, db)
+	lc.Register("notifier", notifier.Stop)
+
 	// Инициализируем приложение internal/app/app.go (Gin, middleware, routes, CSP nonce, CSRF-защиту, Раздаёт статику /assets из web/assets)
-	handler, err := app.New(cfg, db, csrfKey)
+	handler, err := app.New(cfg, db, csrfKey, notifier, lc)
 	if err != nil {
-		core.LogError("Ошибка app.New", map[string]interface{}{"error": err})
-		os.Exit(1)
+		fail("Ошибка app.New", err)
 	}
 
 	// Создаём HTTP-сервер с таймаутами
 	srv := newHTTPServer(cfg, handler)
 
+	// HTTPS без прокси (TLS не offloaded): сертификат перечитывается при обновлении файлов
+	if cfg.ServeTLS() {
+		certs, err := newCertReloader(cfg.CertFile, cfg.KeyFile)
+		if err != nil {
+			fail("Ошибка TLS-сертификата", err)
+		}
+		srv.TLSConfig = newTLSConfig(certs)
+	}
+
+	// Сервер останавливается первым: перестаёт принимать соединения и ждёт
+	// текущие запросы; по дедлайну — закрывает оставшиеся соединения
+	lc.Register("http", func(ctx context.Context) error {
+		err := srv.Shutdown(ctx)
+		if err != nil {
+			_ = srv.Close()
+		}
+		return err
+	})
+
 	// Создаём контекст, который будет отменён при сигнале SIGINT/SIGTERM
 	// (нужно для graceful shutdown)
 	sigs, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
-	defer stop()
 
 	// Запускаем сервер в отдельной горутине
-	go runServer(srv, cfg)
-
-	// Ждём сигнал завершения (Ctrl+C или systemd stop)
-	<-sigs.Done()
-	core.LogInfo("Завершение...", nil)
-
-	// Плавно останавливаем сервер
-	if err := srv.Shutdown(context.Background()); err != nil {
-		core.LogError("Ошибка shutdown", map[string]interface{}{"error": err})
+	serverErr := make(chan error, 1)
+	go func() { serverErr <- runServer(srv, cfg) }()
+
+	// Ждём сигнал завершения (Ctrl+C или systemd stop) или падение сервера
+	code := 0
+	select {
+	case <-sigs.Done():
+		core.LogInfo("Завершение...", map[string]interface{}{"timeout": cfg.ShutdownTimeout.String()})
+	case err := <-serverErr:
+		core.LogError("Сервер упал", map[string]interface{}{"error": err.Error()})
+		code = 1
 	}
+	// Повторный Ctrl+C — немедленный выход (обработка сигналов по умолчанию)
+	stop()
 
-	// Закрываем соединение с БД
-	_ = storage.Close(db)
-
-	// Закрываем логи, если нужно
-	core.Close()
+	// Плавно останавливаем всё в обратном порядке
+	if err := lc.Shutdown(); err != nil {
+		_, _ = fmt.Fprintf(os.Stderr, "shutdown: %v\n", err)
+		code = 1
+	}
+	os.Exit(code)
 }
 
 // newHTTPServer — создаёт http.Server с параметрами из конфига
@@ -98,16 +163,22 @@ func newHTTPServer(cfg core.Config, h http.Handler) *http.Server {
 	}
 }
 
-// runServer — запускает сервер и логирует падения
-func runServer(srv *http.Server, cfg core.Config) {
-	core.LogInfo("Сервер запущен", map[string]interface{}{"addr": cfg.Addr})
-
-	// Запускаем HTTP-сервер
-	if err := srv.ListenAndServe(); !errors.Is(err, http.ErrServerClosed) {
-		// Если ошибка не "сервер закрыт" — это краш
-		core.LogError("Сервер упал", map[string]interface{}{"error": err})
-		os.Exit(1)
+// runServer — запускает сервер (HTTPS, если задан TLSConfig); возвращает
+// ошибку, если сервер упал, и nil — после штатного Shutdown.
+func runServer(srv *http.Server, cfg core.Config) error {
+	core.LogInfo("Сервер запущен", map[string]interface{}{"addr": cfg.Addr, "tls": srv.TLSConfig != nil})
+
+	var err error
+	if srv.TLSConfig != nil {
+		// Пустые пути: сертификат отдаёт TLSConfig.GetCertificate
+		err = srv.ListenAndServeTLS("", "")
+	} else {
+		err = srv.ListenAndServe()
+	}
+	if errors.Is(err, http.ErrServerClosed) {
+		return nil
 	}
+	return err
 }
 
 // deriveSecureKey — генерирует 32-байтовый криптографически стойкий ключ для CSRF, если secret пустой — создаёт новый.
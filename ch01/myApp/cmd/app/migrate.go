@@ -0,0 +1,104 @@
+package main
+
+// migrate.go — подкоманда `app migrate up|down|status|redo`
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strconv"
+
+	"myApp/internal/core"
+	"myApp/internal/storage"
+	"myApp/migrations"
+)
+
+const migrateUsage = `Использование:
+  app migrate up [N]    применить N новых миграций (по умолчанию все)
+  app migrate down [N]  откатить N последних миграций (по умолчанию 1)
+  app migrate status    список миграций и их состояние
+  app migrate redo      откатить и заново применить последнюю миграцию`
+
+// runMigrate выполняет подкоманду и возвращает код выхода процесса.
+func runMigrate(cfg core.Config, args []string) int {
+	if len(args) == 0 {
+		fmt.Fprintln(os.Stderr, migrateUsage)
+		return 2
+	}
+
+	cmd, steps := args[0], 0
+	if len(args) > 1 {
+		n, err := strconv.Atoi(args[1])
+		if err != nil || n < 1 || (cmd != "up" && cmd != "down") {
+			fmt.Fprintln(os.Stderr, migrateUsage)
+			return 2
+		}
+		steps = n
+	}
+
+	src, err := migrations.For(cfg.DBDriver)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	db, err := storage.NewDB(cfg)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "Ошибка БД:", err)
+		return 1
+	}
+	defer func() { _ = storage.Close(db) }()
+
+	ctx := context.Background()
+	m := storage.NewMigrations(db, src)
+
+	switch cmd {
+	case "up":
+		n, err := m.Up(ctx, steps)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "Ошибка миграций:", err)
+			return 1
+		}
+		fmt.Printf("Применено миграций: %d\n", n)
+	case "down":
+		n, err := m.Down(ctx, steps)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "Ошибка отката:", err)
+			return 1
+		}
+		fmt.Printf("Откачено миграций: %d\n", n)
+	case "redo":
+		if err := m.Redo(ctx); err != nil {
+			fmt.Fprintln(os.Stderr, "Ошибка redo:", err)
+			return 1
+		}
+		fmt.Println("Последняя миграция применена заново")
+	case "status":
+		list, err := m.Status(ctx)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "Ошибка статуса:", err)
+			return 1
+		}
+		printMigrationStatus(list)
+	default:
+		fmt.Fprintln(os.Stderr, migrateUsage)
+		return 2
+	}
+	return 0
+}
+
+func printMigrationStatus(list []storage.MigrationStatus) {
+	fmt.Printf("%-8s %-32s %-10s %s\n", "VERSION", "NAME", "STATE", "APPLIED AT")
+	for _, st := range list {
+		state, at := "pending", ""
+		if st.Applied {
+			state, at = "applied", st.AppliedAt.Format("2006-01-02 15:04:05")
+		}
+		switch {
+		case st.Missing:
+			state = "missing" // файл удалён после применения
+		case st.Modified:
+			state = "modified" // файл изменён после применения
+		}
+		fmt.Printf("%03d      %-32s %-10s %s\n", st.Version, st.Name, state, at)
+	}
+}
@@ -0,0 +1,56 @@
+package main
+
+// notify.go — очередь уведомлений о сообщениях из формы /form
+
+import (
+	"context"
+	"time"
+
+	"myApp/internal/core"
+	"myApp/internal/notify"
+	"myApp/internal/storage"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// pendingOnStart — сколько неотправленных сообщений прошлого запуска ставить в очередь.
+const pendingOnStart = 100
+
+// startNotifier — запускает очередь; статус доставки пишется в contact_messages.
+// Сообщения, не отправленные в прошлый раз (pending), ставятся в очередь заново.
+func startNotifier(cfg core.Config, db *sqlx.DB) *notify.Queue {
+	contacts := storage.NewContactRepository(db)
+
+	q := notify.NewQueue(notify.New(cfg), notify.QueueOptions{}, func(r notify.Result) {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+
+		var err error
+		switch {
+		case r.Err == nil:
+			err = contacts.MarkSent(ctx, r.ID, r.Attempts)
+		case r.Final:
+			err = contacts.MarkFailed(ctx, r.ID, r.Attempts, storage.ContactFailed, r.Err.Error())
+		default: // прервано остановкой — останется pending до следующего запуска
+			err = contacts.MarkFailed(ctx, r.ID, r.Attempts, storage.ContactPending, r.Err.Error())
+		}
+		if err != nil {
+			core.LogError("Ошибка сохранения статуса уведомления", map[string]interface{}{"id": r.ID, "error": err.Error()})
+		}
+	})
+	q.Start()
+
+	pending, err := contacts.Pending(context.Background(), pendingOnStart)
+	if err != nil {
+		// Например, миграции ещё не применены — очередь всё равно работает
+		core.LogError("Ошибка чтения неотправленных сообщений", map[string]interface{}{"error": err.Error()})
+		return q
+	}
+	for _, m := range pending {
+		q.Enqueue(notify.ContactNotification(m))
+	}
+	if len(pending) > 0 {
+		core.LogInfo("Неотправленные сообщения поставлены в очередь", map[string]interface{}{"count": len(pending)})
+	}
+	return q
+}
@@ -0,0 +1,119 @@
+package main
+
+// tls.go — HTTPS без прокси: сертификат из TLS_CERT_FILE / TLS_KEY_FILE
+// с подхватом обновлённых файлов (certbot renew и т.п.) без перезапуска.
+
+import (
+	"crypto/tls"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"myApp/internal/core"
+)
+
+// certCheckInterval — как часто (не чаще) проверять, не изменились ли файлы.
+const certCheckInterval = time.Minute
+
+// certReloader — отдаёт текущий сертификат в tls.Config.GetCertificate.
+// Файлы проверяются по времени изменения; ошибка загрузки нового сертификата
+// логируется, а соединения продолжают обслуживаться старым.
+type certReloader struct {
+	certFile, keyFile string
+
+	mu        sync.RWMutex
+	cert      *tls.Certificate
+	modTime   time.Time // новейшее из времён изменения cert/key на момент загрузки
+	checkedAt time.Time
+}
+
+// newCertReloader — сразу загружает сертификат: с битыми файлами сервер не стартует.
+func newCertReloader(certFile, keyFile string) (*certReloader, error) {
+	r := &certReloader{certFile: certFile, keyFile: keyFile}
+	mod, err := r.filesModTime()
+	if err != nil {
+		return nil, err
+	}
+	if err := r.load(mod); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+func (r *certReloader) GetCertificate(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+	r.maybeReload()
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.cert, nil
+}
+
+func (r *certReloader) maybeReload() {
+	r.mu.Lock()
+	if time.Since(r.checkedAt) < certCheckInterval {
+		r.mu.Unlock()
+		return
+	}
+	r.checkedAt = time.Now()
+	known := r.modTime
+	r.mu.Unlock()
+
+	mod, err := r.filesModTime()
+	if err != nil {
+		core.LogError("Ошибка проверки TLS-сертификата", map[string]interface{}{"error": err.Error()})
+		return
+	}
+	if !mod.After(known) {
+		return
+	}
+	if err := r.load(mod); err != nil {
+		// Например, certbot успел записать сертификат, но не ключ — попробуем при следующей проверке
+		core.LogError("Ошибка перезагрузки TLS-сертификата", map[string]interface{}{"error": err.Error()})
+		return
+	}
+	core.LogInfo("TLS-сертификат перезагружен", map[string]interface{}{"cert": r.certFile})
+}
+
+func (r *certReloader) load(mod time.Time) error {
+	cert, err := tls.LoadX509KeyPair(r.certFile, r.keyFile)
+	if err != nil {
+		return fmt.Errorf("load tls key pair: %w", err)
+	}
+	r.mu.Lock()
+	r.cert = &cert
+	r.modTime = mod
+	r.mu.Unlock()
+	return nil
+}
+
+// filesModTime — os.Stat идёт по симлинкам (certbot: live/ → archive/).
+func (r *certReloader) filesModTime() (time.Time, error) {
+	var latest time.Time
+	for _, f := range []string{r.certFile, r.keyFile} {
+		st, err := os.Stat(f)
+		if err != nil {
+			return time.Time{}, err
+		}
+		if st.ModTime().After(latest) {
+			latest = st.ModTime()
+		}
+	}
+	return latest, nil
+}
+
+// newTLSConfig — TLS 1.2+; для 1.2 — только AEAD-шифры с forward secrecy
+// (TLS 1.3 шифры не настраиваются и безопасны по умолчанию).
+func newTLSConfig(certs *certReloader) *tls.Config {
+	return &tls.Config{
+		MinVersion:     tls.VersionTLS12,
+		GetCertificate: certs.GetCertificate,
+		CipherSuites: []uint16{
+			tls.TLS_ECDHE_ECDSA_WITH_AES_128_GCM_SHA256,
+			tls.TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256,
+			tls.TLS_ECDHE_ECDSA_WITH_AES_256_GCM_SHA384,
+			tls.TLS_ECDHE_RSA_WITH_AES_256_GCM_SHA384,
+			tls.TLS_ECDHE_ECDSA_WITH_CHACHA20_POLY1305_SHA256,
+			tls.TLS_ECDHE_RSA_WITH_CHACHA20_POLY1305_SHA256,
+		},
+	}
+}
@@ -0,0 +1,47 @@
+// Command assetmanifest — пишет web/assets/manifest.json (логический путь → путь с хэшем).
+//
+//	go generate ./web                          # из корня проекта
+//	go run ./cmd/assetmanifest -dir web/assets -check   # CI: манифест актуален?
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"myApp/internal/assets"
+)
+
+func main() {
+	dir := flag.String("dir", "web/assets", "каталог статики")
+	check := flag.Bool("check", false, "только проверить, что manifest.json актуален (код выхода 1 — нет)")
+	flag.Parse()
+
+	p, err := assets.Build(os.DirFS(*dir))
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "Ошибка сборки статики:", err)
+		os.Exit(1)
+	}
+	data, err := p.Manifest().Marshal()
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+
+	out := filepath.Join(*dir, assets.ManifestFile)
+	if *check {
+		old, _ := os.ReadFile(out)
+		if !bytes.Equal(old, data) {
+			fmt.Fprintf(os.Stderr, "%s устарел: выполните go generate ./web\n", out)
+			os.Exit(1)
+		}
+		return
+	}
+	if err := os.WriteFile(out, data, 0o644); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	fmt.Printf("%s: %d файлов\n", out, len(p.Manifest()))
+}
@@ -6,16 +6,29 @@ package app
 import (
 	"context"
 	"crypto/rand"
+	"crypto/sha256"
+	"database/sql"
 	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io/fs"
 	"net/http"
 	"os"
+	"path/filepath"
 	"strings"
 	"time"
 
+	"myApp/internal/assets"
+	"myApp/internal/auth"
+	"myApp/internal/cart"
 	"myApp/internal/core"
 	"myApp/internal/http/handler"
+	"myApp/internal/lifecycle"
+	"myApp/internal/notify"
 	"myApp/internal/storage"
 	"myApp/internal/view"
+	"myApp/web"
 
 	"github.com/gin-contrib/requestid"
 	"github.com/gin-contrib/sessions"
@@ -27,17 +40,60 @@ import (
 
 // ВАЖНО: CSRF secret (долгоживущий ключ) ≠ CSP nonce (случайное значение на КАЖДЫЙ запрос).
 
-// initTemplates — Инициализация шаблонов.
-func initTemplates() (*view.Templates, error) {
-	return view.New()
+// webFiles — шаблоны и статика: в dev — каталог web/ с диска (если есть),
+// иначе встроенные в бинарник. dir — каталог шаблонов на диске для наблюдения
+// (пусто — перечитывать не нужно).
+func webFiles(cfg core.Config) (fsys fs.FS, dir string) {
+	if strings.ToLower(cfg.Env) == "dev" {
+		if st, err := os.Stat(web.Dir); err == nil && st.IsDir() {
+			return os.DirFS(web.Dir), filepath.Join(web.Dir, "templates")
+		}
+		core.LogInfo("Каталог web/ не найден, используются встроенные шаблоны", nil)
+	}
+	return web.Embedded(), ""
+}
+
+// initTemplates — Инициализация шаблонов и статики.
+// С диска (dev) статика отдаётся как есть; встроенная — через assets.Pipeline
+// (имена с хэшем, долгий кэш, gzip/brotli), pipeline == nil в dev.
+func initTemplates(files fs.FS, watchDir string) (*view.Templates, fs.FS, *assets.Pipeline, error) {
+	templates, err := fs.Sub(files, "templates")
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	static, err := fs.Sub(files, "assets")
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	var pipeline *assets.Pipeline
+	opts := view.Options{WatchDir: watchDir}
+	if watchDir == "" {
+		if pipeline, err = assets.Build(static); err != nil {
+			return nil, nil, nil, err
+		}
+		opts.AssetURL = pipeline.URL
+	}
+
+	tpl, err := view.New(templates, opts)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	return tpl, static, pipeline, nil
 }
 
 // New — Главный конструктор Gin, собирает всю цепочку middleware и роуты.
-func New(cfg core.Config, db *sqlx.DB, csrfKey []byte) (http.Handler, error) {
-	tpl, err := initTemplates()
+// notifier — очередь уведомлений (запускается и останавливается в main);
+// lc — сюда регистрируются фоновые части приложения (наблюдение за шаблонами в dev).
+func New(cfg core.Config, db *sqlx.DB, csrfKey []byte, notifier *notify.Queue, lc *lifecycle.Manager) (http.Handler, error) {
+	files, watchDir := webFiles(cfg)
+	tpl, static, pipeline, err := initTemplates(files, watchDir)
 	if err != nil {
 		return nil, err
 	}
+	if watchDir != "" {
+		lc.Register("templates", func(context.Context) error { return tpl.Close() })
+	}
 
 	r := gin.New()
 
@@ -46,26 +102,55 @@ func New(cfg core.Config, db *sqlx.DB, csrfKey []byte) (http.Handler, error) {
 		gin.SetMode(gin.ReleaseMode)
 	}
 
-	// Базовые логи/восстановление паник
-	r.Use(gin.Logger(), gin.Recovery())
-
 	// Trust proxy только локально (добавь IP внешних прокси, если нужно)
 	_ = r.SetTrustedProxies([]string{"127.0.0.1", "::1"})
 
 	// Корреляция запросов (RequestID)
 	r.Use(requestid.New())
 
+	// Логгер запроса в context + структурированный access-лог; Recovery — внутри,
+	// чтобы паника попала в access-лог как 500
+	r.Use(accessLog(), gin.Recovery())
+
 	// Таймаут запроса (отсекаем "висящие" клиенты)
 	r.Use(RequestTimeout(cfg.RequestTimeout))
 
 	// Кладём nonce и DB в контекст запроса — это нужно ДО установки CSP.
 	r.Use(withNonceAndDB(db))
 
+	// Репозитории (handler'ы получают интерфейсы, а не *sqlx.DB)
+	products := storage.NewProductRepository(db)
+	categories := storage.NewCategoryRepository(db)
+	users := storage.NewUserRepository(db)
+	contacts := storage.NewContactRepository(db)
+	orders := storage.NewOrderRepository(db)
+
+	// Учётка администратора из ADMIN_EMAIL / ADMIN_PASSWORD
+	if err := ensureAdmin(context.Background(), users, cfg.AdminEmail, cfg.AdminPassword); err != nil {
+		return nil, err
+	}
+
+	// Дерево категорий для меню в layout (кэшируется в репозитории)
+	r.Use(withCategoryTree(categories))
+
 	// Security заголовки (X-Frame-Options, X-Content-Type-Options и пр.)
 	r.Use(core.SecureHeaders())
 
-	// CSP (Content-Security-Policy)
-	r.Use(core.CSPBasic())
+	// HSTS (HSTS, HSTS_MAX_AGE, HSTS_INCLUDE_SUBDOMAINS, HSTS_PRELOAD)
+	if cfg.HSTS {
+		r.Use(core.HSTS(cfg.HSTSMaxAge, cfg.HSTSSubdomains, cfg.HSTSPreload))
+	}
+
+	// CSP (Content-Security-Policy) — пресет из CSP_POLICY
+	csp := core.CSPFromConfig(cfg)
+	if csp != nil {
+		r.Use(core.CSP(csp))
+	}
+
+	// Отчёты о нарушениях CSP. Маршрут регистрируется ДО сессий и CSRF: gin собирает
+	// цепочку middleware в момент регистрации, а браузер шлёт отчёт без CSRF-токена.
+	cspReports := storage.NewCSPReportRepository(db)
+	r.POST("/csp-report", core.NewRateLimiter(10, 20, 300, 100).Middleware(), handler.CSPReport(cspReports))
 
 	// Безопасные cookie-сессии (HttpOnly, SameSite, Secure=prod)
 	store := cookie.NewStore(csrfKey)
@@ -78,6 +163,15 @@ func New(cfg core.Config, db *sqlx.DB, csrfKey []byte) (http.Handler, error) {
 	})
 	r.Use(sessions.Sessions("mysession", store))
 
+	// Вошедший пользователь (id из сессии → users) — в контекст запроса
+	r.Use(auth.LoadUser(users))
+
+	// Число товаров в корзине — для меню
+	r.Use(cart.Load())
+
+	// Лимит тела запроса — до CSRF: middleware читает форму (в т.ч. multipart)
+	r.Use(limitBody(cfg.MaxUploadSize + 1<<20))
+
 	// CSRF защита форм. Использует сессию.
 	r.Use(csrf.Middleware(csrf.Options{
 		Secret:    string(csrfKey),
@@ -85,10 +179,12 @@ func New(cfg core.Config, db *sqlx.DB, csrfKey []byte) (http.Handler, error) {
 	}))
 
 	// Статика (с условным отключением кэша в Dev-режиме)
-	serveStatic(r, cfg.Env)
+	serveStatic(r, static, pipeline)
 
 	// Роуты
-	registerRoutes(r, tpl)
+	registerRoutes(r, tpl, products, categories, users, contacts, notifier)
+	registerShopRoutes(r, tpl, products, orders)
+	registerAdminRoutes(r, cfg, tpl, db, products, categories, contacts, cspReports, csp)
 
 	return r, nil
 }
@@ -111,22 +207,58 @@ func RequestTimeout(d time.Duration) gin.HandlerFunc {
 		// Если контекст протух и ещё ничего не было записано в ответ — вернём 408
 		if ctx.Err() != nil && !c.Writer.Written() {
 			// Логируем факт таймаута для мониторинга
-			core.LogError("Запрос завершился по таймауту", map[string]interface{}{
+			core.LogErrorCtx(ctx, "Запрос завершился по таймауту", map[string]interface{}{
 				"timeout": d.String(),
-				"path":    c.FullPath(),
 			})
 			c.AbortWithStatusJSON(http.StatusRequestTimeout, gin.H{"error": "request timeout"})
 		}
 	}
 }
 
+// accessLog — кладёт в context логгер запроса (request_id, route, ip; user_id
+// добавит auth.LoadUser) и после ответа пишет строку access-лога.
+// nonce_id — короткий хэш CSP nonce: связывает запись с отданной страницей,
+// не записывая в лог сам nonce.
+func accessLog() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		start := time.Now()
+		route := c.FullPath()
+		c.Request = c.Request.WithContext(core.WithLogFields(c.Request.Context(), map[string]interface{}{
+			"request_id": requestid.Get(c),
+			"route":      route,
+			"ip":         c.ClientIP(),
+		}))
+
+		c.Next()
+
+		// Ссылка с секретным токеном (/order/:token) — пишем только шаблон маршрута
+		path := c.Request.URL.Path
+		if strings.Contains(route, ":token") {
+			path = route
+		}
+		fields := map[string]interface{}{
+			"method":     c.Request.Method,
+			"path":       path,
+			"status":     c.Writer.Status(),
+			"bytes":      max(c.Writer.Size(), 0),
+			"latency_ms": float64(time.Since(start).Microseconds()) / 1000,
+		}
+		// c.Request — уже с контекстом, дополненным следующими middleware (nonce, user_id)
+		ctx := c.Request.Context()
+		if nonce, _ := ctx.Value(core.CtxNonce).(string); nonce != "" {
+			fields["nonce_id"] = nonceID(nonce)
+		}
+		core.LogInfoCtx(ctx, "HTTP-запрос", fields)
+	}
+}
+
 // withNonceAndDB — генерирует CSP nonce и кладёт вместе с *sqlx.DB в контекст запроса.
 func withNonceAndDB(db *sqlx.DB) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		nonce, err := generateNonce()
 		if err != nil {
 			// Если nonce не сгенерировался, это критическая внутренняя ошибка
-			core.LogError("Ошибка генерации CSP nonce", map[string]interface{}{"error": err})
+			core.LogErrorCtx(c.Request.Context(), "Ошибка генерации CSP nonce", map[string]interface{}{"error": err})
 			core.FailC(c, core.Internal("nonce generation failed", err))
 			return
 		}
@@ -142,52 +274,166 @@ func withNonceAndDB(db *sqlx.DB) gin.HandlerFunc {
 	}
 }
 
+// withCategoryTree — кладёт дерево категорий в контекст запроса для меню в layout.
+// Ошибка БД не ломает страницу: меню просто не выводится.
+func withCategoryTree(categories storage.CategoryRepository) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if p := c.Request.URL.Path; strings.HasPrefix(p, "/assets/") || strings.HasPrefix(p, "/uploads/") {
+			c.Next()
+			return
+		}
+
+		tree, err := categories.Tree(c.Request.Context())
+		if err != nil {
+			core.LogErrorCtx(c.Request.Context(), "Ошибка загрузки дерева категорий", map[string]interface{}{"error": err.Error()})
+			c.Next()
+			return
+		}
+
+		ctx := context.WithValue(c.Request.Context(), core.CtxCategoryTree, tree)
+		c.Request = c.Request.WithContext(ctx)
+		c.Next()
+	}
+}
+
+// limitBody — 413 для запросов с телом больше max байт.
+// Content-Length проверяется сразу, chunked-тело обрезается MaxBytesReader.
+func limitBody(max int64) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if c.Request.ContentLength > max {
+			core.FailC(c, &core.AppError{
+				Code:    "payload_too_large",
+				Status:  http.StatusRequestEntityTooLarge,
+				Message: "Слишком большой запрос",
+			})
+			return
+		}
+		c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, max)
+		c.Next()
+	}
+}
+
 // csrfError — единообразный ответ на невалидный CSRF-токен (HTTP 403 Forbidden).
 func csrfError(c *gin.Context) {
 	// 403 Forbidden более точен, чем 500 Internal
 	core.FailC(c, core.Forbidden("CSRF token is invalid or missing."))
 }
 
-// serveStatic — раздача файлов из web/assets. Отключает кэш в режиме dev.
-func serveStatic(r *gin.Engine, env string) {
-	if _, err := os.Stat("web/assets"); os.IsNotExist(err) {
-		core.LogError("Папка web/assets отсутствует, статика не будет доступна", nil)
+// serveStatic — раздача web/assets. Без pipeline (dev, файлы с диска) кэш отключён;
+// с pipeline — имена с хэшем и Cache-Control immutable (см. internal/assets).
+func serveStatic(r *gin.Engine, static fs.FS, pipeline *assets.Pipeline) {
+	if pipeline != nil {
+		r.GET("/assets/*filepath", pipeline.Handler())
+		r.HEAD("/assets/*filepath", pipeline.Handler())
 		return
 	}
 
 	// В режиме dev — отключаем кэш для моментального обновления в браузере
-	if strings.ToLower(env) == "dev" {
-		r.Use(func(c *gin.Context) {
-			if strings.HasPrefix(c.Request.URL.Path, "/assets/") {
-				c.Header("Cache-Control", "no-cache, no-store, must-revalidate")
-				c.Header("Pragma", "no-cache")
-				c.Header("Expires", "0")
-			}
-			c.Next()
-		})
-	}
-	// TODO: В prod стоит добавить кэширование (например, max-age=31536000)
+	r.Use(func(c *gin.Context) {
+		if strings.HasPrefix(c.Request.URL.Path, "/assets/") {
+			c.Header("Cache-Control", "no-cache, no-store, must-revalidate")
+			c.Header("Pragma", "no-cache")
+			c.Header("Expires", "0")
+		}
+		c.Next()
+	})
 
-	// Раздача статики
-	r.Static("/assets", "web/assets")
+	// Раздача статики (без листинга каталогов)
+	r.StaticFS("/assets", &gin.OnlyFilesFS{FileSystem: http.FS(static)})
 }
 
 // registerRoutes — Регистрация всех маршрутов приложения.
-func registerRoutes(r *gin.Engine, tpl *view.Templates) {
+func registerRoutes(r *gin.Engine, tpl *view.Templates, products storage.ProductRepository, categories storage.CategoryRepository, users storage.UserRepository, contacts storage.ContactRepository, notifier *notify.Queue) {
 	// Группы роутов и прочие обработчики
 	r.GET("/", handler.Home(tpl))
-	r.GET("/catalog", handler.Catalog(tpl))
-	r.GET("/product/:id", handler.Product(tpl))
+	r.GET("/catalog", handler.Catalog(tpl, products, categories))
+	r.GET("/category/:slug", handler.Category(tpl, products, categories))
+	r.GET("/product/:id", handler.Product(tpl, products))
 	r.GET("/form", handler.FormIndex(tpl))
-	r.POST("/form", handler.FormSubmit(tpl))
+	r.POST("/form", handler.FormSubmit(tpl, contacts, notifier))
 	r.GET("/about", handler.About(tpl))
-	r.GET("/debug", handler.Debug)
-	r.GET("/catalog/json", handler.CatalogJSON())
+	r.GET("/debug", auth.RequireRole(storage.RoleAdmin), handler.Debug)
+	r.GET("/catalog/json", handler.CatalogJSON(products, categories))
+	r.GET("/categories/json", handler.CategoriesJSON(categories))
+
+	// Учётные записи. Перебор паролей и массовая регистрация ограничены по IP
+	// и по email из формы: перебор одного аккаунта с многих IP упирается во второй лимит
+	byIP := core.NewRateLimiter(10, 10, 300, 100).Middleware()
+	byEmail := core.NewRateLimiter(5, 5, 300, 100).MiddlewareKey(func(c *gin.Context) string {
+		return storage.NormalizeEmail(c.PostForm("email"))
+	})
+	r.GET("/register", handler.RegisterIndex(tpl))
+	r.POST("/register", byIP, byEmail, handler.RegisterSubmit(tpl, users))
+	r.GET("/login", handler.LoginIndex(tpl))
+	r.POST("/login", byIP, byEmail, handler.LoginSubmit(tpl, users))
+	r.POST("/logout", handler.Logout)
 
 	// Обработчик 404
 	r.NoRoute(handler.NotFound(tpl))
 }
 
+// registerShopRoutes — корзина (в сессии) и оформление заказа.
+func registerShopRoutes(r *gin.Engine, tpl *view.Templates, products storage.ProductRepository, orders storage.OrderRepository) {
+	r.GET("/cart", handler.Cart(tpl, products))
+	r.POST("/cart/add", handler.CartAdd(products))
+	r.POST("/cart/update", handler.CartUpdate)
+	r.POST("/cart/remove", handler.CartRemove)
+	r.GET("/checkout", handler.CheckoutIndex(tpl, products))
+	r.POST("/checkout", handler.CheckoutSubmit(tpl, products, orders))
+	r.GET("/order/:token", handler.OrderShow(tpl, orders))
+}
+
+// registerAdminRoutes — админка /admin (только роль admin) и раздача загруженных файлов /uploads.
+func registerAdminRoutes(r *gin.Engine, cfg core.Config, tpl *view.Templates, db *sqlx.DB, products storage.ProductRepository, categories storage.CategoryRepository, contacts storage.ContactRepository, cspReports storage.CSPReportRepository, csp *core.CSPPolicy) {
+	r.Static("/uploads", cfg.UploadDir)
+
+	audit := storage.NewAuditRepository(db)
+	images := storage.NewImageStore(cfg.UploadDir, cfg.MaxUploadSize)
+
+	admin := r.Group("/admin", auth.RequireRole(storage.RoleAdmin))
+	admin.GET("", func(c *gin.Context) { c.Redirect(http.StatusFound, "/admin/products") })
+	admin.GET("/products", handler.AdminProducts(tpl, products))
+	admin.GET("/products/new", handler.AdminProductNew(tpl, categories))
+	admin.POST("/products", handler.AdminProductCreate(tpl, products, categories, images))
+	admin.GET("/products/:id", handler.AdminProductEdit(tpl, products, categories))
+	admin.POST("/products/:id", handler.AdminProductUpdate(tpl, products, categories, images))
+	admin.POST("/products/:id/delete", handler.AdminProductDelete(products, images))
+	admin.GET("/audit", handler.AdminAudit(tpl, audit))
+	admin.GET("/messages", handler.AdminMessages(tpl, contacts))
+	admin.GET("/csp", handler.AdminCSP(tpl, cspReports, csp))
+}
+
+// ensureAdmin — создаёт администратора, если пользователя с таким email нет.
+// Существующий admin не меняется. Обычного пользователя с этим email не повышаем:
+// /register открыт, и email мог занять кто угодно со своим паролем — старт прерывается.
+func ensureAdmin(ctx context.Context, users storage.UserRepository, email, password string) error {
+	if email == "" {
+		return nil
+	}
+
+	u, err := users.ByEmail(ctx, email)
+	switch {
+	case err == nil:
+		if u.Role == storage.RoleAdmin {
+			return nil
+		}
+		return fmt.Errorf("ADMIN_EMAIL принадлежит пользователю без роли admin (id %d): укажите другой email или выдайте роль вручную", u.ID)
+	case !errors.Is(err, sql.ErrNoRows):
+		return err
+	}
+
+	hash, err := auth.HashPassword(password)
+	if err != nil {
+		return err
+	}
+	u = &storage.User{Email: email, Name: "Administrator", PasswordHash: hash, Role: storage.RoleAdmin}
+	if err := users.Create(ctx, u); err != nil {
+		return err
+	}
+	core.LogInfo("Создан администратор", map[string]interface{}{"user_id": u.ID})
+	return nil
+}
+
 // generateNonce — Создаёт 16 байт криптографически стойкой случайности и кодирует в Base64.
 func generateNonce() (string, error) {
 	b := make([]byte, 16)
@@ -196,3 +442,9 @@ func generateNonce() (string, error) {
 	}
 	return base64.StdEncoding.EncodeToString(b), nil
 }
+
+// nonceID — первые 8 hex-символов sha256(nonce) для access-лога.
+func nonceID(nonce string) string {
+	sum := sha256.Sum256([]byte(nonce))
+	return hex.EncodeToString(sum[:4])
+}
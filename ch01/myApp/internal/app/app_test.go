@@ -0,0 +1,181 @@
+package app
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"myApp/internal/core"
+	"myApp/internal/lifecycle"
+	"myApp/internal/notify"
+	"myApp/internal/storage"
+	"myApp/migrations"
+
+	"github.com/gin-gonic/gin"
+	"github.com/rs/zerolog"
+)
+
+// newTestApp — приложение поверх SQLite в памяти с применёнными встроенными
+// миграциями (демо-товары и категории) и встроенными шаблонами.
+func newTestApp(t *testing.T) http.Handler {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+	prev := core.SetLogger(core.NewLogger(io.Discard, io.Discard, zerolog.ErrorLevel))
+	t.Cleanup(func() { core.SetLogger(prev) })
+
+	cfg := core.Config{
+		Env:            "test",
+		DBDriver:       "sqlite",
+		DBDSN:          "file::memory:",
+		DBMaxOpenConns: 1,
+		DBMaxIdleConns: 1,
+		RequestTimeout: 5 * time.Second,
+		UploadDir:      t.TempDir(),
+		MaxUploadSize:  1 << 20,
+		CSPPolicy:      "basic",
+		CSPReportURI:   "/csp-report",
+	}
+
+	db, err := storage.NewDB(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = db.Close() })
+
+	src, err := migrations.For(cfg.DBDriver)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := storage.NewMigrations(db, src).RunMigrations(); err != nil {
+		t.Fatalf("миграции: %v", err)
+	}
+
+	lc := lifecycle.New(time.Second)
+	t.Cleanup(func() { _ = lc.Shutdown() })
+	queue := notify.NewQueue(notify.LogNotifier{}, notify.QueueOptions{}, nil)
+
+	h, err := New(cfg, db, []byte("0123456789abcdef0123456789abcdef"), queue, lc)
+	if err != nil {
+		t.Fatalf("app.New: %v", err)
+	}
+	return h
+}
+
+func get(t *testing.T, h http.Handler, target string) *httptest.ResponseRecorder {
+	t.Helper()
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, target, nil))
+	return rec
+}
+
+func TestCatalogJSON(t *testing.T) {
+	h := newTestApp(t)
+
+	rec := get(t, h, "/catalog/json")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("статус %d: %s", rec.Code, rec.Body)
+	}
+	if ct := rec.Header().Get("Content-Type"); ct != "application/json; charset=utf-8" {
+		t.Errorf("Content-Type %q", ct)
+	}
+	if rec.Header().Get("Content-Security-Policy") == "" {
+		t.Error("нет заголовка Content-Security-Policy")
+	}
+	if rec.Header().Get("X-Request-ID") == "" {
+		t.Error("нет заголовка X-Request-ID")
+	}
+
+	var page storage.ProductPage
+	if err := json.Unmarshal(rec.Body.Bytes(), &page); err != nil {
+		t.Fatalf("ответ не JSON: %v\n%s", err, rec.Body)
+	}
+	if len(page.Items) != 5 {
+		t.Fatalf("товаров %d, want 5 (из 002_seed_products)", len(page.Items))
+	}
+	articles := map[string]bool{}
+	for _, p := range page.Items {
+		articles[p.Article] = true
+		if p.CategoryID == nil {
+			t.Errorf("товар %s без категории (008_seed_categories)", p.Article)
+		}
+	}
+	for _, a := range []string{"ART-001", "ART-002", "ART-003", "ART-004", "ART-005"} {
+		if !articles[a] {
+			t.Errorf("нет товара %s", a)
+		}
+	}
+}
+
+func TestCatalogJSONPagination(t *testing.T) {
+	h := newTestApp(t)
+
+	var first storage.ProductPage
+	rec := get(t, h, "/catalog/json?limit=2")
+	if err := json.Unmarshal(rec.Body.Bytes(), &first); err != nil || rec.Code != http.StatusOK {
+		t.Fatalf("статус %d, %v: %s", rec.Code, err, rec.Body)
+	}
+	if len(first.Items) != 2 || first.Next == "" {
+		t.Fatalf("первая страница: %d товаров, next %q", len(first.Items), first.Next)
+	}
+
+	var second storage.ProductPage
+	rec = get(t, h, "/catalog/json?limit=2&cursor="+url.QueryEscape(first.Next))
+	if err := json.Unmarshal(rec.Body.Bytes(), &second); err != nil || rec.Code != http.StatusOK {
+		t.Fatalf("статус %d, %v: %s", rec.Code, err, rec.Body)
+	}
+	if len(second.Items) != 2 || second.Items[0].ID == first.Items[0].ID {
+		t.Fatalf("вторая страница: %+v", second.Items)
+	}
+}
+
+func TestCatalogJSONGroupedByCategory(t *testing.T) {
+	h := newTestApp(t)
+
+	rec := get(t, h, "/catalog/json?group=category")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("статус %d: %s", rec.Code, rec.Body)
+	}
+	var resp struct {
+		Groups []struct {
+			Category *storage.Category `json:"category"`
+			Items    []storage.Product `json:"items"`
+		} `json:"groups"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+		t.Fatal(err)
+	}
+	total := 0
+	for _, g := range resp.Groups {
+		if g.Category == nil {
+			t.Errorf("группа без категории: %+v", g.Items)
+			continue
+		}
+		for _, p := range g.Items {
+			if p.CategoryID == nil || *p.CategoryID != g.Category.ID {
+				t.Errorf("товар %s в чужой группе %q", p.Article, g.Category.Slug)
+			}
+		}
+		total += len(g.Items)
+	}
+	if total != 5 {
+		t.Errorf("товаров в группах %d, want 5", total)
+	}
+}
+
+func TestCatalogJSONRejectsBadParams(t *testing.T) {
+	h := newTestApp(t)
+
+	for _, target := range []string{
+		"/catalog/json?group=brand",
+		"/catalog/json?limit=0",
+		"/catalog/json?sort=nope",
+	} {
+		if rec := get(t, h, target); rec.Code != http.StatusBadRequest {
+			t.Errorf("%s: статус %d, want 400", target, rec.Code)
+		}
+	}
+}
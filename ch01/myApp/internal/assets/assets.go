@@ -0,0 +1,194 @@
+// Package assets — статика с отпечатком содержимого в имени файла.
+//
+// При старте все файлы из web/assets хэшируются (sha256): css/style.css
+// отдаётся как /assets/css/style.<hash>.css с Cache-Control immutable на год.
+// После изменения файла меняется и URL — браузеру не нужно ничего сбрасывать.
+// Имя зависит только от содержимого, поэтому одинаково на всех сборках и
+// серверах; manifest.json (go generate) фиксирует это соответствие в репозитории.
+package assets
+
+import (
+	"bytes"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"mime"
+	"path"
+	"sort"
+	"strings"
+
+	"myApp/internal/core"
+
+	"github.com/andybalholm/brotli"
+)
+
+// ManifestFile — соответствие "логический путь → путь с хэшем" (в корне assets).
+const ManifestFile = "manifest.json"
+
+// hashLen — символов sha256 (hex) в имени файла.
+const hashLen = 8
+
+// Manifest — "css/style.css" → "css/style.1a2b3c4d.css".
+type Manifest map[string]string
+
+// file — содержимое и сжатые варианты (nil — сжатие не выгодно или не нужно).
+type file struct {
+	name   string // логический путь
+	etag   string // sha256 содержимого (без кавычек)
+	data   []byte
+	gzip   []byte
+	brotli []byte
+}
+
+// Pipeline — статика в памяти: по логическому пути и по пути с хэшем.
+type Pipeline struct {
+	manifest Manifest
+	byName   map[string]*file
+	byHashed map[string]*file
+}
+
+// Build — читает все файлы fsys, считает хэши и готовит gzip/brotli для текстовых типов.
+// Если в fsys есть manifest.json и он не совпадает с содержимым — пишется ошибка
+// в лог (нужно запустить go generate), используется посчитанное соответствие.
+func Build(fsys fs.FS) (*Pipeline, error) {
+	p := &Pipeline{
+		manifest: Manifest{},
+		byName:   map[string]*file{},
+		byHashed: map[string]*file{},
+	}
+
+	err := fs.WalkDir(fsys, ".", func(name string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() || name == ManifestFile {
+			return err
+		}
+		data, err := fs.ReadFile(fsys, name)
+		if err != nil {
+			return err
+		}
+		sum := sha256.Sum256(data)
+		f := &file{name: name, etag: hex.EncodeToString(sum[:16]), data: data}
+		if compressible(name) {
+			if err := f.compress(); err != nil {
+				return fmt.Errorf("assets: сжатие %s: %w", name, err)
+			}
+		}
+
+		hashed := HashedName(name, hex.EncodeToString(sum[:])[:hashLen])
+		p.manifest[name] = hashed
+		p.byName[name] = f
+		p.byHashed[hashed] = f
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	p.checkManifest(fsys)
+	return p, nil
+}
+
+// Manifest — копия соответствия путей.
+func (p *Pipeline) Manifest() Manifest {
+	m := make(Manifest, len(p.manifest))
+	for k, v := range p.manifest {
+		m[k] = v
+	}
+	return m
+}
+
+// URL — "/assets/<путь с хэшем>"; неизвестный файл — "/assets/<путь>" (и ошибка в лог).
+func (p *Pipeline) URL(name string) string {
+	name = strings.TrimPrefix(name, "/")
+	if hashed, ok := p.manifest[name]; ok {
+		return "/assets/" + hashed
+	}
+	core.LogError("asset не найден", map[string]interface{}{"path": name})
+	return "/assets/" + name
+}
+
+// HashedName — "css/style.css" + hash → "css/style.<hash>.css".
+func HashedName(name, hash string) string {
+	ext := path.Ext(name)
+	return strings.TrimSuffix(name, ext) + "." + hash + ext
+}
+
+// Marshal — manifest.json: ключи отсортированы, отступы — чтобы diff был читаемым.
+func (m Manifest) Marshal() ([]byte, error) {
+	b, err := json.MarshalIndent(m, "", "  ") // encoding/json сортирует ключи map
+	if err != nil {
+		return nil, err
+	}
+	return append(b, '\n'), nil
+}
+
+func (p *Pipeline) checkManifest(fsys fs.FS) {
+	data, err := fs.ReadFile(fsys, ManifestFile)
+	if err != nil {
+		return // манифест необязателен
+	}
+	var saved Manifest
+	if err := json.Unmarshal(data, &saved); err != nil {
+		core.LogError("assets: manifest.json повреждён", map[string]interface{}{"error": err.Error()})
+		return
+	}
+
+	var stale []string
+	for name, hashed := range p.manifest {
+		if saved[name] != hashed {
+			stale = append(stale, name)
+		}
+	}
+	for name := range saved {
+		if _, ok := p.manifest[name]; !ok {
+			stale = append(stale, name)
+		}
+	}
+	if len(stale) > 0 {
+		sort.Strings(stale)
+		core.LogError("assets: manifest.json устарел, выполните go generate ./web", map[string]interface{}{"files": stale})
+	}
+}
+
+// compress — варианты сохраняются, только если они меньше оригинала.
+func (f *file) compress() error {
+	var gz bytes.Buffer
+	zw, err := gzip.NewWriterLevel(&gz, gzip.BestCompression)
+	if err != nil {
+		return err
+	}
+	if _, err := zw.Write(f.data); err != nil {
+		return err
+	}
+	if err := zw.Close(); err != nil {
+		return err
+	}
+	if gz.Len() < len(f.data) {
+		f.gzip = gz.Bytes()
+	}
+
+	var br bytes.Buffer
+	bw := brotli.NewWriterLevel(&br, brotli.BestCompression)
+	if _, err := bw.Write(f.data); err != nil {
+		return err
+	}
+	if err := bw.Close(); err != nil {
+		return err
+	}
+	if br.Len() < len(f.data) {
+		f.brotli = br.Bytes()
+	}
+	return nil
+}
+
+// compressible — текстовые форматы; картинки и шрифты (woff2) уже сжаты.
+func compressible(name string) bool {
+	switch path.Ext(name) {
+	case ".css", ".js", ".mjs", ".json", ".map", ".svg", ".txt", ".xml", ".html", ".ico":
+		return true
+	}
+	t := mime.TypeByExtension(path.Ext(name))
+	return strings.HasPrefix(t, "text/")
+}
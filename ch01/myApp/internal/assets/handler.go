@@ -0,0 +1,95 @@
+package assets
+
+// internal/assets/handler.go — раздача статики из памяти: ETag, gzip/brotli, кэш.
+
+import (
+	"bytes"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	cacheImmutable  = "public, max-age=31536000, immutable" // путь с хэшем: содержимое не меняется
+	cacheRevalidate = "no-cache"                            // логический путь: сверять ETag
+)
+
+// Handler — GET/HEAD /assets/*filepath.
+// Путь с хэшем кэшируется на год. Логический путь (ссылки без {{asset}})
+// тоже отдаётся, но браузер каждый раз сверяет ETag (304, если не изменился).
+func (p *Pipeline) Handler() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		name := strings.TrimPrefix(c.Param("filepath"), "/")
+		f, ok := p.byHashed[name]
+		cache := cacheImmutable
+		if !ok {
+			f, ok = p.byName[name]
+			cache = cacheRevalidate
+		}
+		if !ok {
+			http.NotFound(c.Writer, c.Request)
+			c.Abort()
+			return
+		}
+
+		h := c.Writer.Header()
+		data, enc := f.data, ""
+		if f.brotli != nil || f.gzip != nil {
+			h.Set("Vary", "Accept-Encoding")
+			ae := c.GetHeader("Accept-Encoding")
+			switch {
+			case f.brotli != nil && acceptsEncoding(ae, "br"):
+				data, enc = f.brotli, "br"
+			case f.gzip != nil && acceptsEncoding(ae, "gzip"):
+				data, enc = f.gzip, "gzip"
+			}
+		}
+
+		// ETag у каждого варианта свой: это разные байты
+		etag := f.etag
+		if enc != "" {
+			h.Set("Content-Encoding", enc)
+			etag += "-" + enc
+		}
+		h.Set("ETag", `"`+etag+`"`)
+		h.Set("Cache-Control", cache)
+
+		// ServeContent: Content-Type по расширению, If-None-Match → 304, Range, HEAD
+		http.ServeContent(c.Writer, c.Request, f.name, time.Time{}, bytes.NewReader(data))
+	}
+}
+
+// acceptsEncoding — разрешён ли coding в Accept-Encoding (q > 0). "*" действует,
+// только если coding не указан явно: "gzip;q=0, *" — gzip запрещён.
+func acceptsEncoding(header, coding string) bool {
+	star := false
+	for _, part := range strings.Split(header, ",") {
+		name, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+		name = strings.ToLower(strings.TrimSpace(name))
+		if name != coding && name != "*" {
+			continue
+		}
+		ok := qvalue(params) > 0
+		if name == coding {
+			return ok
+		}
+		star = ok
+	}
+	return star
+}
+
+// qvalue — q из параметров ("q=0.5"), по умолчанию 1.
+func qvalue(params string) float64 {
+	for _, param := range strings.Split(params, ";") {
+		k, v, ok := strings.Cut(strings.TrimSpace(param), "=")
+		if ok && strings.TrimSpace(k) == "q" {
+			if q, err := strconv.ParseFloat(strings.TrimSpace(v), 64); err == nil {
+				return q
+			}
+		}
+	}
+	return 1
+}
@@ -0,0 +1,162 @@
+package assets
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"testing/fstest"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestAcceptsEncoding(t *testing.T) {
+	tests := []struct {
+		header, coding string
+		want           bool
+	}{
+		{"", "gzip", false},
+		{"gzip", "gzip", true},
+		{"GZIP", "gzip", true},
+		{"deflate, gzip;q=0.5", "gzip", true},
+		{"gzip;q=0", "gzip", false},
+		{"gzip; q=0.0", "gzip", false},
+		{"gzip;q=0.001", "gzip", true},
+		{"br;q=0, gzip", "br", false},
+		{"*", "br", true},
+		{"*;q=0", "br", false},
+		{"gzip;q=0, *", "gzip", false}, // явный отказ важнее "*"
+		{"*, gzip;q=0", "gzip", false},
+		{"br;q=0, *", "gzip", true},
+		{"gzip;q=abc", "gzip", true}, // непонятный q — по умолчанию 1
+		{"xgzip", "gzip", false},
+		{"deflate", "gzip", false},
+	}
+	for _, tt := range tests {
+		if got := acceptsEncoding(tt.header, tt.coding); got != tt.want {
+			t.Errorf("acceptsEncoding(%q, %q) = %v, want %v", tt.header, tt.coding, got, tt.want)
+		}
+	}
+}
+
+func newTestPipeline(t *testing.T) (*Pipeline, http.Handler) {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+	p, err := Build(fstest.MapFS{
+		"css/style.css": {Data: []byte(strings.Repeat("body { color: #333; }\n", 100))},
+		"img/logo.png":  {Data: []byte("\x89PNG\r\n\x1a\n" + strings.Repeat("x", 64))},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	r := gin.New()
+	r.GET("/assets/*filepath", p.Handler())
+	return p, r
+}
+
+func serve(h http.Handler, target string, header map[string]string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodGet, target, nil)
+	for k, v := range header {
+		req.Header.Set(k, v)
+	}
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	return rec
+}
+
+func TestHandlerEncodingETag(t *testing.T) {
+	p, h := newTestPipeline(t)
+	url := p.URL("css/style.css")
+	f := p.byName["css/style.css"]
+	if f.gzip == nil || f.brotli == nil {
+		t.Fatal("css не сжат")
+	}
+
+	tests := []struct {
+		acceptEncoding string
+		enc            string
+		body           []byte
+	}{
+		{"", "", f.data},
+		{"gzip", "gzip", f.gzip},
+		{"gzip, br", "br", f.brotli},
+		{"br;q=0, gzip", "gzip", f.gzip},
+		{"br;q=0, gzip;q=0", "", f.data},
+	}
+	etags := map[string]string{}
+	for _, tt := range tests {
+		rec := serve(h, url, map[string]string{"Accept-Encoding": tt.acceptEncoding})
+		if rec.Code != http.StatusOK || !bytes.Equal(rec.Body.Bytes(), tt.body) {
+			t.Fatalf("%q: %d, %d байт", tt.acceptEncoding, rec.Code, rec.Body.Len())
+		}
+		if got := rec.Header().Get("Content-Encoding"); got != tt.enc {
+			t.Errorf("%q: Content-Encoding %q, want %q", tt.acceptEncoding, got, tt.enc)
+		}
+		if got := rec.Header().Get("Vary"); got != "Accept-Encoding" {
+			t.Errorf("%q: Vary %q", tt.acceptEncoding, got)
+		}
+		etag := rec.Header().Get("ETag")
+		if prev, ok := etags[tt.enc]; ok && prev != etag {
+			t.Errorf("%q: ETag %s, у того же варианта был %s", tt.acceptEncoding, etag, prev)
+		}
+		etags[tt.enc] = etag
+
+		// If-None-Match своего варианта — 304 без тела
+		rec = serve(h, url, map[string]string{"Accept-Encoding": tt.acceptEncoding, "If-None-Match": etag})
+		if rec.Code != http.StatusNotModified || rec.Body.Len() != 0 {
+			t.Errorf("%q: If-None-Match %s → %d", tt.acceptEncoding, etag, rec.Code)
+		}
+	}
+	if len(etags) != 3 || etags[""] == etags["gzip"] || etags["gzip"] == etags["br"] || etags[""] == etags["br"] {
+		t.Errorf("ETag вариантов должны различаться: %v", etags)
+	}
+
+	// ETag gzip-варианта не подходит к несжатому ответу
+	rec := serve(h, url, map[string]string{"If-None-Match": etags["gzip"]})
+	if rec.Code != http.StatusOK || !bytes.Equal(rec.Body.Bytes(), f.data) {
+		t.Errorf("ETag gzip для identity: %d", rec.Code)
+	}
+
+	zr, err := gzip.NewReader(bytes.NewReader(f.gzip))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if plain, err := io.ReadAll(zr); err != nil || !bytes.Equal(plain, f.data) {
+		t.Errorf("gzip-вариант не совпадает с оригиналом: %v", err)
+	}
+}
+
+func TestHandlerCacheControl(t *testing.T) {
+	p, h := newTestPipeline(t)
+
+	tests := []struct {
+		target string
+		code   int
+		cache  string
+	}{
+		{p.URL("css/style.css"), http.StatusOK, cacheImmutable},
+		{"/assets/css/style.css", http.StatusOK, cacheRevalidate},
+		{p.URL("img/logo.png"), http.StatusOK, cacheImmutable},
+		{"/assets/img/logo.png", http.StatusOK, cacheRevalidate},
+		{"/assets/css/style.00000000.css", http.StatusNotFound, ""},
+		{"/assets/css/missing.css", http.StatusNotFound, ""},
+	}
+	for _, tt := range tests {
+		rec := serve(h, tt.target, nil)
+		if rec.Code != tt.code || rec.Header().Get("Cache-Control") != tt.cache {
+			t.Errorf("%s: %d, Cache-Control %q; want %d, %q", tt.target, rec.Code, rec.Header().Get("Cache-Control"), tt.code, tt.cache)
+		}
+	}
+
+	// Картинка не сжимается: ни Vary, ни Content-Encoding
+	rec := serve(h, p.URL("img/logo.png"), map[string]string{"Accept-Encoding": "gzip, br"})
+	if rec.Header().Get("Vary") != "" || rec.Header().Get("Content-Encoding") != "" {
+		t.Errorf("png: Vary %q, Content-Encoding %q", rec.Header().Get("Vary"), rec.Header().Get("Content-Encoding"))
+	}
+	if got := rec.Header().Get("Content-Type"); got != "image/png" {
+		t.Errorf("png: Content-Type %q", got)
+	}
+}
@@ -0,0 +1,108 @@
+package auth
+
+// internal/auth/password.go — хэширование паролей argon2id (формат PHC).
+//
+// Хэш: $argon2id$v=19$m=65536,t=3,p=4$<salt base64>$<key base64>
+// Параметры хранятся в самом хэше: их можно менять, старые хэши
+// продолжают проверяться и пересчитываются при входе (NeedsRehash).
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"runtime"
+	"strings"
+
+	"golang.org/x/crypto/argon2"
+)
+
+// Параметры по RFC 9106 (второй рекомендуемый вариант: 64 MiB памяти).
+const (
+	argonTime    uint32 = 3
+	argonMemory  uint32 = 64 * 1024 // KiB
+	argonThreads uint8  = 4
+	argonKeyLen  uint32 = 32
+	argonSaltLen        = 16
+)
+
+var ErrInvalidHash = errors.New("invalid password hash")
+
+// hashSlots ограничивает число одновременных вычислений: каждое берёт
+// 64 MiB, и поток запросов на /login не должен съесть всю память.
+var hashSlots = make(chan struct{}, runtime.NumCPU())
+
+type argonParams struct {
+	time    uint32
+	memory  uint32
+	threads uint8
+}
+
+var defaultParams = argonParams{time: argonTime, memory: argonMemory, threads: argonThreads}
+
+// HashPassword — argon2id со случайной солью.
+func HashPassword(password string) (string, error) {
+	salt := make([]byte, argonSaltLen)
+	if _, err := rand.Read(salt); err != nil {
+		return "", err
+	}
+	key := derive(password, salt, defaultParams, argonKeyLen)
+
+	b64 := base64.RawStdEncoding
+	return fmt.Sprintf("$argon2id$v=%d$m=%d,t=%d,p=%d$%s$%s",
+		argon2.Version, argonMemory, argonTime, argonThreads,
+		b64.EncodeToString(salt), b64.EncodeToString(key)), nil
+}
+
+// VerifyPassword — сравнение за постоянное время. Ошибка — только для битого хэша.
+func VerifyPassword(password, hash string) (bool, error) {
+	p, salt, key, err := decodeHash(hash)
+	if err != nil {
+		return false, err
+	}
+	other := derive(password, salt, p, uint32(len(key)))
+	return subtle.ConstantTimeCompare(key, other) == 1, nil
+}
+
+// NeedsRehash — хэш посчитан с другими параметрами (их усилили после регистрации).
+func NeedsRehash(hash string) bool {
+	p, _, key, err := decodeHash(hash)
+	return err != nil || p != defaultParams || uint32(len(key)) != argonKeyLen
+}
+
+func derive(password string, salt []byte, p argonParams, keyLen uint32) []byte {
+	hashSlots <- struct{}{}
+	defer func() { <-hashSlots }()
+	return argon2.IDKey([]byte(password), salt, p.time, p.memory, p.threads, keyLen)
+}
+
+func decodeHash(hash string) (argonParams, []byte, []byte, error) {
+	var p argonParams
+	parts := strings.Split(hash, "$")
+	if len(parts) != 6 || parts[1] != "argon2id" {
+		return p, nil, nil, ErrInvalidHash
+	}
+
+	var version int
+	if _, err := fmt.Sscanf(parts[2], "v=%d", &version); err != nil || version != argon2.Version {
+		return p, nil, nil, ErrInvalidHash
+	}
+	if _, err := fmt.Sscanf(parts[3], "m=%d,t=%d,p=%d", &p.memory, &p.time, &p.threads); err != nil {
+		return p, nil, nil, ErrInvalidHash
+	}
+	if p.time == 0 || p.threads == 0 || p.memory == 0 || p.memory > 1<<20 { // не больше 1 GiB
+		return p, nil, nil, ErrInvalidHash
+	}
+
+	b64 := base64.RawStdEncoding
+	salt, err := b64.DecodeString(parts[4])
+	if err != nil || len(salt) < 8 {
+		return p, nil, nil, ErrInvalidHash
+	}
+	key, err := b64.DecodeString(parts[5])
+	if err != nil || len(key) < 16 {
+		return p, nil, nil, ErrInvalidHash
+	}
+	return p, salt, key, nil
+}
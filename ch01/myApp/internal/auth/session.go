@@ -0,0 +1,133 @@
+package auth
+
+// internal/auth/session.go — состояние входа в cookie-сессии и проверка ролей.
+//
+// В сессии хранится только id пользователя (cookie подписана ключом из CSRF_KEY).
+// Пользователь читается из БД на каждый запрос: смена роли или удаление
+// учётной записи действуют сразу, без перелогина.
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"myApp/internal/core"
+	"myApp/internal/storage"
+
+	"github.com/gin-contrib/sessions"
+	"github.com/gin-gonic/gin"
+)
+
+const sessionUserKey = "user_id"
+
+// Login — начинает сессию пользователя. Старая сессия (в т.ч. CSRF-соль)
+// очищается: защита от фиксации сессии. keep — ключи, которые переносятся
+// в новую сессию (например, корзина).
+func Login(c *gin.Context, u *storage.User, keep ...string) error {
+	s := sessions.Default(c)
+	kept := make(map[string]interface{}, len(keep))
+	for _, k := range keep {
+		if v := s.Get(k); v != nil {
+			kept[k] = v
+		}
+	}
+	s.Clear()
+	for k, v := range kept {
+		s.Set(k, v)
+	}
+	s.Set(sessionUserKey, u.ID)
+	return s.Save()
+}
+
+// Logout — завершает сессию.
+func Logout(c *gin.Context) error {
+	s := sessions.Default(c)
+	s.Clear()
+	s.Options(sessions.Options{Path: "/", MaxAge: -1})
+	return s.Save()
+}
+
+// CurrentUser — вошедший пользователь или nil.
+func CurrentUser(c *gin.Context) *storage.User {
+	u, _ := c.Request.Context().Value(core.CtxUser).(*storage.User)
+	return u
+}
+
+// LoadUser — middleware: читает пользователя по id из сессии и кладёт в контекст запроса.
+// Ставится после sessions.Sessions.
+func LoadUser(users storage.UserRepository) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if p := c.Request.URL.Path; strings.HasPrefix(p, "/assets/") || strings.HasPrefix(p, "/uploads/") {
+			c.Next()
+			return
+		}
+
+		s := sessions.Default(c)
+		id, ok := s.Get(sessionUserKey).(int64)
+		if !ok {
+			c.Next()
+			return
+		}
+
+		u, err := users.ByID(c.Request.Context(), id)
+		if err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				// Пользователь удалён — сессия больше не действительна
+				s.Delete(sessionUserKey)
+				_ = s.Save()
+			}
+			// Ошибка БД: продолжаем как гость, защищённые страницы ответят 401/редиректом
+			c.Next()
+			return
+		}
+
+		ctx := context.WithValue(c.Request.Context(), core.CtxUser, u)
+		ctx = core.WithLogFields(ctx, map[string]interface{}{"user_id": u.ID})
+		c.Request = c.Request.WithContext(ctx)
+		c.Next()
+	}
+}
+
+// RequireRole — middleware: доступ только вошедшим пользователям с одной из ролей.
+// Гость: GET → редирект на /login?next=<url>, остальные методы → 401.
+// Вошёл, но роль не подходит → 403.
+func RequireRole(roles ...string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		u := CurrentUser(c)
+		if u == nil {
+			if c.Request.Method == http.MethodGet || c.Request.Method == http.MethodHead {
+				c.Redirect(http.StatusSeeOther, "/login?next="+url.QueryEscape(c.Request.URL.RequestURI()))
+				c.Abort()
+				return
+			}
+			core.FailC(c, &core.AppError{
+				Code:    "unauthorized",
+				Status:  http.StatusUnauthorized,
+				Message: "Требуется вход",
+			})
+			return
+		}
+
+		if !u.HasRole(roles...) {
+			core.LogErrorCtx(c.Request.Context(), "Доступ запрещён", map[string]interface{}{
+				"role": u.Role,
+				"path": c.Request.URL.Path,
+			})
+			core.FailC(c, core.Forbidden("Недостаточно прав"))
+			return
+		}
+		c.Next()
+	}
+}
+
+// SafeRedirect — только локальный путь ("/..."), иначе def.
+// Защита от open redirect через ?next=https://evil.example.
+func SafeRedirect(next, def string) string {
+	if next == "" || !strings.HasPrefix(next, "/") || strings.HasPrefix(next, "//") || strings.HasPrefix(next, "/\\") {
+		return def
+	}
+	return next
+}
@@ -0,0 +1,171 @@
+package cart
+
+// internal/cart/cart.go — корзина в cookie-сессии.
+//
+// В сессии хранятся только id товаров и количества строкой "id:qty,id:qty"
+// (компактно и без gob.Register). Названия и цены всегда берутся из БД:
+// подделать цену через cookie нельзя, а удалённые товары отбрасываются при показе.
+
+import (
+	"context"
+	"strconv"
+	"strings"
+
+	"myApp/internal/core"
+
+	"github.com/gin-contrib/sessions"
+	"github.com/gin-gonic/gin"
+)
+
+// SessionKey — ключ корзины в сессии (см. auth.Login: корзина переживает вход).
+const SessionKey = "cart"
+
+// Ограничения: cookie-сессия не больше 4 КБ.
+const (
+	MaxLines = 50 // разных товаров
+	MaxQty   = 99 // штук одного товара
+)
+
+// Item — строка корзины.
+type Item struct {
+	ProductID int
+	Qty       int
+}
+
+// Cart — строки в порядке добавления.
+type Cart struct {
+	Items []Item
+}
+
+// From — корзина из сессии; повреждённые строки пропускаются.
+func From(c *gin.Context) *Cart {
+	raw, _ := sessions.Default(c).Get(SessionKey).(string)
+	return parse(raw)
+}
+
+// Save — сохраняет корзину (пустая удаляется из сессии).
+func Save(c *gin.Context, ct *Cart) error {
+	s := sessions.Default(c)
+	if len(ct.Items) == 0 {
+		s.Delete(SessionKey)
+	} else {
+		s.Set(SessionKey, ct.String())
+	}
+	return s.Save()
+}
+
+// Load — middleware: кладёт число товаров в корзине в контекст запроса (для меню).
+// Ставится после sessions.Sessions.
+func Load() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if p := c.Request.URL.Path; strings.HasPrefix(p, "/assets/") || strings.HasPrefix(p, "/uploads/") {
+			c.Next()
+			return
+		}
+		if n := From(c).Count(); n > 0 {
+			ctx := context.WithValue(c.Request.Context(), core.CtxCartCount, n)
+			c.Request = c.Request.WithContext(ctx)
+		}
+		c.Next()
+	}
+}
+
+// Add — увеличивает количество товара (не больше MaxQty).
+// false — в корзине уже MaxLines разных товаров.
+func (ct *Cart) Add(productID, qty int) bool {
+	for i := range ct.Items {
+		if ct.Items[i].ProductID == productID {
+			ct.Items[i].Qty = clampQty(ct.Items[i].Qty + qty)
+			return true
+		}
+	}
+	if len(ct.Items) >= MaxLines {
+		return false
+	}
+	ct.Items = append(ct.Items, Item{ProductID: productID, Qty: clampQty(qty)})
+	return true
+}
+
+// Set — задаёт количество; qty <= 0 удаляет товар.
+func (ct *Cart) Set(productID, qty int) {
+	if qty <= 0 {
+		ct.Remove(productID)
+		return
+	}
+	for i := range ct.Items {
+		if ct.Items[i].ProductID == productID {
+			ct.Items[i].Qty = clampQty(qty)
+			return
+		}
+	}
+}
+
+func (ct *Cart) Remove(productID int) {
+	items := ct.Items[:0]
+	for _, it := range ct.Items {
+		if it.ProductID != productID {
+			items = append(items, it)
+		}
+	}
+	ct.Items = items
+}
+
+// Count — всего штук.
+func (ct *Cart) Count() int {
+	n := 0
+	for _, it := range ct.Items {
+		n += it.Qty
+	}
+	return n
+}
+
+func (ct *Cart) IDs() []int {
+	ids := make([]int, len(ct.Items))
+	for i, it := range ct.Items {
+		ids[i] = it.ProductID
+	}
+	return ids
+}
+
+func (ct *Cart) String() string {
+	var b strings.Builder
+	for i, it := range ct.Items {
+		if i > 0 {
+			b.WriteByte(',')
+		}
+		b.WriteString(strconv.Itoa(it.ProductID))
+		b.WriteByte(':')
+		b.WriteString(strconv.Itoa(it.Qty))
+	}
+	return b.String()
+}
+
+func parse(raw string) *Cart {
+	ct := &Cart{}
+	if raw == "" {
+		return ct
+	}
+	for _, part := range strings.Split(raw, ",") {
+		idStr, qtyStr, ok := strings.Cut(part, ":")
+		if !ok {
+			continue
+		}
+		id, err1 := strconv.Atoi(idStr)
+		qty, err2 := strconv.Atoi(qtyStr)
+		if err1 != nil || err2 != nil || id <= 0 || qty <= 0 {
+			continue
+		}
+		ct.Add(id, qty)
+	}
+	return ct
+}
+
+func clampQty(q int) int {
+	if q > MaxQty {
+		return MaxQty
+	}
+	if q < 1 {
+		return 1
+	}
+	return q
+}
@@ -0,0 +1,74 @@
+package cart
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		raw  string
+		want string // String() разобранной корзины
+	}{
+		{"", ""},
+		{"1:2", "1:2"},
+		{"3:1,1:2", "3:1,1:2"}, // порядок добавления сохраняется
+		{"1:2,1:3", "1:5"},     // повтор товара складывается
+		{"1:200", "1:99"},      // больше MaxQty
+		{"1:60,1:60", "1:99"},
+		{"1:0,2:-1,-3:1,0:1", ""},
+		{"abc,1:x,y:1,:,1:,:1,1:2:3", ""},
+		{"1:2,мусор,2:1", "1:2,2:1"},
+		{" 1:2", ""}, // пробелы — тоже повреждение
+		{"1:99999999999999999999", ""},
+		{",,,1:1,,", "1:1"},
+	}
+	for _, tt := range tests {
+		if got := parse(tt.raw).String(); got != tt.want {
+			t.Errorf("parse(%q) = %q, want %q", tt.raw, got, tt.want)
+		}
+	}
+}
+
+func TestParseMaxLines(t *testing.T) {
+	parts := make([]string, MaxLines+10)
+	for i := range parts {
+		parts[i] = fmt.Sprintf("%d:1", i+1)
+	}
+	ct := parse(strings.Join(parts, ","))
+	if len(ct.Items) != MaxLines {
+		t.Fatalf("строк %d, want %d", len(ct.Items), MaxLines)
+	}
+	if last := ct.Items[MaxLines-1].ProductID; last != MaxLines {
+		t.Errorf("последняя строка %d, want %d (лишние отброшены с конца)", last, MaxLines)
+	}
+
+	// Уже лежащий в корзине товар добавляется и при MaxLines строк
+	if !ct.Add(1, 5) || ct.Items[0].Qty != 6 {
+		t.Errorf("Add существующего: %+v", ct.Items[0])
+	}
+	if ct.Add(MaxLines+1, 1) {
+		t.Error("Add новой строки сверх MaxLines")
+	}
+}
+
+func TestCartRoundTrip(t *testing.T) {
+	ct := &Cart{}
+	ct.Add(5, 2)
+	ct.Add(7, MaxQty+1)
+	ct.Add(5, 1)
+	ct.Set(9, 3) // Set не добавляет новых товаров
+	if got := ct.String(); got != "5:3,7:99" {
+		t.Fatalf("String = %q", got)
+	}
+	if got := parse(ct.String()); got.String() != ct.String() || got.Count() != 102 {
+		t.Errorf("parse(String()) = %q, Count %d", got.String(), got.Count())
+	}
+
+	ct.Set(7, 0)
+	ct.Set(5, -1)
+	if got := ct.String(); got != "" || ct.Count() != 0 {
+		t.Errorf("после удаления %q", got)
+	}
+}
@@ -7,6 +7,7 @@ import (
 	"encoding/base64"
 	"fmt"
 	"os"
+	"strconv"
 	"strings"
 	"time"
 )
@@ -21,12 +22,48 @@ type Config struct {
 	TLSOffloaded      bool          // True, если TLS завершается на прокси (Nginx/LB)
 	CertFile          string        // Путь к TLS-сертификату (если TLS не offloaded)
 	KeyFile           string        // Путь к TLS-ключу (если TLS не offloaded)
+	HSTS              bool          // Заголовок Strict-Transport-Security (по умолчанию — если HTTPS отдаёт само приложение)
+	HSTSMaxAge        time.Duration // max-age HSTS
+	HSTSSubdomains    bool          // includeSubDomains
+	HSTSPreload       bool          // preload (требует includeSubDomains и max-age ≥ 1 года)
 	ShutdownTimeout   time.Duration // Таймаут для корректного завершения работы сервера
 	ReadHeaderTimeout time.Duration // Таймаут чтения заголовков HTTP
 	ReadTimeout       time.Duration // Таймаут чтения всего тела HTTP-запроса
 	WriteTimeout      time.Duration // Таймаут записи HTTP-ответа
 	IdleTimeout       time.Duration // Таймаут простоя соединения
 	RequestTimeout    time.Duration // Общий таймаут на обработку запроса в middleware
+	AutoMigrate       bool          // True — применять новые миграции при старте (иначе: app migrate up)
+	DBDriver          string        // Драйвер БД: mysql или sqlite
+	DBDSN             string        // Строка подключения (формат зависит от драйвера)
+	DBMaxOpenConns    int           // Максимум открытых соединений в пуле
+	DBMaxIdleConns    int           // Максимум простаивающих соединений
+	DBConnMaxLifetime time.Duration // Время жизни соединения (ротация)
+	DBConnMaxIdleTime time.Duration // Закрывать соединение после простоя
+	AdminEmail        string        // Email администратора: создаётся при старте, если его ещё нет
+	AdminPassword     string        // Пароль для создаваемого администратора; пусто — не создавать
+	UploadDir         string        // Каталог загруженных файлов (картинки товаров), раздаётся как /uploads
+	MaxUploadSize     int64         // Максимальный размер загружаемого файла, байт
+	NotifyDriver      string        // Доставка уведомлений: log (только в лог) или smtp
+	NotifyTo          string        // Получатель уведомлений (email, можно несколько через запятую)
+	SMTPHost          string        // SMTP-сервер
+	SMTPPort          int           // Порт SMTP (587 — submission со STARTTLS)
+	SMTPUser          string        // Логин SMTP (пусто — без авторизации)
+	SMTPPassword      string        // Пароль SMTP
+	SMTPFrom          string        // Адрес отправителя
+	LogDir            string        // Каталог логов
+	LogLevel          string        // Уровень: debug, info, warn, error
+	LogMaxSize        int64         // Размер файла лога, после которого начинается новый (байт)
+	LogMaxAge         int           // Сколько дней хранить логи
+	LogCompress       bool          // Сжимать закрытые файлы логов (.gz)
+	CSPPolicy         string        // Пресет CSP: basic, strict, relaxed или off (не в prod)
+	CSPReportOnly     bool          // True — Content-Security-Policy-Report-Only (только отчёты, без блокировки)
+	CSPReportURI      string        // Куда браузер шлёт нарушения CSP; пусто — без отчётов
+}
+
+// Дефолтные DSN для разработки. В проде DB_DSN задаётся явно.
+var defaultDSN = map[string]string{
+	"mysql":  "root@tcp(localhost:3306)/shop",
+	"sqlite": "file:data/myapp.db",
 }
 
 // fatalConfigError — централизованно логирует ошибку конфигурации и завершает работу.
@@ -59,6 +96,118 @@ func Load() Config {
 		WriteTimeout:      getEnvDuration("WRITE_TIMEOUT", 30*time.Second),
 		IdleTimeout:       getEnvDuration("IDLE_TIMEOUT", 60*time.Second),
 		RequestTimeout:    getEnvDuration("REQUEST_TIMEOUT", 15*time.Second),
+		AutoMigrate:       getEnvBool("DB_AUTO_MIGRATE", false),
+		DBDriver:          strings.ToLower(getEnv("DB_DRIVER", "mysql")),
+		DBMaxOpenConns:    getEnvInt("DB_MAX_OPEN_CONNS", 25),
+		DBMaxIdleConns:    getEnvInt("DB_MAX_IDLE_CONNS", 25),
+		DBConnMaxLifetime: getEnvDuration("DB_CONN_MAX_LIFETIME", 5*time.Minute),
+		DBConnMaxIdleTime: getEnvDuration("DB_CONN_MAX_IDLE_TIME", 5*time.Minute),
+		AdminEmail:        getEnv("ADMIN_EMAIL", ""),
+		AdminPassword:     getEnv("ADMIN_PASSWORD", ""),
+		UploadDir:         getEnv("UPLOAD_DIR", "data/uploads"),
+		MaxUploadSize:     int64(getEnvInt("UPLOAD_MAX_MB", 5)) << 20,
+		NotifyDriver:      strings.ToLower(getEnv("NOTIFY_DRIVER", "log")),
+		NotifyTo:          getEnv("NOTIFY_TO", ""),
+		SMTPHost:          getEnv("SMTP_HOST", "localhost"),
+		SMTPPort:          getEnvInt("SMTP_PORT", 587),
+		SMTPUser:          getEnv("SMTP_USER", ""),
+		SMTPPassword:      getEnv("SMTP_PASSWORD", ""),
+		SMTPFrom:          getEnv("SMTP_FROM", ""),
+		LogDir:            getEnv("LOG_DIR", "logs"),
+		LogLevel:          strings.ToLower(getEnv("LOG_LEVEL", "info")),
+		LogMaxSize:        int64(getEnvInt("LOG_MAX_SIZE_MB", 100)) << 20,
+		LogMaxAge:         getEnvInt("LOG_MAX_AGE_DAYS", 7),
+		LogCompress:       getEnvBool("LOG_COMPRESS", true),
+		CSPPolicy:         strings.ToLower(getEnv("CSP_POLICY", "basic")),
+		CSPReportOnly:     getEnvBool("CSP_REPORT_ONLY", false),
+		CSPReportURI:      getEnv("CSP_REPORT_URI", "/csp-report"),
+	}
+
+	// HSTS: nginx при offload ставит заголовок сам — по умолчанию не дублируем
+	cfg.HSTS = getEnvBool("HSTS", cfg.Secure && !cfg.TLSOffloaded)
+	cfg.HSTSMaxAge = getEnvDuration("HSTS_MAX_AGE", 365*24*time.Hour)
+	cfg.HSTSSubdomains = getEnvBool("HSTS_INCLUDE_SUBDOMAINS", false)
+	cfg.HSTSPreload = getEnvBool("HSTS_PRELOAD", false)
+
+	// Проверка драйвера БД — без неё приложение не стартует ни в одной среде
+	if _, ok := defaultDSN[cfg.DBDriver]; !ok {
+		fatalConfigError(
+			"Неизвестный DB_DRIVER. Допустимо: mysql, sqlite.",
+			map[string]interface{}{"key": "DB_DRIVER", "value": cfg.DBDriver},
+		)
+	}
+	cfg.DBDSN = getEnv("DB_DSN", defaultDSN[cfg.DBDriver])
+	if cfg.DBMaxIdleConns > cfg.DBMaxOpenConns {
+		cfg.DBMaxIdleConns = cfg.DBMaxOpenConns
+	}
+
+	// Уведомления: для smtp обязательны отправитель и получатель
+	switch cfg.NotifyDriver {
+	case "log":
+	case "smtp":
+		if cfg.SMTPFrom == "" || cfg.NotifyTo == "" {
+			fatalConfigError(
+				"NOTIFY_DRIVER=smtp требует SMTP_FROM и NOTIFY_TO.",
+				map[string]interface{}{"keys": []string{"SMTP_FROM", "NOTIFY_TO"}},
+			)
+		}
+	default:
+		fatalConfigError(
+			"Неизвестный NOTIFY_DRIVER. Допустимо: log, smtp.",
+			map[string]interface{}{"key": "NOTIFY_DRIVER", "value": cfg.NotifyDriver},
+		)
+	}
+
+	// CSP: известный пресет; CSP_REPORT_URI=off — не собирать отчёты
+	switch cfg.CSPPolicy {
+	case "basic", "strict", "relaxed", "off":
+	default:
+		fatalConfigError(
+			"Неизвестный CSP_POLICY. Допустимо: basic, strict, relaxed, off.",
+			map[string]interface{}{"key": "CSP_POLICY", "value": cfg.CSPPolicy},
+		)
+	}
+	if strings.EqualFold(cfg.CSPReportURI, "off") {
+		cfg.CSPReportURI = ""
+	}
+
+	// Уровень логирования
+	if !validLogLevel(cfg.LogLevel) {
+		fatalConfigError(
+			"Неизвестный LOG_LEVEL. Допустимо: debug, info, warn, error.",
+			map[string]interface{}{"key": "LOG_LEVEL", "value": cfg.LogLevel},
+		)
+	}
+
+	// TLS: сертификат и ключ задаются только парой
+	if (cfg.CertFile == "") != (cfg.KeyFile == "") {
+		fatalConfigError(
+			"TLS_CERT_FILE и TLS_KEY_FILE задаются вместе.",
+			map[string]interface{}{"keys": []string{"TLS_CERT_FILE", "TLS_KEY_FILE"}},
+		)
+	}
+
+	// HSTS по HTTP браузер игнорирует, а на localhost без HTTPS он только навредит
+	if cfg.HSTS && !cfg.Secure {
+		fatalConfigError(
+			"HSTS требует SECURE=true.",
+			map[string]interface{}{"key": "HSTS", "tip": "Установите SECURE=true или HSTS=false"},
+		)
+	}
+	// Требования списка предзагрузки (hstspreload.org)
+	if cfg.HSTS && cfg.HSTSPreload && (!cfg.HSTSSubdomains || cfg.HSTSMaxAge < 365*24*time.Hour) {
+		fatalConfigError(
+			"HSTS_PRELOAD требует HSTS_INCLUDE_SUBDOMAINS=true и HSTS_MAX_AGE не меньше 8760h.",
+			map[string]interface{}{"key": "HSTS_PRELOAD", "max_age": cfg.HSTSMaxAge.String(), "include_subdomains": cfg.HSTSSubdomains},
+		)
+	}
+
+	// Учётка администратора: email и пароль задаются только парой
+	if (cfg.AdminEmail == "") != (cfg.AdminPassword == "") {
+		fatalConfigError(
+			"ADMIN_EMAIL и ADMIN_PASSWORD задаются вместе.",
+			map[string]interface{}{"keys": []string{"ADMIN_EMAIL", "ADMIN_PASSWORD"}},
+		)
 	}
 
 	// Валидация для продакшена — ключевой этап безопасности и отказоустойчивости
@@ -93,11 +242,37 @@ func Load() Config {
 				map[string]interface{}{"keys_missing": []string{"TLS_CERT_FILE", "TLS_KEY_FILE"}},
 			)
 		}
+
+		// 5. Строка подключения к БД — только явная (дефолт рассчитан на локальную разработку)
+		if getEnv("DB_DSN", "") == "" {
+			fatalConfigError("Отсутствует DB_DSN в продакшене.", map[string]interface{}{"key": "DB_DSN", "driver": cfg.DBDriver})
+		}
+
+		// 6. Пароль администратора — либо не задан (не создаётся), либо не короче 12 символов
+		if cfg.AdminPassword != "" && len(cfg.AdminPassword) < 12 {
+			fatalConfigError(
+				"Слишком короткий ADMIN_PASSWORD в продакшене. Требуется минимум 12 символов.",
+				map[string]interface{}{"key": "ADMIN_PASSWORD", "provided_length": len(cfg.AdminPassword)},
+			)
+		}
+
+		// 7. CSP нельзя выключать (для обкатки новой политики — CSP_REPORT_ONLY=true)
+		if cfg.CSPPolicy == "off" {
+			fatalConfigError(
+				"CSP_POLICY=off недопустим в продакшене.",
+				map[string]interface{}{"key": "CSP_POLICY", "tip": "Используйте CSP_REPORT_ONLY=true"},
+			)
+		}
 	}
 
 	return cfg
 }
 
+// ServeTLS — true, если HTTPS отдаёт само приложение (TLS не offloaded и есть сертификат).
+func (c Config) ServeTLS() bool {
+	return !c.TLSOffloaded && c.CertFile != "" && c.KeyFile != ""
+}
+
 // getEnv — Извлекает строку из ENV, убирает пробелы, или возвращает дефолт.
 func getEnv(key, def string) string {
 	if val := strings.TrimSpace(os.Getenv(key)); val != "" {
@@ -116,6 +291,20 @@ func getEnvBool(key string, def bool) bool {
 	return v == "true" || v == "1" || v == "yes" || v == "on"
 }
 
+// getEnvInt — Извлекает положительное целое из ENV. При ошибке формата логирует и возвращает дефолт.
+func getEnvInt(key string, def int) int {
+	val := strings.TrimSpace(os.Getenv(key))
+	if val == "" {
+		return def
+	}
+	n, err := strconv.Atoi(val)
+	if err != nil || n < 1 {
+		LogError("Неверное целое значение. Используется дефолт.", map[string]interface{}{"key": key, "value": val, "default": def})
+		return def
+	}
+	return n
+}
+
 // getEnvDuration — Извлекает time.Duration из ENV. Поддерживает форматы Go ("30s") или просто число (интерпретируется как секунды).
 func getEnvDuration(key string, def time.Duration) time.Duration {
 	val := strings.TrimSpace(os.Getenv(key))
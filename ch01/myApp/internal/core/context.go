@@ -8,4 +8,16 @@ type CtxKey string
 const (
 	// CtxNonce — ключ для CSP nonce (кладётся в request.Context в middleware)
 	CtxNonce CtxKey = "nonce"
+
+	// CtxCategoryTree — дерево категорий для меню в layout (кладётся в middleware)
+	CtxCategoryTree CtxKey = "category_tree"
+
+	// CtxUser — вошедший пользователь (*storage.User), кладётся в auth.LoadUser
+	CtxUser CtxKey = "user"
+
+	// CtxCartCount — число товаров в корзине (int) для layout, кладётся в cart.Load
+	CtxCartCount CtxKey = "cart_count"
+
+	// CtxLogger — логгер запроса с request_id, route, ip, user_id (см. WithLogFields)
+	CtxLogger CtxKey = "logger"
 )
@@ -0,0 +1,94 @@
+package core
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestCSPPolicyString(t *testing.T) {
+	base := func() *CSPPolicy {
+		return NewCSPPolicy().
+			Set(CSPDefaultSrc, SrcSelf).
+			Set(CSPScriptSrc, SrcSelf, SrcNonce).
+			Set(CSPObjectSrc, SrcNone)
+	}
+
+	tests := []struct {
+		name   string
+		policy *CSPPolicy
+		nonce  string
+		want   string
+	}{
+		{"nonce подставляется", base(), "abc123",
+			"default-src 'self'; script-src 'self' 'nonce-abc123'; object-src 'none';"},
+		{"без nonce источник пропускается", base(), "",
+			"default-src 'self'; script-src 'self'; object-src 'none';"},
+		{"директива только с nonce", NewCSPPolicy().Set(CSPStyleSrc, SrcNonce), "",
+			"style-src;"},
+		{"report-uri и report-to", base().ReportTo("/csp-report"), "n",
+			"default-src 'self'; script-src 'self' 'nonce-n'; object-src 'none'; report-uri /csp-report; report-to csp-endpoint;"},
+		{"Set заменяет, порядок сохраняется", base().Set(CSPDefaultSrc, SrcNone), "n",
+			"default-src 'none'; script-src 'self' 'nonce-n'; object-src 'none';"},
+		{"Add без повторов", base().Add(CSPScriptSrc, SrcSelf, "https://cdn.example.com").Add(CSPImgSrc, SrcData), "n",
+			"default-src 'self'; script-src 'self' 'nonce-n' https://cdn.example.com; object-src 'none'; img-src data:;"},
+		{"пустая политика", NewCSPPolicy(), "n", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.policy.String(tt.nonce); got != tt.want {
+				t.Errorf("\n got %q\nwant %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCSPPolicyCloneIndependent(t *testing.T) {
+	orig := CSPBasic()
+	want := orig.String("n")
+	clone := orig.Clone().Add(CSPScriptSrc, "https://evil.example.com").ReportTo("/r").ReportOnly(true)
+
+	if got := orig.String("n"); got != want {
+		t.Errorf("оригинал изменён через клон:\n%s", got)
+	}
+	if orig.HeaderName() != "Content-Security-Policy" || clone.HeaderName() != "Content-Security-Policy-Report-Only" {
+		t.Errorf("HeaderName: %q, %q", orig.HeaderName(), clone.HeaderName())
+	}
+}
+
+func TestCSPMiddleware(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	for _, tt := range []struct {
+		name       string
+		policy     *CSPPolicy
+		header     string
+		want       string
+		wantReport string
+	}{
+		{"enforce с отчётами", NewCSPPolicy().Set(CSPScriptSrc, SrcNonce).ReportTo("/csp-report"),
+			"Content-Security-Policy", "script-src 'nonce-xyz'; report-uri /csp-report; report-to csp-endpoint;",
+			`csp-endpoint="/csp-report"`},
+		{"report-only без отчётов", NewCSPPolicy().Set(CSPScriptSrc, SrcNonce).ReportOnly(true),
+			"Content-Security-Policy-Report-Only", "script-src 'nonce-xyz';", ""},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			r := gin.New()
+			r.Use(func(c *gin.Context) {
+				c.Request = c.Request.WithContext(context.WithValue(c.Request.Context(), CtxNonce, "xyz"))
+			}, CSP(tt.policy))
+			r.GET("/", func(c *gin.Context) { c.Status(http.StatusOK) })
+
+			rec := httptest.NewRecorder()
+			r.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+			if got := rec.Header().Get(tt.header); got != tt.want {
+				t.Errorf("%s: %q, want %q", tt.header, got, tt.want)
+			}
+			if got := rec.Header().Get("Reporting-Endpoints"); got != tt.wantReport {
+				t.Errorf("Reporting-Endpoints: %q, want %q", got, tt.wantReport)
+			}
+		})
+	}
+}
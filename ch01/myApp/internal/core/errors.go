@@ -50,3 +50,8 @@ func From(err error) *AppError {
 func Forbidden(msg string) *AppError {
 	return &AppError{Code: "forbidden", Status: http.StatusForbidden, Message: msg}
 }
+
+// BadRequest (HTTP 400) — fields: параметр -> текст ошибки
+func BadRequest(msg string, fields map[string]string) *AppError {
+	return &AppError{Code: "bad_request", Status: http.StatusBadRequest, Message: msg, Fields: fields}
+}
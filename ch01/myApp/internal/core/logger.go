@@ -1,162 +1,206 @@
 package core
 
-// logger.go
+// logger.go — глобальный логгер: info/warn/debug → stdout + <дата>.log,
+// error → stderr + errors-<дата>.log. Файлы ротируются (rotate.go).
+//
+// В обработчиках и репозиториях — Log*Ctx(ctx, ...): логгер запроса из ctx
+// (WithLogFields) добавляет request_id, route, ip, user_id в каждую запись.
+//
+// Логгер заменяемый: в тестах —
+//
+//	var buf bytes.Buffer
+//	prev := core.SetLogger(core.NewLogger(zerolog.SyncWriter(&buf), zerolog.SyncWriter(&buf), zerolog.DebugLevel))
+//	defer core.SetLogger(prev)
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	"io"
 	"os"
-	"path/filepath"
-	"sync"
-	"time"
+	"strings"
+	"sync/atomic"
 
 	"github.com/rs/zerolog"
 )
 
+// Logger — пара zerolog-логгеров и то, что нужно закрыть при остановке.
+// zerolog сам безопасен для конкурентного использования, если безопасны writer'ы.
 type Logger struct {
 	mainLogger  zerolog.Logger
 	errorLogger zerolog.Logger
-	mainFile    *os.File
-	errorFile   *os.File
-	mu          sync.Mutex
+	closers     []io.Closer
 }
 
-var (
-	globalLogger *Logger
-	cleanupOnce  sync.Once
-)
-
-// InitDailyLog — инициализация с ротацией по дням
-func InitDailyLog() {
-	// Закрываем старые файлы
-	if globalLogger != nil {
-		globalLogger.mu.Lock()
-		_ = globalLogger.mainFile.Close()
-		_ = globalLogger.errorFile.Close()
-		globalLogger.mu.Unlock()
-		globalLogger = nil
-	}
+// globalLogger — атомарно: Close может выполняться, пока другие горутины
+// (например, не остановившиеся вовремя при shutdown) ещё пишут в лог.
+var globalLogger atomic.Pointer[Logger]
 
-	// Создаём директорию logs
-	if err := os.MkdirAll("logs", 0755); err != nil {
-		_, _ = fmt.Fprintf(os.Stderr, "Ошибка создания директории logs: %v\n", err)
-		os.Exit(1)
+// NewLogger — логгер поверх произвольных writer'ов (должны быть безопасны
+// для конкурентной записи; bytes.Buffer — через zerolog.SyncWriter).
+func NewLogger(main, errs io.Writer, level zerolog.Level) *Logger {
+	return &Logger{
+		mainLogger:  zerolog.New(main).Level(level).With().Timestamp().Logger(),
+		errorLogger: zerolog.New(errs).Level(level).With().Timestamp().Logger(),
 	}
+}
 
-	// Формируем имена файлов на основе текущей даты
-	dateStr := time.Now().Format("02-01-2006")
-	mainPath := filepath.Join("logs", dateStr+".log")
-	errorPath := filepath.Join("logs", "errors-"+dateStr+".log")
+// SetLogger — подменяет глобальный логгер, возвращает прежний (nil — fallback в stdout/stderr).
+// Прежний не закрывается.
+func SetLogger(l *Logger) *Logger {
+	return globalLogger.Swap(l)
+}
 
-	// Открываем файлы
-	mainFile, err := os.OpenFile(mainPath, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+// InitLog — логи в cfg.LogDir с ротацией по дням и по размеру (LOG_* в ENV).
+func InitLog(cfg Config) error {
+	level, err := zerolog.ParseLevel(cfg.LogLevel)
 	if err != nil {
-		_, _ = fmt.Fprintf(os.Stderr, "Ошибка открытия основного лог-файла: %v\n", err)
-		os.Exit(1)
+		return fmt.Errorf("LOG_LEVEL: %w", err)
 	}
 
-	errorFile, err := os.OpenFile(errorPath, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	opts := RotateOptions{
+		Dir:      cfg.LogDir,
+		MaxSize:  cfg.LogMaxSize,
+		MaxAge:   cfg.LogMaxAge,
+		Compress: cfg.LogCompress,
+	}
+	mainFile, err := NewRotatingWriter(opts)
+	if err != nil {
+		return err
+	}
+	opts.Prefix = "errors-"
+	errorFile, err := NewRotatingWriter(opts)
 	if err != nil {
-		_, _ = fmt.Fprintf(os.Stderr, "Ошибка открытия файла ошибок: %v\n", err)
 		_ = mainFile.Close()
-		os.Exit(1)
+		return err
 	}
 
-	//  MultiWriter + консоль
-	mainWriter := zerolog.MultiLevelWriter(os.Stdout, mainFile)
-	errorWriter := zerolog.MultiLevelWriter(os.Stderr, errorFile)
-
-	mainLogger := zerolog.New(mainWriter).With().Timestamp().Logger()
-	errorLogger := zerolog.New(errorWriter).With().Timestamp().Logger()
+	l := NewLogger(
+		zerolog.MultiLevelWriter(os.Stdout, mainFile),
+		zerolog.MultiLevelWriter(os.Stderr, errorFile),
+		level,
+	)
+	l.closers = []io.Closer{mainFile, errorFile}
 
-	globalLogger = &Logger{
-		mainLogger:  mainLogger,
-		errorLogger: errorLogger,
-		mainFile:    mainFile,
-		errorFile:   errorFile,
+	// Закрываем прежний логгер, если InitLog вызван повторно
+	if prev := SetLogger(l); prev != nil {
+		_ = prev.close()
 	}
+	return nil
+}
 
-	// Очистка старых логов (один раз)
-	cleanupOnce.Do(func() {
-		go cleanupOldLogs("logs", 7)
-	})
+// LogDebug — подробности для отладки (видны при LOG_LEVEL=debug)
+func LogDebug(msg string, fields map[string]interface{}) {
+	LogDebugCtx(context.Background(), msg, fields)
 }
 
 // LogInfo — с fallback в stdout
 func LogInfo(msg string, fields map[string]interface{}) {
-	if globalLogger == nil {
-		l := zerolog.New(os.Stdout).With().Timestamp().Logger()
-		event := l.Info()
-		for k, v := range fields {
-			event = event.Interface(k, v)
-		}
-		event.Msg(msg)
-		return
-	}
+	LogInfoCtx(context.Background(), msg, fields)
+}
 
-	globalLogger.mu.Lock()
-	defer globalLogger.mu.Unlock()
+// LogWarn — нештатная, но не ошибочная ситуация (основной лог)
+func LogWarn(msg string, fields map[string]interface{}) {
+	LogWarnCtx(context.Background(), msg, fields)
+}
 
-	event := globalLogger.mainLogger.Info()
+// LogError — с fallback в stderr
+func LogError(msg string, fields map[string]interface{}) {
+	LogErrorCtx(context.Background(), msg, fields)
+}
+
+// LogDebugCtx — LogDebug с полями логгера запроса из ctx.
+func LogDebugCtx(ctx context.Context, msg string, fields map[string]interface{}) {
+	l := loggerFrom(ctx)
+	logEvent(l.main.Debug(), msg, fields)
+}
+
+// LogInfoCtx — LogInfo с полями логгера запроса из ctx.
+func LogInfoCtx(ctx context.Context, msg string, fields map[string]interface{}) {
+	l := loggerFrom(ctx)
+	logEvent(l.main.Info(), msg, fields)
+}
+
+// LogWarnCtx — LogWarn с полями логгера запроса из ctx.
+func LogWarnCtx(ctx context.Context, msg string, fields map[string]interface{}) {
+	l := loggerFrom(ctx)
+	logEvent(l.main.Warn(), msg, fields)
+}
+
+// LogErrorCtx — LogError с полями логгера запроса из ctx.
+func LogErrorCtx(ctx context.Context, msg string, fields map[string]interface{}) {
+	l := loggerFrom(ctx)
+	logEvent(l.errs.Error(), msg, fields)
+}
+
+// ctxLogger — логгер запроса (лежит в context.Context под CtxLogger).
+type ctxLogger struct {
+	main zerolog.Logger
+	errs zerolog.Logger
+}
+
+// WithLogFields — ctx с логгером, дополненным полями: их получит каждая запись
+// Log*Ctx с этим ctx. Вызовы накапливаются (request_id в начале запроса,
+// user_id — после загрузки пользователя).
+func WithLogFields(ctx context.Context, fields map[string]interface{}) context.Context {
+	base := loggerFrom(ctx)
+	mc, ec := base.main.With(), base.errs.With()
 	for k, v := range fields {
-		event = event.Interface(k, v)
+		mc = mc.Interface(k, v)
+		ec = ec.Interface(k, v)
 	}
-	event.Msg(msg)
+	return context.WithValue(ctx, CtxLogger, &ctxLogger{main: mc.Logger(), errs: ec.Logger()})
 }
 
-// LogError — с fallback в stderr
-func LogError(msg string, fields map[string]interface{}) {
-	if globalLogger == nil {
-		l := zerolog.New(os.Stderr).With().Timestamp().Logger()
-		event := l.Error()
-		for k, v := range fields {
-			event = event.Interface(k, v)
-		}
-		event.Msg(msg)
-		return
+// loggerFrom — логгер запроса; без него — глобальный (или fallback до InitLog).
+func loggerFrom(ctx context.Context) *ctxLogger {
+	if l, ok := ctx.Value(CtxLogger).(*ctxLogger); ok {
+		return l
+	}
+	if g := globalLogger.Load(); g != nil {
+		return &ctxLogger{main: g.mainLogger, errs: g.errorLogger}
 	}
+	return &ctxLogger{main: fallbackLogger(os.Stdout), errs: fallbackLogger(os.Stderr)}
+}
 
-	globalLogger.mu.Lock()
-	defer globalLogger.mu.Unlock()
+// fallbackLogger — до InitLog и после Close
+func fallbackLogger(w io.Writer) zerolog.Logger {
+	return zerolog.New(w).With().Timestamp().Logger()
+}
 
-	event := globalLogger.errorLogger.Error()
+// logEvent — event == nil, если уровень отключён LOG_LEVEL
+func logEvent(event *zerolog.Event, msg string, fields map[string]interface{}) {
+	if event == nil {
+		return
+	}
 	for k, v := range fields {
 		event = event.Interface(k, v)
 	}
 	event.Msg(msg)
 }
 
-// cleanupOldLogs — удаление логов старше N дней
-func cleanupOldLogs(dir string, days int) {
-	files, err := os.ReadDir(dir)
-	if err != nil {
-		return
-	}
-
-	cutoff := time.Now().AddDate(0, 0, -days)
-	for _, file := range files {
-		if file.IsDir() {
-			continue
-		}
-		info, err := file.Info()
-		if err != nil {
-			continue
-		}
-		if info.ModTime().Before(cutoff) {
-			path := filepath.Join(dir, file.Name())
-			_ = os.Remove(path)
+// Close — закрытие файлов (ждёт фонового сжатия логов)
+func Close() {
+	if g := globalLogger.Swap(nil); g != nil {
+		if err := g.close(); err != nil {
+			_, _ = fmt.Fprintf(os.Stderr, "Ошибка закрытия логов: %v\n", err)
 		}
 	}
 }
 
-// Close — закрытие файлов
-func Close() {
-	if globalLogger == nil {
-		return
+func (l *Logger) close() error {
+	var errs []error
+	for _, c := range l.closers {
+		errs = append(errs, c.Close())
 	}
-	globalLogger.mu.Lock()
-	defer globalLogger.mu.Unlock()
+	return errors.Join(errs...)
+}
 
-	_ = globalLogger.mainFile.Close()
-	_ = globalLogger.errorFile.Close()
-	globalLogger = nil
+// validLogLevel — уровни, которые принимает LOG_LEVEL
+func validLogLevel(s string) bool {
+	switch strings.ToLower(s) {
+	case "debug", "info", "warn", "error":
+		return true
+	}
+	return false
 }
@@ -0,0 +1,95 @@
+package core
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/rs/zerolog"
+)
+
+// captureLog — подменяет глобальный логгер буферами до конца теста.
+func captureLog(t *testing.T, level zerolog.Level) (main, errs *bytes.Buffer) {
+	t.Helper()
+	main, errs = new(bytes.Buffer), new(bytes.Buffer)
+	prev := SetLogger(NewLogger(zerolog.SyncWriter(main), zerolog.SyncWriter(errs), level))
+	t.Cleanup(func() { SetLogger(prev) })
+	return main, errs
+}
+
+func decodeEntries(t *testing.T, buf *bytes.Buffer) []map[string]interface{} {
+	t.Helper()
+	var out []map[string]interface{}
+	for _, line := range strings.Split(strings.TrimSpace(buf.String()), "\n") {
+		if line == "" {
+			continue
+		}
+		var e map[string]interface{}
+		if err := json.Unmarshal([]byte(line), &e); err != nil {
+			t.Fatalf("запись не JSON: %q: %v", line, err)
+		}
+		out = append(out, e)
+	}
+	return out
+}
+
+func TestWithLogFieldsAddsFieldsToEntries(t *testing.T) {
+	main, errs := captureLog(t, zerolog.DebugLevel)
+
+	ctx := WithLogFields(context.Background(), map[string]interface{}{"request_id": "req-1", "route": "/form"})
+	ctx = WithLogFields(ctx, map[string]interface{}{"user_id": 42})
+
+	LogInfoCtx(ctx, "info", map[string]interface{}{"k": "v"})
+	LogErrorCtx(ctx, "boom", nil)
+
+	infos, errEntries := decodeEntries(t, main), decodeEntries(t, errs)
+	if len(infos) != 1 || len(errEntries) != 1 {
+		t.Fatalf("записей: main %d, errors %d; want 1 и 1", len(infos), len(errEntries))
+	}
+	for _, e := range []map[string]interface{}{infos[0], errEntries[0]} {
+		if e["request_id"] != "req-1" || e["route"] != "/form" || e["user_id"] != float64(42) {
+			t.Errorf("нет полей запроса: %v", e)
+		}
+	}
+	if infos[0]["level"] != "info" || infos[0]["message"] != "info" || infos[0]["k"] != "v" {
+		t.Errorf("info: %v", infos[0])
+	}
+	if errEntries[0]["level"] != "error" || errEntries[0]["message"] != "boom" {
+		t.Errorf("error: %v", errEntries[0])
+	}
+}
+
+func TestLogWithoutCtxFieldsUsesGlobalLogger(t *testing.T) {
+	main, _ := captureLog(t, zerolog.DebugLevel)
+
+	LogInfo("plain", nil)
+	LogInfoCtx(context.Background(), "no fields", nil)
+
+	entries := decodeEntries(t, main)
+	if len(entries) != 2 {
+		t.Fatalf("записей %d, want 2", len(entries))
+	}
+	for _, e := range entries {
+		if _, ok := e["request_id"]; ok {
+			t.Errorf("лишнее поле request_id: %v", e)
+		}
+	}
+}
+
+func TestLogLevelFiltersEntries(t *testing.T) {
+	main, errs := captureLog(t, zerolog.WarnLevel)
+
+	LogDebug("debug", nil)
+	LogInfo("info", nil)
+	LogWarn("warn", nil)
+	LogError("error", nil)
+
+	if entries := decodeEntries(t, main); len(entries) != 1 || entries[0]["message"] != "warn" {
+		t.Errorf("main: %v, want только warn", entries)
+	}
+	if entries := decodeEntries(t, errs); len(entries) != 1 || entries[0]["message"] != "error" {
+		t.Errorf("errors: %v, want только error", entries)
+	}
+}
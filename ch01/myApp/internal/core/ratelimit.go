@@ -0,0 +1,99 @@
+package core
+
+// ratelimit.go — ограничение частоты запросов (token bucket) по IP клиента и общее.
+// Основной rate limit стоит в NGINX; этот — для отдельных маршрутов, которые
+// может дёргать кто угодно без сессии (например, /csp-report, /login).
+
+import (
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"golang.org/x/time/rate"
+)
+
+const (
+	limiterIdleTTL    = 10 * time.Minute // забываем IP, который столько молчал
+	limiterMaxClients = 10000            // защита памяти от перебора IP
+)
+
+// RateLimiter — per-IP лимит плюс общий на все IP.
+type RateLimiter struct {
+	perIP  rate.Limit
+	burst  int
+	global *rate.Limiter
+
+	mu        sync.Mutex
+	clients   map[string]*limiterClient
+	lastSweep time.Time
+}
+
+type limiterClient struct {
+	lim  *rate.Limiter
+	seen time.Time
+}
+
+// NewRateLimiter — perMinute запросов в минуту с одного IP (или другого ключа,
+// см. MiddlewareKey; всплеск до burst), globalPerMinute — со всех вместе.
+func NewRateLimiter(perMinute, burst, globalPerMinute, globalBurst int) *RateLimiter {
+	return &RateLimiter{
+		perIP:     rate.Limit(float64(perMinute) / 60),
+		burst:     burst,
+		global:    rate.NewLimiter(rate.Limit(float64(globalPerMinute)/60), globalBurst),
+		clients:   map[string]*limiterClient{},
+		lastSweep: time.Now(),
+	}
+}
+
+// Allow — можно ли обработать ещё один запрос с ip.
+func (l *RateLimiter) Allow(ip string) bool {
+	now := time.Now()
+
+	l.mu.Lock()
+	if now.Sub(l.lastSweep) > time.Minute {
+		for k, c := range l.clients {
+			if now.Sub(c.seen) > limiterIdleTTL {
+				delete(l.clients, k)
+			}
+		}
+		l.lastSweep = now
+	}
+	c, ok := l.clients[ip]
+	if !ok {
+		if len(l.clients) >= limiterMaxClients {
+			l.mu.Unlock()
+			return false
+		}
+		c = &limiterClient{lim: rate.NewLimiter(l.perIP, l.burst)}
+		l.clients[ip] = c
+	}
+	c.seen = now
+	allowed := c.lim.Allow()
+	l.mu.Unlock()
+
+	return allowed && l.global.Allow()
+}
+
+// Middleware — 429 Too Many Requests сверх лимита с IP клиента.
+func (l *RateLimiter) Middleware() gin.HandlerFunc {
+	return l.MiddlewareKey((*gin.Context).ClientIP)
+}
+
+// MiddlewareKey — то же, но ключ лимита берётся из запроса (например, email
+// из формы входа). Пустой ключ не ограничивается.
+func (l *RateLimiter) MiddlewareKey(key func(c *gin.Context) string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		k := key(c)
+		if k != "" && !l.Allow(k) {
+			c.Header("Retry-After", "60")
+			FailC(c, &AppError{
+				Code:    "too_many_requests",
+				Status:  http.StatusTooManyRequests,
+				Message: "Слишком много запросов",
+			})
+			return
+		}
+		c.Next()
+	}
+}
@@ -0,0 +1,76 @@
+package core
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/rs/zerolog"
+)
+
+func newLimitedEngine(t *testing.T, mw gin.HandlerFunc) *gin.Engine {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+	prev := SetLogger(NewLogger(io.Discard, io.Discard, zerolog.ErrorLevel))
+	t.Cleanup(func() { SetLogger(prev) })
+
+	r := gin.New()
+	r.POST("/login", mw, func(c *gin.Context) {
+		// Форма, прочитанная middleware, доступна и хэндлеру
+		c.String(http.StatusOK, c.PostForm("email"))
+	})
+	return r
+}
+
+func postLogin(r http.Handler, ip, email string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodPost, "/login", strings.NewReader(url.Values{"email": {email}}.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.RemoteAddr = ip + ":5000"
+	rec := httptest.NewRecorder()
+	r.ServeHTTP(rec, req)
+	return rec
+}
+
+func TestRateLimiterMiddlewareKey(t *testing.T) {
+	r := newLimitedEngine(t, NewRateLimiter(2, 2, 1000, 1000).MiddlewareKey(func(c *gin.Context) string {
+		return c.PostForm("email")
+	}))
+
+	// Один email с разных IP — общий лимит
+	for i, ip := range []string{"192.0.2.1", "192.0.2.2"} {
+		if rec := postLogin(r, ip, "a@example.com"); rec.Code != http.StatusOK || rec.Body.String() != "a@example.com" {
+			t.Fatalf("запрос %d: %d %q", i, rec.Code, rec.Body)
+		}
+	}
+	rec := postLogin(r, "192.0.2.3", "a@example.com")
+	if rec.Code != http.StatusTooManyRequests || rec.Header().Get("Retry-After") == "" {
+		t.Fatalf("сверх лимита: %d, Retry-After %q", rec.Code, rec.Header().Get("Retry-After"))
+	}
+
+	if rec := postLogin(r, "192.0.2.3", "b@example.com"); rec.Code != http.StatusOK {
+		t.Errorf("другой email: %d", rec.Code)
+	}
+	for i := 0; i < 5; i++ {
+		if rec := postLogin(r, "192.0.2.3", ""); rec.Code != http.StatusOK {
+			t.Fatalf("пустой ключ ограничен: %d", rec.Code)
+		}
+	}
+}
+
+func TestRateLimiterMiddlewareByIP(t *testing.T) {
+	r := newLimitedEngine(t, NewRateLimiter(1, 1, 1000, 1000).Middleware())
+
+	if rec := postLogin(r, "192.0.2.1", "a@example.com"); rec.Code != http.StatusOK {
+		t.Fatalf("первый запрос: %d", rec.Code)
+	}
+	if rec := postLogin(r, "192.0.2.1", "b@example.com"); rec.Code != http.StatusTooManyRequests {
+		t.Errorf("тот же IP: %d, want 429", rec.Code)
+	}
+	if rec := postLogin(r, "192.0.2.2", "a@example.com"); rec.Code != http.StatusOK {
+		t.Errorf("другой IP: %d", rec.Code)
+	}
+}
@@ -63,26 +63,33 @@ func FailC(c *gin.Context, err error) {
 	// В ней есть код, HTTP-статус, сообщение и т.д.
 	ae := From(err)
 
-	// Получаем уникальный идентификатор запроса (request_id)
-	reqID := requestid.Get(c)
-	if reqID == "" {
-		// Если middleware requestid не сработал, пробуем из заголовка
-		reqID = c.GetHeader("X-Request-ID")
+	fields := map[string]interface{}{
+		"code":    ae.Code,    // Внутренний код ошибки (например "db_error")
+		"status":  ae.Status,  // HTTP статус
+		"message": ae.Message, // Сообщение для пользователя
+		"fields":  ae.Fields,  // Ошибки по полям (если есть)
+		"error":   ae.Err,     // Исходная ошибка (Go error)
+	}
+
+	// request_id и маршрут обычно уже есть в логгере запроса (WithLogFields);
+	// без него — берём сами
+	ctx := c.Request.Context()
+	if _, ok := ctx.Value(CtxLogger).(*ctxLogger); !ok {
+		// Получаем уникальный идентификатор запроса (request_id)
+		reqID := requestid.Get(c)
 		if reqID == "" {
-			reqID = "n/a"
+			// Если middleware requestid не сработал, пробуем из заголовка
+			reqID = c.GetHeader("X-Request-ID")
+			if reqID == "" {
+				reqID = "n/a"
+			}
 		}
+		fields["request_id"] = reqID  // ID запроса (для трейсинга)
+		fields["path"] = c.FullPath() // URL маршрута (например /api/users/:id)
 	}
 
 	// Логируем ошибку в единообразном виде
-	LogError("Ошибка запроса", map[string]interface{}{
-		"request_id": reqID,        // ID запроса (для трейсинга)
-		"path":       c.FullPath(), // URL маршрута (например /api/users/:id)
-		"code":       ae.Code,      // Внутренний код ошибки (например "db_error")
-		"status":     ae.Status,    // HTTP статус
-		"message":    ae.Message,   // Сообщение для пользователя
-		"fields":     ae.Fields,    // Ошибки по полям (если есть)
-		"error":      ae.Err,       // Исходная ошибка (Go error)
-	})
+	LogErrorCtx(ctx, "Ошибка запроса", fields)
 
 	// Готовим тело ответа в формате RFC 7807
 	problem := ProblemDetail{
@@ -0,0 +1,322 @@
+package core
+
+// rotate.go — файл лога с ротацией: новый файл в полночь и при превышении размера,
+// старые файлы сжимаются в .gz и удаляются по истечении срока хранения.
+//
+// Имена (prefix — "" для основного лога, "errors-" для ошибок):
+//
+//	17-10-2026.log        — текущий файл дня
+//	17-10-2026.1.log.gz   — ротация по размеру в течение дня
+//	16-10-2026.log.gz     — прошедший день
+
+import (
+	"compress/gzip"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// logDateLayout — дата в имени файла лога.
+const logDateLayout = "02-01-2006"
+
+// RotateOptions — параметры RotatingWriter.
+type RotateOptions struct {
+	Dir      string // каталог логов
+	Prefix   string // префикс имени файла
+	MaxSize  int64  // байт на файл; 0 — без ограничения (только по дням)
+	MaxAge   int    // дней хранения; 0 — не удалять
+	Compress bool   // сжимать закрытые файлы в .gz
+}
+
+// RotatingWriter — io.WriteCloser, безопасен для конкурентной записи.
+// Сжатие и удаление старых файлов идут в фоне; Close их дожидается.
+type RotatingWriter struct {
+	opts RotateOptions
+	now  func() time.Time // часы (в тестах — подменяемые)
+
+	mu       sync.Mutex
+	file     *os.File
+	day      string    // дата текущего файла (logDateLayout)
+	size     int64     // байт в текущем файле
+	midnight time.Time // когда переключиться на следующий день
+
+	rotateFailed bool // ошибка ротации уже выведена в stderr
+
+	wg   sync.WaitGroup // фоновое сжатие/очистка
+	bgMu sync.Mutex     // фоновые задачи — по одной: не сжимать файл дважды
+}
+
+// NewRotatingWriter — открывает (дописывает) файл текущего дня и в фоне
+// досжимает/удаляет файлы, оставшиеся от прошлых запусков.
+func NewRotatingWriter(opts RotateOptions) (*RotatingWriter, error) {
+	return newRotatingWriter(opts, time.Now)
+}
+
+func newRotatingWriter(opts RotateOptions, now func() time.Time) (*RotatingWriter, error) {
+	if err := os.MkdirAll(opts.Dir, 0755); err != nil {
+		return nil, fmt.Errorf("создание каталога логов: %w", err)
+	}
+	w := &RotatingWriter{opts: opts, now: now}
+	if err := w.open(now()); err != nil {
+		return nil, err
+	}
+	w.background("")
+	return w, nil
+}
+
+// Write — если ротация не удалась (нет места, нет прав, каталог удалён),
+// запись продолжается в текущий файл, а ротация повторяется при следующей записи:
+// логи не пропадают до перезапуска.
+func (w *RotatingWriter) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.file == nil {
+		return 0, os.ErrClosed
+	}
+
+	now := w.now()
+	var err error
+	switch {
+	case !now.Before(w.midnight):
+		err = w.rotateDay(now)
+	case w.opts.MaxSize > 0 && w.size > 0 && w.size+int64(len(p)) > w.opts.MaxSize:
+		err = w.rotateSize(now)
+	}
+	w.reportRotate(err)
+
+	n, err := w.file.Write(p)
+	w.size += int64(n)
+	return n, err
+}
+
+// reportRotate — ошибка ротации в stderr, один раз до следующей успешной ротации
+// (логгер тут использовать нельзя — он пишет в этот же writer).
+func (w *RotatingWriter) reportRotate(err error) {
+	switch {
+	case err == nil:
+		w.rotateFailed = false
+	case !w.rotateFailed:
+		w.rotateFailed = true
+		_, _ = fmt.Fprintf(os.Stderr, "Ошибка ротации лога (запись продолжается в текущий файл): %v\n", err)
+	}
+}
+
+// Close — закрывает файл и ждёт окончания фонового сжатия.
+func (w *RotatingWriter) Close() error {
+	w.mu.Lock()
+	var err error
+	if w.file != nil {
+		err = w.file.Close()
+		w.file = nil
+	}
+	w.mu.Unlock()
+
+	w.wg.Wait()
+	return err
+}
+
+func (w *RotatingWriter) open(now time.Time) error {
+	f, size, err := w.openFile(now)
+	if err != nil {
+		return err
+	}
+	w.setFile(f, size, now)
+	return nil
+}
+
+// openFile — открывает (дописывает) файл дня, не трогая текущий.
+func (w *RotatingWriter) openFile(now time.Time) (*os.File, int64, error) {
+	f, err := os.OpenFile(w.path(now.Format(logDateLayout), 0, false), os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return nil, 0, fmt.Errorf("открытие лог-файла: %w", err)
+	}
+	st, err := f.Stat()
+	if err != nil {
+		_ = f.Close()
+		return nil, 0, err
+	}
+	return f, st.Size(), nil
+}
+
+func (w *RotatingWriter) setFile(f *os.File, size int64, now time.Time) {
+	y, m, d := now.Date()
+	w.file, w.day, w.size = f, now.Format(logDateLayout), size
+	w.midnight = time.Date(y, m, d+1, 0, 0, 0, 0, now.Location())
+}
+
+// rotateDay — полночь: файл прошедшего дня закрывается и сжимается целиком.
+// Новый файл открывается до закрытия старого: при ошибке пишем в старый.
+func (w *RotatingWriter) rotateDay(now time.Time) error {
+	old := w.path(w.day, 0, false)
+	f, size, err := w.openFile(now)
+	if err != nil {
+		return err
+	}
+	prev := w.file
+	w.setFile(f, size, now)
+	_ = prev.Close()
+	w.background(old)
+	return nil
+}
+
+// rotateSize — файл дня переименовывается в <дата>.N.log, запись продолжается в новый.
+// Открытый дескриптор переименование переживает: если новый файл не открылся,
+// имя возвращается обратно и запись идёт в прежний файл.
+func (w *RotatingWriter) rotateSize(now time.Time) error {
+	n := 1
+	for w.exists(w.path(w.day, n, false)) || w.exists(w.path(w.day, n, true)) {
+		n++
+	}
+	cur, rotated := w.path(w.day, 0, false), w.path(w.day, n, false)
+
+	if err := os.Rename(cur, rotated); err != nil {
+		return err
+	}
+	f, size, err := w.openFile(now)
+	if err != nil {
+		_ = os.Rename(rotated, cur)
+		return err
+	}
+	prev := w.file
+	w.setFile(f, size, now)
+	_ = prev.Close()
+	w.background(rotated)
+	return nil
+}
+
+// background — сжимает только что закрытый файл (если есть), затем чистит каталог.
+// Вызывается под w.mu либо до начала записи.
+func (w *RotatingWriter) background(closed string) {
+	w.wg.Add(1)
+	go func() {
+		defer w.wg.Done()
+		w.bgMu.Lock()
+		defer w.bgMu.Unlock()
+		if closed != "" && w.opts.Compress {
+			w.compress(closed)
+		}
+		w.prune()
+	}()
+}
+
+// prune — удаляет файлы старше MaxAge дней (по дате в имени) и сжимает
+// несжатые файлы прошлых дней и ротаций (остались, например, после падения).
+func (w *RotatingWriter) prune() {
+	entries, err := os.ReadDir(w.opts.Dir)
+	if err != nil {
+		return
+	}
+
+	w.mu.Lock()
+	today := w.day
+	w.mu.Unlock()
+	current := w.opts.Prefix + today + ".log"
+
+	var cutoff time.Time
+	if w.opts.MaxAge > 0 {
+		y, m, d := w.now().Date()
+		cutoff = time.Date(y, m, d-w.opts.MaxAge, 0, 0, 0, 0, time.Local)
+	}
+
+	for _, e := range entries {
+		name := e.Name()
+		day, ok := w.parseName(name)
+		if e.IsDir() || !ok || name == current {
+			continue
+		}
+		path := filepath.Join(w.opts.Dir, name)
+		switch {
+		case !cutoff.IsZero() && day.Before(cutoff):
+			_ = os.Remove(path)
+		case w.opts.Compress && strings.HasSuffix(name, ".log"):
+			w.compress(path)
+		}
+	}
+}
+
+// parseName — дата из имени файла этого writer'а (<prefix><дата>[.N].log[.gz]).
+// Файлы с другим префиксом (errors- для основного лога) не подходят.
+func (w *RotatingWriter) parseName(name string) (time.Time, bool) {
+	rest, ok := strings.CutPrefix(name, w.opts.Prefix)
+	if !ok || len(rest) < len(logDateLayout) {
+		return time.Time{}, false
+	}
+	day, err := time.ParseInLocation(logDateLayout, rest[:len(logDateLayout)], time.Local)
+	if err != nil {
+		return time.Time{}, false
+	}
+
+	rest = strings.TrimSuffix(rest[len(logDateLayout):], ".gz")
+	rest, ok = strings.CutSuffix(rest, ".log")
+	if !ok {
+		return time.Time{}, false
+	}
+	if rest != "" {
+		if n, err := strconv.Atoi(strings.TrimPrefix(rest, ".")); err != nil || n < 1 || rest[0] != '.' {
+			return time.Time{}, false
+		}
+	}
+	return day, true
+}
+
+// compress — path → path.gz; исходный файл удаляется только после успешной записи.
+func (w *RotatingWriter) compress(path string) {
+	if err := gzipFile(path); err != nil && !os.IsNotExist(err) {
+		// Логгер тут использовать нельзя — ошибка могла прийти из него самого
+		_, _ = fmt.Fprintf(os.Stderr, "Ошибка сжатия лога %s: %v\n", path, err)
+	}
+}
+
+func gzipFile(path string) error {
+	src, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = src.Close() }()
+
+	tmp := path + ".gz.tmp"
+	dst, err := os.OpenFile(tmp, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0644)
+	if err != nil {
+		return err
+	}
+	zw := gzip.NewWriter(dst)
+	_, err = io.Copy(zw, src)
+	if cerr := zw.Close(); err == nil {
+		err = cerr
+	}
+	if cerr := dst.Close(); err == nil {
+		err = cerr
+	}
+	if err == nil {
+		err = os.Rename(tmp, path+".gz")
+	}
+	if err != nil {
+		_ = os.Remove(tmp)
+		return err
+	}
+	return os.Remove(path)
+}
+
+// path — <dir>/<prefix><day>[.n].log[.gz]
+func (w *RotatingWriter) path(day string, n int, gz bool) string {
+	name := w.opts.Prefix + day
+	if n > 0 {
+		name += "." + strconv.Itoa(n)
+	}
+	name += ".log"
+	if gz {
+		name += ".gz"
+	}
+	return filepath.Join(w.opts.Dir, name)
+}
+
+func (w *RotatingWriter) exists(path string) bool {
+	_, err := os.Stat(path)
+	return err == nil
+}
@@ -0,0 +1,307 @@
+package core
+
+import (
+	"compress/gzip"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeClock — часы для RotatingWriter, двигаются только вручную.
+type fakeClock struct {
+	mu sync.Mutex
+	t  time.Time
+}
+
+func (c *fakeClock) now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.t
+}
+
+func (c *fakeClock) set(t time.Time) {
+	c.mu.Lock()
+	c.t = t
+	c.mu.Unlock()
+}
+
+func newTestWriter(t *testing.T, opts RotateOptions, clock *fakeClock) *RotatingWriter {
+	t.Helper()
+	if opts.Dir == "" {
+		opts.Dir = t.TempDir()
+	}
+	w, err := newRotatingWriter(opts, clock.now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return w
+}
+
+func write(t *testing.T, w *RotatingWriter, s string) {
+	t.Helper()
+	if _, err := w.Write([]byte(s)); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func listDir(t *testing.T, dir string) []string {
+	t.Helper()
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	for _, e := range entries {
+		names = append(names, e.Name())
+	}
+	sort.Strings(names)
+	return names
+}
+
+func readFile(t *testing.T, path string) string {
+	t.Helper()
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(data)
+}
+
+func readGzip(t *testing.T, path string) string {
+	t.Helper()
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = f.Close() }()
+	zr, err := gzip.NewReader(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := io.ReadAll(zr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(data)
+}
+
+func TestRotatingWriterSizeRotation(t *testing.T) {
+	clock := &fakeClock{t: time.Date(2026, 10, 17, 12, 0, 0, 0, time.Local)}
+	w := newTestWriter(t, RotateOptions{MaxSize: 10}, clock)
+	dir := w.opts.Dir
+
+	write(t, w, "aaaaaaaa\n") // 9 байт
+	write(t, w, "bbbbbbbb\n") // 18 > 10 — ротация в .1
+	write(t, w, "cccccccc\n") // ротация в .2
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	want := []string{"17-10-2026.1.log", "17-10-2026.2.log", "17-10-2026.log"}
+	if got := listDir(t, dir); strings.Join(got, " ") != strings.Join(want, " ") {
+		t.Fatalf("файлы %v, want %v", got, want)
+	}
+	for name, content := range map[string]string{
+		"17-10-2026.1.log": "aaaaaaaa\n",
+		"17-10-2026.2.log": "bbbbbbbb\n",
+		"17-10-2026.log":   "cccccccc\n",
+	} {
+		if got := readFile(t, filepath.Join(dir, name)); got != content {
+			t.Errorf("%s: %q, want %q", name, got, content)
+		}
+	}
+}
+
+func TestRotatingWriterSizeRotationSkipsExistingNumbers(t *testing.T) {
+	dir := t.TempDir()
+	// .1 остался сжатым от прошлого запуска — новая ротация берёт .2
+	if err := os.WriteFile(filepath.Join(dir, "17-10-2026.1.log.gz"), nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+	clock := &fakeClock{t: time.Date(2026, 10, 17, 12, 0, 0, 0, time.Local)}
+	w := newTestWriter(t, RotateOptions{Dir: dir, MaxSize: 10}, clock)
+
+	write(t, w, "aaaaaaaa\n")
+	write(t, w, "bbbbbbbb\n")
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	if got := readFile(t, filepath.Join(dir, "17-10-2026.2.log")); got != "aaaaaaaa\n" {
+		t.Errorf("17-10-2026.2.log: %q", got)
+	}
+}
+
+func TestRotatingWriterCompressesClosedFiles(t *testing.T) {
+	clock := &fakeClock{t: time.Date(2026, 10, 17, 12, 0, 0, 0, time.Local)}
+	w := newTestWriter(t, RotateOptions{MaxSize: 10, Compress: true}, clock)
+	dir := w.opts.Dir
+
+	write(t, w, "aaaaaaaa\n")
+	write(t, w, "bbbbbbbb\n")
+	if err := w.Close(); err != nil { // ждёт фоновое сжатие
+		t.Fatal(err)
+	}
+
+	want := []string{"17-10-2026.1.log.gz", "17-10-2026.log"}
+	if got := listDir(t, dir); strings.Join(got, " ") != strings.Join(want, " ") {
+		t.Fatalf("файлы %v, want %v", got, want)
+	}
+	if got := readGzip(t, filepath.Join(dir, "17-10-2026.1.log.gz")); got != "aaaaaaaa\n" {
+		t.Errorf("содержимое .gz: %q", got)
+	}
+	if got := readFile(t, filepath.Join(dir, "17-10-2026.log")); got != "bbbbbbbb\n" {
+		t.Errorf("текущий файл: %q", got)
+	}
+}
+
+func TestRotatingWriterSwitchesAtMidnight(t *testing.T) {
+	clock := &fakeClock{t: time.Date(2026, 10, 16, 23, 59, 59, 0, time.Local)}
+	w := newTestWriter(t, RotateOptions{Compress: true}, clock)
+	dir := w.opts.Dir
+
+	write(t, w, "before\n")
+	clock.set(time.Date(2026, 10, 16, 23, 59, 59, 999, time.Local))
+	write(t, w, "still\n")
+	clock.set(time.Date(2026, 10, 17, 0, 0, 0, 0, time.Local))
+	write(t, w, "after\n")
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	want := []string{"16-10-2026.log.gz", "17-10-2026.log"}
+	if got := listDir(t, dir); strings.Join(got, " ") != strings.Join(want, " ") {
+		t.Fatalf("файлы %v, want %v", got, want)
+	}
+	if got := readGzip(t, filepath.Join(dir, "16-10-2026.log.gz")); got != "before\nstill\n" {
+		t.Errorf("прошедший день: %q", got)
+	}
+	if got := readFile(t, filepath.Join(dir, "17-10-2026.log")); got != "after\n" {
+		t.Errorf("новый день: %q", got)
+	}
+}
+
+func TestRotatingWriterPrunesByNameDate(t *testing.T) {
+	dir := t.TempDir()
+	files := []string{
+		"01-10-2026.log.gz",        // старше MaxAge — удалить
+		"01-10-2026.3.log.gz",      // то же, ротация по размеру
+		"13-10-2026.log",           // старше MaxAge, несжатый — удалить
+		"14-10-2026.log.gz",        // ровно MaxAge дней — оставить
+		"16-10-2026.log",           // вчера, несжатый — сжать
+		"errors-01-10-2026.log.gz", // чужой префикс — не трогать
+		"notes.txt",                // не лог — не трогать
+	}
+	for _, name := range files {
+		// mtime у всех «сейчас»: срок считается только по дате в имени
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(name), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	clock := &fakeClock{t: time.Date(2026, 10, 17, 12, 0, 0, 0, time.Local)}
+	w := newTestWriter(t, RotateOptions{Dir: dir, MaxAge: 3, Compress: true}, clock)
+	if err := w.Close(); err != nil { // ждёт очистку, запущенную при открытии
+		t.Fatal(err)
+	}
+
+	want := []string{
+		"14-10-2026.log.gz",
+		"16-10-2026.log.gz",
+		"17-10-2026.log",
+		"errors-01-10-2026.log.gz",
+		"notes.txt",
+	}
+	if got := listDir(t, dir); strings.Join(got, " ") != strings.Join(want, " ") {
+		t.Fatalf("файлы %v, want %v", got, want)
+	}
+	if got := readGzip(t, filepath.Join(dir, "16-10-2026.log.gz")); got != "16-10-2026.log" {
+		t.Errorf("сжатый вчерашний файл: %q", got)
+	}
+}
+
+func TestRotatingWriterParseName(t *testing.T) {
+	w := &RotatingWriter{opts: RotateOptions{Prefix: "errors-"}}
+	for name, ok := range map[string]bool{
+		"errors-17-10-2026.log":      true,
+		"errors-17-10-2026.2.log":    true,
+		"errors-17-10-2026.2.log.gz": true,
+		"errors-17-10-2026.log.gz":   true,
+		"17-10-2026.log":             false,
+		"errors-17-10-2026.0.log":    false,
+		"errors-17-10-2026.x.log":    false,
+		"errors-17-10-2026.txt":      false,
+		"errors-32-10-2026.log":      false,
+	} {
+		if _, got := w.parseName(name); got != ok {
+			t.Errorf("parseName(%q) = %v, want %v", name, got, ok)
+		}
+	}
+}
+
+func TestRotatingWriterKeepsWritingWhenDirUnwritable(t *testing.T) {
+	if os.Geteuid() == 0 {
+		t.Skip("root игнорирует права на каталог")
+	}
+	clock := &fakeClock{t: time.Date(2026, 10, 16, 23, 59, 0, 0, time.Local)}
+	w := newTestWriter(t, RotateOptions{}, clock)
+	dir := w.opts.Dir
+
+	write(t, w, "before\n")
+	if err := os.Chmod(dir, 0500); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = os.Chmod(dir, 0700) })
+
+	clock.set(time.Date(2026, 10, 17, 0, 0, 1, 0, time.Local))
+	write(t, w, "during\n") // новый файл не создать — пишем в старый
+
+	if err := os.Chmod(dir, 0700); err != nil {
+		t.Fatal(err)
+	}
+	write(t, w, "after\n") // ротация повторена
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	if got := readFile(t, filepath.Join(dir, "16-10-2026.log")); got != "before\nduring\n" {
+		t.Errorf("прошедший день: %q", got)
+	}
+	if got := readFile(t, filepath.Join(dir, "17-10-2026.log")); got != "after\n" {
+		t.Errorf("новый день: %q", got)
+	}
+}
+
+func TestRotatingWriterRecoversWhenDirRemoved(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "logs")
+	clock := &fakeClock{t: time.Date(2026, 10, 16, 23, 59, 0, 0, time.Local)}
+	w := newTestWriter(t, RotateOptions{Dir: dir, MaxSize: 10}, clock)
+
+	write(t, w, "before\n")
+	if err := os.RemoveAll(dir); err != nil {
+		t.Fatal(err)
+	}
+
+	// Ни ротация по размеру, ни по дню не удаются — Write не возвращает ошибку
+	write(t, w, "size!!\n")
+	clock.set(time.Date(2026, 10, 17, 0, 0, 1, 0, time.Local))
+	write(t, w, "during\n")
+
+	// Каталог вернули — следующая запись открывает файл нового дня
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	write(t, w, "after\n")
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	if got := readFile(t, filepath.Join(dir, "17-10-2026.log")); got != "after\n" {
+		t.Errorf("новый день: %q", got)
+	}
+}
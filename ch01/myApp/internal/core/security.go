@@ -3,6 +3,9 @@ package core
 // security.go — отвечает за установку безопасных HTTP-заголовков.
 
 import (
+	"strconv"
+	"time"
+
 	"github.com/gin-gonic/gin"
 )
 
@@ -19,7 +22,26 @@ func SecureHeaders() gin.HandlerFunc {
 	}
 }
 
-// CSP возвращает middleware для установки заголовка Content-Security-Policy.
+// HSTS — Strict-Transport-Security: браузер запоминает, что сайт открывается
+// только по HTTPS, и сам меняет http:// на https:// (в т.ч. набранный руками адрес).
+// Включается HSTS=true (по умолчанию — при SECURE=true без TLS offload; за nginx
+// заголовок ставит nginx). max-age=0 — снять ранее выданный HSTS.
+func HSTS(maxAge time.Duration, includeSubdomains, preload bool) gin.HandlerFunc {
+	value := "max-age=" + strconv.FormatInt(int64(maxAge/time.Second), 10)
+	if includeSubdomains {
+		value += "; includeSubDomains"
+	}
+	if preload {
+		value += "; preload"
+	}
+	return func(c *gin.Context) {
+		c.Header("Strict-Transport-Security", value)
+		c.Next()
+	}
+}
+
+// CSP возвращает middleware для установки заголовка Content-Security-Policy
+// (или Content-Security-Policy-Report-Only, см. CSPPolicy.ReportOnly).
 // CSP — это система защиты браузера от XSS, инъекций скриптов и стилей.
 // Она говорит браузеру: "Разреши загружать только то, что я укажу".
 //
@@ -30,89 +52,100 @@ func SecureHeaders() gin.HandlerFunc {
 // 4. Всё остальное (инлайн style="", внешние скрипты без разрешения) — блокируется.
 //
 // Директивы (настройки):
-//   default-src      — что разрешено по умолчанию
-//   style-src        — откуда стили (файлы, инлайн)
-//   script-src       — откуда скрипты
-//   img-src          — картинки
-//   font-src         — шрифты
-//   object-src       — плагины (flash и т.п.)
-//   frame-ancestors  — кто может вставить нас в <iframe>
-//   base-uri         — откуда <base>
+//
+//	default-src      — что разрешено по умолчанию
+//	style-src        — откуда стили (файлы, инлайн)
+//	script-src       — откуда скрипты
+//	img-src          — картинки
+//	font-src         — шрифты
+//	object-src       — плагины (flash и т.п.)
+//	frame-ancestors  — кто может вставить нас в <iframe>
+//	base-uri         — откуда <base>
 //
 // Значения:
-//   'self'           — только с нашего домена
-//   'none'           — ничего
-//   https://cdn...   — конкретный внешний источник
-//   'nonce-ABC123'   — только с этим nonce
-//   data:            — data-uri (например, base64-картинки)
 //
-// --- ВАРИАНТ 1: БАЗОВЫЙ (БЕЗОПАСНЫЙ, КАК СЕЙЧАС) ---
-
-func CSPBasic() gin.HandlerFunc {
+//	'self'           — только с нашего домена
+//	'none'           — ничего
+//	https://cdn...   — конкретный внешний источник
+//	'nonce-ABC123'   — только с этим nonce
+//	data:            — data-uri (например, base64-картинки)
+//
+// Политика собирается построителем CSPPolicy (csp.go); готовые варианты — ниже,
+// выбирается через CSP_POLICY (см. CSPFromConfig).
+func CSP(p *CSPPolicy) gin.HandlerFunc {
+	header := p.HeaderName()
 	return func(c *gin.Context) {
 		nonce, _ := c.Request.Context().Value(CtxNonce).(string)
-
-		c.Header("Content-Security-Policy",
-			"default-src 'self'; "+
-				"base-uri 'self'; "+
-				"object-src 'none'; "+
-				"frame-ancestors 'none'; "+
-				"connect-src 'self'; "+ // Разрешает AJAX, Fetch, WebSockets только на свой домен
-				"worker-src 'self'; "+ // Разрешает Web/Service Workers только со своего домена
-				"style-src 'self' https://cdn.jsdelivr.net 'nonce-"+nonce+"'; "+
-				"script-src 'self' https://cdn.jsdelivr.net 'nonce-"+nonce+"'; "+
-				"img-src 'self' data: https://cdn.jsdelivr.net; "+
-				"font-src 'self' https://cdn.jsdelivr.net; ")
+		c.Header(header, p.String(nonce))
+		if p.reportURL != "" {
+			c.Header("Reporting-Endpoints", cspReportGroup+`="`+p.reportURL+`"`)
+		}
 		c.Next()
 	}
 }
 
-// --- ВАРИАНТ 2: РАЗРЕШИТЬ ВСЕ ИНЛАЙН СТИЛИ (НЕБЕЗОПАСНО!) ---
-// Добавляем 'unsafe-inline' — браузер разрешит style="..."
-// НО: это ослабляет защиту! Используй только для тестов.
-
-func CSPAllowInlineStyles() gin.HandlerFunc {
-	return func(c *gin.Context) {
-		nonce, _ := c.Request.Context().Value(CtxNonce).(string)
-
-		c.Header("Content-Security-Policy",
-			"default-src 'self'; "+
-				"style-src 'self' 'unsafe-inline' https://cdn.jsdelivr.net 'nonce-"+nonce+"'; "+ // ← 'unsafe-inline'
-				"script-src 'self' 'nonce-"+nonce+"'; "+
-				"img-src 'self' data:; ")
-		c.Next()
+// CSPFromConfig — пресет по CSP_POLICY с настройками отчётов; nil — CSP выключен ("off").
+func CSPFromConfig(cfg Config) *CSPPolicy {
+	var p *CSPPolicy
+	switch cfg.CSPPolicy {
+	case "strict":
+		p = CSPStrictLocalOnly()
+	case "relaxed":
+		p = CSPAllowInlineStyles()
+	case "off":
+		return nil
+	default:
+		p = CSPBasic()
 	}
+	return p.ReportTo(cfg.CSPReportURI).ReportOnly(cfg.CSPReportOnly)
 }
 
-// --- ВАРИАНТ 3: СТРОГИЙ — ТОЛЬКО СВОИ РЕСУРСЫ, БЕЗ CDN ---
-// Никаких внешних библиотек. Только локальные файлы и nonce.
+// --- ВАРИАНТ 1: БАЗОВЫЙ (БЕЗОПАСНЫЙ, ПО УМОЛЧАНИЮ) --- CSP_POLICY=basic
+
+func CSPBasic() *CSPPolicy {
+	return NewCSPPolicy().
+		Set(CSPDefaultSrc, SrcSelf).
+		Set(CSPBaseURI, SrcSelf).
+		Set(CSPObjectSrc, SrcNone).
+		Set(CSPFrameAncestors, SrcNone).
+		Set(CSPConnectSrc, SrcSelf). // Разрешает AJAX, Fetch, WebSockets только на свой домен
+		Set(CSPWorkerSrc, SrcSelf).  // Разрешает Web/Service Workers только со своего домена
+		Set(CSPStyleSrc, SrcSelf, "https://cdn.jsdelivr.net", SrcNonce).
+		Set(CSPScriptSrc, SrcSelf, "https://cdn.jsdelivr.net", SrcNonce).
+		Set(CSPImgSrc, SrcSelf, SrcData, "https://cdn.jsdelivr.net").
+		Set(CSPFontSrc, SrcSelf, "https://cdn.jsdelivr.net")
+}
 
-func CSPStrictLocalOnly() gin.HandlerFunc {
-	return func(c *gin.Context) {
-		nonce, _ := c.Request.Context().Value(CtxNonce).(string)
+// --- ВАРИАНТ 2: РАЗРЕШИТЬ ВСЕ ИНЛАЙН СТИЛИ (НЕБЕЗОПАСНО!) --- CSP_POLICY=relaxed
+// Добавляем 'unsafe-inline' — браузер разрешит style="..."
+// НО: это ослабляет защиту! Используй только для тестов.
+// (При наличии nonce браузеры игнорируют 'unsafe-inline', поэтому nonce в style-src не ставим.)
 
-		c.Header("Content-Security-Policy",
-			"default-src 'self'; "+
-				"style-src 'self' 'nonce-"+nonce+"'; "+ // только свои CSS и nonce
-				"script-src 'self' 'nonce-"+nonce+"'; "+ // только свои JS и nonce
-				"img-src 'self' data:; "+ // картинки только с сервера или data:
-				"font-src 'self'; "+ // шрифты только свои
-				"object-src 'none'; "+
-				"frame-ancestors 'none'; ")
-		c.Next()
-	}
+func CSPAllowInlineStyles() *CSPPolicy {
+	return CSPBasic().
+		Set(CSPStyleSrc, SrcSelf, SrcUnsafeInline, "https://cdn.jsdelivr.net") // ← 'unsafe-inline'
 }
 
-// --- ВАРИАНТ 4: ДЛЯ РАЗРАБОТКИ — ОТКЛЮЧИТЬ CSP СОВСЕМ ---
-// Внимание: НЕ ИСПОЛЬЗУЙ В ПРОДАКШЕНЕ!
+// --- ВАРИАНТ 3: СТРОГИЙ — ТОЛЬКО СВОИ РЕСУРСЫ, БЕЗ CDN --- CSP_POLICY=strict
+// Никаких внешних библиотек. Только локальные файлы и nonce.
 
-func CSPDisabled() gin.HandlerFunc {
-	return func(c *gin.Context) {
-		// Ничего не ставим — CSP не будет
-		c.Next()
-	}
+func CSPStrictLocalOnly() *CSPPolicy {
+	return NewCSPPolicy().
+		Set(CSPDefaultSrc, SrcSelf).
+		Set(CSPBaseURI, SrcSelf).
+		Set(CSPFormAction, SrcSelf).
+		Set(CSPStyleSrc, SrcSelf, SrcNonce).  // только свои CSS и nonce
+		Set(CSPScriptSrc, SrcSelf, SrcNonce). // только свои JS и nonce
+		Set(CSPImgSrc, SrcSelf, SrcData).     // картинки только с сервера или data:
+		Set(CSPFontSrc, SrcSelf).             // шрифты только свои
+		Set(CSPObjectSrc, SrcNone).
+		Set(CSPFrameAncestors, SrcNone)
 }
 
+// --- ВАРИАНТ 4: ДЛЯ РАЗРАБОТКИ — ОТКЛЮЧИТЬ CSP СОВСЕМ --- CSP_POLICY=off
+// Внимание: НЕ ИСПОЛЬЗУЙ В ПРОДАКШЕНЕ! (в prod Load не пропустит)
+// Вместо отключения лучше CSP_REPORT_ONLY=true: ничего не блокируется, но нарушения видны в /admin/csp.
+
 /*
 
 ### 🧠 Краткое объяснение, зачем нужны эти заголовки
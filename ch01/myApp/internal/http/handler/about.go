@@ -14,7 +14,7 @@ import (
 func About(tpl *view.Templates) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		if err := tpl.Render(c, "about", "О нас", nil); err != nil {
-			core.LogError("Ошибка рендеринга шаблона about", map[string]interface{}{
+			core.LogErrorCtx(c.Request.Context(), "Ошибка рендеринга шаблона about", map[string]interface{}{
 				"error": err.Error(),
 				"path":  c.Request.URL.Path,
 			})
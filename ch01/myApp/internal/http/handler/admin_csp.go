@@ -0,0 +1,42 @@
+package handler
+
+// admin_csp.go — админка: сводка нарушений CSP и текущая политика
+import (
+	"myApp/internal/core"
+	"myApp/internal/storage"
+	"myApp/internal/view"
+
+	"github.com/gin-gonic/gin"
+)
+
+// AdminCSPView — данные шаблона admin_csp.
+type AdminCSPView struct {
+	Items  []storage.CSPReportSummary
+	Window int    // по скольким последним отчётам сводка
+	Header string // имя заголовка (Report-Only — только отчёты)
+	Policy string // значение заголовка (nonce заменён на заглушку)
+}
+
+const (
+	cspSummaryWindow = 1000
+	cspSummaryLimit  = 100
+)
+
+// AdminCSP — группы нарушений (директива + заблокированный URI), частые первыми.
+// policy — nil, если CSP выключен (CSP_POLICY=off).
+func AdminCSP(tpl *view.Templates, reports storage.CSPReportRepository, policy *core.CSPPolicy) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		items, err := reports.Summary(c.Request.Context(), cspSummaryWindow, cspSummaryLimit)
+		if err != nil {
+			core.FailC(c, core.Internal("Ошибка загрузки отчётов CSP", err))
+			return
+		}
+
+		data := AdminCSPView{Items: items, Window: cspSummaryWindow}
+		if policy != nil {
+			data.Header = policy.HeaderName()
+			data.Policy = policy.String("…")
+		}
+		renderAdmin(c, tpl, "admin_csp", "CSP — админка", data)
+	}
+}
@@ -0,0 +1,47 @@
+package handler
+
+// admin_messages.go — админка: сообщения из формы /form и статус уведомлений
+import (
+	"strconv"
+
+	"myApp/internal/core"
+	"myApp/internal/storage"
+	"myApp/internal/view"
+
+	"github.com/gin-gonic/gin"
+)
+
+// AdminMessagesView — данные шаблона admin_messages.
+type AdminMessagesView struct {
+	Items   []storage.ContactMessage
+	NextURL string
+}
+
+const messagesPageSize = 50
+
+// AdminMessages — сообщения от новых к старым (?before=<id> — следующая страница).
+func AdminMessages(tpl *view.Templates, contacts storage.ContactRepository) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var before int64
+		if v := c.Query("before"); v != "" {
+			n, err := strconv.ParseInt(v, 10, 64)
+			if err != nil || n <= 0 {
+				core.FailC(c, core.BadRequest("Неверные параметры списка", map[string]string{"before": "ожидается положительное целое"}))
+				return
+			}
+			before = n
+		}
+
+		items, err := contacts.List(c.Request.Context(), messagesPageSize, before)
+		if err != nil {
+			core.FailC(c, core.Internal("Ошибка загрузки сообщений", err))
+			return
+		}
+
+		data := AdminMessagesView{Items: items}
+		if len(items) == messagesPageSize {
+			data.NextURL = "/admin/messages?before=" + strconv.FormatInt(items[len(items)-1].ID, 10)
+		}
+		renderAdmin(c, tpl, "admin_messages", "Сообщения — админка", data)
+	}
+}
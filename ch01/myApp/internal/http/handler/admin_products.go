@@ -0,0 +1,469 @@
+package handler
+
+// admin_products.go — админка товаров: список, создание, редактирование, удаление, журнал
+import (
+	"database/sql"
+	"errors"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"myApp/internal/auth"
+	"myApp/internal/core"
+	"myApp/internal/storage"
+	"myApp/internal/view"
+
+	"github.com/gin-gonic/gin"
+	"github.com/go-playground/validator/v10"
+)
+
+// ProductForm — поля формы товара (как ввёл пользователь, после санитизации).
+type ProductForm struct {
+	Name       string `validate:"required,min=2,max=255"`
+	Article    string `validate:"required,max=100"`
+	Price      string `validate:"required"`
+	CategoryID string
+	ImageAlt   string `validate:"max=255"`
+}
+
+// CategoryOption — пункт выпадающего списка категорий (Label с отступом по глубине).
+type CategoryOption struct {
+	ID    int64
+	Label string
+}
+
+// AdminProductsView — данные шаблона admin_products.
+type AdminProductsView struct {
+	Items   []storage.Product
+	Query   string
+	NextURL string
+	PrevURL string
+	Deleted bool // ?deleted=1 после удаления
+}
+
+// AdminProductFormView — данные шаблона admin_product_form (ID == "" — новый товар).
+type AdminProductFormView struct {
+	ID         string
+	Form       ProductForm
+	Errors     map[string]string
+	Categories []CategoryOption
+	ImagePath  string // текущая картинка (относительно /uploads)
+	Saved      bool   // ?saved=1 после сохранения
+}
+
+// AdminAuditView — данные шаблона admin_audit.
+type AdminAuditView struct {
+	Items   []storage.AuditEntry
+	NextURL string
+}
+
+const auditPageSize = 50
+
+// AdminProducts — список товаров (новые сверху), поиск ?q=, пагинация ?cursor=.
+func AdminProducts(tpl *view.Templates, products storage.ProductRepository) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		filter := storage.ProductFilter{
+			Query:  strings.TrimSpace(c.Query("q")),
+			Sort:   storage.SortNewest,
+			Limit:  storage.MaxPageSize,
+			Cursor: c.Query("cursor"),
+		}
+		page, ok := listProducts(c, products, filter)
+		if !ok {
+			return
+		}
+
+		data := AdminProductsView{
+			Items:   page.Items,
+			Query:   filter.Query,
+			NextURL: pageURL(c, page.Next),
+			PrevURL: pageURL(c, page.Prev),
+			Deleted: c.Query("deleted") == "1",
+		}
+		renderAdmin(c, tpl, "admin_products", "Товары — админка", data)
+	}
+}
+
+// AdminProductNew — пустая форма нового товара.
+func AdminProductNew(tpl *view.Templates, categories storage.CategoryRepository) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		options, ok := categoryOptions(c, categories)
+		if !ok {
+			return
+		}
+		data := AdminProductFormView{Errors: map[string]string{}, Categories: options}
+		renderAdmin(c, tpl, "admin_product_form", "Новый товар", data)
+	}
+}
+
+// AdminProductCreate — POST формы нового товара (multipart, с картинкой).
+func AdminProductCreate(tpl *view.Templates, products storage.ProductRepository, categories storage.CategoryRepository, images *storage.ImageStore) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		form, in, errs := bindProductForm(c)
+		if !checkCategory(c, categories, in.CategoryID, errs) {
+			return
+		}
+
+		imagePath, ok := saveUploadedImage(c, images, errs)
+		if !ok {
+			return
+		}
+
+		if len(errs) > 0 {
+			images.Remove(c.Request.Context(), imagePath)
+			renderProductFormErrors(c, tpl, categories, AdminProductFormView{Form: form, Errors: errs})
+			return
+		}
+
+		if imagePath != "" {
+			in.ImagePath = &imagePath
+		}
+		id, err := products.Create(c.Request.Context(), in, adminActor(c))
+		if err != nil {
+			images.Remove(c.Request.Context(), imagePath)
+			core.FailC(c, core.Internal("Ошибка сохранения товара", err))
+			return
+		}
+
+		// PRG: редирект на форму редактирования
+		c.Redirect(http.StatusSeeOther, "/admin/products/"+strconv.FormatInt(id, 10)+"?saved=1")
+	}
+}
+
+// AdminProductEdit — форма редактирования существующего товара.
+func AdminProductEdit(tpl *view.Templates, products storage.ProductRepository, categories storage.CategoryRepository) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		_, p, ok := loadAdminProduct(c, products)
+		if !ok {
+			return
+		}
+		options, ok := categoryOptions(c, categories)
+		if !ok {
+			return
+		}
+
+		form := ProductForm{
+			Name:     p.Name,
+			Article:  p.Article,
+			Price:    strconv.FormatFloat(p.Price, 'f', 2, 64),
+			ImageAlt: deref(p.ImageAlt),
+		}
+		if p.CategoryID != nil {
+			form.CategoryID = strconv.FormatInt(*p.CategoryID, 10)
+		}
+
+		data := AdminProductFormView{
+			ID:         p.ID,
+			Form:       form,
+			Errors:     map[string]string{},
+			Categories: options,
+			ImagePath:  deref(p.ImagePath),
+			Saved:      c.Query("saved") == "1",
+		}
+		renderAdmin(c, tpl, "admin_product_form", p.Name+" — админка", data)
+	}
+}
+
+// AdminProductUpdate — POST формы редактирования. Новая картинка заменяет
+// старую (старый файл удаляется только после успешного сохранения),
+// флажок remove_image убирает картинку совсем.
+func AdminProductUpdate(tpl *view.Templates, products storage.ProductRepository, categories storage.CategoryRepository, images *storage.ImageStore) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id, p, ok := loadAdminProduct(c, products)
+		if !ok {
+			return
+		}
+
+		form, in, errs := bindProductForm(c)
+		if !checkCategory(c, categories, in.CategoryID, errs) {
+			return
+		}
+
+		imagePath, ok := saveUploadedImage(c, images, errs)
+		if !ok {
+			return
+		}
+
+		if len(errs) > 0 {
+			images.Remove(c.Request.Context(), imagePath)
+			renderProductFormErrors(c, tpl, categories, AdminProductFormView{
+				ID:        p.ID,
+				Form:      form,
+				Errors:    errs,
+				ImagePath: deref(p.ImagePath),
+			})
+			return
+		}
+
+		in.ImagePath = p.ImagePath
+		switch {
+		case imagePath != "":
+			in.ImagePath = &imagePath
+		case c.PostForm("remove_image") == "1":
+			in.ImagePath = nil
+		}
+
+		if err := products.Update(c.Request.Context(), id, in, adminActor(c)); err != nil {
+			images.Remove(c.Request.Context(), imagePath)
+			if errors.Is(err, sql.ErrNoRows) { // удалили между чтением и записью
+				core.FailC(c, &core.AppError{Code: "not_found", Status: http.StatusNotFound, Message: "Товар не найден"})
+				return
+			}
+			core.FailC(c, core.Internal("Ошибка сохранения товара", err))
+			return
+		}
+
+		if old := deref(p.ImagePath); old != "" && old != deref(in.ImagePath) {
+			images.Remove(c.Request.Context(), old)
+		}
+		c.Redirect(http.StatusSeeOther, "/admin/products/"+strconv.Itoa(id)+"?saved=1")
+	}
+}
+
+// AdminProductDelete — POST удаления (кнопка на форме редактирования).
+func AdminProductDelete(products storage.ProductRepository, images *storage.ImageStore) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id, ok := adminProductID(c)
+		if !ok {
+			return
+		}
+
+		p, err := products.Delete(c.Request.Context(), id, adminActor(c))
+		if err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				core.FailC(c, &core.AppError{Code: "not_found", Status: http.StatusNotFound, Message: "Товар не найден"})
+				return
+			}
+			core.FailC(c, core.Internal("Ошибка удаления товара", err))
+			return
+		}
+
+		images.Remove(c.Request.Context(), deref(p.ImagePath))
+		c.Redirect(http.StatusSeeOther, "/admin/products?deleted=1")
+	}
+}
+
+// AdminAudit — журнал изменений, от новых к старым (?before=<id> — следующая страница).
+func AdminAudit(tpl *view.Templates, audit storage.AuditRepository) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var before int64
+		if v := c.Query("before"); v != "" {
+			n, err := strconv.ParseInt(v, 10, 64)
+			if err != nil || n <= 0 {
+				core.FailC(c, core.BadRequest("Неверные параметры журнала", map[string]string{"before": "ожидается положительное целое"}))
+				return
+			}
+			before = n
+		}
+
+		items, err := audit.List(c.Request.Context(), auditPageSize, before)
+		if err != nil {
+			core.FailC(c, core.Internal("Ошибка загрузки журнала", err))
+			return
+		}
+
+		data := AdminAuditView{Items: items}
+		if len(items) == auditPageSize {
+			data.NextURL = "/admin/audit?before=" + strconv.FormatInt(items[len(items)-1].ID, 10)
+		}
+		renderAdmin(c, tpl, "admin_audit", "Журнал изменений", data)
+	}
+}
+
+// bindProductForm — санитизация и валидация полей формы.
+// Возвращает введённые значения (для повторного показа), разобранный ввод и ошибки.
+func bindProductForm(c *gin.Context) (ProductForm, storage.ProductInput, map[string]string) {
+	f := ProductForm{
+		Name:       sanitizeText(c.PostForm("name")),
+		Article:    sanitizeText(c.PostForm("article")),
+		Price:      sanitizeText(c.PostForm("price")),
+		CategoryID: strings.TrimSpace(c.PostForm("category_id")),
+		ImageAlt:   sanitizeText(c.PostForm("image_alt")),
+	}
+	in := storage.ProductInput{Name: f.Name, Article: f.Article}
+	errs := map[string]string{}
+
+	if err := validate.Struct(f); err != nil {
+		var verrs validator.ValidationErrors
+		if !errors.As(err, &verrs) {
+			core.LogErrorCtx(c.Request.Context(), "Неожиданная ошибка валидации", map[string]interface{}{"error": err.Error()})
+			errs["form"] = "Ошибка валидации"
+			return f, in, errs
+		}
+		for _, e := range verrs {
+			switch e.Field() {
+			case "Name":
+				switch e.Tag() {
+				case "required":
+					errs["name"] = "Укажите название"
+				case "min":
+					errs["name"] = "Название должно быть не короче 2 символов"
+				default:
+					errs["name"] = "Слишком длинное название (макс. 255)"
+				}
+			case "Article":
+				if e.Tag() == "required" {
+					errs["article"] = "Укажите артикул"
+				} else {
+					errs["article"] = "Слишком длинный артикул (макс. 100)"
+				}
+			case "Price":
+				errs["price"] = "Укажите цену"
+			case "ImageAlt":
+				errs["image_alt"] = "Слишком длинное описание (макс. 255)"
+			}
+		}
+	}
+
+	if _, ok := errs["price"]; !ok {
+		price, err := strconv.ParseFloat(strings.Replace(f.Price, ",", ".", 1), 64)
+		// ParseFloat принимает "NaN" и "Inf": NaN проходит оба сравнения
+		if err != nil || math.IsNaN(price) || math.IsInf(price, 0) || price < 0 || price >= 1e8 { // DECIMAL(10,2)
+			errs["price"] = "Цена — неотрицательное число до 99 999 999.99"
+		} else {
+			in.Price = price
+		}
+	}
+
+	if f.CategoryID != "" {
+		id, err := strconv.ParseInt(f.CategoryID, 10, 64)
+		if err != nil || id <= 0 {
+			errs["category_id"] = "Неверная категория"
+		} else {
+			in.CategoryID = &id
+		}
+	}
+
+	if f.ImageAlt != "" {
+		alt := f.ImageAlt
+		in.ImageAlt = &alt
+	}
+	return f, in, errs
+}
+
+// saveUploadedImage — сохраняет файл из поля image, если он прислан.
+// Ошибки проверки файла добавляются в errs; false — ответ уже отправлен (500).
+func saveUploadedImage(c *gin.Context, images *storage.ImageStore, errs map[string]string) (string, bool) {
+	fh, err := c.FormFile("image")
+	if errors.Is(err, http.ErrMissingFile) {
+		return "", true
+	}
+	if err != nil {
+		errs["image"] = "Не удалось прочитать файл"
+		return "", true
+	}
+
+	rel, err := images.Save(fh)
+	switch {
+	case errors.Is(err, storage.ErrImageTooLarge):
+		errs["image"] = "Файл слишком большой"
+	case errors.Is(err, storage.ErrImageType):
+		errs["image"] = "Допустимы JPEG, PNG и WebP"
+	case err != nil:
+		core.LogErrorCtx(c.Request.Context(), "Ошибка сохранения картинки", map[string]interface{}{"error": err.Error()})
+		core.FailC(c, core.Internal("Ошибка сохранения картинки", err))
+		return "", false
+	}
+	return rel, true
+}
+
+// renderProductFormErrors — повторный показ формы с ошибками (400).
+func renderProductFormErrors(c *gin.Context, tpl *view.Templates, categories storage.CategoryRepository, data AdminProductFormView) {
+	options, ok := categoryOptions(c, categories)
+	if !ok {
+		return
+	}
+	data.Categories = options
+	c.Status(http.StatusBadRequest) // статус до рендера
+	renderAdmin(c, tpl, "admin_product_form", "Ошибка в форме — админка", data)
+}
+
+// checkCategory — выбранная категория должна существовать (иначе FK-ошибка → 500).
+func checkCategory(c *gin.Context, categories storage.CategoryRepository, id *int64, errs map[string]string) bool {
+	if id == nil {
+		return true
+	}
+	tree, err := categories.Tree(c.Request.Context())
+	if err != nil {
+		core.FailC(c, core.Internal("Ошибка загрузки категорий", err))
+		return false
+	}
+	for _, n := range storage.FlattenCategories(tree) {
+		if n.ID == *id {
+			return true
+		}
+	}
+	errs["category_id"] = "Категория не найдена"
+	return true
+}
+
+// categoryOptions — дерево категорий плоским списком с отступами.
+func categoryOptions(c *gin.Context, categories storage.CategoryRepository) ([]CategoryOption, bool) {
+	tree, err := categories.Tree(c.Request.Context())
+	if err != nil {
+		core.FailC(c, core.Internal("Ошибка загрузки категорий", err))
+		return nil, false
+	}
+	var out []CategoryOption
+	var walk func(nodes []*storage.CategoryNode, depth int)
+	walk = func(nodes []*storage.CategoryNode, depth int) {
+		for _, n := range nodes {
+			out = append(out, CategoryOption{ID: n.ID, Label: strings.Repeat("— ", depth) + n.Name})
+			walk(n.Children, depth+1)
+		}
+	}
+	walk(tree, 0)
+	return out, true
+}
+
+// adminProductID — :id из маршрута; при ошибке отвечает 400.
+func adminProductID(c *gin.Context) (int, bool) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil || id <= 0 {
+		core.FailC(c, core.BadRequest("Неверный ID товара", map[string]string{"id": "ожидается положительное целое"}))
+		return 0, false
+	}
+	return id, true
+}
+
+// loadAdminProduct — :id и товар по нему (400 / 404 / 500 отвечает сам).
+func loadAdminProduct(c *gin.Context, products storage.ProductRepository) (int, *storage.Product, bool) {
+	id, ok := adminProductID(c)
+	if !ok {
+		return 0, nil, false
+	}
+	p, err := products.GetByID(c.Request.Context(), id)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			core.FailC(c, &core.AppError{Code: "not_found", Status: http.StatusNotFound, Message: "Товар не найден"})
+			return 0, nil, false
+		}
+		core.FailC(c, core.Internal("Ошибка загрузки товара", err))
+		return 0, nil, false
+	}
+	return id, p, true
+}
+
+// adminActor — кто вносит изменение (email вошедшего пользователя и IP).
+func adminActor(c *gin.Context) storage.Actor {
+	a := storage.Actor{IP: c.ClientIP()}
+	if u := auth.CurrentUser(c); u != nil {
+		a.Name = u.Email
+	}
+	return a
+}
+
+func renderAdmin(c *gin.Context, tpl *view.Templates, name, title string, data any) {
+	if err := tpl.Render(c, name, title, data); err != nil {
+		core.LogErrorCtx(c.Request.Context(), "Ошибка рендеринга "+name, map[string]interface{}{"error": err.Error()})
+		core.FailC(c, core.Internal("Ошибка отображения", err))
+	}
+}
+
+func deref(s *string) string {
+	if s == nil {
+		return ""
+	}
+	return *s
+}
@@ -0,0 +1,43 @@
+package handler
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func bindPrice(t *testing.T, price string) (float64, bool) {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+	form := url.Values{"name": {"Товар"}, "article": {"ART-1"}, "price": {price}}
+	req := httptest.NewRequest(http.MethodPost, "/admin/products", strings.NewReader(form.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	c, _ := gin.CreateTestContext(httptest.NewRecorder())
+	c.Request = req
+
+	_, in, errs := bindProductForm(c)
+	_, bad := errs["price"]
+	return in.Price, !bad
+}
+
+func TestBindProductFormPrice(t *testing.T) {
+	for price, want := range map[string]float64{
+		"0":           0,
+		"199.99":      199.99,
+		"199,99":      199.99,
+		"99999999.99": 99999999.99,
+	} {
+		if got, ok := bindPrice(t, price); !ok || got != want {
+			t.Errorf("%q: %v (ok=%v), want %v", price, got, ok, want)
+		}
+	}
+	for _, price := range []string{"NaN", "nan", "Inf", "+Inf", "-Inf", "infinity", "-1", "1e8", "abc", "1,2,3"} {
+		if got, ok := bindPrice(t, price); ok {
+			t.Errorf("%q принята как %v", price, got)
+		}
+	}
+}
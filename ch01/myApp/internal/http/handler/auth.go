@@ -0,0 +1,236 @@
+package handler
+
+// auth.go — регистрация, вход и выход (сессия, пароли argon2id)
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"net/http"
+	"sync"
+
+	"myApp/internal/auth"
+	"myApp/internal/cart"
+	"myApp/internal/core"
+	"myApp/internal/storage"
+	"myApp/internal/view"
+
+	"github.com/gin-gonic/gin"
+	"github.com/go-playground/validator/v10"
+)
+
+// RegisterForm — поля формы регистрации.
+type RegisterForm struct {
+	Name            string `validate:"required,min=2,max=100"`
+	Email           string `validate:"required,email,max=255"`
+	Password        string `validate:"required,min=10,max=128"`
+	PasswordConfirm string `validate:"eqfield=Password"`
+}
+
+// LoginForm — поля формы входа.
+type LoginForm struct {
+	Email    string `validate:"required,email,max=255"`
+	Password string `validate:"required,max=128"`
+}
+
+// AuthView — данные шаблонов login и register. Пароль в шаблон не возвращается.
+type AuthView struct {
+	Name   string
+	Email  string
+	Next   string // куда вернуться после входа
+	Errors map[string]string
+}
+
+// dummyHash — для входа с несуществующим email: хэш всё равно проверяется,
+// и по времени ответа нельзя узнать, зарегистрирован ли адрес.
+var (
+	dummyHash     string
+	dummyHashOnce sync.Once
+)
+
+// LoginIndex — GET /login
+func LoginIndex(tpl *view.Templates) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if auth.CurrentUser(c) != nil {
+			c.Redirect(http.StatusSeeOther, auth.SafeRedirect(c.Query("next"), "/"))
+			return
+		}
+		data := AuthView{Next: auth.SafeRedirect(c.Query("next"), ""), Errors: map[string]string{}}
+		renderAuth(c, tpl, "login", "Вход", data)
+	}
+}
+
+// LoginSubmit — POST /login
+func LoginSubmit(tpl *view.Templates, users storage.UserRepository) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		f := LoginForm{
+			Email:    storage.NormalizeEmail(c.PostForm("email")),
+			Password: c.PostForm("password"),
+		}
+		next := auth.SafeRedirect(c.PostForm("next"), "")
+		data := AuthView{Email: f.Email, Next: next, Errors: map[string]string{}}
+
+		if err := validate.Struct(f); err != nil {
+			data.Errors["form"] = "Введите email и пароль"
+			c.Status(http.StatusBadRequest)
+			renderAuth(c, tpl, "login", "Вход", data)
+			return
+		}
+
+		u, err := users.ByEmail(c.Request.Context(), f.Email)
+		if err != nil && !errors.Is(err, sql.ErrNoRows) {
+			core.FailC(c, core.Internal("Ошибка входа", err))
+			return
+		}
+
+		var hash string
+		if u != nil {
+			hash = u.PasswordHash
+		} else {
+			dummyHashOnce.Do(func() { dummyHash, _ = auth.HashPassword("dummy password") })
+			hash = dummyHash
+		}
+		// Пароль не обрезается и не санитизируется: допустимы любые символы
+		ok, err := auth.VerifyPassword(f.Password, hash)
+		if err != nil {
+			core.LogErrorCtx(c.Request.Context(), "Повреждённый хэш пароля", map[string]interface{}{"email": f.Email, "error": err.Error()})
+		}
+		if u == nil || !ok {
+			core.LogInfoCtx(c.Request.Context(), "Неудачный вход", map[string]interface{}{"email": f.Email})
+			data.Errors["form"] = "Неверный email или пароль"
+			c.Status(http.StatusUnauthorized)
+			renderAuth(c, tpl, "login", "Вход", data)
+			return
+		}
+
+		ctx := c.Request.Context()
+		if auth.NeedsRehash(u.PasswordHash) {
+			if h, err := auth.HashPassword(f.Password); err == nil {
+				_ = users.SetPasswordHash(ctx, u.ID, h)
+			}
+		}
+		_ = users.TouchLogin(ctx, u.ID)
+
+		if err := auth.Login(c, u, cart.SessionKey); err != nil {
+			core.FailC(c, core.Internal("Ошибка сессии", err))
+			return
+		}
+		core.LogInfoCtx(c.Request.Context(), "Вход", map[string]interface{}{"user_id": u.ID})
+		c.Redirect(http.StatusSeeOther, auth.SafeRedirect(next, "/"))
+	}
+}
+
+// RegisterIndex — GET /register
+func RegisterIndex(tpl *view.Templates) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if auth.CurrentUser(c) != nil {
+			c.Redirect(http.StatusSeeOther, "/")
+			return
+		}
+		renderAuth(c, tpl, "register", "Регистрация", AuthView{Errors: map[string]string{}})
+	}
+}
+
+// RegisterSubmit — POST /register: создаёт пользователя с ролью user и сразу входит.
+func RegisterSubmit(tpl *view.Templates, users storage.UserRepository) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		f := RegisterForm{
+			Name:            sanitizeText(c.PostForm("name")),
+			Email:           storage.NormalizeEmail(c.PostForm("email")),
+			Password:        c.PostForm("password"),
+			PasswordConfirm: c.PostForm("password_confirm"),
+		}
+		data := AuthView{Name: f.Name, Email: f.Email, Errors: registerErrors(c.Request.Context(), f)}
+
+		if len(data.Errors) == 0 {
+			hash, err := auth.HashPassword(f.Password)
+			if err != nil {
+				core.FailC(c, core.Internal("Ошибка регистрации", err))
+				return
+			}
+			u := &storage.User{Email: f.Email, Name: f.Name, PasswordHash: hash, Role: storage.RoleUser}
+			err = users.Create(c.Request.Context(), u)
+			switch {
+			case errors.Is(err, storage.ErrEmailTaken):
+				data.Errors["email"] = "Этот email уже зарегистрирован"
+			case err != nil:
+				core.FailC(c, core.Internal("Ошибка регистрации", err))
+				return
+			default:
+				if err := auth.Login(c, u, cart.SessionKey); err != nil {
+					core.FailC(c, core.Internal("Ошибка сессии", err))
+					return
+				}
+				core.LogInfoCtx(c.Request.Context(), "Регистрация", map[string]interface{}{"user_id": u.ID})
+				c.Redirect(http.StatusSeeOther, "/")
+				return
+			}
+		}
+
+		c.Status(http.StatusBadRequest) // статус до рендера
+		renderAuth(c, tpl, "register", "Регистрация", data)
+	}
+}
+
+// Logout — POST /logout (только POST с CSRF-токеном: выйти по ссылке с чужого сайта нельзя).
+func Logout(c *gin.Context) {
+	if err := auth.Logout(c); err != nil {
+		core.FailC(c, core.Internal("Ошибка сессии", err))
+		return
+	}
+	c.Redirect(http.StatusSeeOther, "/")
+}
+
+func registerErrors(ctx context.Context, f RegisterForm) map[string]string {
+	errs := map[string]string{}
+	err := validate.Struct(f)
+	if err == nil {
+		return errs
+	}
+	var verrs validator.ValidationErrors
+	if !errors.As(err, &verrs) {
+		core.LogErrorCtx(ctx, "Неожиданная ошибка валидации", map[string]interface{}{"error": err.Error()})
+		errs["form"] = "Ошибка валидации"
+		return errs
+	}
+	for _, e := range verrs {
+		switch e.Field() {
+		case "Name":
+			switch e.Tag() {
+			case "required":
+				errs["name"] = "Укажите имя"
+			case "min":
+				errs["name"] = "Имя должно быть не короче 2 символов"
+			default:
+				errs["name"] = "Слишком длинное имя (макс. 100)"
+			}
+		case "Email":
+			if e.Tag() == "required" {
+				errs["email"] = "Укажите email"
+			} else {
+				errs["email"] = "Введите корректный email"
+			}
+		case "Password":
+			switch e.Tag() {
+			case "required":
+				errs["password"] = "Придумайте пароль"
+			case "min":
+				errs["password"] = "Пароль должен быть не короче 10 символов"
+			default:
+				errs["password"] = "Слишком длинный пароль (макс. 128)"
+			}
+		case "PasswordConfirm":
+			errs["password_confirm"] = "Пароли не совпадают"
+		}
+	}
+	return errs
+}
+
+func renderAuth(c *gin.Context, tpl *view.Templates, name, title string, data AuthView) {
+	if err := tpl.Render(c, name, title, data); err != nil {
+		core.LogErrorCtx(c.Request.Context(), "Ошибка рендеринга шаблона "+name, map[string]interface{}{
+			"error": err.Error(),
+			"path":  c.Request.URL.Path,
+		})
+		c.String(http.StatusInternalServerError, "Ошибка отображения страницы")
+	}
+}
@@ -0,0 +1,176 @@
+package handler
+
+// cart.go — корзина: /cart, добавление со страницы товара, изменение количества, удаление
+import (
+	"database/sql"
+	"errors"
+	"math"
+	"net/http"
+	"strconv"
+
+	"myApp/internal/cart"
+	"myApp/internal/core"
+	"myApp/internal/storage"
+	"myApp/internal/view"
+
+	"github.com/gin-gonic/gin"
+)
+
+// CartLine — товар корзины с актуальной ценой из БД.
+type CartLine struct {
+	ProductID int
+	Product   storage.Product
+	Qty       int
+	Sum       float64
+}
+
+// CartView — данные шаблона cart.
+type CartView struct {
+	Lines  []CartLine
+	Total  float64
+	Count  int
+	Notice string // сообщение после редиректа (?full=1, ?unavailable=1)
+}
+
+// Cart — GET /cart
+func Cart(tpl *view.Templates, products storage.ProductRepository) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		data, err := loadCart(c, products)
+		if err != nil {
+			core.FailC(c, core.Internal("Ошибка загрузки корзины", err))
+			return
+		}
+		switch {
+		case c.Query("full") == "1":
+			data.Notice = "В корзине не может быть больше " + strconv.Itoa(cart.MaxLines) + " разных товаров"
+		case c.Query("unavailable") == "1":
+			data.Notice = "Некоторые товары больше недоступны — корзина обновлена, проверьте её перед оформлением"
+		}
+		if err := tpl.Render(c, "cart", "Корзина", data); err != nil {
+			core.LogErrorCtx(c.Request.Context(), "Ошибка рендеринга cart", map[string]interface{}{"error": err.Error()})
+			core.FailC(c, core.Internal("Ошибка отображения", err))
+		}
+	}
+}
+
+// CartAdd — POST /cart/add (product_id, qty — по умолчанию 1)
+func CartAdd(products storage.ProductRepository) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id, err := strconv.Atoi(c.PostForm("product_id"))
+		if err != nil || id <= 0 {
+			core.FailC(c, core.BadRequest("Неверный товар", map[string]string{"product_id": "ожидается положительное целое"}))
+			return
+		}
+		qty := 1
+		if v := c.PostForm("qty"); v != "" {
+			qty, err = strconv.Atoi(v)
+			if err != nil || qty <= 0 {
+				core.FailC(c, core.BadRequest("Неверное количество", map[string]string{"qty": "ожидается положительное целое"}))
+				return
+			}
+		}
+
+		if _, err := products.GetByID(c.Request.Context(), id); err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				core.FailC(c, &core.AppError{Code: "not_found", Status: http.StatusNotFound, Message: "Товар не найден"})
+				return
+			}
+			core.FailC(c, core.Internal("Ошибка загрузки товара", err))
+			return
+		}
+
+		ct := cart.From(c)
+		if !ct.Add(id, qty) {
+			c.Redirect(http.StatusSeeOther, "/cart?full=1")
+			return
+		}
+		if err := cart.Save(c, ct); err != nil {
+			core.FailC(c, core.Internal("Ошибка сохранения корзины", err))
+			return
+		}
+		c.Redirect(http.StatusSeeOther, "/cart")
+	}
+}
+
+// CartUpdate — POST /cart/update: поля qty_<id> для товаров корзины, 0 — удалить.
+func CartUpdate(c *gin.Context) {
+	ct := cart.From(c)
+	for _, id := range ct.IDs() {
+		v, ok := c.GetPostForm("qty_" + strconv.Itoa(id))
+		if !ok {
+			continue
+		}
+		qty, err := strconv.Atoi(v)
+		if err != nil || qty < 0 {
+			core.FailC(c, core.BadRequest("Неверное количество", map[string]string{"qty_" + strconv.Itoa(id): "ожидается целое от 0"}))
+			return
+		}
+		ct.Set(id, qty)
+	}
+	if err := cart.Save(c, ct); err != nil {
+		core.FailC(c, core.Internal("Ошибка сохранения корзины", err))
+		return
+	}
+	c.Redirect(http.StatusSeeOther, "/cart")
+}
+
+// CartRemove — POST /cart/remove (product_id)
+func CartRemove(c *gin.Context) {
+	id, err := strconv.Atoi(c.PostForm("product_id"))
+	if err != nil || id <= 0 {
+		core.FailC(c, core.BadRequest("Неверный товар", map[string]string{"product_id": "ожидается положительное целое"}))
+		return
+	}
+	ct := cart.From(c)
+	ct.Remove(id)
+	if err := cart.Save(c, ct); err != nil {
+		core.FailC(c, core.Internal("Ошибка сохранения корзины", err))
+		return
+	}
+	c.Redirect(http.StatusSeeOther, "/cart")
+}
+
+// loadCart — корзина из сессии с товарами из БД. Удалённые товары выбрасываются
+// из корзины (и сессия пересохраняется). Сумма считается в центах, чтобы
+// не накапливать ошибку float.
+func loadCart(c *gin.Context, products storage.ProductRepository) (CartView, error) {
+	ct := cart.From(c)
+	data := CartView{}
+	if len(ct.Items) == 0 {
+		return data, nil
+	}
+
+	items, err := products.ByIDs(c.Request.Context(), ct.IDs())
+	if err != nil {
+		return data, err
+	}
+	byID := make(map[string]storage.Product, len(items))
+	for _, p := range items {
+		byID[p.ID] = p
+	}
+
+	var total int64
+	var missing []int
+	for _, it := range ct.Items {
+		p, ok := byID[strconv.Itoa(it.ProductID)]
+		if !ok {
+			missing = append(missing, it.ProductID)
+			continue
+		}
+		sum := int64(math.Round(p.Price*100)) * int64(it.Qty)
+		total += sum
+		data.Lines = append(data.Lines, CartLine{ProductID: it.ProductID, Product: p, Qty: it.Qty, Sum: float64(sum) / 100})
+		data.Count += it.Qty
+	}
+	data.Total = float64(total) / 100
+
+	if len(missing) > 0 {
+		for _, id := range missing {
+			ct.Remove(id)
+		}
+		if err := cart.Save(c, ct); err != nil {
+			core.LogErrorCtx(c.Request.Context(), "Ошибка сохранения корзины", map[string]interface{}{"error": err.Error()})
+		}
+	}
+	return data, nil
+}
@@ -2,6 +2,11 @@ package handler
 
 // catalog.go
 import (
+	"errors"
+	"net/url"
+	"strconv"
+	"strings"
+
 	"myApp/internal/core"
 	"myApp/internal/storage"
 	"myApp/internal/view"
@@ -9,28 +14,146 @@ import (
 	"github.com/gin-gonic/gin"
 )
 
-// Catalog — отображает каталог товаров из MySQL
-func Catalog(tpl *view.Templates) gin.HandlerFunc {
+// CatalogView — данные шаблона catalog: товары, текущие фильтры и ссылки пагинации.
+type CatalogView struct {
+	Items    []storage.Product
+	Query    string
+	Sort     string
+	Category string // ?category_id= — сохраняется в форме поиска
+	Min      string // как ввёл пользователь, для повторного заполнения формы
+	Max      string
+	NextURL  string // пусто — страницы нет
+	PrevURL  string
+}
+
+// Catalog — отображает каталог товаров с фильтрами и пагинацией
+// (?q=, ?category_id=, ?min_price=, ?max_price=, ?sort=, ?limit=, ?cursor=)
+func Catalog(tpl *view.Templates, products storage.ProductRepository, categories storage.CategoryRepository) gin.HandlerFunc {
 	return func(c *gin.Context) {
-		// Достаём *sqlx.DB из контекста запроса (мы его туда положили в middleware withNonceAndDB)
-		db := storage.GetDBFromContext(c.Request.Context())
-		if db == nil {
-			core.LogError("DB недоступна в контексте", nil)
-			core.FailC(c, core.Internal("Внутренняя ошибка", nil))
+		filter, ok := parseProductFilter(c)
+		if !ok || !expandCategory(c, categories, &filter) {
 			return
 		}
 
-		items, err := storage.ListAllProducts(c.Request.Context(), db)
-		if err != nil {
-			core.LogError("Ошибка загрузки каталога", map[string]interface{}{"error": err.Error()})
-			core.FailC(c, core.Internal("Ошибка каталога", err))
+		page, ok := listProducts(c, products, filter)
+		if !ok {
 			return
 		}
 
-		if err := tpl.Render(c, "catalog", "Каталог товаров", items); err != nil {
-			core.LogError("Ошибка рендеринга catalog", map[string]interface{}{"error": err.Error()})
+		data := CatalogView{
+			Items:    page.Items,
+			Query:    filter.Query,
+			Sort:     filter.Sort,
+			Category: c.Query("category_id"),
+			Min:      c.Query("min_price"),
+			Max:      c.Query("max_price"),
+			NextURL:  pageURL(c, page.Next),
+			PrevURL:  pageURL(c, page.Prev),
+		}
+		if err := tpl.Render(c, "catalog", "Каталог товаров", data); err != nil {
+			core.LogErrorCtx(c.Request.Context(), "Ошибка рендеринга catalog", map[string]interface{}{"error": err.Error()})
 			core.FailC(c, core.Internal("Ошибка отображения", err))
 			return
 		}
 	}
 }
+
+// parseProductFilter — читает и валидирует query-параметры каталога.
+// При ошибке сам отвечает 400 и возвращает false.
+func parseProductFilter(c *gin.Context) (storage.ProductFilter, bool) {
+	f := storage.ProductFilter{
+		Query:  strings.TrimSpace(c.Query("q")),
+		Sort:   c.DefaultQuery("sort", storage.SortNameAsc),
+		Cursor: c.Query("cursor"),
+	}
+	fields := map[string]string{}
+
+	if len([]rune(f.Query)) > 100 {
+		fields["q"] = "не длиннее 100 символов"
+	}
+	if !storage.ValidProductSort(f.Sort) {
+		fields["sort"] = "допустимо: name, -name, price, -price, new"
+	}
+	if v := c.Query("category_id"); v != "" {
+		id, err := strconv.ParseInt(v, 10, 64)
+		if err != nil || id <= 0 {
+			fields["category_id"] = "ожидается положительное целое"
+		} else {
+			f.CategoryIDs = []int64{id}
+		}
+	}
+	for _, p := range []struct {
+		name string
+		dst  **float64
+	}{{"min_price", &f.MinPrice}, {"max_price", &f.MaxPrice}} {
+		v := c.Query(p.name)
+		if v == "" {
+			continue
+		}
+		n, err := strconv.ParseFloat(strings.Replace(v, ",", ".", 1), 64)
+		if err != nil || n < 0 {
+			fields[p.name] = "ожидается неотрицательное число"
+			continue
+		}
+		*p.dst = &n
+	}
+	if f.MinPrice != nil && f.MaxPrice != nil && *f.MinPrice > *f.MaxPrice {
+		fields["max_price"] = "должна быть не меньше min_price"
+	}
+	if v := c.Query("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 || n > storage.MaxPageSize {
+			fields["limit"] = "от 1 до " + strconv.Itoa(storage.MaxPageSize)
+		} else {
+			f.Limit = n
+		}
+	}
+
+	if len(fields) > 0 {
+		core.FailC(c, core.BadRequest("Неверные параметры каталога", fields))
+		return f, false
+	}
+	return f, true
+}
+
+// expandCategory — ?category_id= включает товары подкатегорий.
+func expandCategory(c *gin.Context, categories storage.CategoryRepository, f *storage.ProductFilter) bool {
+	if len(f.CategoryIDs) != 1 {
+		return true
+	}
+	tree, err := categories.Tree(c.Request.Context())
+	if err != nil {
+		core.FailC(c, core.Internal("Ошибка загрузки категорий", err))
+		return false
+	}
+	f.CategoryIDs = storage.SubtreeIDs(tree, f.CategoryIDs[0])
+	return true
+}
+
+// listProducts — выборка с единой обработкой ошибок (курсор → 400, остальное → 500).
+func listProducts(c *gin.Context, products storage.ProductRepository, f storage.ProductFilter) (*storage.ProductPage, bool) {
+	page, err := products.List(c.Request.Context(), f)
+	if errors.Is(err, storage.ErrInvalidCursor) {
+		core.FailC(c, core.BadRequest("Неверный курсор страницы", map[string]string{"cursor": "устарел или повреждён"}))
+		return nil, false
+	}
+	if err != nil {
+		core.LogErrorCtx(c.Request.Context(), "Ошибка загрузки каталога", map[string]interface{}{"error": err.Error()})
+		core.FailC(c, core.Internal("Ошибка каталога", err))
+		return nil, false
+	}
+	return page, true
+}
+
+// pageURL — текущий URL с подставленным курсором (фильтры сохраняются).
+func pageURL(c *gin.Context, cursor string) string {
+	if cursor == "" {
+		return ""
+	}
+	q := url.Values{}
+	for k, v := range c.Request.URL.Query() {
+		q[k] = v
+	}
+	q.Set("cursor", cursor)
+	return c.Request.URL.Path + "?" + q.Encode()
+}
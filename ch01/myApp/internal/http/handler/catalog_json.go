@@ -9,24 +9,92 @@ import (
 	"github.com/gin-gonic/gin"
 )
 
-// CatalogJSON — JSON-эндпоинт каталога (Gin-версия)
-func CatalogJSON() gin.HandlerFunc {
+// ProductGroup — товары одной категории (Category == nil — без категории).
+type ProductGroup struct {
+	Category *storage.Category `json:"category"`
+	Items    []storage.Product `json:"items"`
+}
+
+// CatalogJSON — JSON-эндпоинт каталога (Gin-версия), параметры те же, что у /catalog.
+// Ответ: {"items": [...], "next": "<cursor>", "prev": "<cursor>"}
+// С ?group=category: {"groups": [{"category": {...}, "items": [...]}], "next", "prev"}
+func CatalogJSON(products storage.ProductRepository, categories storage.CategoryRepository) gin.HandlerFunc {
 	return func(c *gin.Context) {
-		db := storage.GetDBFromContext(c.Request.Context())
-		if db == nil {
-			core.LogError("DB недоступна в контексте", nil)
-			core.FailC(c, core.Internal("Внутренняя ошибка", nil))
+		group := c.Query("group")
+		if group != "" && group != "category" {
+			core.FailC(c, core.BadRequest("Неверные параметры каталога", map[string]string{"group": "допустимо: category"}))
+			return
+		}
+
+		filter, ok := parseProductFilter(c)
+		if !ok || !expandCategory(c, categories, &filter) {
+			return
+		}
+
+		page, ok := listProducts(c, products, filter)
+		if !ok {
+			return
+		}
+
+		if group == "" {
+			core.JSON(c, http.StatusOK, page)
 			return
 		}
 
-		items, err := storage.ListAllProducts(c.Request.Context(), db)
+		tree, err := categories.Tree(c.Request.Context())
 		if err != nil {
-			core.LogError("Ошибка загрузки каталога (JSON)", map[string]interface{}{"error": err.Error()})
-			core.FailC(c, core.Internal("Ошибка каталога", err))
+			core.FailC(c, core.Internal("Ошибка загрузки категорий", err))
 			return
 		}
+		core.JSON(c, http.StatusOK, gin.H{
+			"groups": groupByCategory(page.Items, tree),
+			"next":   page.Next,
+			"prev":   page.Prev,
+		})
+	}
+}
 
-		// Можно через ваш helper, если он есть: core.JSON(c, http.StatusOK, items)
-		c.JSON(http.StatusOK, items)
+// CategoriesJSON — дерево категорий.
+func CategoriesJSON(categories storage.CategoryRepository) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		tree, err := categories.Tree(c.Request.Context())
+		if err != nil {
+			core.FailC(c, core.Internal("Ошибка загрузки категорий", err))
+			return
+		}
+		core.JSON(c, http.StatusOK, tree)
+	}
+}
+
+// groupByCategory — группы в порядке дерева категорий, товары без категории
+// (или с удалённой категорией) — последней группой. Порядок товаров внутри
+// группы сохраняется.
+func groupByCategory(items []storage.Product, tree []*storage.CategoryNode) []ProductGroup {
+	nodes := storage.FlattenCategories(tree)
+	known := make(map[int64]bool, len(nodes))
+	for _, n := range nodes {
+		known[n.ID] = true
+	}
+
+	byID := map[int64][]storage.Product{}
+	var rest []storage.Product
+	for _, p := range items {
+		if p.CategoryID == nil || !known[*p.CategoryID] {
+			rest = append(rest, p)
+			continue
+		}
+		byID[*p.CategoryID] = append(byID[*p.CategoryID], p)
+	}
+
+	groups := make([]ProductGroup, 0, len(byID)+1)
+	for _, n := range nodes {
+		if list, ok := byID[n.ID]; ok {
+			cat := n.Category
+			groups = append(groups, ProductGroup{Category: &cat, Items: list})
+		}
+	}
+	if len(rest) > 0 {
+		groups = append(groups, ProductGroup{Items: rest})
 	}
+	return groups
 }
@@ -0,0 +1,80 @@
+package handler
+
+// category.go — страница категории /category/:slug
+import (
+	"database/sql"
+	"errors"
+	"net/http"
+
+	"myApp/internal/core"
+	"myApp/internal/storage"
+	"myApp/internal/view"
+
+	"github.com/gin-gonic/gin"
+)
+
+// CategoryView — данные шаблона category.
+type CategoryView struct {
+	Category    *storage.CategoryNode
+	Breadcrumbs []storage.Category // от корня до родителя (без текущей)
+	Catalog     CatalogView        // товары категории и её подкатегорий
+}
+
+// Category — товары категории и всех подкатегорий, хлебные крошки,
+// ссылки на дочерние категории. Параметры фильтра те же, что у /catalog.
+func Category(tpl *view.Templates, products storage.ProductRepository, categories storage.CategoryRepository) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx := c.Request.Context()
+
+		node, err := categories.BySlug(ctx, c.Param("slug"))
+		if err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				core.FailC(c, &core.AppError{
+					Code:    "not_found",
+					Status:  http.StatusNotFound,
+					Message: "Категория не найдена",
+				})
+				return
+			}
+			core.FailC(c, core.Internal("Ошибка загрузки категорий", err))
+			return
+		}
+
+		path, err := categories.Path(ctx, node.ID)
+		if err != nil {
+			core.FailC(c, core.Internal("Ошибка загрузки категорий", err))
+			return
+		}
+
+		filter, ok := parseProductFilter(c)
+		if !ok {
+			return
+		}
+		filter.CategoryIDs = storage.SubtreeIDs([]*storage.CategoryNode{node}, node.ID)
+
+		page, ok := listProducts(c, products, filter)
+		if !ok {
+			return
+		}
+
+		data := CategoryView{
+			Category:    node,
+			Breadcrumbs: path[:len(path)-1],
+			Catalog: CatalogView{
+				Items:   page.Items,
+				Query:   filter.Query,
+				Sort:    filter.Sort,
+				NextURL: pageURL(c, page.Next),
+				PrevURL: pageURL(c, page.Prev),
+			},
+		}
+		if err := tpl.Render(c, "category", node.Name, data); err != nil {
+			core.LogErrorCtx(c.Request.Context(), "Ошибка рендеринга category", map[string]interface{}{
+				"slug":  node.Slug,
+				"error": err.Error(),
+			})
+			core.FailC(c, core.Internal("Ошибка отображения", err))
+			return
+		}
+	}
+}
@@ -0,0 +1,210 @@
+package handler
+
+// checkout.go — оформление заказа (/checkout) и страница подтверждения (/order/:token)
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"myApp/internal/auth"
+	"myApp/internal/cart"
+	"myApp/internal/core"
+	"myApp/internal/storage"
+	"myApp/internal/view"
+
+	"github.com/gin-gonic/gin"
+	"github.com/go-playground/validator/v10"
+)
+
+// CheckoutForm — поля формы оформления заказа.
+type CheckoutForm struct {
+	Name    string `validate:"required,min=2,max=100"`
+	Email   string `validate:"required,email,max=255"`
+	Phone   string `validate:"max=30"`
+	Address string `validate:"required,max=500"`
+	Comment string `validate:"max=1000"`
+}
+
+// CheckoutView — данные шаблона checkout: форма и содержимое корзины.
+type CheckoutView struct {
+	Form   CheckoutForm
+	Cart   CartView
+	Errors map[string]string
+}
+
+// CheckoutIndex — GET /checkout (поля имени и email заполняются для вошедшего).
+func CheckoutIndex(tpl *view.Templates, products storage.ProductRepository) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ct, err := loadCart(c, products)
+		if err != nil {
+			core.FailC(c, core.Internal("Ошибка загрузки корзины", err))
+			return
+		}
+		if len(ct.Lines) == 0 {
+			c.Redirect(http.StatusSeeOther, "/cart")
+			return
+		}
+
+		data := CheckoutView{Cart: ct, Errors: map[string]string{}}
+		if u := auth.CurrentUser(c); u != nil {
+			data.Form.Name, data.Form.Email = u.Name, u.Email
+		}
+		renderCheckout(c, tpl, data)
+	}
+}
+
+// CheckoutSubmit — POST /checkout: заказ создаётся по текущим ценам из БД,
+// корзина очищается, редирект на страницу заказа.
+func CheckoutSubmit(tpl *view.Templates, products storage.ProductRepository, orders storage.OrderRepository) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ct, err := loadCart(c, products)
+		if err != nil {
+			core.FailC(c, core.Internal("Ошибка загрузки корзины", err))
+			return
+		}
+		if len(ct.Lines) == 0 {
+			c.Redirect(http.StatusSeeOther, "/cart")
+			return
+		}
+
+		f := CheckoutForm{
+			Name:    sanitizeText(c.PostForm("name")),
+			Email:   storage.NormalizeEmail(c.PostForm("email")),
+			Phone:   sanitizeText(c.PostForm("phone")),
+			Address: sanitizeText(c.PostForm("address")),
+			Comment: sanitizeText(c.PostForm("comment")),
+		}
+		if errs := checkoutErrors(c.Request.Context(), f); len(errs) > 0 {
+			c.Status(http.StatusBadRequest)
+			renderCheckout(c, tpl, CheckoutView{Form: f, Cart: ct, Errors: errs})
+			return
+		}
+
+		in := storage.OrderInput{
+			Name:    f.Name,
+			Email:   f.Email,
+			Phone:   f.Phone,
+			Address: f.Address,
+		}
+		if f.Comment != "" {
+			in.Comment = &f.Comment
+		}
+		if u := auth.CurrentUser(c); u != nil {
+			in.UserID = &u.ID
+		}
+		for _, l := range ct.Lines {
+			in.Lines = append(in.Lines, storage.OrderLine{ProductID: l.ProductID, Qty: l.Qty})
+		}
+
+		order, err := orders.Create(c.Request.Context(), in)
+		if err != nil {
+			if errors.Is(err, storage.ErrProductUnavailable) {
+				// Товар удалили между показом корзины и отправкой формы
+				c.Redirect(http.StatusSeeOther, "/cart?unavailable=1")
+				return
+			}
+			core.FailC(c, core.Internal("Ошибка оформления заказа", err))
+			return
+		}
+		core.LogInfoCtx(c.Request.Context(), "Новый заказ", map[string]interface{}{"order_id": order.ID, "total": order.Total})
+
+		if err := cart.Save(c, &cart.Cart{}); err != nil {
+			core.LogErrorCtx(c.Request.Context(), "Ошибка очистки корзины", map[string]interface{}{"error": err.Error()})
+		}
+		c.Redirect(http.StatusSeeOther, "/order/"+order.Token)
+	}
+}
+
+// OrderShow — GET /order/:token — подтверждение заказа. Доступ по токену из ссылки:
+// номер заказа в URL не используется, чтобы чужие заказы нельзя было перебрать.
+func OrderShow(tpl *view.Templates, orders storage.OrderRepository) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		token := c.Param("token")
+		if !isOrderToken(token) {
+			core.FailC(c, &core.AppError{Code: "not_found", Status: http.StatusNotFound, Message: "Заказ не найден"})
+			return
+		}
+		order, err := orders.ByToken(c.Request.Context(), token)
+		if err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				core.FailC(c, &core.AppError{Code: "not_found", Status: http.StatusNotFound, Message: "Заказ не найден"})
+				return
+			}
+			core.FailC(c, core.Internal("Ошибка загрузки заказа", err))
+			return
+		}
+		// Ссылка на заказ не должна утекать в Referer и кэши
+		c.Header("Cache-Control", "no-store")
+		c.Header("Referrer-Policy", "no-referrer")
+		if err := tpl.Render(c, "order", "Заказ №"+strconv.FormatInt(order.ID, 10), order); err != nil {
+			core.LogErrorCtx(c.Request.Context(), "Ошибка рендеринга order", map[string]interface{}{"error": err.Error()})
+			core.FailC(c, core.Internal("Ошибка отображения", err))
+		}
+	}
+}
+
+func renderCheckout(c *gin.Context, tpl *view.Templates, data CheckoutView) {
+	if err := tpl.Render(c, "checkout", "Оформление заказа", data); err != nil {
+		core.LogErrorCtx(c.Request.Context(), "Ошибка рендеринга checkout", map[string]interface{}{"error": err.Error()})
+		core.FailC(c, core.Internal("Ошибка отображения", err))
+	}
+}
+
+func checkoutErrors(ctx context.Context, f CheckoutForm) map[string]string {
+	errs := map[string]string{}
+	err := validate.Struct(f)
+	if err == nil {
+		return errs
+	}
+	var verrs validator.ValidationErrors
+	if !errors.As(err, &verrs) {
+		core.LogErrorCtx(ctx, "Неожиданная ошибка валидации", map[string]interface{}{"error": err.Error()})
+		errs["form"] = "Ошибка валидации"
+		return errs
+	}
+	for _, e := range verrs {
+		switch e.Field() {
+		case "Name":
+			switch e.Tag() {
+			case "required":
+				errs["name"] = "Укажите имя"
+			case "min":
+				errs["name"] = "Имя должно быть не короче 2 символов"
+			default:
+				errs["name"] = "Слишком длинное имя (макс. 100)"
+			}
+		case "Email":
+			if e.Tag() == "required" {
+				errs["email"] = "Укажите email"
+			} else {
+				errs["email"] = "Введите корректный email"
+			}
+		case "Phone":
+			errs["phone"] = "Слишком длинный телефон (макс. 30)"
+		case "Address":
+			if e.Tag() == "required" {
+				errs["address"] = "Укажите адрес доставки"
+			} else {
+				errs["address"] = "Слишком длинный адрес (макс. 500)"
+			}
+		case "Comment":
+			errs["comment"] = "Слишком длинный комментарий (макс. 1000)"
+		}
+	}
+	return errs
+}
+
+// isOrderToken — 32 hex-символа (см. storage.orderToken).
+func isOrderToken(s string) bool {
+	if len(s) != 32 {
+		return false
+	}
+	for _, r := range s {
+		if (r < '0' || r > '9') && (r < 'a' || r > 'f') {
+			return false
+		}
+	}
+	return true
+}
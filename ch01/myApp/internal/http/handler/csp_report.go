@@ -0,0 +1,172 @@
+package handler
+
+// csp_report.go — приём отчётов о нарушениях CSP (POST /csp-report).
+//
+// Браузеры шлют два формата:
+//   - report-uri: application/csp-report, {"csp-report": {"blocked-uri": ..., ...}}
+//   - report-to:  application/reports+json, [{"type": "csp-violation", "body": {"blockedURL": ...}}]
+// Маршрут без CSRF (браузер токен не пришлёт) и с rate limit (см. app.New).
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"strings"
+	"unicode/utf8"
+
+	"myApp/internal/core"
+	"myApp/internal/storage"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	cspReportMaxBody    = 64 << 10 // отчёт — несколько сотен байт, с original-policy — пара КБ
+	cspReportsPerBatch  = 10       // report-to может прислать пачку
+	cspReportsKeep      = 10000    // сколько последних отчётов хранить
+	cspReportPruneEvery = 100      // чистка старых — на каждом сотом отчёте
+)
+
+// cspReportURI — тело report-uri (имена полей через дефис).
+type cspReportURI struct {
+	Report struct {
+		DocumentURI        string `json:"document-uri"`
+		ViolatedDirective  string `json:"violated-directive"`
+		EffectiveDirective string `json:"effective-directive"`
+		BlockedURI         string `json:"blocked-uri"`
+		SourceFile         string `json:"source-file"`
+		LineNumber         int    `json:"line-number"`
+		Disposition        string `json:"disposition"`
+		ScriptSample       string `json:"script-sample"`
+	} `json:"csp-report"`
+}
+
+// cspReportTo — элемент массива Reporting API (camelCase).
+type cspReportTo struct {
+	Type      string `json:"type"`
+	UserAgent string `json:"user_agent"`
+	Body      struct {
+		DocumentURL        string `json:"documentURL"`
+		EffectiveDirective string `json:"effectiveDirective"`
+		BlockedURL         string `json:"blockedURL"`
+		SourceFile         string `json:"sourceFile"`
+		LineNumber         int    `json:"lineNumber"`
+		Disposition        string `json:"disposition"`
+		Sample             string `json:"sample"`
+	} `json:"body"`
+}
+
+// CSPReport — POST /csp-report: сохраняет нарушения, отвечает 204.
+func CSPReport(reports storage.CSPReportRepository) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		body, err := io.ReadAll(http.MaxBytesReader(c.Writer, c.Request.Body, cspReportMaxBody))
+		if err != nil {
+			core.FailC(c, core.BadRequest("Слишком большой отчёт", nil))
+			return
+		}
+
+		items, ok := parseCSPReports(c.ContentType(), body)
+		if !ok {
+			core.FailC(c, core.BadRequest("Неверный формат отчёта CSP", nil))
+			return
+		}
+
+		ua := c.Request.UserAgent()
+		for _, r := range items {
+			if r.UserAgent == "" {
+				r.UserAgent = ua
+			}
+			r.IP = c.ClientIP()
+			normalizeCSPReport(&r)
+
+			if err := reports.Create(c.Request.Context(), &r); err != nil {
+				core.FailC(c, core.Internal("Ошибка сохранения отчёта CSP", err))
+				return
+			}
+			if r.ID%cspReportPruneEvery == 0 {
+				if err := reports.Prune(c.Request.Context(), cspReportsKeep); err != nil {
+					core.LogErrorCtx(c.Request.Context(), "Ошибка очистки отчётов CSP", map[string]interface{}{"error": err.Error()})
+				}
+			}
+		}
+		c.Status(http.StatusNoContent)
+	}
+}
+
+// parseCSPReports — оба формата; Content-Type у браузеров бывает и application/json.
+func parseCSPReports(contentType string, body []byte) ([]storage.CSPReport, bool) {
+	if contentType == "application/reports+json" || (len(body) > 0 && body[0] == '[') {
+		var batch []cspReportTo
+		if err := json.Unmarshal(body, &batch); err != nil {
+			return nil, false
+		}
+		var out []storage.CSPReport
+		for _, b := range batch {
+			if b.Type != "csp-violation" {
+				continue
+			}
+			out = append(out, storage.CSPReport{
+				Directive:   b.Body.EffectiveDirective,
+				BlockedURI:  b.Body.BlockedURL,
+				DocumentURI: b.Body.DocumentURL,
+				SourceFile:  b.Body.SourceFile,
+				LineNumber:  b.Body.LineNumber,
+				Disposition: b.Body.Disposition,
+				Sample:      b.Body.Sample,
+				UserAgent:   b.UserAgent,
+			})
+			if len(out) == cspReportsPerBatch {
+				break
+			}
+		}
+		return out, true
+	}
+
+	var r cspReportURI
+	if err := json.Unmarshal(body, &r); err != nil {
+		return nil, false
+	}
+	directive := r.Report.EffectiveDirective
+	if directive == "" {
+		// Старые браузеры: violated-directive = "script-src 'self' ..." — берём имя
+		directive, _, _ = strings.Cut(r.Report.ViolatedDirective, " ")
+	}
+	if directive == "" {
+		return nil, false
+	}
+	return []storage.CSPReport{{
+		Directive:   directive,
+		BlockedURI:  r.Report.BlockedURI,
+		DocumentURI: r.Report.DocumentURI,
+		SourceFile:  r.Report.SourceFile,
+		LineNumber:  r.Report.LineNumber,
+		Disposition: r.Report.Disposition,
+		Sample:      r.Report.ScriptSample,
+	}}, true
+}
+
+// normalizeCSPReport — обрезка под размеры колонок; поля — от клиента, доверять им нельзя.
+func normalizeCSPReport(r *storage.CSPReport) {
+	r.Directive = truncateRunes(r.Directive, 100)
+	if r.Directive == "" {
+		r.Directive = "unknown"
+	}
+	r.BlockedURI = truncateRunes(r.BlockedURI, 512)
+	r.DocumentURI = truncateRunes(r.DocumentURI, 512)
+	r.SourceFile = truncateRunes(r.SourceFile, 512)
+	r.Sample = truncateRunes(r.Sample, 255)
+	r.UserAgent = truncateRunes(r.UserAgent, 512)
+	if r.Disposition != "report" {
+		r.Disposition = "enforce"
+	}
+	if r.LineNumber < 0 {
+		r.LineNumber = 0
+	}
+}
+
+func truncateRunes(s string, n int) string {
+	s = strings.ToValidUTF8(s, "")
+	if utf8.RuneCountInString(s) <= n {
+		return s
+	}
+	return string([]rune(s)[:n])
+}
@@ -0,0 +1,120 @@
+package handler
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"myApp/internal/storage"
+)
+
+func TestParseCSPReportsReportURI(t *testing.T) {
+	tests := []struct {
+		name string
+		body string
+		want storage.CSPReport
+	}{
+		{"effective-directive",
+			`{"csp-report":{"document-uri":"https://shop/","violated-directive":"script-src-elem","effective-directive":"script-src-elem",
+			"blocked-uri":"https://evil/x.js","source-file":"https://shop/app.js","line-number":12,"disposition":"report","script-sample":"alert(1)"}}`,
+			storage.CSPReport{Directive: "script-src-elem", BlockedURI: "https://evil/x.js", DocumentURI: "https://shop/",
+				SourceFile: "https://shop/app.js", LineNumber: 12, Disposition: "report", Sample: "alert(1)"}},
+		{"имя из violated-directive",
+			`{"csp-report":{"violated-directive":"img-src 'self' data:","blocked-uri":"inline"}}`,
+			storage.CSPReport{Directive: "img-src", BlockedURI: "inline"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := parseCSPReports("application/csp-report", []byte(tt.body))
+			if !ok || len(got) != 1 {
+				t.Fatalf("ok=%v, отчётов %d", ok, len(got))
+			}
+			if got[0] != tt.want {
+				t.Errorf("\n got %+v\nwant %+v", got[0], tt.want)
+			}
+		})
+	}
+}
+
+func TestParseCSPReportsReportTo(t *testing.T) {
+	body := `[
+		{"type":"csp-violation","user_agent":"UA/1","body":{"documentURL":"https://shop/","effectiveDirective":"style-src",
+			"blockedURL":"inline","sourceFile":"https://shop/","lineNumber":3,"disposition":"enforce","sample":"color:red"}},
+		{"type":"deprecation","body":{"id":"x"}},
+		{"type":"csp-violation","body":{"effectiveDirective":"img-src"}}
+	]`
+	want := []storage.CSPReport{
+		{Directive: "style-src", BlockedURI: "inline", DocumentURI: "https://shop/", SourceFile: "https://shop/",
+			LineNumber: 3, Disposition: "enforce", Sample: "color:red", UserAgent: "UA/1"},
+		{Directive: "img-src"},
+	}
+
+	// Формат определяется и по Content-Type, и по '[' в начале тела
+	for _, ct := range []string{"application/reports+json", "application/json"} {
+		got, ok := parseCSPReports(ct, []byte(body))
+		if !ok || len(got) != len(want) {
+			t.Fatalf("%s: ok=%v, отчётов %d, want %d", ct, ok, len(got), len(want))
+		}
+		for i := range want {
+			if got[i] != want[i] {
+				t.Errorf("%s [%d]:\n got %+v\nwant %+v", ct, i, got[i], want[i])
+			}
+		}
+	}
+}
+
+func TestParseCSPReportsBatchLimit(t *testing.T) {
+	items := make([]string, cspReportsPerBatch+5)
+	for i := range items {
+		items[i] = fmt.Sprintf(`{"type":"csp-violation","body":{"effectiveDirective":"d%d"}}`, i)
+	}
+	got, ok := parseCSPReports("application/reports+json", []byte("["+strings.Join(items, ",")+"]"))
+	if !ok || len(got) != cspReportsPerBatch {
+		t.Fatalf("ok=%v, отчётов %d, want %d", ok, len(got), cspReportsPerBatch)
+	}
+	if last := got[len(got)-1].Directive; last != fmt.Sprintf("d%d", cspReportsPerBatch-1) {
+		t.Errorf("последний отчёт %q — лишние отбрасываются с конца", last)
+	}
+}
+
+func TestParseCSPReportsGarbage(t *testing.T) {
+	tests := []struct {
+		contentType string
+		body        string
+	}{
+		{"application/csp-report", ``},
+		{"application/csp-report", `not json`},
+		{"application/csp-report", `{}`},
+		{"application/csp-report", `null`},
+		{"application/csp-report", `"x"`},
+		{"application/csp-report", `{"csp-report":"x"}`},
+		{"application/csp-report", `{"csp-report":{"blocked-uri":"inline"}}`}, // без директивы
+		{"application/json", `[1,2]`},
+		{"application/reports+json", `{"type":"csp-violation"}`},
+		{"application/reports+json", `[{"type":"csp-violation"`},
+	}
+	for _, tt := range tests {
+		if got, ok := parseCSPReports(tt.contentType, []byte(tt.body)); ok {
+			t.Errorf("%s %q принят: %+v", tt.contentType, tt.body, got)
+		}
+	}
+}
+
+func TestNormalizeCSPReport(t *testing.T) {
+	r := storage.CSPReport{
+		BlockedURI:  strings.Repeat("я", 600),
+		Sample:      "ok\xff" + strings.Repeat("a", 300),
+		Disposition: "whatever",
+		LineNumber:  -5,
+	}
+	normalizeCSPReport(&r)
+	if r.Directive != "unknown" || r.Disposition != "enforce" || r.LineNumber != 0 {
+		t.Errorf("значения по умолчанию: %+v", r)
+	}
+	if r.BlockedURI != strings.Repeat("я", 512) {
+		t.Errorf("BlockedURI обрезан до %d символов", len([]rune(r.BlockedURI)))
+	}
+	if len(r.Sample) != 255 || !strings.HasPrefix(r.Sample, "oka") {
+		t.Errorf("Sample %q", r.Sample)
+	}
+}
@@ -8,13 +8,21 @@ import (
 	"github.com/gin-gonic/gin"
 )
 
-// Debug — возвращает отладочную информацию в JSON
+// Debug — возвращает отладочную информацию в JSON (только для роли admin, см. app.registerRoutes)
 func Debug(c *gin.Context) {
+	// Секреты из заголовков не выводим даже администратору
+	headers := c.Request.Header.Clone()
+	for _, h := range []string{"Cookie", "Authorization", "X-Csrf-Token"} {
+		if headers.Get(h) != "" {
+			headers.Set(h, "[hidden]")
+		}
+	}
+
 	info := map[string]interface{}{
 		"request": map[string]interface{}{
 			"method":  c.Request.Method,
 			"url":     c.Request.URL.String(),
-			"headers": c.Request.Header,
+			"headers": headers,
 			"remote":  c.Request.RemoteAddr,
 		},
 		"response": map[string]interface{}{
@@ -3,11 +3,13 @@ package handler
 // form.go (Gin)
 import (
 	"errors"
-	"log"
+	"html"
 	"net/http"
 	"strings"
 
 	"myApp/internal/core"
+	"myApp/internal/notify"
+	"myApp/internal/storage"
 	"myApp/internal/view"
 
 	"github.com/gin-gonic/gin"
@@ -30,10 +32,17 @@ type FormView struct {
 }
 
 var (
-	validate  = validator.New()        // Валидатор структуры
-	sanitizer = bluemonday.UGCPolicy() // Санитизатор ввода
+	validate  = validator.New()           // Валидатор структуры
+	sanitizer = bluemonday.StrictPolicy() // Санитизатор ввода: только текст, без тегов
 )
 
+// sanitizeText — текст без тегов. Сущности раскодируются обратно: в БД хранится
+// «как ввёл пользователь», экранирует при выводе html/template
+// (иначе "&" сохранился бы как "&amp;" и вывелся бы как "&amp;amp;").
+func sanitizeText(s string) string {
+	return html.UnescapeString(sanitizer.Sanitize(strings.TrimSpace(s)))
+}
+
 // FormIndex — GET-страница формы (OWASP A03: Injection)
 func FormIndex(tpl *view.Templates) gin.HandlerFunc {
 	return func(c *gin.Context) {
@@ -46,7 +55,7 @@ func FormIndex(tpl *view.Templates) gin.HandlerFunc {
 		}
 
 		if err := tpl.Render(c, "form", "Форма", data); err != nil {
-			core.LogError("Ошибка рендеринга шаблона form", map[string]interface{}{
+			core.LogErrorCtx(c.Request.Context(), "Ошибка рендеринга шаблона form", map[string]interface{}{
 				"error": err.Error(),
 				"path":  c.Request.URL.Path,
 			})
@@ -56,8 +65,10 @@ func FormIndex(tpl *view.Templates) gin.HandlerFunc {
 	}
 }
 
-// FormSubmit — POST-обработчик отправки формы (OWASP A03, A05)
-func FormSubmit(tpl *view.Templates) gin.HandlerFunc {
+// FormSubmit — POST-обработчик отправки формы (OWASP A03, A05).
+// Сообщение сохраняется в contact_messages, уведомление уходит через очередь
+// (ответ пользователю не ждёт SMTP).
+func FormSubmit(tpl *view.Templates, contacts storage.ContactRepository, notifier *notify.Queue) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		if c.Request.Method != http.MethodPost {
 			c.String(http.StatusMethodNotAllowed, "Метод не разрешён")
@@ -75,9 +86,9 @@ func FormSubmit(tpl *view.Templates) gin.HandlerFunc {
 
 		// Санитизация данных формы
 		f := FormData{
-			Name:    sanitizer.Sanitize(strings.TrimSpace(c.Request.Form.Get("name"))),
-			Email:   sanitizer.Sanitize(strings.TrimSpace(c.Request.Form.Get("email"))),
-			Message: sanitizer.Sanitize(strings.TrimSpace(c.Request.Form.Get("message"))),
+			Name:    sanitizeText(c.Request.Form.Get("name")),
+			Email:   sanitizeText(c.Request.Form.Get("email")),
+			Message: sanitizeText(c.Request.Form.Get("message")),
 		}
 
 		// Валидация
@@ -118,15 +129,15 @@ func FormSubmit(tpl *view.Templates) gin.HandlerFunc {
 						}
 					}
 				}
-				log.Printf("INFO: Ошибка валидации формы: %v", errs)
+				core.LogInfoCtx(c.Request.Context(), "Ошибка валидации формы", map[string]interface{}{"errors": errs})
 			} else {
 				var invErr *validator.InvalidValidationError
 				if errors.As(err, &invErr) {
 					errs["form"] = "Неверная конфигурация валидации"
-					core.LogError("InvalidValidationError", map[string]interface{}{"error": invErr.Error()})
+					core.LogErrorCtx(c.Request.Context(), "InvalidValidationError", map[string]interface{}{"error": invErr.Error()})
 				} else {
 					errs["form"] = "Ошибка валидации"
-					core.LogError("Неожиданная ошибка валидации", map[string]interface{}{"error": err.Error()})
+					core.LogErrorCtx(c.Request.Context(), "Неожиданная ошибка валидации", map[string]interface{}{"error": err.Error()})
 				}
 			}
 		}
@@ -140,7 +151,7 @@ func FormSubmit(tpl *view.Templates) gin.HandlerFunc {
 			}
 			c.Status(http.StatusBadRequest) // статус до рендера
 			if err := tpl.Render(c, "form", "Форма", data); err != nil {
-				core.LogError("Ошибка рендеринга шаблона form", map[string]interface{}{
+				core.LogErrorCtx(c.Request.Context(), "Ошибка рендеринга шаблона form", map[string]interface{}{
 					"error": err.Error(),
 					"path":  c.Request.URL.Path,
 				})
@@ -149,6 +160,19 @@ func FormSubmit(tpl *view.Templates) gin.HandlerFunc {
 			return
 		}
 
+		msg := &storage.ContactMessage{
+			Name:    f.Name,
+			Email:   f.Email,
+			Message: f.Message,
+			IP:      c.ClientIP(),
+		}
+		if err := contacts.Create(c.Request.Context(), msg); err != nil {
+			core.FailC(c, core.Internal("Ошибка сохранения сообщения", err))
+			return
+		}
+		// Не поместилось в очередь — останется pending и уйдёт после перезапуска
+		notifier.Enqueue(notify.ContactNotification(*msg))
+
 		// PRG-паттерн: редирект на GET /form?ok=1
 		c.Redirect(http.StatusSeeOther, "/form?ok=1")
 	}
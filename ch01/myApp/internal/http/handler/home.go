@@ -20,7 +20,7 @@ func Home(tpl *view.Templates) gin.HandlerFunc {
 
 		// Рендерим шаблон "home"
 		if err := tpl.Render(c, "home", "Главная", data); err != nil {
-			core.LogError("Ошибка рендеринга шаблона home", map[string]interface{}{
+			core.LogErrorCtx(c.Request.Context(), "Ошибка рендеринга шаблона home", map[string]interface{}{
 				"error": err.Error(),
 				"path":  c.Request.URL.Path,
 			})
@@ -15,7 +15,7 @@ func NotFound(tpl *view.Templates) gin.HandlerFunc {
 		c.Status(http.StatusNotFound)
 
 		if err := tpl.Render(c, "notfound", "Страница не найдена", nil); err != nil {
-			core.LogError("Ошибка рендеринга шаблона notfound", map[string]interface{}{
+			core.LogErrorCtx(c.Request.Context(), "Ошибка рендеринга шаблона notfound", map[string]interface{}{
 				"error": err.Error(),
 				"path":  c.Request.URL.Path,
 			})
@@ -15,21 +15,13 @@ import (
 )
 
 // Product — детальная страница товара
-func Product(tpl *view.Templates) gin.HandlerFunc {
+func Product(tpl *view.Templates, products storage.ProductRepository) gin.HandlerFunc {
 	return func(c *gin.Context) {
-		// 1) Достаём DB из контекста (кладётся в middleware withNonceAndDB)
-		db := storage.GetDBFromContext(c.Request.Context())
-		if db == nil {
-			core.LogError("DB недоступна в контексте", nil)
-			core.FailC(c, core.Internal("Внутренняя ошибка", nil))
-			return
-		}
-
-		// 2) Берём :id из маршрута (/product/:id) и валидируем
+		// 1) Берём :id из маршрута (/product/:id) и валидируем
 		idStr := c.Param("id")
 		id, err := strconv.Atoi(idStr)
 		if err != nil || id <= 0 {
-			core.LogError("Неверный ID товара", map[string]interface{}{
+			core.LogErrorCtx(c.Request.Context(), "Неверный ID товара", map[string]interface{}{
 				"id":    idStr,
 				"error": err,
 			})
@@ -43,11 +35,11 @@ func Product(tpl *view.Templates) gin.HandlerFunc {
 			return
 		}
 
-		// 3) Достаём товар из БД
-		product, err := storage.GetProductByID(c.Request.Context(), db, id)
+		// 2) Достаём товар из БД
+		product, err := products.GetByID(c.Request.Context(), id)
 		if err != nil {
 			if errors.Is(err, sql.ErrNoRows) {
-				core.LogError("Товар не найден", map[string]interface{}{"id": id})
+				core.LogErrorCtx(c.Request.Context(), "Товар не найден", map[string]interface{}{"id": id})
 				// 404 Not Found в формате RFC7807
 				core.FailC(c, &core.AppError{
 					Code:    "not_found",
@@ -56,7 +48,7 @@ func Product(tpl *view.Templates) gin.HandlerFunc {
 				})
 				return
 			}
-			core.LogError("Ошибка загрузки товара", map[string]interface{}{
+			core.LogErrorCtx(c.Request.Context(), "Ошибка загрузки товара", map[string]interface{}{
 				"id":    id,
 				"error": err.Error(),
 			})
@@ -64,9 +56,9 @@ func Product(tpl *view.Templates) gin.HandlerFunc {
 			return
 		}
 
-		// 4) Рендерим шаблон "product" (заголовок — имя товара)
+		// 3) Рендерим шаблон "product" (заголовок — имя товара)
 		if err := tpl.Render(c, "product", product.Name, product); err != nil {
-			core.LogError("Ошибка рендеринга product", map[string]interface{}{
+			core.LogErrorCtx(c.Request.Context(), "Ошибка рендеринга product", map[string]interface{}{
 				"id":    id,
 				"error": err.Error(),
 			})
@@ -0,0 +1,152 @@
+package lifecycle
+
+// internal/lifecycle/lifecycle.go — упорядоченная остановка компонентов приложения.
+//
+// Компоненты регистрируются по мере запуска (логгер, БД, фоновые воркеры,
+// HTTP-сервер), а останавливаются в обратном порядке: сначала перестаём
+// принимать запросы, потом дожидаемся фоновых задач, и только затем закрываем
+// БД и логи. На всю остановку — один общий дедлайн (SHUTDOWN_TIMEOUT).
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"myApp/internal/core"
+)
+
+// ErrForced — хотя бы один компонент не остановился до дедлайна.
+var ErrForced = errors.New("shutdown forced")
+
+// forceGrace — сколько ждать компонент сверх дедлайна: на принудительное закрытие
+// (например, http.Server.Close) после отмены ctx, а если дедлайн уже истёк
+// до его очереди — на всю остановку.
+const forceGrace = time.Second
+
+// StopFunc — остановка компонента; ctx истекает вместе с общим дедлайном.
+type StopFunc func(ctx context.Context) error
+
+type component struct {
+	name string
+	stop StopFunc
+}
+
+// Manager — реестр компонентов; безопасен для использования из нескольких горутин.
+type Manager struct {
+	timeout time.Duration
+
+	mu         sync.Mutex
+	components []component
+
+	once sync.Once
+	err  error
+}
+
+// New — timeout — на остановку всех компонентов вместе.
+func New(timeout time.Duration) *Manager {
+	return &Manager{timeout: timeout}
+}
+
+// Register — добавляет компонент; остановлен он будет раньше всех,
+// зарегистрированных до него.
+func (m *Manager) Register(name string, stop StopFunc) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.components = append(m.components, component{name: name, stop: stop})
+}
+
+// Go — запускает фоновую задачу и регистрирует её остановку: ctx задачи
+// отменяется, остановка ждёт возврата fn.
+func (m *Manager) Go(name string, fn func(ctx context.Context)) {
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		fn(ctx)
+	}()
+
+	m.Register(name, func(stopCtx context.Context) error {
+		cancel()
+		select {
+		case <-done:
+			return nil
+		case <-stopCtx.Done():
+			return stopCtx.Err()
+		}
+	})
+}
+
+// Shutdown — останавливает компоненты в обратном порядке регистрации.
+// Возвращает ErrForced, если кто-то не уложился в дедлайн (вместе с ошибками
+// остальных компонентов). Повторные вызовы возвращают результат первого.
+func (m *Manager) Shutdown() error {
+	m.once.Do(func() {
+		m.mu.Lock()
+		components := append([]component(nil), m.components...)
+		m.mu.Unlock()
+
+		ctx, cancel := context.WithTimeout(context.Background(), m.timeout)
+		defer cancel()
+
+		var errs []error
+		forced := false
+		for i := len(components) - 1; i >= 0; i-- {
+			c := components[i]
+			start := time.Now()
+			err := stopComponent(ctx, c)
+			fields := map[string]interface{}{"component": c.name, "duration": time.Since(start).String()}
+
+			switch {
+			case err == nil:
+				core.LogInfo("Компонент остановлен", fields)
+			case errors.Is(err, context.DeadlineExceeded):
+				forced = true
+				fields["timeout"] = m.timeout.String()
+				core.LogError("Компонент не остановился вовремя", fields)
+			default:
+				fields["error"] = err.Error()
+				core.LogError("Ошибка остановки компонента", fields)
+				errs = append(errs, fmt.Errorf("%s: %w", c.name, err))
+			}
+		}
+
+		if forced {
+			errs = append([]error{ErrForced}, errs...)
+		}
+		m.err = errors.Join(errs...)
+	})
+	return m.err
+}
+
+// stopComponent — ждёт компонент до дедлайна и ещё forceGrace после него;
+// не вернувшийся компонент остаётся работать в своей горутине.
+func stopComponent(ctx context.Context, c component) error {
+	if ctx.Err() != nil {
+		// Дедлайн израсходован предыдущими компонентами — БД, логи и т.п.
+		// всё равно нужно закрыть
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(context.Background(), forceGrace)
+		defer cancel()
+	}
+
+	done := make(chan error, 1)
+	go func() { done <- c.stop(ctx) }()
+
+	select {
+	case err := <-done:
+		return err
+	case <-ctx.Done():
+	}
+
+	select {
+	case err := <-done:
+		if err == nil {
+			return nil
+		}
+		return fmt.Errorf("%w: %v", context.DeadlineExceeded, err)
+	case <-time.After(forceGrace):
+		return context.DeadlineExceeded
+	}
+}
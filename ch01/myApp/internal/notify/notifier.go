@@ -0,0 +1,79 @@
+package notify
+
+// internal/notify/notifier.go — уведомления (email администратору и т.п.).
+//
+// Notifier — точка расширения: SMTP, только лог, в будущем — Telegram/Slack.
+// Отправка идёт через Queue (фоновые воркеры с повторами), HTTP-запрос её не ждёт.
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"myApp/internal/core"
+	"myApp/internal/storage"
+)
+
+// Message — уведомление в виде, не зависящем от канала доставки.
+type Message struct {
+	ID      int64  // идентификатор источника (например, contact_messages.id) — для статуса
+	Subject string // тема
+	Body    string // текст (plain text)
+	ReplyTo string // куда отвечать (email отправителя формы), может быть пустым
+}
+
+// Notifier — способ доставки уведомления.
+type Notifier interface {
+	Notify(ctx context.Context, m Message) error
+}
+
+// ErrPermanent — повтор не поможет (например, SMTP 5xx: адрес отклонён).
+var ErrPermanent = errors.New("permanent notification error")
+
+// New — Notifier по NOTIFY_DRIVER: "smtp" или "log" (по умолчанию).
+func New(cfg core.Config) Notifier {
+	if cfg.NotifyDriver == "smtp" {
+		return NewSMTPNotifier(SMTPConfig{
+			Host:     cfg.SMTPHost,
+			Port:     cfg.SMTPPort,
+			Username: cfg.SMTPUser,
+			Password: cfg.SMTPPassword,
+			From:     cfg.SMTPFrom,
+			To:       cfg.NotifyTo,
+		})
+	}
+	return LogNotifier{}
+}
+
+// ContactNotification — уведомление о новом сообщении из формы /form.
+func ContactNotification(m storage.ContactMessage) Message {
+	var b strings.Builder
+	fmt.Fprintf(&b, "Новое сообщение с сайта (#%d)\n\n", m.ID)
+	fmt.Fprintf(&b, "Имя:   %s\n", m.Name)
+	fmt.Fprintf(&b, "Email: %s\n", m.Email)
+	if m.IP != "" {
+		fmt.Fprintf(&b, "IP:    %s\n", m.IP)
+	}
+	fmt.Fprintf(&b, "\n%s\n", m.Message)
+
+	return Message{
+		ID:      m.ID,
+		Subject: "Сообщение с сайта от " + m.Name,
+		Body:    b.String(),
+		ReplyTo: m.Email,
+	}
+}
+
+// LogNotifier — ничего не отправляет, только пишет в лог (dev, тесты).
+type LogNotifier struct{}
+
+func (LogNotifier) Notify(_ context.Context, m Message) error {
+	core.LogInfo("Уведомление (log)", map[string]interface{}{
+		"id":       m.ID,
+		"subject":  m.Subject,
+		"reply_to": m.ReplyTo,
+		"body":     m.Body,
+	})
+	return nil
+}
@@ -0,0 +1,166 @@
+package notify
+
+// internal/notify/queue.go — фоновая очередь отправки с повторами.
+//
+// Enqueue не блокирует: при переполнении сообщение остаётся в БД со статусом
+// pending и ставится в очередь снова при следующем старте. То же происходит
+// с сообщениями, чьи повторы прервал Stop.
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"myApp/internal/core"
+)
+
+// Result — итог доставки одного сообщения.
+type Result struct {
+	ID       int64
+	Attempts int
+	Err      error // nil — доставлено
+	Final    bool  // false — прервано остановкой, можно повторить позже
+}
+
+// QueueOptions — параметры очереди (нулевые значения заменяются дефолтами).
+type QueueOptions struct {
+	Workers     int           // параллельных отправок (2)
+	Buffer      int           // ёмкость очереди (100)
+	MaxAttempts int           // попыток на сообщение (5)
+	Backoff     time.Duration // пауза перед 2-й попыткой, дальше удваивается (2s)
+	Timeout     time.Duration // на одну попытку (30s)
+}
+
+type Queue struct {
+	n        Notifier
+	opts     QueueOptions
+	onResult func(Result)
+
+	jobs   chan Message
+	ctx    context.Context // отменяется в Stop: прерывает отправку и паузы между повторами
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+
+	mu     sync.RWMutex
+	closed bool
+}
+
+// NewQueue — onResult вызывается из воркера после каждой завершённой доставки.
+func NewQueue(n Notifier, opts QueueOptions, onResult func(Result)) *Queue {
+	if opts.Workers <= 0 {
+		opts.Workers = 2
+	}
+	if opts.Buffer <= 0 {
+		opts.Buffer = 100
+	}
+	if opts.MaxAttempts <= 0 {
+		opts.MaxAttempts = 5
+	}
+	if opts.Backoff <= 0 {
+		opts.Backoff = 2 * time.Second
+	}
+	if opts.Timeout <= 0 {
+		opts.Timeout = 30 * time.Second
+	}
+	if onResult == nil {
+		onResult = func(Result) {}
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	return &Queue{
+		n:        n,
+		opts:     opts,
+		onResult: onResult,
+		jobs:     make(chan Message, opts.Buffer),
+		ctx:      ctx,
+		cancel:   cancel,
+	}
+}
+
+// Start — запускает воркеры.
+func (q *Queue) Start() {
+	for i := 0; i < q.opts.Workers; i++ {
+		q.wg.Add(1)
+		go q.worker()
+	}
+}
+
+// Enqueue — false, если очередь остановлена или переполнена.
+func (q *Queue) Enqueue(m Message) bool {
+	q.mu.RLock()
+	defer q.mu.RUnlock()
+	if q.closed {
+		return false
+	}
+	select {
+	case q.jobs <- m:
+		return true
+	default:
+		core.LogError("Очередь уведомлений переполнена", map[string]interface{}{"id": m.ID})
+		return false
+	}
+}
+
+// Stop — перестаёт принимать сообщения и ждёт, пока воркеры отправят уже
+// поставленные. Когда ctx истекает, отправка и паузы между повторами прерываются.
+func (q *Queue) Stop(ctx context.Context) error {
+	q.mu.Lock()
+	if !q.closed {
+		q.closed = true
+		close(q.jobs)
+	}
+	q.mu.Unlock()
+
+	done := make(chan struct{})
+	go func() {
+		q.wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		q.cancel()
+		return nil
+	case <-ctx.Done():
+		q.cancel()
+		<-done
+		return ctx.Err()
+	}
+}
+
+func (q *Queue) worker() {
+	defer q.wg.Done()
+	for m := range q.jobs {
+		q.onResult(q.deliver(m))
+	}
+}
+
+func (q *Queue) deliver(m Message) Result {
+	backoff := q.opts.Backoff
+	var err error
+	for attempt := 1; attempt <= q.opts.MaxAttempts; attempt++ {
+		ctx, cancel := context.WithTimeout(q.ctx, q.opts.Timeout)
+		err = q.n.Notify(ctx, m)
+		cancel()
+		if err == nil {
+			return Result{ID: m.ID, Attempts: attempt, Final: true}
+		}
+
+		core.LogError("Ошибка отправки уведомления", map[string]interface{}{
+			"id":      m.ID,
+			"attempt": attempt,
+			"error":   err.Error(),
+		})
+		if errors.Is(err, ErrPermanent) || attempt == q.opts.MaxAttempts {
+			return Result{ID: m.ID, Attempts: attempt, Err: err, Final: true}
+		}
+
+		select {
+		case <-time.After(backoff):
+			backoff *= 2
+		case <-q.ctx.Done():
+			return Result{ID: m.ID, Attempts: attempt, Err: err, Final: false}
+		}
+	}
+	return Result{ID: m.ID, Attempts: q.opts.MaxAttempts, Err: err, Final: true}
+}
@@ -0,0 +1,157 @@
+package notify
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+)
+
+// funcNotifier — Notifier из функции; attempt считается с 1.
+type funcNotifier struct {
+	mu    sync.Mutex
+	calls []time.Time
+	fn    func(attempt int) error
+}
+
+func (n *funcNotifier) Notify(_ context.Context, _ Message) error {
+	n.mu.Lock()
+	n.calls = append(n.calls, time.Now())
+	attempt := len(n.calls)
+	n.mu.Unlock()
+	return n.fn(attempt)
+}
+
+func (n *funcNotifier) attempts() []time.Time {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	return append([]time.Time(nil), n.calls...)
+}
+
+var errTemporary = errors.New("temporary")
+
+func startQueue(t *testing.T, n Notifier, opts QueueOptions) (*Queue, <-chan Result) {
+	t.Helper()
+	results := make(chan Result, 10)
+	opts.Workers = 1
+	q := NewQueue(n, opts, func(r Result) { results <- r })
+	q.Start()
+	t.Cleanup(func() { _ = q.Stop(context.Background()) })
+	return q, results
+}
+
+func waitResult(t *testing.T, results <-chan Result) Result {
+	t.Helper()
+	select {
+	case r := <-results:
+		return r
+	case <-time.After(5 * time.Second):
+		t.Fatal("нет результата доставки")
+		return Result{}
+	}
+}
+
+func TestQueueRetriesWithBackoff(t *testing.T) {
+	const backoff = 20 * time.Millisecond
+	n := &funcNotifier{fn: func(attempt int) error {
+		if attempt < 3 {
+			return errTemporary
+		}
+		return nil
+	}}
+	q, results := startQueue(t, n, QueueOptions{MaxAttempts: 5, Backoff: backoff})
+
+	if !q.Enqueue(Message{ID: 1}) {
+		t.Fatal("Enqueue вернул false")
+	}
+	r := waitResult(t, results)
+	if r.ID != 1 || r.Attempts != 3 || r.Err != nil || !r.Final {
+		t.Fatalf("результат %+v, want доставлено с 3-й попытки", r)
+	}
+
+	calls := n.attempts()
+	if len(calls) != 3 {
+		t.Fatalf("попыток %d, want 3", len(calls))
+	}
+	// Пауза удваивается: backoff перед 2-й попыткой, 2*backoff перед 3-й
+	if d := calls[1].Sub(calls[0]); d < backoff {
+		t.Errorf("пауза перед 2-й попыткой %v < %v", d, backoff)
+	}
+	if d := calls[2].Sub(calls[1]); d < 2*backoff {
+		t.Errorf("пауза перед 3-й попыткой %v < %v", d, 2*backoff)
+	}
+}
+
+func TestQueueGivesUpAfterMaxAttempts(t *testing.T) {
+	n := &funcNotifier{fn: func(int) error { return errTemporary }}
+	q, results := startQueue(t, n, QueueOptions{MaxAttempts: 3, Backoff: time.Millisecond})
+
+	q.Enqueue(Message{ID: 2})
+	r := waitResult(t, results)
+	if r.Attempts != 3 || !errors.Is(r.Err, errTemporary) || !r.Final {
+		t.Fatalf("результат %+v, want 3 неудачные попытки, Final", r)
+	}
+}
+
+func TestQueuePermanentErrorIsNotRetried(t *testing.T) {
+	n := &funcNotifier{fn: func(int) error { return ErrPermanent }}
+	q, results := startQueue(t, n, QueueOptions{MaxAttempts: 5, Backoff: time.Millisecond})
+
+	q.Enqueue(Message{ID: 3})
+	r := waitResult(t, results)
+	if r.Attempts != 1 || !errors.Is(r.Err, ErrPermanent) || !r.Final {
+		t.Fatalf("результат %+v, want одна попытка, Final", r)
+	}
+	if got := len(n.attempts()); got != 1 {
+		t.Errorf("попыток %d, want 1", got)
+	}
+}
+
+func TestQueueStopLeavesInterruptedPending(t *testing.T) {
+	failed := make(chan struct{}, 1)
+	n := &funcNotifier{fn: func(int) error {
+		select {
+		case failed <- struct{}{}:
+		default:
+		}
+		return errTemporary
+	}}
+	q, results := startQueue(t, n, QueueOptions{MaxAttempts: 5, Backoff: time.Hour})
+
+	q.Enqueue(Message{ID: 4})
+	select {
+	case <-failed:
+	case <-time.After(5 * time.Second):
+		t.Fatal("первая попытка не выполнена")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if err := q.Stop(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("Stop: %v, want DeadlineExceeded", err)
+	}
+
+	r := waitResult(t, results)
+	if r.ID != 4 || r.Attempts != 1 || r.Final || r.Err == nil {
+		t.Fatalf("результат %+v, want прервано после 1-й попытки (Final=false)", r)
+	}
+	if q.Enqueue(Message{ID: 5}) {
+		t.Error("Enqueue после Stop вернул true")
+	}
+}
+
+func TestQueueStopWaitsForQueued(t *testing.T) {
+	n := &funcNotifier{fn: func(int) error { return nil }}
+	q, results := startQueue(t, n, QueueOptions{})
+
+	for id := int64(1); id <= 3; id++ {
+		q.Enqueue(Message{ID: id})
+	}
+	if err := q.Stop(context.Background()); err != nil {
+		t.Fatalf("Stop: %v", err)
+	}
+	if len(results) != 3 {
+		t.Fatalf("доставлено %d из 3 до возврата Stop", len(results))
+	}
+}
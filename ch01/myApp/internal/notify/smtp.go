@@ -0,0 +1,172 @@
+package notify
+
+// internal/notify/smtp.go — отправка через SMTP (STARTTLS, если сервер его предлагает).
+//
+// Для разработки подходит любой локальный «фейковый» SMTP (MailHog, Mailpit:
+// SMTP_HOST=localhost SMTP_PORT=1025) — письма видны в его веб-интерфейсе.
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"crypto/tls"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"mime"
+	"mime/quotedprintable"
+	"net"
+	"net/smtp"
+	"net/textproto"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type SMTPConfig struct {
+	Host     string
+	Port     int
+	Username string // пусто — без AUTH
+	Password string
+	From     string
+	To       string // получатель уведомлений (можно несколько через запятую)
+}
+
+type SMTPNotifier struct {
+	cfg     SMTPConfig
+	timeout time.Duration // на всё соединение, если в ctx нет дедлайна
+}
+
+var _ Notifier = (*SMTPNotifier)(nil)
+
+func NewSMTPNotifier(cfg SMTPConfig) *SMTPNotifier {
+	return &SMTPNotifier{cfg: cfg, timeout: 30 * time.Second}
+}
+
+func (s *SMTPNotifier) Notify(ctx context.Context, m Message) error {
+	rcpts := splitAddrs(s.cfg.To)
+	if len(rcpts) == 0 {
+		return fmt.Errorf("%w: no recipients", ErrPermanent)
+	}
+
+	addr := net.JoinHostPort(s.cfg.Host, strconv.Itoa(s.cfg.Port))
+	d := net.Dialer{Timeout: 10 * time.Second}
+	conn, err := d.DialContext(ctx, "tcp", addr)
+	if err != nil {
+		return err
+	}
+	deadline, ok := ctx.Deadline()
+	if !ok {
+		deadline = time.Now().Add(s.timeout)
+	}
+	_ = conn.SetDeadline(deadline)
+	// Отмена без дедлайна (остановка очереди) тоже прерывает зависший обмен
+	stop := context.AfterFunc(ctx, func() { _ = conn.SetDeadline(time.Now()) })
+	defer stop()
+
+	c, err := smtp.NewClient(conn, s.cfg.Host)
+	if err != nil {
+		_ = conn.Close()
+		return err
+	}
+	defer func() { _ = c.Close() }()
+
+	if err := s.send(c, rcpts, m); err != nil {
+		return classify(err)
+	}
+	return c.Quit()
+}
+
+func (s *SMTPNotifier) send(c *smtp.Client, rcpts []string, m Message) error {
+	if err := c.Hello("localhost"); err != nil {
+		return err
+	}
+	if ok, _ := c.Extension("STARTTLS"); ok {
+		if err := c.StartTLS(&tls.Config{ServerName: s.cfg.Host, MinVersion: tls.VersionTLS12}); err != nil {
+			return err
+		}
+	}
+	if s.cfg.Username != "" {
+		// PlainAuth сам откажется передавать пароль без TLS (кроме localhost)
+		if err := c.Auth(smtp.PlainAuth("", s.cfg.Username, s.cfg.Password, s.cfg.Host)); err != nil {
+			return err
+		}
+	}
+
+	if err := c.Mail(s.cfg.From); err != nil {
+		return err
+	}
+	for _, r := range rcpts {
+		if err := c.Rcpt(r); err != nil {
+			return err
+		}
+	}
+
+	w, err := c.Data()
+	if err != nil {
+		return err
+	}
+	if _, err := w.Write(s.compose(rcpts, m)); err != nil {
+		return err
+	}
+	return w.Close()
+}
+
+// compose — письмо text/plain UTF-8 (quoted-printable), тема по RFC 2047.
+func (s *SMTPNotifier) compose(rcpts []string, m Message) []byte {
+	var b bytes.Buffer
+	header := func(k, v string) {
+		fmt.Fprintf(&b, "%s: %s\r\n", k, stripCRLF(v))
+	}
+	header("From", s.cfg.From)
+	header("To", strings.Join(rcpts, ", "))
+	if m.ReplyTo != "" {
+		header("Reply-To", m.ReplyTo)
+	}
+	header("Subject", mime.QEncoding.Encode("utf-8", m.Subject))
+	header("Date", time.Now().Format(time.RFC1123Z))
+	header("Message-ID", messageID(s.cfg.From))
+	header("MIME-Version", "1.0")
+	header("Content-Type", "text/plain; charset=utf-8")
+	header("Content-Transfer-Encoding", "quoted-printable")
+	b.WriteString("\r\n")
+
+	qp := quotedprintable.NewWriter(&b)
+	_, _ = qp.Write([]byte(strings.ReplaceAll(m.Body, "\n", "\r\n")))
+	_ = qp.Close()
+	return b.Bytes()
+}
+
+// classify — ответы 5xx (адрес/письмо отклонены) повторять бессмысленно.
+func classify(err error) error {
+	var te *textproto.Error
+	if errors.As(err, &te) && te.Code >= 500 {
+		return fmt.Errorf("%w: %v", ErrPermanent, err)
+	}
+	return err
+}
+
+// stripCRLF — защита от внедрения заголовков через значения из формы.
+func stripCRLF(s string) string {
+	return strings.NewReplacer("\r", "", "\n", "").Replace(s)
+}
+
+func splitAddrs(s string) []string {
+	var out []string
+	for _, a := range strings.Split(s, ",") {
+		if a = strings.TrimSpace(a); a != "" {
+			out = append(out, a)
+		}
+	}
+	return out
+}
+
+func messageID(from string) string {
+	b := make([]byte, 12)
+	_, _ = rand.Read(b)
+	domain := "localhost"
+	if i := strings.LastIndex(from, "@"); i >= 0 {
+		domain = strings.Trim(from[i+1:], "> ")
+	}
+	return "<" + hex.EncodeToString(b) + "@" + domain + ">"
+}
@@ -0,0 +1,243 @@
+package notify
+
+import (
+	"context"
+	"errors"
+	"io"
+	"mime"
+	"mime/quotedprintable"
+	"net"
+	"net/mail"
+	"net/textproto"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+// fakeSMTP — минимальный SMTP-сервер на одно соединение: без STARTTLS и AUTH,
+// на RCPT отвечает rcptReply (пусто — 250).
+type fakeSMTP struct {
+	ln        net.Listener
+	rcptReply string
+
+	done chan struct{}
+	from string
+	rcpt []string
+	data []byte
+}
+
+func startFakeSMTP(t *testing.T, rcptReply string) *fakeSMTP {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := &fakeSMTP{ln: ln, rcptReply: rcptReply, done: make(chan struct{})}
+	t.Cleanup(func() { _ = ln.Close() })
+	go s.serve(t)
+	return s
+}
+
+func (s *fakeSMTP) serve(t *testing.T) {
+	defer close(s.done)
+	conn, err := s.ln.Accept()
+	if err != nil {
+		return
+	}
+	defer func() { _ = conn.Close() }()
+	_ = conn.SetDeadline(time.Now().Add(5 * time.Second))
+
+	tc := textproto.NewConn(conn)
+	reply := func(line string) {
+		if err := tc.PrintfLine("%s", line); err != nil {
+			t.Errorf("fake smtp: %v", err)
+		}
+	}
+
+	reply("220 fake ESMTP")
+	for {
+		line, err := tc.ReadLine()
+		if err != nil {
+			return
+		}
+		verb, arg, _ := strings.Cut(line, " ")
+		switch strings.ToUpper(verb) {
+		case "EHLO", "HELO":
+			reply("250 fake")
+		case "MAIL":
+			s.from = arg
+			reply("250 ok")
+		case "RCPT":
+			if s.rcptReply != "" {
+				reply(s.rcptReply)
+				continue
+			}
+			s.rcpt = append(s.rcpt, arg)
+			reply("250 ok")
+		case "DATA":
+			reply("354 go ahead")
+			s.data, err = io.ReadAll(tc.DotReader())
+			if err != nil {
+				return
+			}
+			reply("250 queued")
+		case "RSET", "NOOP":
+			reply("250 ok")
+		case "QUIT":
+			reply("221 bye")
+			return
+		default:
+			reply("502 not implemented")
+		}
+	}
+}
+
+func (s *fakeSMTP) notifier(t *testing.T) *SMTPNotifier {
+	t.Helper()
+	host, port, _ := net.SplitHostPort(s.ln.Addr().String())
+	p, _ := strconv.Atoi(port)
+	return NewSMTPNotifier(SMTPConfig{
+		Host: host,
+		Port: p,
+		From: "shop@example.com",
+		To:   "admin@example.com, ops@example.com",
+	})
+}
+
+func (s *fakeSMTP) wait(t *testing.T) {
+	t.Helper()
+	select {
+	case <-s.done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("fake smtp: соединение не завершилось")
+	}
+}
+
+func TestSMTPNotifierSendsMessage(t *testing.T) {
+	srv := startFakeSMTP(t, "")
+	body := "Привет!\nСтрока с = и длинным хвостом " + strings.Repeat("x", 100) + "\n"
+	msg := Message{
+		ID:      7,
+		Subject: "Сообщение с сайта",
+		Body:    body,
+		ReplyTo: "user@example.com\r\nBcc: evil@example.com",
+	}
+
+	if err := srv.notifier(t).Notify(context.Background(), msg); err != nil {
+		t.Fatalf("Notify: %v", err)
+	}
+	srv.wait(t)
+
+	if srv.from != "FROM:<shop@example.com>" {
+		t.Errorf("MAIL %q", srv.from)
+	}
+	if want := []string{"TO:<admin@example.com>", "TO:<ops@example.com>"}; strings.Join(srv.rcpt, ";") != strings.Join(want, ";") {
+		t.Errorf("RCPT %q, want %q", srv.rcpt, want)
+	}
+
+	parsed, err := mail.ReadMessage(strings.NewReader(string(srv.data)))
+	if err != nil {
+		t.Fatalf("разбор письма: %v\n%s", err, srv.data)
+	}
+	h := parsed.Header
+	for k, want := range map[string]string{
+		"From":                      "shop@example.com",
+		"To":                        "admin@example.com, ops@example.com",
+		"Reply-To":                  "user@example.comBcc: evil@example.com",
+		"Mime-Version":              "1.0",
+		"Content-Type":              "text/plain; charset=utf-8",
+		"Content-Transfer-Encoding": "quoted-printable",
+	} {
+		if got := h.Get(k); got != want {
+			t.Errorf("%s: %q, want %q", k, got, want)
+		}
+	}
+	if h.Get("Bcc") != "" {
+		t.Error("заголовок внедрён через Reply-To")
+	}
+	if !strings.HasSuffix(h.Get("Message-Id"), "@example.com>") {
+		t.Errorf("Message-ID %q", h.Get("Message-Id"))
+	}
+	if _, err := h.Date(); err != nil {
+		t.Errorf("Date: %v", err)
+	}
+
+	raw := h.Get("Subject")
+	if !strings.HasPrefix(raw, "=?utf-8?q?") {
+		t.Errorf("Subject не закодирован по RFC 2047: %q", raw)
+	}
+	subject, err := new(mime.WordDecoder).DecodeHeader(raw)
+	if err != nil || subject != msg.Subject {
+		t.Errorf("Subject %q (%v), want %q", subject, err, msg.Subject)
+	}
+
+	// В проводе — только ASCII: кириллица и "=" закодированы, длинная строка перенесена
+	if !strings.Contains(string(srv.data), "=D0=9F=D1=80") || !strings.Contains(string(srv.data), " =3D ") {
+		t.Errorf("тело не в quoted-printable:\n%s", srv.data)
+	}
+	_, rawBody, _ := strings.Cut(string(srv.data), "\n\n")
+	for _, line := range strings.Split(rawBody, "\n") {
+		if len(line) > 76 {
+			t.Errorf("строка длиннее 76 символов: %q", line)
+		}
+	}
+
+	decoded, err := io.ReadAll(quotedprintable.NewReader(parsed.Body))
+	if err != nil {
+		t.Fatalf("quoted-printable: %v", err)
+	}
+	// DotReader уже вернул переводы строк к \n
+	if string(decoded) != body {
+		t.Errorf("тело %q, want %q", decoded, body)
+	}
+}
+
+func TestSMTPNotifierPermanentError(t *testing.T) {
+	srv := startFakeSMTP(t, "550 5.1.1 no such user")
+
+	err := srv.notifier(t).Notify(context.Background(), Message{Subject: "s", Body: "b"})
+	if !errors.Is(err, ErrPermanent) {
+		t.Fatalf("err = %v, want ErrPermanent", err)
+	}
+}
+
+func TestSMTPNotifierTemporaryError(t *testing.T) {
+	srv := startFakeSMTP(t, "451 4.3.0 try again later")
+
+	err := srv.notifier(t).Notify(context.Background(), Message{Subject: "s", Body: "b"})
+	if err == nil || errors.Is(err, ErrPermanent) {
+		t.Fatalf("err = %v, want временную ошибку", err)
+	}
+}
+
+func TestSMTPNotifierCancelInterruptsStalledServer(t *testing.T) {
+	// Сервер принимает соединение и молчит: без приветствия клиент ждал бы timeout (30s)
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = ln.Close() })
+	go func() {
+		conn, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		t.Cleanup(func() { _ = conn.Close() })
+	}()
+
+	host, port, _ := net.SplitHostPort(ln.Addr().String())
+	p, _ := strconv.Atoi(port)
+	n := NewSMTPNotifier(SMTPConfig{Host: host, Port: p, From: "shop@example.com", To: "admin@example.com"})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(50*time.Millisecond, cancel)
+
+	start := time.Now()
+	if err := n.Notify(ctx, Message{Subject: "s", Body: "b"}); err == nil {
+		t.Fatal("Notify без ответа сервера вернул nil")
+	}
+	if d := time.Since(start); d > 5*time.Second {
+		t.Errorf("отмена контекста не прервала обмен: %v", d)
+	}
+}
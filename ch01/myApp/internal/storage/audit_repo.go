@@ -0,0 +1,95 @@
+package storage
+
+// internal/storage/audit_repo.go — журнал изменений (audit_log)
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"strings"
+	"time"
+
+	"myApp/internal/core"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// Действия в журнале
+const (
+	AuditCreate = "create"
+	AuditUpdate = "update"
+	AuditDelete = "delete"
+)
+
+// Actor — кто вносит изменение (пишется в журнал вместе с изменением).
+type Actor struct {
+	Name string
+	IP   string
+}
+
+type AuditEntry struct {
+	ID        int64     `db:"id" json:"id"`
+	Actor     string    `db:"actor" json:"actor"`
+	IP        string    `db:"ip" json:"ip"`
+	Action    string    `db:"action" json:"action"`
+	Entity    string    `db:"entity" json:"entity"`
+	EntityID  int64     `db:"entity_id" json:"entity_id"`
+	Changes   string    `db:"changes" json:"changes"` // JSON: поле -> {"old","new"} или снимок объекта
+	CreatedAt time.Time `db:"created_at" json:"created_at"`
+}
+
+// FieldChange — значение поля до и после изменения.
+type FieldChange struct {
+	Old interface{} `json:"old"`
+	New interface{} `json:"new"`
+}
+
+type AuditRepository interface {
+	// List — записи от новых к старым; beforeID > 0 — только старше этой записи.
+	List(ctx context.Context, limit int, beforeID int64) ([]AuditEntry, error)
+}
+
+type SQLAuditRepository struct {
+	db *sqlx.DB
+}
+
+var _ AuditRepository = (*SQLAuditRepository)(nil)
+
+func NewAuditRepository(db *sqlx.DB) *SQLAuditRepository {
+	return &SQLAuditRepository{db: db}
+}
+
+func (r *SQLAuditRepository) List(ctx context.Context, limit int, beforeID int64) ([]AuditEntry, error) {
+	q := `SELECT id, actor, ip, action, entity, entity_id, changes, created_at FROM audit_log`
+	var args []interface{}
+	if beforeID > 0 {
+		q += ` WHERE id < ?`
+		args = append(args, beforeID)
+	}
+	q += ` ORDER BY id DESC LIMIT ?`
+	args = append(args, limit)
+
+	items := make([]AuditEntry, 0, limit)
+	if err := r.db.SelectContext(ctx, &items, q, args...); err != nil {
+		core.LogErrorCtx(ctx, "list audit log", map[string]interface{}{
+			"query": q,
+			"error": err.Error(),
+		})
+		return nil, err
+	}
+	return items, nil
+}
+
+// writeAudit пишет запись в той же транзакции, что и само изменение:
+// изменение без записи в журнале (или наоборот) невозможно.
+func writeAudit(ctx context.Context, tx *sqlx.Tx, actor Actor, action, entity string, entityID int64, changes interface{}) error {
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false) // журнал читают люди: "&" вместо "\u0026"
+	if err := enc.Encode(changes); err != nil {
+		return err
+	}
+	_, err := tx.ExecContext(ctx,
+		`INSERT INTO audit_log (actor, ip, action, entity, entity_id, changes) VALUES (?, ?, ?, ?, ?, ?)`,
+		actor.Name, actor.IP, action, entity, entityID, strings.TrimSpace(buf.String()))
+	return err
+}
@@ -0,0 +1,218 @@
+package storage
+
+// internal/storage/categories_repo.go — дерево категорий (adjacency list: parent_id)
+import (
+	"context"
+	"database/sql"
+	"sync"
+	"time"
+
+	"myApp/internal/core"
+
+	"github.com/jmoiron/sqlx"
+	"golang.org/x/sync/singleflight"
+)
+
+type Category struct {
+	ID        int64     `db:"id" json:"id"`
+	ParentID  *int64    `db:"parent_id" json:"parent_id,omitempty"`
+	Slug      string    `db:"slug" json:"slug"`
+	Name      string    `db:"name" json:"name"`
+	SortOrder int       `db:"sort_order" json:"sort_order"`
+	CreatedAt time.Time `db:"created_at" json:"created_at"`
+}
+
+// CategoryNode — категория с дочерними (для меню и JSON).
+type CategoryNode struct {
+	Category
+	Children []*CategoryNode `json:"children,omitempty"`
+}
+
+// CategoryRepository — доступ к категориям. Дерево общее для всех
+// вызывающих — менять полученные узлы нельзя.
+type CategoryRepository interface {
+	Tree(ctx context.Context) ([]*CategoryNode, error)
+	BySlug(ctx context.Context, slug string) (*CategoryNode, error)
+	Path(ctx context.Context, id int64) ([]Category, error)
+}
+
+// categoryTreeTTL — категорий мало и меняются они редко: дерево читается
+// целиком и кэшируется, меню в layout не делает запрос на каждую страницу.
+// Правки категорий (только миграциями) видны не позже чем через TTL.
+const (
+	categoryTreeTTL     = time.Minute
+	categoryLoadTimeout = 5 * time.Second
+)
+
+// SQLCategoryRepository — реализация для MySQL и SQLite.
+type SQLCategoryRepository struct {
+	db *sqlx.DB
+
+	load     singleflight.Group
+	mu       sync.Mutex // только tree/loadedAt, на время запроса не держится
+	tree     []*CategoryNode
+	loadedAt time.Time
+}
+
+var _ CategoryRepository = (*SQLCategoryRepository)(nil)
+
+func NewCategoryRepository(db *sqlx.DB) *SQLCategoryRepository {
+	return &SQLCategoryRepository{db: db}
+}
+
+// Tree — корневые категории с потомками, по sort_order и имени.
+// Если перечитать дерево не удалось, отдаётся прежнее (устаревшее) —
+// меню и каталог не падают вместе с БД; ошибка — только при пустом кэше.
+func (r *SQLCategoryRepository) Tree(ctx context.Context) ([]*CategoryNode, error) {
+	r.mu.Lock()
+	tree, loadedAt := r.tree, r.loadedAt
+	r.mu.Unlock()
+	if tree != nil && time.Since(loadedAt) < categoryTreeTTL {
+		return tree, nil
+	}
+
+	// Один SELECT на всех, кто пришёл за деревом одновременно. Запрос не
+	// зависит от отмены контекста первого из них: его ждут и остальные
+	v, err, _ := r.load.Do("tree", func() (interface{}, error) {
+		loadCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), categoryLoadTimeout)
+		defer cancel()
+		return r.loadTree(loadCtx)
+	})
+	if err != nil {
+		if tree != nil {
+			core.LogWarnCtx(ctx, "Категории из устаревшего кэша", map[string]interface{}{
+				"age":   time.Since(loadedAt).String(),
+				"error": err.Error(),
+			})
+			return tree, nil
+		}
+		return nil, err
+	}
+	return v.([]*CategoryNode), nil
+}
+
+// loadTree читает дерево из БД и кладёт его в кэш.
+func (r *SQLCategoryRepository) loadTree(ctx context.Context) ([]*CategoryNode, error) {
+	const q = `
+		SELECT id, parent_id, slug, name, sort_order, created_at
+		FROM categories
+		ORDER BY sort_order ASC, name ASC, id ASC`
+
+	var rows []Category
+	if err := r.db.SelectContext(ctx, &rows, q); err != nil {
+		core.LogErrorCtx(ctx, "list categories", map[string]interface{}{
+			"query": q,
+			"error": err.Error(),
+		})
+		return nil, err
+	}
+
+	tree := buildCategoryTree(rows)
+	r.mu.Lock()
+	r.tree, r.loadedAt = tree, time.Now()
+	r.mu.Unlock()
+	return tree, nil
+}
+
+// BySlug — узел категории (с дочерними). sql.ErrNoRows, если не найдена.
+func (r *SQLCategoryRepository) BySlug(ctx context.Context, slug string) (*CategoryNode, error) {
+	tree, err := r.Tree(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if n := findCategory(tree, func(n *CategoryNode) bool { return n.Slug == slug }); n != nil {
+		return n, nil
+	}
+	return nil, sql.ErrNoRows
+}
+
+// Path — цепочка от корня до категории включительно (хлебные крошки).
+func (r *SQLCategoryRepository) Path(ctx context.Context, id int64) ([]Category, error) {
+	tree, err := r.Tree(ctx)
+	if err != nil {
+		return nil, err
+	}
+	var path []Category
+	if !categoryPath(tree, id, &path) {
+		return nil, sql.ErrNoRows
+	}
+	return path, nil
+}
+
+// SubtreeIDs — id категории и всех её потомков (фильтр товаров по ветке).
+// Неизвестный id возвращается как есть — выборка будет пустой.
+func SubtreeIDs(tree []*CategoryNode, id int64) []int64 {
+	root := findCategory(tree, func(n *CategoryNode) bool { return n.ID == id })
+	if root == nil {
+		return []int64{id}
+	}
+	var ids []int64
+	var walk func(n *CategoryNode)
+	walk = func(n *CategoryNode) {
+		ids = append(ids, n.ID)
+		for _, ch := range n.Children {
+			walk(ch)
+		}
+	}
+	walk(root)
+	return ids
+}
+
+// FlattenCategories — узлы дерева в порядке обхода (родитель перед детьми).
+func FlattenCategories(tree []*CategoryNode) []*CategoryNode {
+	var out []*CategoryNode
+	var walk func(nodes []*CategoryNode)
+	walk = func(nodes []*CategoryNode) {
+		for _, n := range nodes {
+			out = append(out, n)
+			walk(n.Children)
+		}
+	}
+	walk(tree)
+	return out
+}
+
+// buildCategoryTree — rows уже отсортированы, порядок детей сохраняется.
+// Категории с несуществующим родителем поднимаются в корень, а не теряются.
+func buildCategoryTree(rows []Category) []*CategoryNode {
+	nodes := make(map[int64]*CategoryNode, len(rows))
+	for _, c := range rows {
+		nodes[c.ID] = &CategoryNode{Category: c}
+	}
+
+	roots := make([]*CategoryNode, 0)
+	for _, c := range rows {
+		n := nodes[c.ID]
+		if c.ParentID != nil {
+			if parent, ok := nodes[*c.ParentID]; ok && parent != n {
+				parent.Children = append(parent.Children, n)
+				continue
+			}
+		}
+		roots = append(roots, n)
+	}
+	return roots
+}
+
+func findCategory(nodes []*CategoryNode, match func(*CategoryNode) bool) *CategoryNode {
+	for _, n := range nodes {
+		if match(n) {
+			return n
+		}
+		if found := findCategory(n.Children, match); found != nil {
+			return found
+		}
+	}
+	return nil
+}
+
+func categoryPath(nodes []*CategoryNode, id int64, path *[]Category) bool {
+	for _, n := range nodes {
+		*path = append(*path, n.Category)
+		if n.ID == id || categoryPath(n.Children, id, path) {
+			return true
+		}
+		*path = (*path)[:len(*path)-1]
+	}
+	return false
+}
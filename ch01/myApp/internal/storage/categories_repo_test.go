@@ -0,0 +1,101 @@
+package storage
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+)
+
+func newTestCategories(t *testing.T) *SQLCategoryRepository {
+	t.Helper()
+	db := newMemoryDB(t)
+	m, _ := newTestMigrations(t, db)
+	if err := m.RunMigrations(); err != nil {
+		t.Fatal(err)
+	}
+	return NewCategoryRepository(db)
+}
+
+func TestCategoryTreeCached(t *testing.T) {
+	r := newTestCategories(t)
+	ctx := context.Background()
+
+	tree, err := r.Tree(ctx)
+	if err != nil || len(tree) == 0 {
+		t.Fatalf("Tree: %d корней, %v", len(tree), err)
+	}
+	again, err := r.Tree(ctx)
+	if err != nil || again[0] != tree[0] {
+		t.Errorf("повторный вызов в пределах TTL не из кэша: %v", err)
+	}
+
+	// Отменённый контекст вызывающего не мешает загрузке общего дерева
+	r.mu.Lock()
+	r.loadedAt = time.Time{}
+	r.mu.Unlock()
+	cancelled, cancel := context.WithCancel(ctx)
+	cancel()
+	if tree, err := r.Tree(cancelled); err != nil || len(tree) == 0 {
+		t.Errorf("Tree с отменённым контекстом: %v", err)
+	}
+}
+
+func TestCategoryTreeConcurrentLoad(t *testing.T) {
+	r := newTestCategories(t)
+
+	var wg sync.WaitGroup
+	trees := make([][]*CategoryNode, 16)
+	for i := range trees {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			tree, err := r.Tree(context.Background())
+			if err != nil {
+				t.Error(err)
+			}
+			trees[i] = tree
+		}()
+	}
+	wg.Wait()
+	for i, tree := range trees {
+		if len(tree) != len(trees[0]) {
+			t.Errorf("вызов %d: %d корней, want %d", i, len(tree), len(trees[0]))
+		}
+	}
+}
+
+func TestCategoryTreeStaleOnError(t *testing.T) {
+	r := newTestCategories(t)
+	ctx := context.Background()
+
+	tree, err := r.Tree(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// TTL истёк, а БД недоступна — отдаётся прежнее дерево
+	r.mu.Lock()
+	r.loadedAt = time.Now().Add(-2 * categoryTreeTTL)
+	r.mu.Unlock()
+	if err := r.db.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	stale, err := r.Tree(ctx)
+	if err != nil || len(stale) != len(tree) || stale[0] != tree[0] {
+		t.Fatalf("устаревшее дерево: %d корней, %v", len(stale), err)
+	}
+	if n, err := r.BySlug(ctx, tree[0].Slug); err != nil || n.ID != tree[0].ID {
+		t.Errorf("BySlug по устаревшему дереву: %v", err)
+	}
+}
+
+func TestCategoryTreeErrorWithoutCache(t *testing.T) {
+	r := newTestCategories(t)
+	if err := r.db.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if tree, err := r.Tree(context.Background()); err == nil {
+		t.Fatalf("пустой кэш и недоступная БД: %d корней без ошибки", len(tree))
+	}
+}
@@ -0,0 +1,107 @@
+package storage
+
+// internal/storage/contacts_repo.go — сообщения формы обратной связи (contact_messages)
+import (
+	"context"
+	"time"
+
+	"myApp/internal/core"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// Статусы уведомления о сообщении
+const (
+	ContactPending = "pending" // ещё не отправлено (в очереди или ждёт перезапуска)
+	ContactSent    = "sent"
+	ContactFailed  = "failed" // попытки исчерпаны или ошибка постоянная
+)
+
+type ContactMessage struct {
+	ID        int64      `db:"id" json:"id"`
+	Name      string     `db:"name" json:"name"`
+	Email     string     `db:"email" json:"email"`
+	Message   string     `db:"message" json:"message"`
+	IP        string     `db:"ip" json:"ip"`
+	Status    string     `db:"status" json:"status"`
+	Attempts  int        `db:"attempts" json:"attempts"`
+	LastError *string    `db:"last_error" json:"last_error,omitempty"`
+	CreatedAt time.Time  `db:"created_at" json:"created_at"`
+	SentAt    *time.Time `db:"sent_at" json:"sent_at,omitempty"`
+}
+
+type ContactRepository interface {
+	Create(ctx context.Context, m *ContactMessage) error // заполняет m.ID
+	// List — от новых к старым; beforeID > 0 — только старше этого сообщения.
+	List(ctx context.Context, limit int, beforeID int64) ([]ContactMessage, error)
+	// Pending — неотправленные (для повторной постановки в очередь при старте).
+	Pending(ctx context.Context, limit int) ([]ContactMessage, error)
+	MarkSent(ctx context.Context, id int64, attempts int) error
+	MarkFailed(ctx context.Context, id int64, attempts int, status, lastErr string) error
+}
+
+type SQLContactRepository struct {
+	db *sqlx.DB
+}
+
+var _ ContactRepository = (*SQLContactRepository)(nil)
+
+func NewContactRepository(db *sqlx.DB) *SQLContactRepository {
+	return &SQLContactRepository{db: db}
+}
+
+const contactColumns = `id, name, email, message, ip, status, attempts, last_error, created_at, sent_at`
+
+func (r *SQLContactRepository) Create(ctx context.Context, m *ContactMessage) error {
+	m.Status = ContactPending
+	res, err := r.db.ExecContext(ctx,
+		`INSERT INTO contact_messages (name, email, message, ip, status) VALUES (?, ?, ?, ?, ?)`,
+		m.Name, m.Email, m.Message, m.IP, m.Status)
+	if err != nil {
+		core.LogErrorCtx(ctx, "create contact message", map[string]interface{}{"error": err.Error()})
+		return err
+	}
+	m.ID, err = res.LastInsertId()
+	return err
+}
+
+func (r *SQLContactRepository) List(ctx context.Context, limit int, beforeID int64) ([]ContactMessage, error) {
+	q := `SELECT ` + contactColumns + ` FROM contact_messages`
+	var args []interface{}
+	if beforeID > 0 {
+		q += ` WHERE id < ?`
+		args = append(args, beforeID)
+	}
+	q += ` ORDER BY id DESC LIMIT ?`
+	args = append(args, limit)
+
+	items := make([]ContactMessage, 0, limit)
+	if err := r.db.SelectContext(ctx, &items, q, args...); err != nil {
+		core.LogErrorCtx(ctx, "list contact messages", map[string]interface{}{"error": err.Error()})
+		return nil, err
+	}
+	return items, nil
+}
+
+func (r *SQLContactRepository) Pending(ctx context.Context, limit int) ([]ContactMessage, error) {
+	items := make([]ContactMessage, 0, limit)
+	err := r.db.SelectContext(ctx, &items,
+		`SELECT `+contactColumns+` FROM contact_messages WHERE status = ? ORDER BY id LIMIT ?`,
+		ContactPending, limit)
+	return items, err
+}
+
+func (r *SQLContactRepository) MarkSent(ctx context.Context, id int64, attempts int) error {
+	_, err := r.db.ExecContext(ctx,
+		`UPDATE contact_messages SET status = ?, attempts = ?, last_error = NULL, sent_at = CURRENT_TIMESTAMP WHERE id = ?`,
+		ContactSent, attempts, id)
+	return err
+}
+
+// MarkFailed — status: ContactFailed (окончательно) или ContactPending (повторить после перезапуска).
+func (r *SQLContactRepository) MarkFailed(ctx context.Context, id int64, attempts int, status, lastErr string) error {
+	_, err := r.db.ExecContext(ctx,
+		`UPDATE contact_messages SET status = ?, attempts = ?, last_error = ? WHERE id = ?`,
+		status, attempts, lastErr, id)
+	return err
+}
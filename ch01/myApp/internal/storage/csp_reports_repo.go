@@ -0,0 +1,100 @@
+package storage
+
+// internal/storage/csp_reports_repo.go — отчёты браузеров о нарушениях CSP (csp_reports)
+import (
+	"context"
+	"time"
+
+	"myApp/internal/core"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// CSPReport — одно нарушение (поля report-uri и report-to приводятся к одному виду).
+type CSPReport struct {
+	ID          int64     `db:"id" json:"id"`
+	Directive   string    `db:"directive" json:"directive"` // effective-directive, например "script-src-elem"
+	BlockedURI  string    `db:"blocked_uri" json:"blocked_uri"`
+	DocumentURI string    `db:"document_uri" json:"document_uri"`
+	SourceFile  string    `db:"source_file" json:"source_file"`
+	LineNumber  int       `db:"line_number" json:"line_number"`
+	Disposition string    `db:"disposition" json:"disposition"` // enforce | report
+	Sample      string    `db:"sample" json:"sample"`           // начало заблокированного кода (до 40 символов)
+	UserAgent   string    `db:"user_agent" json:"user_agent"`
+	IP          string    `db:"ip" json:"ip"`
+	CreatedAt   time.Time `db:"created_at" json:"created_at"`
+}
+
+// CSPReportSummary — нарушения, сгруппированные по директиве и заблокированному URI.
+type CSPReportSummary struct {
+	Directive   string    `db:"directive"`
+	BlockedURI  string    `db:"blocked_uri"`
+	Count       int       `db:"cnt"`
+	LastSeen    time.Time `db:"last_seen"`    // время последнего отчёта группы
+	DocumentURI string    `db:"document_uri"` // страница из последнего отчёта
+}
+
+type CSPReportRepository interface {
+	Create(ctx context.Context, r *CSPReport) error // заполняет r.ID
+	// Summary — группы по последним window отчётам, самые частые первыми.
+	Summary(ctx context.Context, window, limit int) ([]CSPReportSummary, error)
+	// Prune — оставляет только keep последних отчётов.
+	Prune(ctx context.Context, keep int) error
+}
+
+type SQLCSPReportRepository struct {
+	db *sqlx.DB
+}
+
+var _ CSPReportRepository = (*SQLCSPReportRepository)(nil)
+
+func NewCSPReportRepository(db *sqlx.DB) *SQLCSPReportRepository {
+	return &SQLCSPReportRepository{db: db}
+}
+
+func (r *SQLCSPReportRepository) Create(ctx context.Context, rep *CSPReport) error {
+	res, err := r.db.ExecContext(ctx,
+		`INSERT INTO csp_reports (directive, blocked_uri, document_uri, source_file, line_number, disposition, sample, user_agent, ip)
+		 VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		rep.Directive, rep.BlockedURI, rep.DocumentURI, rep.SourceFile, rep.LineNumber, rep.Disposition, rep.Sample, rep.UserAgent, rep.IP)
+	if err != nil {
+		core.LogErrorCtx(ctx, "create csp report", map[string]interface{}{"error": err.Error()})
+		return err
+	}
+	rep.ID, err = res.LastInsertId()
+	return err
+}
+
+// Summary — окно по id, а не по времени: одинаково для MySQL и SQLite.
+// Время и страница берутся из последнего отчёта группы (MAX(id)).
+func (r *SQLCSPReportRepository) Summary(ctx context.Context, window, limit int) ([]CSPReportSummary, error) {
+	const q = `
+		SELECT s.directive, s.blocked_uri, s.cnt, l.created_at AS last_seen, l.document_uri
+		FROM (
+			SELECT w.directive, w.blocked_uri, COUNT(*) AS cnt, MAX(w.id) AS last_id
+			FROM (SELECT id, directive, blocked_uri FROM csp_reports ORDER BY id DESC LIMIT ?) w
+			GROUP BY w.directive, w.blocked_uri
+		) s
+		JOIN csp_reports l ON l.id = s.last_id
+		ORDER BY s.cnt DESC, s.last_id DESC
+		LIMIT ?`
+
+	items := make([]CSPReportSummary, 0, limit)
+	if err := r.db.SelectContext(ctx, &items, q, window, limit); err != nil {
+		core.LogErrorCtx(ctx, "csp report summary", map[string]interface{}{"error": err.Error()})
+		return nil, err
+	}
+	return items, nil
+}
+
+func (r *SQLCSPReportRepository) Prune(ctx context.Context, keep int) error {
+	var maxID int64
+	if err := r.db.GetContext(ctx, &maxID, `SELECT COALESCE(MAX(id), 0) FROM csp_reports`); err != nil {
+		return err
+	}
+	if maxID <= int64(keep) {
+		return nil
+	}
+	_, err := r.db.ExecContext(ctx, `DELETE FROM csp_reports WHERE id <= ?`, maxID-int64(keep))
+	return err
+}
@@ -2,66 +2,83 @@ package storage
 
 import (
 	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
 	"strings"
 	"time"
 
 	"myApp/internal/core"
 
-	_ "github.com/go-sql-driver/mysql"
+	"github.com/go-sql-driver/mysql"
 	"github.com/jmoiron/sqlx"
+	_ "modernc.org/sqlite" // pure-Go SQLite, драйвер "sqlite"
 )
 
+func init() {
+	// sqlx знает "sqlite3" (cgo), но не "sqlite" из modernc — те же плейсхолдеры "?"
+	sqlx.BindDriver("sqlite", sqlx.QUESTION)
+}
+
 // CtxDBKey - ключ для хранения *sqlx.DB в контексте запроса
 type CtxDBKey struct{}
 
-// Константы подключения к MySQL (заменить на продакшн значения)
-const (
-	MySQLUser     = "root"           // Пользователь БД
-	MySQLPassword = "admin"          // Пароль БД (ЗАМЕНИТЬ!)
-	MySQLHost     = "localhost:3306" // Хост и порт MySQL
-	MySQLDatabase = "shop"           // Имя базы данных
-)
-
-// NewDB создаёт пул подключений к MySQL с продакшн-настройками
+// NewDB создаёт пул подключений по настройкам из конфига (DB_DRIVER, DB_DSN, DB_*)
 // Инициализирует connection pool и проверяет подключение
-func NewDB() (*sqlx.DB, error) {
-	dsn := getMySQLDSN()
+func NewDB(cfg core.Config) (*sqlx.DB, error) {
+	dsn, err := prepareDSN(cfg.DBDriver, cfg.DBDSN)
+	if err != nil {
+		core.LogError("ошибка DB_DSN", map[string]interface{}{
+			"driver": cfg.DBDriver,
+			"error":  err.Error(),
+		})
+		return nil, err
+	}
 
-	// Подключение к MySQL
-	db, err := sqlx.ConnectContext(context.Background(), "mysql", dsn)
+	// Подключение к БД
+	db, err := sqlx.ConnectContext(context.Background(), cfg.DBDriver, dsn)
 	if err != nil {
-		core.LogError("ошибка подключения к MySQL", map[string]interface{}{
-			"error": err.Error(),
-			"dsn":   getSanitizedDSN(dsn),
+		core.LogError("ошибка подключения к БД", map[string]interface{}{
+			"driver": cfg.DBDriver,
+			"error":  err.Error(),
+			"dsn":    getSanitizedDSN(cfg.DBDriver, dsn),
 		})
 		return nil, err
 	}
 
-	// Настройка connection pool для продакшена
-	db.SetMaxOpenConns(25)                 // Максимум одновременных подключений
-	db.SetMaxIdleConns(25)                 // Подключения в пуле ожидания
-	db.SetConnMaxLifetime(5 * time.Minute) // Ротация соединений
+	// Настройка connection pool
+	maxOpen, maxIdle := cfg.DBMaxOpenConns, cfg.DBMaxIdleConns
+	if cfg.DBDriver == "sqlite" && strings.Contains(dsn, ":memory:") {
+		// у каждого соединения своя in-memory БД — держим ровно одно
+		maxOpen, maxIdle = 1, 1
+	}
+	db.SetMaxOpenConns(maxOpen)                  // Максимум одновременных подключений
+	db.SetMaxIdleConns(maxIdle)                  // Подключения в пуле ожидания
+	db.SetConnMaxLifetime(cfg.DBConnMaxLifetime) // Ротация соединений
+	db.SetConnMaxIdleTime(cfg.DBConnMaxIdleTime) // Закрытие простаивающих
 
 	// Проверка подключения
 	if err := db.PingContext(context.Background()); err != nil {
 		//  обрабатываем ошибку закрытия, чтобы не было "Unhandled error"
 		if cerr := db.Close(); cerr != nil {
-			core.LogError("ошибка закрытия MySQL пула после неуспешного ping", map[string]interface{}{
+			core.LogError("ошибка закрытия пула после неуспешного ping", map[string]interface{}{
 				"error": cerr.Error(),
 			})
 		}
-		core.LogError("ошибка проверки подключения MySQL", map[string]interface{}{
-			"error": err.Error(),
+		core.LogError("ошибка проверки подключения к БД", map[string]interface{}{
+			"driver": cfg.DBDriver,
+			"error":  err.Error(),
 		})
 		return nil, err
 	}
 
 	// Успешное подключение - логируем через LogInfo
-	core.LogInfo("MySQL подключение успешно", map[string]interface{}{
-		"host":     MySQLHost,
-		"database": MySQLDatabase,
-		"max_open": 25,
-		"max_idle": 25,
+	core.LogInfo("Подключение к БД успешно", map[string]interface{}{
+		"driver":   cfg.DBDriver,
+		"dsn":      getSanitizedDSN(cfg.DBDriver, dsn),
+		"max_open": maxOpen,
+		"max_idle": maxIdle,
 	})
 
 	return db, nil
@@ -75,13 +92,13 @@ func Close(db *sqlx.DB) error {
 	}
 
 	if err := db.Close(); err != nil {
-		core.LogError("ошибка закрытия MySQL пула", map[string]interface{}{
+		core.LogError("ошибка закрытия пула БД", map[string]interface{}{
 			"error": err.Error(),
 		})
 		return err
 	}
 
-	core.LogInfo("MySQL пул закрыт", nil)
+	core.LogInfo("Пул БД закрыт", nil)
 	return nil
 }
 
@@ -94,26 +111,97 @@ func GetDBFromContext(ctx context.Context) *sqlx.DB {
 	return nil
 }
 
-// getMySQLDSN формирует строку подключения MySQL из констант
-func getMySQLDSN() string {
-	dsn := MySQLUser + ":" + MySQLPassword + "@tcp(" + MySQLHost + ")/" + MySQLDatabase
-	dsn += "?parseTime=true"         // Парсинг времени
-	dsn += "&charset=utf8mb4"        // Unicode + эмодзи
-	dsn += "&timeout=5s"             // Таймаут подключения
-	dsn += "&readTimeout=5s"         // Таймаут чтения
-	dsn += "&writeTimeout=10s"       // Таймаут записи
-	dsn += "&interpolateParams=true" // Prepared statements
-	dsn += "&multiStatements=false"  // Безопасность SQL
-	dsn += "&loc=Local"              // Локальная временная зона
-	return dsn
+// prepareDSN дополняет DSN обязательными для приложения параметрами,
+// если они не заданы явно.
+func prepareDSN(driver, dsn string) (string, error) {
+	switch driver {
+	case "mysql":
+		return prepareMySQLDSN(dsn)
+	case "sqlite":
+		return prepareSQLiteDSN(dsn)
+	default:
+		return "", fmt.Errorf("драйвер %q не поддерживается", driver)
+	}
 }
 
-// getSanitizedDSN удаляет пароль из DSN для логирования
-func getSanitizedDSN(dsn string) string {
-	if passIdx := strings.Index(dsn, ":"); passIdx > 0 {
-		if atIdx := strings.Index(dsn[passIdx:], "@"); atIdx > 0 {
-			return dsn[:passIdx] + ":***@tcp(" + dsn[passIdx+atIdx+1:]
+// prepareMySQLDSN — parseTime, utf8mb4, таймауты, без multiStatements
+func prepareMySQLDSN(dsn string) (string, error) {
+	c, err := mysql.ParseDSN(dsn)
+	if err != nil {
+		return "", err
+	}
+	c.ParseTime = true         // Парсинг времени
+	c.InterpolateParams = true // Prepared statements на клиенте
+	c.MultiStatements = false  // Безопасность SQL
+	if c.Params == nil {
+		c.Params = map[string]string{}
+	}
+	if _, ok := c.Params["charset"]; !ok {
+		c.Params["charset"] = "utf8mb4" // Unicode + эмодзи
+	}
+	if c.Timeout == 0 {
+		c.Timeout = 5 * time.Second // Таймаут подключения
+	}
+	if c.ReadTimeout == 0 {
+		c.ReadTimeout = 5 * time.Second // Таймаут чтения
+	}
+	if c.WriteTimeout == 0 {
+		c.WriteTimeout = 10 * time.Second // Таймаут записи
+	}
+	if !strings.Contains(dsn, "loc=") {
+		c.Loc = time.Local // Локальная временная зона
+	}
+	return c.FormatDSN(), nil
+}
+
+// prepareSQLiteDSN — внешние ключи, ожидание блокировки, WAL для файловой БД
+func prepareSQLiteDSN(dsn string) (string, error) {
+	if dsn == "" {
+		return "", errors.New("пустой DSN")
+	}
+
+	pragmas := []string{"foreign_keys(1)", "busy_timeout(5000)"}
+	if !strings.Contains(dsn, ":memory:") {
+		pragmas = append(pragmas, "journal_mode(WAL)")
+
+		// Каталог для файла БД создаём сами (data/ не хранится в git)
+		path := strings.TrimPrefix(dsn, "file:")
+		if i := strings.IndexByte(path, '?'); i >= 0 {
+			path = path[:i]
+		}
+		if dir := filepath.Dir(path); dir != "." {
+			if err := os.MkdirAll(dir, 0o755); err != nil {
+				return "", err
+			}
 		}
 	}
-	return dsn
+
+	sep := "?"
+	if strings.Contains(dsn, "?") {
+		sep = "&"
+	}
+	for _, p := range pragmas {
+		name := p[:strings.IndexByte(p, '(')]
+		if strings.Contains(dsn, "_pragma="+name) {
+			continue // задано в DB_DSN явно
+		}
+		dsn += sep + "_pragma=" + p
+		sep = "&"
+	}
+	return dsn, nil
+}
+
+// getSanitizedDSN удаляет пароль из DSN для логирования
+func getSanitizedDSN(driver, dsn string) string {
+	if driver != "mysql" {
+		return dsn
+	}
+	c, err := mysql.ParseDSN(dsn)
+	if err != nil {
+		return "***"
+	}
+	if c.Passwd != "" {
+		c.Passwd = "***"
+	}
+	return c.FormatDSN()
 }
@@ -0,0 +1,109 @@
+package storage
+
+// internal/storage/images.go — картинки товаров на диске (UPLOAD_DIR/products/)
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"image"
+	_ "image/jpeg" // DecodeConfig для JPEG
+	_ "image/png"  // DecodeConfig для PNG
+	"io"
+	"mime/multipart"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"myApp/internal/core"
+)
+
+var (
+	ErrImageTooLarge = errors.New("image too large")
+	ErrImageType     = errors.New("unsupported image type")
+)
+
+// Допустимые типы: определяются по содержимому, а не по имени файла
+// или Content-Type из запроса (их присылает клиент).
+var imageExtensions = map[string]string{
+	"image/jpeg": ".jpg",
+	"image/png":  ".png",
+	"image/webp": ".webp",
+}
+
+// maxImageSide — защита от «бомб»: маленький файл с огромными размерами.
+const maxImageSide = 8000
+
+// ImageStore сохраняет файлы под случайными именами: имя от клиента
+// не используется, перезаписать чужой файл или выйти из каталога нельзя.
+type ImageStore struct {
+	dir      string // UPLOAD_DIR
+	maxBytes int64
+}
+
+func NewImageStore(dir string, maxBytes int64) *ImageStore {
+	return &ImageStore{dir: dir, maxBytes: maxBytes}
+}
+
+// Save проверяет и сохраняет картинку, возвращает путь относительно UPLOAD_DIR.
+func (s *ImageStore) Save(fh *multipart.FileHeader) (string, error) {
+	if fh.Size > s.maxBytes {
+		return "", ErrImageTooLarge
+	}
+	f, err := fh.Open()
+	if err != nil {
+		return "", err
+	}
+	defer func() { _ = f.Close() }()
+
+	data, err := io.ReadAll(io.LimitReader(f, s.maxBytes+1))
+	if err != nil {
+		return "", err
+	}
+	if int64(len(data)) > s.maxBytes {
+		return "", ErrImageTooLarge
+	}
+
+	ext, ok := imageExtensions[http.DetectContentType(data)]
+	if !ok {
+		return "", ErrImageType
+	}
+	if ext != ".webp" { // для WebP в stdlib нет декодера — проверяем только сигнатуру
+		cfg, _, err := image.DecodeConfig(bytes.NewReader(data))
+		if err != nil || cfg.Width > maxImageSide || cfg.Height > maxImageSide {
+			return "", ErrImageType
+		}
+	}
+
+	name := make([]byte, 16)
+	if _, err := rand.Read(name); err != nil {
+		return "", err
+	}
+	rel := path.Join("products", hex.EncodeToString(name)+ext)
+
+	full := filepath.Join(s.dir, filepath.FromSlash(rel))
+	if err := os.MkdirAll(filepath.Dir(full), 0o755); err != nil {
+		return "", err
+	}
+	if err := os.WriteFile(full, data, 0o644); err != nil {
+		return "", err
+	}
+	return rel, nil
+}
+
+// Remove удаляет ранее сохранённый файл. Ошибки только логируются —
+// «осиротевший» файл не повод ломать запрос.
+func (s *ImageStore) Remove(ctx context.Context, rel string) {
+	if rel == "" || strings.Contains(rel, "..") || path.IsAbs(rel) {
+		return
+	}
+	if err := os.Remove(filepath.Join(s.dir, filepath.FromSlash(rel))); err != nil && !os.IsNotExist(err) {
+		core.LogErrorCtx(ctx, "Ошибка удаления картинки", map[string]interface{}{
+			"path":  rel,
+			"error": err.Error(),
+		})
+	}
+}
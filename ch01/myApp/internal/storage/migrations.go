@@ -1,10 +1,29 @@
 package storage
 
-// migrations.go
+// migrations.go — версионные миграции (up/down) с таблицей schema_migrations.
+//
+// Файлы NNN_name.up.sql / NNN_name.down.sql берутся из fs.FS (migrations.FS,
+// встроен через embed). Каждая миграция выполняется в транзакции, запуск
+// защищён advisory lock — два экземпляра приложения не применят одну
+// миграцию дважды.
+//
+// ВАЖНО (MySQL): DDL (CREATE/ALTER/DROP) делает неявный COMMIT, поэтому
+// откатить упавшую на середине DDL-миграцию транзакция не сможет. Держите
+// в одной миграции одно DDL-изменение.
+
 import (
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"errors"
 	"fmt"
-	"os"
+	"io/fs"
+	"regexp"
+	"sort"
+	"strconv"
 	"strings"
+	"time"
 
 	"myApp/internal/core"
 
@@ -12,76 +31,448 @@ import (
 )
 
 const (
-	EnableMigrations = false                       // true → выполнять миграцию
-	MigrationFile    = "migrations/001_schema.sql" // путь к файлу миграции
+	migrationsTable   = "schema_migrations"
+	migrationLockName = "myapp_schema_migrations" // имя advisory lock
+	migrationLockWait = 30 * time.Second          // сколько ждать чужую миграцию
 )
 
+var migrationFileRe = regexp.MustCompile(`^(\d+)_([a-zA-Z0-9_]+)\.(up|down)\.sql$`)
+
+// Migration — одна версия схемы.
+type Migration struct {
+	Version  int64
+	Name     string
+	Up       string
+	Down     string // пусто — откат не поддерживается
+	Checksum string // sha256 от up-файла
+}
+
+// MigrationStatus — состояние версии для `migrate status`.
+type MigrationStatus struct {
+	Version   int64
+	Name      string
+	Applied   bool
+	AppliedAt time.Time
+	Modified  bool // up-файл изменён после применения
+	Missing   bool // применена, но файла больше нет
+}
+
+type appliedMigration struct {
+	Version   int64     `db:"version"`
+	Name      string    `db:"name"`
+	Checksum  string    `db:"checksum"`
+	AppliedAt time.Time `db:"applied_at"`
+}
+
 type Migrations struct {
-	db *sqlx.DB
+	db  *sqlx.DB
+	src fs.FS
 }
 
-func NewMigrations(db *sqlx.DB) *Migrations {
-	return &Migrations{db: db}
+// NewMigrations — src обычно migrations.For(driver).
+func NewMigrations(db *sqlx.DB, src fs.FS) *Migrations {
+	return &Migrations{db: db, src: src}
 }
 
+// RunMigrations применяет все новые миграции (используется при старте).
 func (m *Migrations) RunMigrations() error {
-	if !EnableMigrations {
-		core.LogInfo("Миграции отключены (EnableMigrations=false)", nil)
+	_, err := m.Up(context.Background(), 0)
+	return err
+}
+
+// Up применяет до steps новых миграций (0 — все). Возвращает число применённых.
+func (m *Migrations) Up(ctx context.Context, steps int) (int, error) {
+	all, err := m.load()
+	if err != nil {
+		return 0, err
+	}
+
+	done := 0
+	err = m.withLock(ctx, func(conn *sqlx.Conn) error {
+		applied, err := m.applied(ctx, conn)
+		if err != nil {
+			return err
+		}
+		if err := verifyChecksums(all, applied); err != nil {
+			return err
+		}
+
+		for _, mig := range all {
+			if _, ok := applied[mig.Version]; ok {
+				continue
+			}
+			if steps > 0 && done >= steps {
+				break
+			}
+			if err := m.apply(ctx, conn, mig, true); err != nil {
+				return err
+			}
+			done++
+		}
+		return nil
+	})
+	return done, err
+}
+
+// Down откатывает steps последних применённых миграций (минимум одну).
+func (m *Migrations) Down(ctx context.Context, steps int) (int, error) {
+	if steps <= 0 {
+		steps = 1
+	}
+	all, err := m.load()
+	if err != nil {
+		return 0, err
+	}
+	byVersion := make(map[int64]Migration, len(all))
+	for _, mig := range all {
+		byVersion[mig.Version] = mig
+	}
+
+	done := 0
+	err = m.withLock(ctx, func(conn *sqlx.Conn) error {
+		applied, err := m.applied(ctx, conn)
+		if err != nil {
+			return err
+		}
+
+		versions := make([]int64, 0, len(applied))
+		for v := range applied {
+			versions = append(versions, v)
+		}
+		sort.Slice(versions, func(i, j int) bool { return versions[i] > versions[j] })
+
+		for _, v := range versions {
+			if done >= steps {
+				break
+			}
+			mig, ok := byVersion[v]
+			if !ok {
+				return fmt.Errorf("миграция %03d применена, но файла нет", v)
+			}
+			if mig.Down == "" {
+				return fmt.Errorf("миграция %03d_%s: нет down-файла", mig.Version, mig.Name)
+			}
+			if err := m.apply(ctx, conn, mig, false); err != nil {
+				return err
+			}
+			done++
+		}
 		return nil
+	})
+	return done, err
+}
+
+// Redo откатывает и заново применяет последнюю применённую миграцию — ту же
+// версию, под одним lock: между down и up никто не вклинится, и при наличии
+// более ранних неприменённых версий up не подхватит другую.
+func (m *Migrations) Redo(ctx context.Context) error {
+	all, err := m.load()
+	if err != nil {
+		return err
+	}
+	byVersion := make(map[int64]Migration, len(all))
+	for _, mig := range all {
+		byVersion[mig.Version] = mig
 	}
 
-	core.LogInfo("Начало выполнения миграции", map[string]interface{}{
-		"file": MigrationFile,
+	return m.withLock(ctx, func(conn *sqlx.Conn) error {
+		applied, err := m.applied(ctx, conn)
+		if err != nil {
+			return err
+		}
+		if len(applied) == 0 {
+			return errors.New("нет применённых миграций")
+		}
+
+		var last int64
+		for v := range applied {
+			last = max(last, v)
+		}
+		mig, ok := byVersion[last]
+		if !ok {
+			return fmt.Errorf("миграция %03d применена, но файла нет", last)
+		}
+		if mig.Down == "" {
+			return fmt.Errorf("миграция %03d_%s: нет down-файла", mig.Version, mig.Name)
+		}
+		// Сама переприменяемая версия может быть изменена — ради этого redo
+		// обычно и запускают; остальные должны совпадать, как в Up.
+		delete(applied, last)
+		if err := verifyChecksums(all, applied); err != nil {
+			return err
+		}
+
+		if err := m.apply(ctx, conn, mig, false); err != nil {
+			return err
+		}
+		return m.apply(ctx, conn, mig, true)
 	})
+}
 
-	content, err := os.ReadFile(MigrationFile)
+// Status — все известные версии: из файлов и из schema_migrations.
+func (m *Migrations) Status(ctx context.Context) ([]MigrationStatus, error) {
+	all, err := m.load()
 	if err != nil {
-		core.LogError("Ошибка чтения файла миграции", map[string]interface{}{
-			"file":  MigrationFile,
-			"error": err.Error(),
-		})
-		fmt.Println("Ошибка применения миграции")
+		return nil, err
+	}
+
+	var applied map[int64]appliedMigration
+	err = m.withLock(ctx, func(conn *sqlx.Conn) error {
+		applied, err = m.applied(ctx, conn)
 		return err
+	})
+	if err != nil {
+		return nil, err
 	}
 
-	sqlText := cleanSQL(string(content))
-	stmts := strings.Split(sqlText, ";")
+	var out []MigrationStatus
+	for _, mig := range all {
+		st := MigrationStatus{Version: mig.Version, Name: mig.Name}
+		if a, ok := applied[mig.Version]; ok {
+			st.Applied = true
+			st.AppliedAt = a.AppliedAt
+			st.Modified = a.Checksum != mig.Checksum
+			delete(applied, mig.Version)
+		}
+		out = append(out, st)
+	}
+	for _, a := range applied {
+		out = append(out, MigrationStatus{Version: a.Version, Name: a.Name, Applied: true, AppliedAt: a.AppliedAt, Missing: true})
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Version < out[j].Version })
+	return out, nil
+}
+
+// load читает и проверяет файлы миграций.
+func (m *Migrations) load() ([]Migration, error) {
+	entries, err := fs.ReadDir(m.src, ".")
+	if err != nil {
+		return nil, fmt.Errorf("чтение миграций: %w", err)
+	}
 
-	executed := 0
-	for _, raw := range stmts {
-		stmt := strings.TrimSpace(raw)
-		if stmt == "" {
+	byVersion := map[int64]*Migration{}
+	for _, e := range entries {
+		if e.IsDir() || !strings.HasSuffix(e.Name(), ".sql") {
 			continue
 		}
-		if _, err := m.db.Exec(stmt); err != nil {
-			core.LogError("Ошибка SQL", map[string]interface{}{
-				"sql":   stmt,
-				"error": err.Error(),
+		parts := migrationFileRe.FindStringSubmatch(e.Name())
+		if parts == nil {
+			return nil, fmt.Errorf("миграция %s: имя должно быть NNN_name.up.sql или NNN_name.down.sql", e.Name())
+		}
+		version, _ := strconv.ParseInt(parts[1], 10, 64)
+		content, err := fs.ReadFile(m.src, e.Name())
+		if err != nil {
+			return nil, err
+		}
+
+		mig, ok := byVersion[version]
+		if !ok {
+			mig = &Migration{Version: version, Name: parts[2]}
+			byVersion[version] = mig
+		} else if mig.Name != parts[2] {
+			return nil, fmt.Errorf("миграция %03d: разные имена %q и %q", version, mig.Name, parts[2])
+		}
+
+		if parts[3] == "up" {
+			sum := sha256.Sum256(content)
+			mig.Up = string(content)
+			mig.Checksum = hex.EncodeToString(sum[:])
+		} else {
+			mig.Down = string(content)
+		}
+	}
+
+	out := make([]Migration, 0, len(byVersion))
+	for _, mig := range byVersion {
+		if mig.Up == "" {
+			return nil, fmt.Errorf("миграция %03d_%s: нет up-файла", mig.Version, mig.Name)
+		}
+		out = append(out, *mig)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Version < out[j].Version })
+	return out, nil
+}
+
+// verifyChecksums — применённый файл изменили: схема в БД уже не та, что в
+// репозитории, молча продолжать нельзя.
+func verifyChecksums(all []Migration, applied map[int64]appliedMigration) error {
+	for _, mig := range all {
+		if a, ok := applied[mig.Version]; ok && a.Checksum != mig.Checksum {
+			return fmt.Errorf("миграция %03d_%s изменена после применения (checksum), создайте новую миграцию вместо правки", mig.Version, mig.Name)
+		}
+	}
+	return nil
+}
+
+// apply выполняет up или down миграцию в транзакции вместе с записью в schema_migrations.
+func (m *Migrations) apply(ctx context.Context, conn *sqlx.Conn, mig Migration, up bool) error {
+	direction, script := "up", mig.Up
+	if !up {
+		direction, script = "down", mig.Down
+	}
+	start := time.Now()
+
+	tx, err := conn.BeginTxx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	for _, stmt := range splitStatements(script) {
+		if _, err := tx.ExecContext(ctx, stmt); err != nil {
+			core.LogError("Ошибка SQL миграции", map[string]interface{}{
+				"version":   mig.Version,
+				"direction": direction,
+				"sql":       stmt,
+				"error":     err.Error(),
 			})
-			fmt.Println(" Ошибка применения миграции")
-			return fmt.Errorf("ошибка выполнения SQL: %w", err)
+			return fmt.Errorf("миграция %03d_%s (%s): %w", mig.Version, mig.Name, direction, err)
 		}
-		executed++
 	}
 
-	core.LogInfo("Миграция успешно применена", map[string]interface{}{
-		"file":       MigrationFile,
-		"statements": executed,
+	if up {
+		_, err = tx.ExecContext(ctx,
+			`INSERT INTO `+migrationsTable+` (version, name, checksum) VALUES (?, ?, ?)`,
+			mig.Version, mig.Name, mig.Checksum)
+	} else {
+		_, err = tx.ExecContext(ctx, `DELETE FROM `+migrationsTable+` WHERE version = ?`, mig.Version)
+	}
+	if err != nil {
+		return err
+	}
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+
+	core.LogInfo("Миграция применена", map[string]interface{}{
+		"version":   mig.Version,
+		"name":      mig.Name,
+		"direction": direction,
+		"duration":  time.Since(start).String(),
 	})
-	fmt.Println(" Миграция применена")
 	return nil
 }
 
-// cleanSQL — убирает комментарии и пустые строки
-func cleanSQL(sql string) string {
-	lines := strings.Split(sql, "\n")
-	var clean []string
-	for _, ln := range lines {
-		t := strings.TrimSpace(ln)
-		if t == "" || strings.HasPrefix(t, "--") {
+func (m *Migrations) applied(ctx context.Context, conn *sqlx.Conn) (map[int64]appliedMigration, error) {
+	var rows []appliedMigration
+	if err := conn.SelectContext(ctx, &rows,
+		`SELECT version, name, checksum, applied_at FROM `+migrationsTable+` ORDER BY version`); err != nil {
+		return nil, err
+	}
+	out := make(map[int64]appliedMigration, len(rows))
+	for _, r := range rows {
+		out[r.Version] = r
+	}
+	return out, nil
+}
+
+// withLock берёт отдельное соединение, advisory lock и гарантирует таблицу
+// schema_migrations. Lock привязан к соединению, поэтому все миграции
+// выполняются через conn.
+func (m *Migrations) withLock(ctx context.Context, fn func(conn *sqlx.Conn) error) error {
+	conn, err := m.db.Connx(ctx)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = conn.Close() }()
+
+	unlock, err := acquireMigrationLock(ctx, conn, m.db.DriverName())
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	if _, err := conn.ExecContext(ctx, `CREATE TABLE IF NOT EXISTS `+migrationsTable+` (
+		version    BIGINT       NOT NULL PRIMARY KEY,
+		name       VARCHAR(255) NOT NULL,
+		checksum   CHAR(64)     NOT NULL,
+		applied_at TIMESTAMP    NOT NULL DEFAULT CURRENT_TIMESTAMP
+	)`); err != nil {
+		return fmt.Errorf("создание %s: %w", migrationsTable, err)
+	}
+
+	return fn(conn)
+}
+
+// acquireMigrationLock — advisory lock на уровне БД (MySQL GET_LOCK).
+// В SQLite advisory lock нет: запись в файл и так сериализована блокировкой
+// БД, а PRIMARY KEY в schema_migrations не даст применить версию дважды.
+func acquireMigrationLock(ctx context.Context, conn *sqlx.Conn, driver string) (func(), error) {
+	switch driver {
+	case "mysql":
+		var got sql.NullInt64
+		err := conn.GetContext(ctx, &got, `SELECT GET_LOCK(?, ?)`,
+			migrationLockName, int(migrationLockWait/time.Second))
+		if err != nil {
+			return nil, fmt.Errorf("advisory lock: %w", err)
+		}
+		if !got.Valid || got.Int64 != 1 {
+			return nil, fmt.Errorf("advisory lock: не дождались %s (миграции выполняет другой процесс?)", migrationLockWait)
+		}
+		return func() {
+			_, _ = conn.ExecContext(context.Background(), `SELECT RELEASE_LOCK(?)`, migrationLockName)
+		}, nil
+	case "sqlite":
+		return func() {}, nil
+	default:
+		return nil, fmt.Errorf("advisory lock: драйвер %q не поддерживается", driver)
+	}
+}
+
+// splitStatements делит скрипт на запросы по ";" вне строк и комментариев
+// (multiStatements=false в DSN, поэтому по одному запросу за Exec).
+func splitStatements(script string) []string {
+	var (
+		out   []string
+		cur   strings.Builder
+		quote byte // ', ", ` — внутри строки/идентификатора
+	)
+	flush := func() {
+		if s := strings.TrimSpace(cur.String()); s != "" {
+			out = append(out, s)
+		}
+		cur.Reset()
+	}
+
+	for i := 0; i < len(script); i++ {
+		ch := script[i]
+
+		if quote != 0 {
+			cur.WriteByte(ch)
+			switch {
+			case ch == '\\' && quote != '`' && i+1 < len(script):
+				i++
+				cur.WriteByte(script[i])
+			case ch == quote:
+				quote = 0
+			}
 			continue
 		}
-		clean = append(clean, t)
+
+		switch {
+		case ch == '\'' || ch == '"' || ch == '`':
+			quote = ch
+			cur.WriteByte(ch)
+		case ch == '-' && strings.HasPrefix(script[i:], "--"):
+			// комментарий до конца строки
+			if j := strings.IndexByte(script[i:], '\n'); j >= 0 {
+				i += j
+				cur.WriteByte('\n')
+			} else {
+				i = len(script)
+			}
+		case ch == '/' && strings.HasPrefix(script[i:], "/*"):
+			if j := strings.Index(script[i+2:], "*/"); j >= 0 {
+				i += j + 3
+			} else {
+				i = len(script)
+			}
+		case ch == ';':
+			flush()
+		default:
+			cur.WriteByte(ch)
+		}
 	}
-	return strings.Join(clean, "\n")
+	flush()
+	return out
 }
@@ -0,0 +1,108 @@
+package storage
+
+import (
+	"context"
+	"io"
+	"testing"
+
+	"myApp/internal/core"
+	"myApp/migrations"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/rs/zerolog"
+)
+
+// newMemoryDB — SQLite в памяти (одно соединение) без логов.
+func newMemoryDB(t *testing.T) *sqlx.DB {
+	t.Helper()
+	prev := core.SetLogger(core.NewLogger(io.Discard, io.Discard, zerolog.ErrorLevel))
+	t.Cleanup(func() { core.SetLogger(prev) })
+
+	db, err := NewDB(core.Config{DBDriver: "sqlite", DBDSN: "file::memory:", DBMaxOpenConns: 1, DBMaxIdleConns: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = db.Close() })
+	return db
+}
+
+func newTestMigrations(t *testing.T, db *sqlx.DB) (*Migrations, int) {
+	t.Helper()
+	src, err := migrations.For("sqlite")
+	if err != nil {
+		t.Fatal(err)
+	}
+	m := NewMigrations(db, src)
+	all, err := m.load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return m, len(all)
+}
+
+func countProducts(t *testing.T, db *sqlx.DB, where string, args ...any) int {
+	t.Helper()
+	var n int
+	if err := db.Get(&n, `SELECT COUNT(*) FROM products WHERE `+where, args...); err != nil {
+		t.Fatal(err)
+	}
+	return n
+}
+
+func TestSeedProductsFreshDB(t *testing.T) {
+	db := newMemoryDB(t)
+	m, total := newTestMigrations(t, db)
+	ctx := context.Background()
+
+	if err := m.RunMigrations(); err != nil {
+		t.Fatal(err)
+	}
+	if n := countProducts(t, db, "article LIKE 'ART-%'"); n != 5 {
+		t.Fatalf("демо-товаров %d, want 5", n)
+	}
+
+	// Откат всего, кроме 001: демо-товары удалены, таблица осталась
+	if _, err := m.Down(ctx, total-1); err != nil {
+		t.Fatal(err)
+	}
+	if n := countProducts(t, db, "1=1"); n != 0 {
+		t.Errorf("после отката 002 товаров %d, want 0", n)
+	}
+}
+
+// БД из старого 001_schema.sql: products и демо-товары уже есть, schema_migrations нет.
+func TestSeedProductsAdoptsExistingDB(t *testing.T) {
+	db := newMemoryDB(t)
+	db.MustExec(`CREATE TABLE products (
+		id INTEGER PRIMARY KEY AUTOINCREMENT, name TEXT NOT NULL, article TEXT NOT NULL,
+		price REAL NOT NULL, image_alt TEXT, created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP)`)
+	db.MustExec(`INSERT INTO products (name, article, price) VALUES
+		('Старый смартфон', 'ART-001', 1), ('Старый ноутбук', 'ART-002', 2), ('Свой товар', 'OWN-1', 3)`)
+
+	m, total := newTestMigrations(t, db)
+	ctx := context.Background()
+	if err := m.RunMigrations(); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, a := range []string{"ART-001", "ART-002", "ART-003", "ART-004", "ART-005"} {
+		if n := countProducts(t, db, "article = ?", a); n != 1 {
+			t.Errorf("%s: %d строк, want 1", a, n)
+		}
+	}
+	if n := countProducts(t, db, "name = 'Старый смартфон'"); n != 1 {
+		t.Error("существующий товар перезаписан")
+	}
+
+	// Откат 002 удаляет только добавленные им ART-003..005
+	if _, err := m.Down(ctx, total-1); err != nil {
+		t.Fatal(err)
+	}
+	var left []string
+	if err := db.Select(&left, `SELECT article FROM products ORDER BY article`); err != nil {
+		t.Fatal(err)
+	}
+	if got := len(left); got != 3 || left[0] != "ART-001" || left[1] != "ART-002" || left[2] != "OWN-1" {
+		t.Errorf("после отката: %v, want [ART-001 ART-002 OWN-1]", left)
+	}
+}
@@ -0,0 +1,189 @@
+package storage
+
+// internal/storage/orders_repo.go — заказы (orders + order_items).
+//
+// Название, артикул и цена товара копируются в order_items в момент заказа
+// (INSERT ... SELECT в той же транзакции): последующие правки товара в админке
+// заказ не меняют. Сумма считается в SQL (DECIMAL в MySQL) и округляется до копеек.
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"math"
+	"time"
+
+	"myApp/internal/core"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// Статусы заказа
+const (
+	OrderNew = "new"
+)
+
+// ErrProductUnavailable — товар из корзины удалён до оформления заказа.
+var ErrProductUnavailable = errors.New("product unavailable")
+
+// ErrEmptyOrder — заказ без позиций.
+var ErrEmptyOrder = errors.New("empty order")
+
+type Order struct {
+	ID        int64       `db:"id" json:"id"`
+	Token     string      `db:"token" json:"-"` // ссылка на страницу заказа: /order/<token>
+	UserID    *int64      `db:"user_id" json:"user_id,omitempty"`
+	Name      string      `db:"name" json:"name"`
+	Email     string      `db:"email" json:"email"`
+	Phone     string      `db:"phone" json:"phone"`
+	Address   string      `db:"address" json:"address"`
+	Comment   *string     `db:"comment" json:"comment,omitempty"`
+	Status    string      `db:"status" json:"status"`
+	Total     float64     `db:"total" json:"total"`
+	CreatedAt time.Time   `db:"created_at" json:"created_at"`
+	Items     []OrderItem `db:"-" json:"items"`
+}
+
+// OrderItem — позиция заказа; ProductID — nil, если товар потом удалили.
+type OrderItem struct {
+	ID        int64   `db:"id" json:"id"`
+	OrderID   int64   `db:"order_id" json:"-"`
+	ProductID *int64  `db:"product_id" json:"product_id,omitempty"`
+	Name      string  `db:"name" json:"name"`
+	Article   string  `db:"article" json:"article"`
+	Price     float64 `db:"price" json:"price"` // цена на момент заказа
+	Qty       int     `db:"qty" json:"qty"`
+}
+
+// Sum — цена × количество (в центах, как и total в БД, без накопления ошибки float).
+func (it OrderItem) Sum() float64 {
+	return float64(int64(math.Round(it.Price*100))*int64(it.Qty)) / 100
+}
+
+// OrderLine — товар и количество из корзины.
+type OrderLine struct {
+	ProductID int
+	Qty       int
+}
+
+// OrderInput — данные формы оформления и содержимое корзины.
+type OrderInput struct {
+	UserID  *int64
+	Name    string
+	Email   string
+	Phone   string
+	Address string
+	Comment *string
+	Lines   []OrderLine
+}
+
+type OrderRepository interface {
+	// Create — заказ и его позиции одной транзакцией, возвращает заказ без Items.
+	// ErrProductUnavailable — какого-то товара уже нет (ничего не сохраняется).
+	Create(ctx context.Context, in OrderInput) (*Order, error)
+	// ByToken — заказ с позициями; sql.ErrNoRows — нет такого.
+	ByToken(ctx context.Context, token string) (*Order, error)
+}
+
+type SQLOrderRepository struct {
+	db *sqlx.DB
+}
+
+var _ OrderRepository = (*SQLOrderRepository)(nil)
+
+func NewOrderRepository(db *sqlx.DB) *SQLOrderRepository {
+	return &SQLOrderRepository{db: db}
+}
+
+const orderColumns = `id, token, user_id, name, email, phone, address, comment, status, total, created_at`
+
+func (r *SQLOrderRepository) Create(ctx context.Context, in OrderInput) (*Order, error) {
+	if len(in.Lines) == 0 {
+		return nil, ErrEmptyOrder
+	}
+	token, err := orderToken()
+	if err != nil {
+		return nil, err
+	}
+
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	res, err := tx.ExecContext(ctx,
+		`INSERT INTO orders (token, user_id, name, email, phone, address, comment, status) VALUES (?, ?, ?, ?, ?, ?, ?, ?)`,
+		token, in.UserID, in.Name, in.Email, in.Phone, in.Address, in.Comment, OrderNew)
+	if err != nil {
+		return nil, r.writeError(ctx, "create order", 0, err)
+	}
+	id, err := res.LastInsertId()
+	if err != nil {
+		return nil, err
+	}
+
+	for _, l := range in.Lines {
+		res, err := tx.ExecContext(ctx,
+			`INSERT INTO order_items (order_id, product_id, name, article, price, qty)
+			 SELECT ?, p.id, p.name, p.article, p.price, ? FROM products p WHERE p.id = ?`,
+			id, l.Qty, l.ProductID)
+		if err != nil {
+			return nil, r.writeError(ctx, "create order item", id, err)
+		}
+		if n, err := res.RowsAffected(); err != nil {
+			return nil, err
+		} else if n == 0 {
+			return nil, fmt.Errorf("%w: %d", ErrProductUnavailable, l.ProductID)
+		}
+	}
+
+	// ROUND: в MySQL сумма DECIMAL и так точна, а в SQLite NUMERIC хранится
+	// как REAL — без округления 0.1*3 + 0.7*3 даёт 2.3999999999999995
+	if _, err := tx.ExecContext(ctx,
+		`UPDATE orders SET total = (SELECT ROUND(COALESCE(SUM(price * qty), 0), 2) FROM order_items WHERE order_id = ?) WHERE id = ?`,
+		id, id); err != nil {
+		return nil, r.writeError(ctx, "order total", id, err)
+	}
+
+	var o Order
+	if err := tx.GetContext(ctx, &o, `SELECT `+orderColumns+` FROM orders WHERE id = ?`, id); err != nil {
+		return nil, err
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	return &o, nil
+}
+
+func (r *SQLOrderRepository) ByToken(ctx context.Context, token string) (*Order, error) {
+	var o Order
+	if err := r.db.GetContext(ctx, &o, `SELECT `+orderColumns+` FROM orders WHERE token = ?`, token); err != nil {
+		return nil, err
+	}
+	if err := r.db.SelectContext(ctx, &o.Items,
+		`SELECT id, order_id, product_id, name, article, price, qty FROM order_items WHERE order_id = ? ORDER BY id`,
+		o.ID); err != nil {
+		core.LogErrorCtx(ctx, "list order items", map[string]interface{}{"order_id": o.ID, "error": err.Error()})
+		return nil, err
+	}
+	return &o, nil
+}
+
+func (r *SQLOrderRepository) writeError(ctx context.Context, op string, id int64, err error) error {
+	core.LogErrorCtx(ctx, op, map[string]interface{}{
+		"order_id": id,
+		"error":    err.Error(),
+	})
+	return err
+}
+
+// orderToken — 128 бит случайности: номер заказа в URL не перебирается.
+func orderToken() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
@@ -0,0 +1,104 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/jmoiron/sqlx"
+)
+
+func newTestOrders(t *testing.T) (*SQLOrderRepository, *sqlx.DB) {
+	t.Helper()
+	db := newMemoryDB(t)
+	m, _ := newTestMigrations(t, db)
+	if err := m.RunMigrations(); err != nil {
+		t.Fatal(err)
+	}
+	return NewOrderRepository(db), db
+}
+
+func insertProduct(t *testing.T, db *sqlx.DB, name, article string, price float64) int {
+	t.Helper()
+	res, err := db.Exec(`INSERT INTO products (name, article, price) VALUES (?, ?, ?)`, name, article, price)
+	if err != nil {
+		t.Fatal(err)
+	}
+	id, err := res.LastInsertId()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return int(id)
+}
+
+func countRows(t *testing.T, db *sqlx.DB, table string) int {
+	t.Helper()
+	var n int
+	if err := db.Get(&n, `SELECT COUNT(*) FROM `+table); err != nil {
+		t.Fatal(err)
+	}
+	return n
+}
+
+func testOrderInput(lines ...OrderLine) OrderInput {
+	return OrderInput{Name: "Иван", Email: "ivan@example.com", Phone: "+7 900 000-00-00", Address: "Москва", Lines: lines}
+}
+
+func TestOrderCreateSnapshotsPrices(t *testing.T) {
+	r, db := newTestOrders(t)
+	ctx := context.Background()
+	a := insertProduct(t, db, "Кабель", "T-1", 0.10)
+	b := insertProduct(t, db, "Переходник", "T-2", 0.70)
+
+	o, err := r.Create(ctx, testOrderInput(OrderLine{ProductID: a, Qty: 3}, OrderLine{ProductID: b, Qty: 3}))
+	if err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+	// 0.1*3 + 0.7*3 в float64 — 2.3999999999999995; сумма должна быть до копейки
+	if o.Total != 2.4 {
+		t.Errorf("total %v, want 2.4", o.Total)
+	}
+	if o.Status != OrderNew || len(o.Token) != 32 || o.ID == 0 {
+		t.Errorf("заказ %+v", o)
+	}
+
+	// Правка товара после заказа не меняет позиции заказа
+	db.MustExec(`UPDATE products SET name = 'Кабель 2', price = 99 WHERE id = ?`, a)
+
+	got, err := r.ByToken(ctx, o.Token)
+	if err != nil {
+		t.Fatalf("ByToken: %v", err)
+	}
+	if got.Total != 2.4 || len(got.Items) != 2 {
+		t.Fatalf("заказ %+v", got)
+	}
+	first := got.Items[0]
+	if first.Name != "Кабель" || first.Article != "T-1" || first.Price != 0.10 || first.Qty != 3 ||
+		first.ProductID == nil || *first.ProductID != int64(a) {
+		t.Errorf("позиция %+v", first)
+	}
+	if first.Sum() != 0.3 {
+		t.Errorf("Sum %v, want 0.3", first.Sum())
+	}
+}
+
+func TestOrderCreateRollsBackUnavailableProduct(t *testing.T) {
+	r, db := newTestOrders(t)
+	ctx := context.Background()
+	a := insertProduct(t, db, "Кабель", "T-1", 10)
+
+	_, err := r.Create(ctx, testOrderInput(OrderLine{ProductID: a, Qty: 1}, OrderLine{ProductID: 999999, Qty: 1}))
+	if !errors.Is(err, ErrProductUnavailable) {
+		t.Fatalf("err = %v, want ErrProductUnavailable", err)
+	}
+	if n := countRows(t, db, "orders"); n != 0 {
+		t.Errorf("заказов %d после отката, want 0", n)
+	}
+	if n := countRows(t, db, "order_items"); n != 0 {
+		t.Errorf("позиций %d после отката, want 0", n)
+	}
+
+	if _, err := r.Create(ctx, testOrderInput()); !errors.Is(err, ErrEmptyOrder) {
+		t.Errorf("пустой заказ: %v, want ErrEmptyOrder", err)
+	}
+}
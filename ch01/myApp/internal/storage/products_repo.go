@@ -3,55 +3,255 @@ package storage
 // internal/storage/ products_repo.go
 import (
 	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
 	"myApp/internal/core"
+	"strconv"
+	"strings"
 	"time"
+	"unicode"
 
 	"github.com/jmoiron/sqlx"
 )
 
 type Product struct {
 	ID         string    `db:"id" json:"id"`
-	CategoryID *string   `db:"category_id" json:"category_id,omitempty"`
+	CategoryID *int64    `db:"category_id" json:"category_id,omitempty"`
 	Name       string    `db:"name" json:"name"`
 	Article    string    `db:"article" json:"article"`
 	Price      float64   `db:"price" json:"price"`
 	ImageAlt   *string   `db:"image_alt" json:"image_alt,omitempty"`
+	ImagePath  *string   `db:"image_path" json:"image_path,omitempty"` // относительно UPLOAD_DIR, URL: /uploads/<path>
 	CreatedAt  time.Time `db:"created_at" json:"created_at"`
 }
 
-func ListAllProducts(ctx context.Context, db *sqlx.DB) ([]Product, error) {
-	const q = `
-		SELECT p.id, p.name, p.price, p.image_alt
-		FROM products p
-		ORDER BY p.name ASC`
+// ProductInput — поля товара, которые задаются из админки.
+type ProductInput struct {
+	CategoryID *int64
+	Name       string
+	Article    string
+	Price      float64
+	ImageAlt   *string
+	ImagePath  *string
+}
+
+// Сортировки каталога (?sort=...)
+const (
+	SortNameAsc   = "name"   // по названию А→Я (по умолчанию)
+	SortNameDesc  = "-name"  // по названию Я→А
+	SortPriceAsc  = "price"  // сначала дешёвые
+	SortPriceDesc = "-price" // сначала дорогие
+	SortNewest    = "new"    // сначала новые
+)
+
+// Размер страницы каталога (?limit=...)
+const (
+	DefaultPageSize = 12
+	MaxPageSize     = 100
+)
+
+// ErrInvalidCursor — курсор повреждён или выдан для другой сортировки.
+var ErrInvalidCursor = errors.New("invalid cursor")
+
+// ProductFilter — параметры выборки каталога. Пустые поля не фильтруют.
+type ProductFilter struct {
+	Query       string   // поиск по названию и артикулу
+	CategoryIDs []int64  // товары любой из категорий (см. SubtreeIDs)
+	MinPrice    *float64 // цена от (включительно)
+	MaxPrice    *float64 // цена до (включительно)
+	Sort        string   // Sort*; пусто — SortNameAsc
+	Limit       int      // 1..MaxPageSize; 0 — DefaultPageSize
+	Cursor      string   // Next или Prev из предыдущей страницы
+}
+
+// ProductPage — страница каталога. Next/Prev — курсоры соседних страниц
+// (пусто — страницы нет).
+type ProductPage struct {
+	Items []Product `json:"items"`
+	Next  string    `json:"next,omitempty"`
+	Prev  string    `json:"prev,omitempty"`
+}
+
+// ProductRepository — доступ к товарам (handler'ы зависят от интерфейса, не от SQL).
+// Изменения пишутся в audit_log в той же транзакции.
+type ProductRepository interface {
+	List(ctx context.Context, f ProductFilter) (*ProductPage, error)
+	GetByID(ctx context.Context, id int) (*Product, error)
+	ByIDs(ctx context.Context, ids []int) ([]Product, error) // отсутствующие id пропускаются
+	Create(ctx context.Context, in ProductInput, actor Actor) (int64, error)
+	Update(ctx context.Context, id int, in ProductInput, actor Actor) error // sql.ErrNoRows — товара нет
+	Delete(ctx context.Context, id int, actor Actor) (*Product, error)      // возвращает удалённый товар
+}
+
+// SQLProductRepository — реализация для MySQL и SQLite.
+type SQLProductRepository struct {
+	db *sqlx.DB
+}
+
+var _ ProductRepository = (*SQLProductRepository)(nil)
+
+func NewProductRepository(db *sqlx.DB) *SQLProductRepository {
+	return &SQLProductRepository{db: db}
+}
+
+// productSort — колонка сортировки; пустая column — только по id.
+// id всегда добавляется вторым ключом, чтобы порядок был однозначным.
+type productSort struct {
+	column string
+	desc   bool
+}
+
+var productSorts = map[string]productSort{
+	SortNameAsc:   {column: "p.name"},
+	SortNameDesc:  {column: "p.name", desc: true},
+	SortPriceAsc:  {column: "p.price"},
+	SortPriceDesc: {column: "p.price", desc: true},
+	SortNewest:    {desc: true}, // id растёт вместе с created_at
+}
+
+// ValidProductSort — поддерживается ли значение ?sort=.
+func ValidProductSort(s string) bool {
+	_, ok := productSorts[s]
+	return ok
+}
+
+const productColumns = `p.id, p.category_id, p.name, p.article, p.price, p.image_alt, p.image_path, p.created_at`
+
+// List — keyset-пагинация: вместо OFFSET курсор хранит ключ сортировки
+// и id крайнего товара, следующая страница начинается строго после него.
+// Скорость не зависит от номера страницы, вставки не сдвигают выдачу.
+func (r *SQLProductRepository) List(ctx context.Context, f ProductFilter) (*ProductPage, error) {
+	if f.Sort == "" {
+		f.Sort = SortNameAsc
+	}
+	sort, ok := productSorts[f.Sort]
+	if !ok {
+		return nil, fmt.Errorf("unknown sort %q", f.Sort)
+	}
+	if f.Limit <= 0 {
+		f.Limit = DefaultPageSize
+	}
+	if f.Limit > MaxPageSize {
+		f.Limit = MaxPageSize
+	}
 
-	var items []Product
+	var cur *productCursor
+	if f.Cursor != "" {
+		c, err := decodeProductCursor(f.Cursor, f.Sort)
+		if err != nil {
+			return nil, err
+		}
+		cur = c
+	}
+
+	var (
+		where []string
+		args  []interface{}
+	)
+	if cond, condArgs := searchCondition(r.db.DriverName(), f.Query); cond != "" {
+		where = append(where, cond)
+		args = append(args, condArgs...)
+	}
+	if len(f.CategoryIDs) > 0 {
+		where = append(where, "p.category_id IN (?"+strings.Repeat(", ?", len(f.CategoryIDs)-1)+")")
+		for _, id := range f.CategoryIDs {
+			args = append(args, id)
+		}
+	}
+	if f.MinPrice != nil {
+		where = append(where, "p.price >= ?")
+		args = append(args, *f.MinPrice)
+	}
+	if f.MaxPrice != nil {
+		where = append(where, "p.price <= ?")
+		args = append(args, *f.MaxPrice)
+	}
+
+	// Назад (Prev) — тот же запрос в обратном порядке, результат переворачиваем
+	backward := cur != nil && cur.Back
+	desc := sort.desc != backward
+	if cur != nil {
+		op := ">"
+		if desc {
+			op = "<"
+		}
+		if sort.column == "" {
+			where = append(where, "p.id "+op+" ?")
+			args = append(args, cur.ID)
+		} else {
+			where = append(where, "("+sort.column+" "+op+" ? OR ("+sort.column+" = ? AND p.id "+op+" ?))")
+			args = append(args, cur.Key, cur.Key, cur.ID)
+		}
+	}
 
-	// context.Context — “контейнер” для управления временем жизни операции и передачи метаданных.
-	// db.SelectContext - Возвращает много строк (срез структур)
-	if err := db.SelectContext(ctx, &items, q); err != nil {
-		core.LogError("list all products", map[string]interface{}{
+	dir := "ASC"
+	if desc {
+		dir = "DESC"
+	}
+	order := "p.id " + dir
+	if sort.column != "" {
+		order = sort.column + " " + dir + ", " + order
+	}
+
+	q := `SELECT ` + productColumns + ` FROM products p`
+	if len(where) > 0 {
+		q += ` WHERE ` + strings.Join(where, " AND ")
+	}
+	q += ` ORDER BY ` + order + ` LIMIT ?`
+	args = append(args, f.Limit+1) // +1 — узнать, есть ли ещё страница
+
+	items := make([]Product, 0, f.Limit+1)
+	if err := r.db.SelectContext(ctx, &items, q, args...); err != nil {
+		core.LogErrorCtx(ctx, "list products", map[string]interface{}{
 			"query": q,
 			"error": err.Error(),
 		})
 		return nil, err
 	}
-	return items, nil
+
+	more := len(items) > f.Limit
+	if more {
+		items = items[:f.Limit]
+	}
+	if backward {
+		for i, j := 0, len(items)-1; i < j; i, j = i+1, j-1 {
+			items[i], items[j] = items[j], items[i]
+		}
+	}
+
+	page := &ProductPage{Items: items}
+	if len(items) == 0 {
+		return page, nil
+	}
+
+	hasNext, hasPrev := more, cur != nil
+	if backward {
+		hasNext, hasPrev = true, more
+	}
+	if hasNext {
+		page.Next = encodeProductCursor(f.Sort, items[len(items)-1], false)
+	}
+	if hasPrev {
+		page.Prev = encodeProductCursor(f.Sort, items[0], true)
+	}
+	return page, nil
 }
 
-// GetProductByID — находим товар по ID
+// GetByID — находим товар по ID
 // context.Context — “контейнер” для управления временем жизни операции и передачи метаданных.
 // db.GetContext - Возвращает одну строку (один объект).
-func GetProductByID(ctx context.Context, db *sqlx.DB, id int) (*Product, error) {
+func (r *SQLProductRepository) GetByID(ctx context.Context, id int) (*Product, error) {
 	var p Product
 
 	const q = `
-		SELECT id, name, article, price, image_alt
-		FROM products 
-		WHERE id = ?`
+		SELECT ` + productColumns + `
+		FROM products p
+		WHERE p.id = ?`
 
-	if err := db.GetContext(ctx, &p, q, id); err != nil {
-		core.LogError("get product by id", map[string]interface{}{
+	if err := r.db.GetContext(ctx, &p, q, id); err != nil {
+		core.LogErrorCtx(ctx, "get product by id", map[string]interface{}{
 			"id":    id,
 			"error": err.Error(),
 			"query": q,
@@ -60,3 +260,232 @@ func GetProductByID(ctx context.Context, db *sqlx.DB, id int) (*Product, error)
 	}
 	return &p, nil
 }
+
+// ByIDs — товары с указанными id (для корзины), порядок не гарантируется.
+func (r *SQLProductRepository) ByIDs(ctx context.Context, ids []int) ([]Product, error) {
+	if len(ids) == 0 {
+		return nil, nil
+	}
+	q := `SELECT ` + productColumns + ` FROM products p WHERE p.id IN (?` + strings.Repeat(", ?", len(ids)-1) + `)`
+	args := make([]interface{}, len(ids))
+	for i, id := range ids {
+		args[i] = id
+	}
+
+	items := make([]Product, 0, len(ids))
+	if err := r.db.SelectContext(ctx, &items, q, args...); err != nil {
+		core.LogErrorCtx(ctx, "get products by ids", map[string]interface{}{"ids": ids, "error": err.Error()})
+		return nil, err
+	}
+	return items, nil
+}
+
+// Create — новый товар, возвращает его id.
+func (r *SQLProductRepository) Create(ctx context.Context, in ProductInput, actor Actor) (int64, error) {
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return 0, err
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	res, err := tx.ExecContext(ctx,
+		`INSERT INTO products (category_id, name, article, price, image_alt, image_path) VALUES (?, ?, ?, ?, ?, ?)`,
+		in.CategoryID, in.Name, in.Article, in.Price, in.ImageAlt, in.ImagePath)
+	if err != nil {
+		return 0, r.writeError(ctx, "create product", 0, err)
+	}
+	id, err := res.LastInsertId()
+	if err != nil {
+		return 0, err
+	}
+
+	if err := writeAudit(ctx, tx, actor, AuditCreate, "product", id, in.fields()); err != nil {
+		return 0, r.writeError(ctx, "audit create product", id, err)
+	}
+	return id, tx.Commit()
+}
+
+// Update — меняет все поля товара; в журнал попадают только изменившиеся.
+func (r *SQLProductRepository) Update(ctx context.Context, id int, in ProductInput, actor Actor) error {
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	var old Product
+	if err := tx.GetContext(ctx, &old, `SELECT `+productColumns+` FROM products p WHERE p.id = ?`, id); err != nil {
+		return err
+	}
+
+	if _, err := tx.ExecContext(ctx,
+		`UPDATE products SET category_id = ?, name = ?, article = ?, price = ?, image_alt = ?, image_path = ? WHERE id = ?`,
+		in.CategoryID, in.Name, in.Article, in.Price, in.ImageAlt, in.ImagePath, id); err != nil {
+		return r.writeError(ctx, "update product", int64(id), err)
+	}
+
+	oldFields, newFields := old.fields(), in.fields()
+	changes := map[string]FieldChange{}
+	for k, v := range newFields {
+		if oldFields[k] != v {
+			changes[k] = FieldChange{Old: oldFields[k], New: v}
+		}
+	}
+	if len(changes) > 0 {
+		if err := writeAudit(ctx, tx, actor, AuditUpdate, "product", int64(id), changes); err != nil {
+			return r.writeError(ctx, "audit update product", int64(id), err)
+		}
+	}
+	return tx.Commit()
+}
+
+// Delete — удаляет товар; файл картинки удаляет вызывающий (после успеха).
+func (r *SQLProductRepository) Delete(ctx context.Context, id int, actor Actor) (*Product, error) {
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	var old Product
+	if err := tx.GetContext(ctx, &old, `SELECT `+productColumns+` FROM products p WHERE p.id = ?`, id); err != nil {
+		return nil, err
+	}
+	if _, err := tx.ExecContext(ctx, `DELETE FROM products WHERE id = ?`, id); err != nil {
+		return nil, r.writeError(ctx, "delete product", int64(id), err)
+	}
+	if err := writeAudit(ctx, tx, actor, AuditDelete, "product", int64(id), old.fields()); err != nil {
+		return nil, r.writeError(ctx, "audit delete product", int64(id), err)
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	return &old, nil
+}
+
+func (r *SQLProductRepository) writeError(ctx context.Context, op string, id int64, err error) error {
+	core.LogErrorCtx(ctx, op, map[string]interface{}{
+		"id":    id,
+		"error": err.Error(),
+	})
+	return err
+}
+
+// fields — значения для журнала (указатели разыменованы, nil — пусто).
+func (in ProductInput) fields() map[string]interface{} {
+	return map[string]interface{}{
+		"category_id": derefInt64(in.CategoryID),
+		"name":        in.Name,
+		"article":     in.Article,
+		"price":       in.Price,
+		"image_alt":   derefString(in.ImageAlt),
+		"image_path":  derefString(in.ImagePath),
+	}
+}
+
+func (p Product) fields() map[string]interface{} {
+	return ProductInput{
+		CategoryID: p.CategoryID,
+		Name:       p.Name,
+		Article:    p.Article,
+		Price:      p.Price,
+		ImageAlt:   p.ImageAlt,
+		ImagePath:  p.ImagePath,
+	}.fields()
+}
+
+func derefInt64(v *int64) interface{} {
+	if v == nil {
+		return nil
+	}
+	return *v
+}
+
+func derefString(v *string) interface{} {
+	if v == nil {
+		return nil
+	}
+	return *v
+}
+
+// searchCondition — поиск по словам запроса, все слова обязательны.
+// MySQL: FULLTEXT (ft_products_search) в BOOLEAN MODE с префиксным совпадением.
+// SQLite: LIKE по подстроке (без учёта регистра только для латиницы).
+// Запрос режется на буквы/цифры, поэтому операторы MATCH и % _ в него не попадают.
+func searchCondition(driver, query string) (string, []interface{}) {
+	terms := strings.FieldsFunc(query, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	if len(terms) == 0 {
+		return "", nil
+	}
+	if len(terms) > 8 {
+		terms = terms[:8]
+	}
+
+	if driver == "mysql" {
+		var b strings.Builder
+		for i, t := range terms {
+			if i > 0 {
+				b.WriteByte(' ')
+			}
+			b.WriteString("+" + t + "*")
+		}
+		return "MATCH(p.name, p.article) AGAINST (? IN BOOLEAN MODE)", []interface{}{b.String()}
+	}
+
+	conds := make([]string, 0, len(terms))
+	args := make([]interface{}, 0, 2*len(terms))
+	for _, t := range terms {
+		conds = append(conds, "(p.name LIKE ? OR p.article LIKE ?)")
+		args = append(args, "%"+t+"%", "%"+t+"%")
+	}
+	return strings.Join(conds, " AND "), args
+}
+
+// productCursor — непрозрачный для клиента курсор (base64url от JSON).
+type productCursor struct {
+	Sort string      `json:"s"`
+	Key  interface{} `json:"k,omitempty"` // значение колонки сортировки
+	ID   int64       `json:"i"`
+	Back bool        `json:"b,omitempty"` // курсор на предыдущую страницу
+}
+
+func encodeProductCursor(sort string, p Product, back bool) string {
+	id, _ := strconv.ParseInt(p.ID, 10, 64)
+	c := productCursor{Sort: sort, ID: id, Back: back}
+	switch productSorts[sort].column {
+	case "p.name":
+		c.Key = p.Name
+	case "p.price":
+		c.Key = p.Price
+	}
+	b, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+func decodeProductCursor(s, sort string) (*productCursor, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+	var c productCursor
+	if err := json.Unmarshal(raw, &c); err != nil || c.Sort != sort {
+		return nil, ErrInvalidCursor
+	}
+
+	// Тип ключа должен соответствовать колонке — иначе сравнение в SQL бессмысленно
+	switch productSorts[sort].column {
+	case "p.name":
+		if _, ok := c.Key.(string); !ok {
+			return nil, ErrInvalidCursor
+		}
+	case "p.price":
+		if _, ok := c.Key.(float64); !ok {
+			return nil, ErrInvalidCursor
+		}
+	default:
+		c.Key = nil
+	}
+	return &c, nil
+}
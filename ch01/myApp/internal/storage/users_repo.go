@@ -0,0 +1,133 @@
+package storage
+
+// internal/storage/users_repo.go — пользователи (users)
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"strings"
+	"time"
+
+	"myApp/internal/core"
+
+	"github.com/go-sql-driver/mysql"
+	"github.com/jmoiron/sqlx"
+)
+
+// Роли пользователей
+const (
+	RoleUser  = "user"
+	RoleAdmin = "admin"
+)
+
+// ErrEmailTaken — пользователь с таким email уже есть.
+var ErrEmailTaken = errors.New("email already registered")
+
+type User struct {
+	ID           int64      `db:"id" json:"id"`
+	Email        string     `db:"email" json:"email"`
+	Name         string     `db:"name" json:"name"`
+	PasswordHash string     `db:"password_hash" json:"-"`
+	Role         string     `db:"role" json:"role"`
+	CreatedAt    time.Time  `db:"created_at" json:"created_at"`
+	LastLoginAt  *time.Time `db:"last_login_at" json:"last_login_at,omitempty"`
+}
+
+// HasRole — true, если роль пользователя входит в roles.
+func (u *User) HasRole(roles ...string) bool {
+	for _, r := range roles {
+		if u.Role == r {
+			return true
+		}
+	}
+	return false
+}
+
+// UserRepository — доступ к пользователям. Email хранится в нижнем регистре.
+type UserRepository interface {
+	Create(ctx context.Context, u *User) error // заполняет u.ID; ErrEmailTaken — email занят
+	ByID(ctx context.Context, id int64) (*User, error)
+	ByEmail(ctx context.Context, email string) (*User, error) // sql.ErrNoRows — нет такого
+	SetRole(ctx context.Context, id int64, role string) error
+	SetPasswordHash(ctx context.Context, id int64, hash string) error
+	TouchLogin(ctx context.Context, id int64) error
+}
+
+type SQLUserRepository struct {
+	db *sqlx.DB
+}
+
+var _ UserRepository = (*SQLUserRepository)(nil)
+
+func NewUserRepository(db *sqlx.DB) *SQLUserRepository {
+	return &SQLUserRepository{db: db}
+}
+
+const userColumns = `id, email, name, password_hash, role, created_at, last_login_at`
+
+// NormalizeEmail — email сравнивается без учёта регистра и пробелов по краям.
+func NormalizeEmail(email string) string {
+	return strings.ToLower(strings.TrimSpace(email))
+}
+
+func (r *SQLUserRepository) Create(ctx context.Context, u *User) error {
+	u.Email = NormalizeEmail(u.Email)
+	if u.Role == "" {
+		u.Role = RoleUser
+	}
+	res, err := r.db.ExecContext(ctx,
+		`INSERT INTO users (email, name, password_hash, role) VALUES (?, ?, ?, ?)`,
+		u.Email, u.Name, u.PasswordHash, u.Role)
+	if err != nil {
+		if isUniqueViolation(err) {
+			return ErrEmailTaken
+		}
+		core.LogErrorCtx(ctx, "create user", map[string]interface{}{"error": err.Error()})
+		return err
+	}
+	u.ID, err = res.LastInsertId()
+	return err
+}
+
+func (r *SQLUserRepository) ByID(ctx context.Context, id int64) (*User, error) {
+	return r.get(ctx, `SELECT `+userColumns+` FROM users WHERE id = ?`, id)
+}
+
+func (r *SQLUserRepository) ByEmail(ctx context.Context, email string) (*User, error) {
+	return r.get(ctx, `SELECT `+userColumns+` FROM users WHERE email = ?`, NormalizeEmail(email))
+}
+
+func (r *SQLUserRepository) SetRole(ctx context.Context, id int64, role string) error {
+	_, err := r.db.ExecContext(ctx, `UPDATE users SET role = ? WHERE id = ?`, role, id)
+	return err
+}
+
+func (r *SQLUserRepository) SetPasswordHash(ctx context.Context, id int64, hash string) error {
+	_, err := r.db.ExecContext(ctx, `UPDATE users SET password_hash = ? WHERE id = ?`, hash, id)
+	return err
+}
+
+func (r *SQLUserRepository) TouchLogin(ctx context.Context, id int64) error {
+	_, err := r.db.ExecContext(ctx, `UPDATE users SET last_login_at = CURRENT_TIMESTAMP WHERE id = ?`, id)
+	return err
+}
+
+func (r *SQLUserRepository) get(ctx context.Context, q string, arg interface{}) (*User, error) {
+	var u User
+	if err := r.db.GetContext(ctx, &u, q, arg); err != nil {
+		if !errors.Is(err, sql.ErrNoRows) {
+			core.LogErrorCtx(ctx, "get user", map[string]interface{}{"error": err.Error()})
+		}
+		return nil, err
+	}
+	return &u, nil
+}
+
+// isUniqueViolation — нарушение UNIQUE: MySQL 1062, SQLite — по тексту ошибки.
+func isUniqueViolation(err error) bool {
+	var me *mysql.MySQLError
+	if errors.As(err, &me) {
+		return me.Number == 1062
+	}
+	return strings.Contains(err.Error(), "UNIQUE constraint failed")
+}
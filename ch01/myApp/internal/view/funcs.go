@@ -0,0 +1,63 @@
+package view
+
+// internal/view/funcs.go — функции шаблонов: {{price .Price}}, {{asset "css/style.css"}},
+// {{plural .Count "товар" "товара" "товаров"}}.
+
+import (
+	"html/template"
+	"math"
+	"strconv"
+	"strings"
+)
+
+func (t *Templates) funcMap() template.FuncMap {
+	return template.FuncMap{
+		"price":  Price,
+		"asset":  t.assetURL, // см. Options.AssetURL
+		"plural": Plural,
+	}
+}
+
+// Price — цена по-русски: "1 498,50 €" (тысячи и валюта — через неразрывный пробел).
+func Price(v float64) string {
+	cents := int64(math.Round(v * 100))
+	sign := ""
+	if cents < 0 {
+		sign, cents = "-", -cents
+	}
+
+	digits := strconv.FormatInt(cents/100, 10)
+	var b strings.Builder
+	b.WriteString(sign)
+	for i, r := range digits {
+		if i > 0 && (len(digits)-i)%3 == 0 {
+			b.WriteRune('\u00a0')
+		}
+		b.WriteRune(r)
+	}
+	b.WriteByte(',')
+	frac := cents % 100
+	if frac < 10 {
+		b.WriteByte('0')
+	}
+	b.WriteString(strconv.FormatInt(frac, 10))
+	b.WriteString("\u00a0€")
+	return b.String()
+}
+
+// Plural — форма слова для числа n: 1 товар, 2 товара, 5 товаров, 21 товар, 11 товаров.
+func Plural(n int, one, few, many string) string {
+	if n < 0 {
+		n = -n
+	}
+	switch {
+	case n%100 >= 11 && n%100 <= 14:
+		return many
+	case n%10 == 1:
+		return one
+	case n%10 >= 2 && n%10 <= 4:
+		return few
+	default:
+		return many
+	}
+}
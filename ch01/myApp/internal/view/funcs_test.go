@@ -0,0 +1,60 @@
+package view
+
+import "testing"
+
+func TestPlural(t *testing.T) {
+	tests := []struct {
+		n    int
+		want string
+	}{
+		{0, "товаров"},
+		{1, "товар"},
+		{2, "товара"},
+		{4, "товара"},
+		{5, "товаров"},
+		{11, "товаров"},
+		{12, "товаров"},
+		{14, "товаров"},
+		{21, "товар"},
+		{22, "товара"},
+		{25, "товаров"},
+		{101, "товар"},
+		{111, "товаров"},
+		{112, "товаров"},
+		{121, "товар"},
+		{-1, "товар"},
+		{-11, "товаров"},
+	}
+	for _, tt := range tests {
+		if got := Plural(tt.n, "товар", "товара", "товаров"); got != tt.want {
+			t.Errorf("Plural(%d) = %q, want %q", tt.n, got, tt.want)
+		}
+	}
+}
+
+func TestPrice(t *testing.T) {
+	const nbsp = "\u00a0"
+	tests := []struct {
+		v    float64
+		want string
+	}{
+		{0, "0,00" + nbsp + "€"},
+		{1.5, "1,50" + nbsp + "€"},
+		{0.07, "0,07" + nbsp + "€"},
+		{999.99, "999,99" + nbsp + "€"},
+		{1000, "1" + nbsp + "000,00" + nbsp + "€"},
+		{1498.5, "1" + nbsp + "498,50" + nbsp + "€"},
+		{1234567.891, "1" + nbsp + "234" + nbsp + "567,89" + nbsp + "€"},
+		{0.1 + 0.2, "0,30" + nbsp + "€"},
+		{2.675, "2,68" + nbsp + "€"},
+		{9.999, "10,00" + nbsp + "€"}, // перенос в целую часть
+		{-5.5, "-5,50" + nbsp + "€"},
+		{-1234.5, "-1" + nbsp + "234,50" + nbsp + "€"},
+		{-0.001, "0,00" + nbsp + "€"}, // не "-0,00"
+	}
+	for _, tt := range tests {
+		if got := Price(tt.v); got != tt.want {
+			t.Errorf("Price(%v) = %q, want %q", tt.v, got, tt.want)
+		}
+	}
+}
@@ -5,61 +5,200 @@ package view
 import (
 	"fmt"
 	"html/template"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"sync"
+	"sync/atomic"
 
 	"myApp/internal/core"
 
+	"github.com/fsnotify/fsnotify"
 	"github.com/gin-gonic/gin"
 	csrf "github.com/utrack/gin-csrf"
 )
 
 // Templates — хранилище всех HTML-шаблонов в памяти.
-// Ключ — имя страницы (например, "home").
+// Ключ — имя страницы (имя файла в pages/ без .html, например "home").
 type Templates struct {
+	fsys  fs.FS
+	funcs template.FuncMap
+
+	mu        sync.RWMutex
 	templates map[string]*template.Template
+	dirty     atomic.Bool // dev: файлы изменились, перечитать при следующем Render
+	watcher   *fsnotify.Watcher
+
+	assetURL func(string) string // {{asset}}
+}
+
+// Options — откуда брать шаблоны и статику.
+type Options struct {
+	// WatchDir — каталог шаблонов на диске (тот же, что fsys); не пусто —
+	// следить за изменениями и перечитывать шаблоны (APP_ENV=dev).
+	WatchDir string
+	// AssetURL — URL файла статики для {{asset}} (например, с хэшем из assets.Pipeline);
+	// nil — "/assets/<путь>".
+	AssetURL func(name string) string
 }
 
 // PageData — структура данных, передаваемая в шаблоны.
 type PageData struct {
-	Title     string        // Заголовок страницы
-	CSRFField template.HTML // Скрытое поле <input> с CSRF-токеном (для защиты форм)
-	Nonce     string        // CSP nonce для inline-скриптов/стилей (для защиты от XSS)
-	Data      any           // Пользовательские данные, специфичные для страницы
+	Title      string        // Заголовок страницы
+	CSRFField  template.HTML // Скрытое поле <input> с CSRF-токеном (для защиты форм)
+	Nonce      string        // CSP nonce для inline-скриптов/стилей (для защиты от XSS)
+	Categories any           // Дерево категорий для меню (partial "category_nodes"), может быть nil
+	User       any           // Вошедший пользователь (*storage.User) или nil
+	CartCount  int           // Товаров в корзине (для меню)
+	Data       any           // Пользовательские данные, специфичные для страницы
 }
 
-// New — парсит layout, partials и страницы один раз при старте приложения и кэширует.
-func New() (*Templates, error) {
-	// Общие layout и частичные шаблоны, которые включаются во все страницы
-	layouts := []string{
-		"web/templates/layouts/base.gohtml",
-		"web/templates/partials/nav.gohtml",
-		"web/templates/partials/footer.gohtml",
+// New — находит и парсит шаблоны из fsys (layouts/, partials/, pages/) и кэширует.
+// Каждая страница парсится вместе со всеми layouts и partials.
+func New(fsys fs.FS, opts Options) (*Templates, error) {
+	t := &Templates{
+		fsys:     fsys,
+		assetURL: opts.AssetURL,
+	}
+	if t.assetURL == nil {
+		t.assetURL = func(name string) string { return "/assets/" + strings.TrimPrefix(name, "/") }
 	}
+	t.funcs = t.funcMap()
 
-	// Страницы -> файлы
-	pages := map[string][]string{
-		"home":     {"web/templates/pages/home.gohtml"},
-		"about":    {"web/templates/pages/about.gohtml"},
-		"form":     {"web/templates/pages/form.gohtml"},
-		"catalog":  {"web/templates/pages/catalog.gohtml"},
-		"product":  {"web/templates/pages/show_product.gohtml"},
-		"notfound": {"web/templates/pages/404.gohtml"},
+	set, err := t.parse()
+	if err != nil {
+		return nil, err
 	}
+	t.templates = set
+
+	if opts.WatchDir != "" {
+		if err := t.watch(opts.WatchDir); err != nil {
+			return nil, fmt.Errorf("наблюдение за шаблонами: %w", err)
+		}
+	}
+	return t, nil
+}
 
-	t := &Templates{templates: make(map[string]*template.Template)}
+// parse — собирает набор шаблонов: layouts + partials + одна страница.
+func (t *Templates) parse() (map[string]*template.Template, error) {
+	layouts, err := fs.Glob(t.fsys, "layouts/*.html")
+	if err != nil {
+		return nil, err
+	}
+	if len(layouts) == 0 {
+		return nil, fmt.Errorf("шаблоны: нет файлов layouts/*.html")
+	}
+	partials, err := fs.Glob(t.fsys, "partials/*.html")
+	if err != nil {
+		return nil, err
+	}
+	pages, err := fs.Glob(t.fsys, "pages/*.html")
+	if err != nil {
+		return nil, err
+	}
 
-	for name, pageFiles := range pages {
-		// Собираем полный список файлов: layout + partials + страница
-		files := append([]string{}, layouts...)
-		files = append(files, pageFiles...)
+	shared := append(layouts, partials...)
+	set := make(map[string]*template.Template, len(pages))
+	for _, page := range pages {
+		name := strings.TrimSuffix(path.Base(page), ".html")
+		files := append(append([]string{}, shared...), page)
 
-		tpl, err := template.ParseFiles(files...)
+		tpl, err := template.New(name).Funcs(t.funcs).ParseFS(t.fsys, files...)
 		if err != nil {
 			return nil, fmt.Errorf("ошибка парсинга шаблона %q: %w", name, err)
 		}
-		t.templates[name] = tpl
+		set[name] = tpl
 	}
+	return set, nil
+}
 
-	return t, nil
+// lookup — шаблон страницы; в dev сначала перечитывает изменённые файлы.
+func (t *Templates) lookup(name string) (*template.Template, error) {
+	if t.dirty.CompareAndSwap(true, false) {
+		set, err := t.parse()
+		if err != nil {
+			// Остаёмся на старых шаблонах, повторим на следующем запросе
+			t.dirty.Store(true)
+			return nil, err
+		}
+		t.mu.Lock()
+		t.templates = set
+		t.mu.Unlock()
+		core.LogInfo("Шаблоны перечитаны", map[string]interface{}{"count": len(set)})
+	}
+
+	t.mu.RLock()
+	tpl, ok := t.templates[name]
+	t.mu.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("шаблон не найден: %s", name)
+	}
+	return tpl, nil
+}
+
+// watch — помечает шаблоны изменёнными при любом событии с *.html в dir и подкаталогах.
+// fsnotify не рекурсивен: подкаталоги добавляются при старте и по событию Create.
+func (t *Templates) watch(dir string) error {
+	w, err := fsnotify.NewWatcher()
+	if err != nil {
+		return err
+	}
+	if err := addDirs(w, dir); err != nil {
+		_ = w.Close()
+		return err
+	}
+	t.watcher = w
+
+	// Горутина завершается, когда Close закрывает каналы watcher'а
+	go func() {
+		for {
+			select {
+			case ev, ok := <-w.Events:
+				if !ok {
+					return
+				}
+				if ev.Has(fsnotify.Create) {
+					if fi, err := os.Stat(ev.Name); err == nil && fi.IsDir() {
+						if err := addDirs(w, ev.Name); err != nil {
+							core.LogError("Ошибка наблюдения за каталогом шаблонов", map[string]interface{}{"dir": ev.Name, "error": err.Error()})
+						}
+						// Файлы могли появиться в каталоге раньше, чем он попал под наблюдение
+						t.dirty.Store(true)
+						continue
+					}
+				}
+				if strings.HasSuffix(ev.Name, ".html") {
+					t.dirty.Store(true)
+				}
+			case err, ok := <-w.Errors:
+				if !ok {
+					return
+				}
+				core.LogError("Ошибка наблюдения за шаблонами", map[string]interface{}{"error": err.Error()})
+			}
+		}
+	}()
+	return nil
+}
+
+// addDirs — ставит под наблюдение dir и все его подкаталоги.
+func addDirs(w *fsnotify.Watcher, dir string) error {
+	return filepath.WalkDir(dir, func(p string, d fs.DirEntry, err error) error {
+		if err != nil || !d.IsDir() {
+			return err
+		}
+		return w.Add(p)
+	})
+}
+
+// Close — останавливает наблюдение за файлами (если оно включено).
+func (t *Templates) Close() error {
+	if t.watcher == nil {
+		return nil
+	}
+	return t.watcher.Close()
 }
 
 // Render — отрисовывает HTML-шаблон с добавлением данных безопасности (CSRF/CSP).
@@ -70,11 +209,11 @@ func (t *Templates) Render(
 	title string,
 	data any,
 ) error {
-	// 1) Проверка наличия шаблона
-	tpl, ok := t.templates[templateName]
-	if !ok {
-		core.LogError("Шаблон не найден", map[string]interface{}{"template": templateName})
-		return fmt.Errorf("шаблон не найден: %s", templateName)
+	// 1) Проверка наличия шаблона (в dev — с перечитыванием изменённых файлов)
+	tpl, err := t.lookup(templateName)
+	if err != nil {
+		core.LogErrorCtx(c.Request.Context(), "Шаблон недоступен", map[string]interface{}{"template": templateName, "error": err.Error()})
+		return err
 	}
 
 	// 2) 🛠️ ИСПРАВЛЕНИЕ: Достаём CSP nonce ТОЛЬКО из request.Context.
@@ -86,7 +225,7 @@ func (t *Templates) Render(
 
 	if nonce == "" {
 		// Если nonce не найден — это критическая ошибка: не сработал middleware безопасности
-		core.LogError("CSP Nonce не найден в контексте запроса", nil)
+		core.LogErrorCtx(c.Request.Context(), "CSP Nonce не найден в контексте запроса", nil)
 		return fmt.Errorf("nonce не найден: критическая ошибка безопасности")
 	}
 
@@ -98,28 +237,31 @@ func (t *Templates) Render(
 	token := csrf.GetToken(c)
 	if token == "" {
 		// Это может произойти, если сессия не установлена; логируем, но продолжаем рендеринг.
-		core.LogError("CSRF токен пуст. Форма будет отправлена без защиты.", nil)
+		core.LogErrorCtx(c.Request.Context(), "CSRF токен пуст. Форма будет отправлена без защиты.", nil)
 	}
 
 	// Безопасное формирование HTML-поля с CSRF-токеном, используя HTMLEscapeString.
 	csrfField := template.HTML(
 		fmt.Sprintf(`<input type="hidden" name="%s" value="%s">`,
-			"csrf_token",                     // Имя поля, ожидаемое gin-csrf
+			"_csrf",                          // Имя поля, ожидаемое gin-csrf
 			template.HTMLEscapeString(token), // Экранирование для безопасности
 		),
 	)
 
 	// 5) Собираем PageData и рендерим
 	page := PageData{
-		Title:     title,
-		CSRFField: csrfField,
-		Nonce:     nonce,
-		Data:      data,
+		Title:      title,
+		CSRFField:  csrfField,
+		Nonce:      nonce,
+		Categories: c.Request.Context().Value(core.CtxCategoryTree), // кладётся middleware; меню не обязательно
+		User:       c.Request.Context().Value(core.CtxUser),         // кладётся auth.LoadUser
+		Data:       data,
 	}
+	page.CartCount, _ = c.Request.Context().Value(core.CtxCartCount).(int) // кладётся cart.Load
 
 	// ExecuteTemplate пишет прямо в ResponseWriter, используя корневой шаблон "base"
 	if err := tpl.ExecuteTemplate(c.Writer, "base", page); err != nil {
-		core.LogError("Ошибка рендеринга шаблона", map[string]interface{}{
+		core.LogErrorCtx(c.Request.Context(), "Ошибка рендеринга шаблона", map[string]interface{}{
 			"template": templateName,
 			"error":    err.Error(),
 		})
@@ -131,15 +273,18 @@ func (t *Templates) Render(
 
 // 🧠 Как это работает в нашей версии (Gin + utrack/gin-csrf):
 //
-// 1) При запуске сервера вызывается view.New() — шаблоны парсятся один раз и хранятся в памяти.
+// 1) При запуске сервера вызывается view.New(fsys, opts) — шаблоны находятся по каталогам
+//    (layouts/, partials/, pages/), парсятся один раз и хранятся в памяти.
+//    В prod fsys — встроенный web.Embedded(); в dev — web/ с диска и WatchDir:
+//    изменённые файлы перечитываются на следующем запросе.
 //
 // 2) Каждый Gin-хендлер вызывает tpl.Render(c, "имя", "заголовок", data).
 //    Render получает nonce из Gin-контекста (кладётся middleware) и подготавливает PageData.
 //
 // 3) CSRF: токен берём из utrack/gin-csrf: token := csrf.GetToken(c).
 //    Скрытое поле собираем вручную:
-//       <input type="hidden" name="csrf_token" value="...">
-//    (имя параметра — "csrf_token" по умолчанию у utrack/gin-csrf).
+//       <input type="hidden" name="_csrf" value="...">
+//    (utrack/gin-csrf ищет токен в поле/параметре "_csrf" или в заголовке X-CSRF-TOKEN).
 //
 // 4) CSP: nonce пробрасывается в PageData.Nonce и используется в шаблоне:
 //       <script nonce="{{ .Nonce }}">...</script>
@@ -148,7 +293,7 @@ func (t *Templates) Render(
 //
 // 5) Контент-тайп: Render ставит заголовок "Content-Type: text/html; charset=utf-8".
 //
-// 6) В base.gohtml должен быть корневой шаблон с именем "base" ({{ define "base" }} ... {{ end }}),
+// 6) В layouts/*.html должен быть корневой шаблон с именем "base" ({{ define "base" }} ... {{ end }}),
 //    в который дочерние страницы подключаются через {{ template }} или {{ block }}.
 
 /*
@@ -0,0 +1,54 @@
+package view
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func writeFile(t *testing.T, name, data string) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(name), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(name, []byte(data), 0o644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// waitDirty — ждёт, пока watcher пометит шаблоны изменёнными, и сбрасывает флаг.
+func waitDirty(t *testing.T, tpl *Templates, what string) {
+	t.Helper()
+	deadline := time.Now().Add(2 * time.Second)
+	for !tpl.dirty.CompareAndSwap(true, false) {
+		if time.Now().After(deadline) {
+			t.Fatalf("%s: изменение не замечено", what)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestWatchNewDirectory(t *testing.T) {
+	dir := t.TempDir()
+	writeFile(t, filepath.Join(dir, "layouts", "base.html"), `{{define "base"}}{{template "content" .}}{{end}}`)
+	writeFile(t, filepath.Join(dir, "pages", "home.html"), `{{define "content"}}home{{end}}`)
+
+	tpl, err := New(os.DirFS(dir), Options{WatchDir: dir})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = tpl.Close() })
+
+	// Каталог, созданный после старта, тоже под наблюдением
+	if err := os.MkdirAll(filepath.Join(dir, "partials", "shop"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	waitDirty(t, tpl, "новый каталог")
+
+	writeFile(t, filepath.Join(dir, "partials", "shop", "card.html"), `{{define "card"}}{{end}}`)
+	waitDirty(t, tpl, "файл в новом подкаталоге")
+
+	writeFile(t, filepath.Join(dir, "pages", "home.html"), `{{define "content"}}home 2{{end}}`)
+	waitDirty(t, tpl, "файл в старом каталоге")
+}
@@ -6,7 +6,7 @@ set APP_ENV=dev
 set HTTP_ADDR=:8080
 
 if "%1"=="" (
-    echo Usage: make [run ^| build ^| start ^| clean ^| test ^| lint ^| tidy]
+    echo Usage: make [run ^| build ^| start ^| clean ^| test ^| lint ^| tidy ^| migrate ^| assets]
     exit /b 0
 )
 
@@ -16,8 +16,21 @@ if "%1"=="run" (
     exit /b
 )
 
+if "%1"=="migrate" (
+    echo Applying migrations...
+    go run ./cmd/app migrate up
+    exit /b
+)
+
+if "%1"=="assets" (
+    echo Updating web\assets\manifest.json...
+    go generate ./web
+    exit /b
+)
+
 if "%1"=="build" (
     echo Building binary...
+    go generate ./web
     if not exist bin mkdir bin
     go build -o bin\app.exe ./cmd/app
     echo Build complete: bin\app.exe
@@ -77,5 +90,5 @@ if "%1"=="tidy" (
 )
 
 echo Unknown command: %1
-echo Usage: make [run ^| build ^| start ^| clean ^| test ^| lint ^| tidy]
+echo Usage: make [run ^| build ^| start ^| clean ^| test ^| lint ^| tidy ^| migrate ^| assets]
 exit /b 1
\ No newline at end of file
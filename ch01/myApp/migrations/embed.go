@@ -0,0 +1,27 @@
+// Package migrations — SQL-миграции, встроенные в бинарник.
+//
+// Файлы: NNN_name.up.sql (обязателен) и NNN_name.down.sql (для отката),
+// отдельный каталог на каждый диалект: mysql/, sqlite/. Номера версий в
+// каталогах совпадают. Номер — версия; применённые версии хранятся в
+// таблице schema_migrations. Уже применённый файл не редактируют — для
+// изменений добавляют новый (в оба каталога).
+package migrations
+
+import (
+	"embed"
+	"fmt"
+	"io/fs"
+)
+
+//go:embed mysql/*.sql sqlite/*.sql
+var files embed.FS
+
+// For возвращает миграции для драйвера БД (DB_DRIVER).
+func For(driver string) (fs.FS, error) {
+	switch driver {
+	case "mysql", "sqlite":
+		return fs.Sub(files, driver)
+	default:
+		return nil, fmt.Errorf("миграции: драйвер %q не поддерживается", driver)
+	}
+}
@@ -0,0 +1,10 @@
+-- 001_create_products.up.sql — таблица каталога
+
+CREATE TABLE IF NOT EXISTS products (
+ id          INT AUTO_INCREMENT PRIMARY KEY,
+ name        VARCHAR(255) NOT NULL,
+ article     VARCHAR(100) NOT NULL,
+ price       DECIMAL(10,2) NOT NULL,
+ image_alt   VARCHAR(255),
+ created_at  TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
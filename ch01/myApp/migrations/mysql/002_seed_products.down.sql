@@ -0,0 +1,4 @@
+-- 002_seed_products.down.sql — только товары, добавленные 002 (см. seed_products)
+
+DELETE FROM products WHERE article IN (SELECT article FROM seed_products);
+DROP TABLE seed_products;
@@ -0,0 +1,29 @@
+-- 002_seed_products.up.sql — демо-товары
+--
+-- Идемпотентно: в БД, созданной старым 001_schema.sql (001 её «принимает»
+-- через IF NOT EXISTS), товары с этими артикулами уже есть — их не дублируем.
+-- seed_products запоминает, что добавлено здесь: down удалит только это.
+
+CREATE TABLE IF NOT EXISTS seed_products (
+ article VARCHAR(100) NOT NULL PRIMARY KEY
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
+
+INSERT INTO seed_products (article)
+SELECT s.article FROM (
+ SELECT 'ART-001' AS article UNION ALL
+ SELECT 'ART-002' UNION ALL
+ SELECT 'ART-003' UNION ALL
+ SELECT 'ART-004' UNION ALL
+ SELECT 'ART-005'
+) s
+WHERE NOT EXISTS (SELECT 1 FROM products p WHERE p.article = s.article);
+
+INSERT INTO products (name, article, price, image_alt)
+SELECT s.name, s.article, s.price, s.image_alt FROM (
+ SELECT 'Смартфон XYZ Pro' AS name, 'ART-001' AS article, 299.99 AS price, 'Смартфон с 128GB' AS image_alt UNION ALL
+ SELECT 'Ноутбук ABC Ultra',         'ART-002', 899.00, 'Ноутбук 16" i7'   UNION ALL
+ SELECT 'Планшет DEF Mini',          'ART-003', 199.50, 'Планшет 10"'      UNION ALL
+ SELECT 'Наушники GHI Wireless',     'ART-004',  79.90, 'Беспроводные TWS' UNION ALL
+ SELECT 'Клавиатура KLM Mechanical', 'ART-005', 129.00, NULL
+) s
+WHERE s.article IN (SELECT article FROM seed_products);
@@ -0,0 +1,5 @@
+-- 003_add_products_category.down.sql
+
+ALTER TABLE products
+ DROP INDEX idx_products_category,
+ DROP COLUMN category_id;
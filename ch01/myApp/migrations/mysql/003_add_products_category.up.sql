@@ -0,0 +1,5 @@
+-- 003_add_products_category.up.sql — привязка товара к категории (фильтр category_id)
+
+ALTER TABLE products
+ ADD COLUMN category_id INT NULL AFTER id,
+ ADD INDEX idx_products_category (category_id, id);
@@ -0,0 +1,5 @@
+-- 004_add_products_sort_indexes.down.sql
+
+ALTER TABLE products
+ DROP INDEX idx_products_name,
+ DROP INDEX idx_products_price;
@@ -0,0 +1,5 @@
+-- 004_add_products_sort_indexes.up.sql — индексы под keyset-пагинацию (колонка сортировки + id)
+
+ALTER TABLE products
+ ADD INDEX idx_products_name (name, id),
+ ADD INDEX idx_products_price (price, id);
@@ -0,0 +1,3 @@
+-- 005_add_products_fulltext.down.sql
+
+DROP INDEX ft_products_search ON products;
@@ -0,0 +1,3 @@
+-- 005_add_products_fulltext.up.sql — полнотекстовый поиск по названию и артикулу
+
+CREATE FULLTEXT INDEX ft_products_search ON products (name, article);
@@ -0,0 +1,3 @@
+-- 006_create_categories.down.sql
+
+DROP TABLE IF EXISTS categories;
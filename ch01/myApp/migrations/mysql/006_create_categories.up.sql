@@ -0,0 +1,13 @@
+-- 006_create_categories.up.sql — дерево категорий (adjacency list: parent_id)
+
+CREATE TABLE IF NOT EXISTS categories (
+ id          INT AUTO_INCREMENT PRIMARY KEY,
+ parent_id   INT NULL,
+ slug        VARCHAR(100) NOT NULL,
+ name        VARCHAR(255) NOT NULL,
+ sort_order  INT NOT NULL DEFAULT 0,
+ created_at  TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+ UNIQUE KEY uq_categories_slug (slug),
+ KEY idx_categories_parent (parent_id, sort_order),
+ CONSTRAINT fk_categories_parent FOREIGN KEY (parent_id) REFERENCES categories (id) ON DELETE RESTRICT
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
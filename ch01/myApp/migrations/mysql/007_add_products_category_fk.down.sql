@@ -0,0 +1,3 @@
+-- 007_add_products_category_fk.down.sql
+
+ALTER TABLE products DROP FOREIGN KEY fk_products_category;
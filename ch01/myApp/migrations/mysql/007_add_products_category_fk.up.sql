@@ -0,0 +1,4 @@
+-- 007_add_products_category_fk.up.sql — удаление категории не оставляет «висящих» товаров
+
+ALTER TABLE products
+ ADD CONSTRAINT fk_products_category FOREIGN KEY (category_id) REFERENCES categories (id) ON DELETE SET NULL;
@@ -0,0 +1,5 @@
+-- 008_seed_categories.down.sql
+
+UPDATE products SET category_id = NULL WHERE article IN ('ART-001', 'ART-002', 'ART-003', 'ART-004', 'ART-005');
+DELETE FROM categories WHERE slug IN ('phones-tablets', 'computers', 'audio');
+DELETE FROM categories WHERE slug IN ('electronics', 'accessories');
@@ -0,0 +1,19 @@
+-- 008_seed_categories.up.sql — демо-категории и привязка демо-товаров
+
+INSERT INTO categories (parent_id, slug, name, sort_order) VALUES
+(NULL, 'electronics', 'Электроника', 1),
+(NULL, 'accessories', 'Аксессуары',  2);
+
+INSERT INTO categories (parent_id, slug, name, sort_order)
+SELECT id, 'phones-tablets', 'Смартфоны и планшеты', 1 FROM categories WHERE slug = 'electronics';
+
+INSERT INTO categories (parent_id, slug, name, sort_order)
+SELECT id, 'computers', 'Компьютеры', 2 FROM categories WHERE slug = 'electronics';
+
+INSERT INTO categories (parent_id, slug, name, sort_order)
+SELECT id, 'audio', 'Аудио', 1 FROM categories WHERE slug = 'accessories';
+
+UPDATE products SET category_id = (SELECT id FROM categories WHERE slug = 'phones-tablets') WHERE article IN ('ART-001', 'ART-003');
+UPDATE products SET category_id = (SELECT id FROM categories WHERE slug = 'computers')      WHERE article = 'ART-002';
+UPDATE products SET category_id = (SELECT id FROM categories WHERE slug = 'audio')          WHERE article = 'ART-004';
+UPDATE products SET category_id = (SELECT id FROM categories WHERE slug = 'accessories')    WHERE article = 'ART-005';
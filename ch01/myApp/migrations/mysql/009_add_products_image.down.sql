@@ -0,0 +1,3 @@
+-- 009_add_products_image.down.sql
+
+ALTER TABLE products DROP COLUMN image_path;
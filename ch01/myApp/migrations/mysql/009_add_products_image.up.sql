@@ -0,0 +1,3 @@
+-- 009_add_products_image.up.sql — картинка товара (путь относительно UPLOAD_DIR)
+
+ALTER TABLE products ADD COLUMN image_path VARCHAR(255) NULL AFTER image_alt;
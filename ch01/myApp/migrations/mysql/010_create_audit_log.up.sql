@@ -0,0 +1,13 @@
+-- 010_create_audit_log.up.sql — журнал изменений из админки (кто, что, когда)
+
+CREATE TABLE IF NOT EXISTS audit_log (
+ id          BIGINT AUTO_INCREMENT PRIMARY KEY,
+ actor       VARCHAR(100) NOT NULL,
+ ip          VARCHAR(45) NOT NULL DEFAULT '',
+ action      VARCHAR(20) NOT NULL,
+ entity      VARCHAR(50) NOT NULL,
+ entity_id   BIGINT NOT NULL,
+ changes     TEXT NOT NULL,
+ created_at  TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+ KEY idx_audit_entity (entity, entity_id)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
@@ -0,0 +1,3 @@
+-- 011_create_users.down.sql
+
+DROP TABLE IF EXISTS users;
@@ -0,0 +1,12 @@
+-- 011_create_users.up.sql — пользователи (пароль: argon2id в формате PHC)
+
+CREATE TABLE IF NOT EXISTS users (
+ id             BIGINT AUTO_INCREMENT PRIMARY KEY,
+ email          VARCHAR(255) NOT NULL,
+ name           VARCHAR(100) NOT NULL,
+ password_hash  VARCHAR(255) NOT NULL,
+ role           VARCHAR(20) NOT NULL DEFAULT 'user',
+ created_at     TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+ last_login_at  TIMESTAMP NULL,
+ UNIQUE KEY uq_users_email (email)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
@@ -0,0 +1,15 @@
+-- 012_create_contact_messages.up.sql — сообщения формы /form и статус уведомления
+
+CREATE TABLE IF NOT EXISTS contact_messages (
+ id           BIGINT AUTO_INCREMENT PRIMARY KEY,
+ name         VARCHAR(100) NOT NULL,
+ email        VARCHAR(255) NOT NULL,
+ message      TEXT NOT NULL,
+ ip           VARCHAR(45) NOT NULL DEFAULT '',
+ status       VARCHAR(20) NOT NULL DEFAULT 'pending',
+ attempts     INT NOT NULL DEFAULT 0,
+ last_error   TEXT NULL,
+ created_at   TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+ sent_at      TIMESTAMP NULL,
+ KEY idx_contact_messages_status (status, id)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
@@ -0,0 +1,3 @@
+-- 013_create_orders.down.sql
+
+DROP TABLE IF EXISTS orders;
@@ -0,0 +1,18 @@
+-- 013_create_orders.up.sql — заказы (total — сумма снимков цен из order_items)
+
+CREATE TABLE IF NOT EXISTS orders (
+ id          BIGINT AUTO_INCREMENT PRIMARY KEY,
+ token       CHAR(32) NOT NULL,
+ user_id     BIGINT NULL,
+ name        VARCHAR(100) NOT NULL,
+ email       VARCHAR(255) NOT NULL,
+ phone       VARCHAR(30) NOT NULL DEFAULT '',
+ address     VARCHAR(500) NOT NULL,
+ comment     TEXT NULL,
+ status      VARCHAR(20) NOT NULL DEFAULT 'new',
+ total       DECIMAL(12,2) NOT NULL DEFAULT 0,
+ created_at  TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+ UNIQUE KEY uq_orders_token (token),
+ KEY idx_orders_user (user_id, id),
+ CONSTRAINT fk_orders_user FOREIGN KEY (user_id) REFERENCES users (id) ON DELETE SET NULL
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
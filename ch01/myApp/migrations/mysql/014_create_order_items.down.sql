@@ -0,0 +1,3 @@
+-- 014_create_order_items.down.sql
+
+DROP TABLE IF EXISTS order_items;
@@ -0,0 +1,14 @@
+-- 014_create_order_items.up.sql — позиции заказа: снимок названия и цены на момент заказа
+
+CREATE TABLE IF NOT EXISTS order_items (
+ id          BIGINT AUTO_INCREMENT PRIMARY KEY,
+ order_id    BIGINT NOT NULL,
+ product_id  INT NULL,
+ name        VARCHAR(255) NOT NULL,
+ article     VARCHAR(100) NOT NULL,
+ price       DECIMAL(10,2) NOT NULL,
+ qty         INT NOT NULL,
+ KEY idx_order_items_order (order_id),
+ CONSTRAINT fk_order_items_order FOREIGN KEY (order_id) REFERENCES orders (id) ON DELETE CASCADE,
+ CONSTRAINT fk_order_items_product FOREIGN KEY (product_id) REFERENCES products (id) ON DELETE SET NULL
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
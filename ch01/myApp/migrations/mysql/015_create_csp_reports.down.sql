@@ -0,0 +1,3 @@
+-- 015_create_csp_reports.down.sql
+
+DROP TABLE IF EXISTS csp_reports;
@@ -0,0 +1,15 @@
+-- 015_create_csp_reports.up.sql — отчёты о нарушениях CSP (/csp-report)
+
+CREATE TABLE IF NOT EXISTS csp_reports (
+ id           BIGINT AUTO_INCREMENT PRIMARY KEY,
+ directive    VARCHAR(100) NOT NULL,
+ blocked_uri  VARCHAR(512) NOT NULL DEFAULT '',
+ document_uri VARCHAR(512) NOT NULL DEFAULT '',
+ source_file  VARCHAR(512) NOT NULL DEFAULT '',
+ line_number  INT NOT NULL DEFAULT 0,
+ disposition  VARCHAR(20) NOT NULL DEFAULT 'enforce',
+ sample       VARCHAR(255) NOT NULL DEFAULT '',
+ user_agent   VARCHAR(512) NOT NULL DEFAULT '',
+ ip           VARCHAR(45) NOT NULL DEFAULT '',
+ created_at   TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
@@ -0,0 +1,3 @@
+-- 001_create_products.down.sql
+
+DROP TABLE IF EXISTS products;
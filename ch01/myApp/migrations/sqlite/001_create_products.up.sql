@@ -0,0 +1,10 @@
+-- 001_create_products.up.sql — таблица каталога (SQLite)
+
+CREATE TABLE IF NOT EXISTS products (
+ id          INTEGER PRIMARY KEY AUTOINCREMENT,
+ name        TEXT NOT NULL,
+ article     TEXT NOT NULL,
+ price       REAL NOT NULL,
+ image_alt   TEXT,
+ created_at  TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
@@ -0,0 +1,4 @@
+-- 003_add_products_category.down.sql
+
+DROP INDEX IF EXISTS idx_products_category;
+ALTER TABLE products DROP COLUMN category_id;
@@ -0,0 +1,4 @@
+-- 003_add_products_category.up.sql — привязка товара к категории (фильтр category_id)
+
+ALTER TABLE products ADD COLUMN category_id INTEGER;
+CREATE INDEX idx_products_category ON products (category_id, id);
@@ -0,0 +1,4 @@
+-- 004_add_products_sort_indexes.down.sql
+
+DROP INDEX IF EXISTS idx_products_name;
+DROP INDEX IF EXISTS idx_products_price;
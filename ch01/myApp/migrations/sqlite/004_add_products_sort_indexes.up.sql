@@ -0,0 +1,4 @@
+-- 004_add_products_sort_indexes.up.sql — индексы под keyset-пагинацию (колонка сортировки + id)
+
+CREATE INDEX idx_products_name ON products (name, id);
+CREATE INDEX idx_products_price ON products (price, id);
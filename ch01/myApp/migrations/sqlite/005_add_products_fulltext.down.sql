@@ -0,0 +1 @@
+-- 005_add_products_fulltext.down.sql — нечего откатывать (см. up)
@@ -0,0 +1,4 @@
+-- 005_add_products_fulltext.up.sql
+--
+-- В SQLite поиск идёт через LIKE (storage.searchCondition), индекс не нужен.
+-- Версия оставлена, чтобы номера совпадали с mysql/.
@@ -0,0 +1,11 @@
+-- 006_create_categories.up.sql — дерево категорий (adjacency list: parent_id)
+
+CREATE TABLE IF NOT EXISTS categories (
+ id          INTEGER PRIMARY KEY AUTOINCREMENT,
+ parent_id   INTEGER REFERENCES categories (id) ON DELETE RESTRICT,
+ slug        TEXT NOT NULL UNIQUE,
+ name        TEXT NOT NULL,
+ sort_order  INTEGER NOT NULL DEFAULT 0,
+ created_at  TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+CREATE INDEX idx_categories_parent ON categories (parent_id, sort_order);
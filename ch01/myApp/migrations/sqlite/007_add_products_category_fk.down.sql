@@ -0,0 +1 @@
+-- 007_add_products_category_fk.down.sql — нечего откатывать (см. up)
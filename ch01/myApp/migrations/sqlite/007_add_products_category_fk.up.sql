@@ -0,0 +1,5 @@
+-- 007_add_products_category_fk.up.sql
+--
+-- SQLite не умеет добавлять FOREIGN KEY в существующую таблицу (только
+-- пересоздание). Для dev/тестов достаточно индекса из 003; версия оставлена,
+-- чтобы номера совпадали с mysql/.
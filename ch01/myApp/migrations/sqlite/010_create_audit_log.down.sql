@@ -0,0 +1,3 @@
+-- 010_create_audit_log.down.sql
+
+DROP TABLE IF EXISTS audit_log;
@@ -0,0 +1,13 @@
+-- 010_create_audit_log.up.sql — журнал изменений из админки (кто, что, когда)
+
+CREATE TABLE IF NOT EXISTS audit_log (
+ id          INTEGER PRIMARY KEY AUTOINCREMENT,
+ actor       TEXT NOT NULL,
+ ip          TEXT NOT NULL DEFAULT '',
+ action      TEXT NOT NULL,
+ entity      TEXT NOT NULL,
+ entity_id   INTEGER NOT NULL,
+ changes     TEXT NOT NULL,
+ created_at  TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+CREATE INDEX idx_audit_entity ON audit_log (entity, entity_id);
@@ -0,0 +1,12 @@
+-- 011_create_users.up.sql — пользователи (пароль: argon2id в формате PHC) (SQLite)
+
+CREATE TABLE IF NOT EXISTS users (
+ id             INTEGER PRIMARY KEY AUTOINCREMENT,
+ email          TEXT NOT NULL,
+ name           TEXT NOT NULL,
+ password_hash  TEXT NOT NULL,
+ role           TEXT NOT NULL DEFAULT 'user',
+ created_at     TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+ last_login_at  TIMESTAMP NULL
+);
+CREATE UNIQUE INDEX uq_users_email ON users (email);
@@ -0,0 +1,3 @@
+-- 012_create_contact_messages.down.sql
+
+DROP TABLE IF EXISTS contact_messages;
@@ -0,0 +1,15 @@
+-- 012_create_contact_messages.up.sql — сообщения формы /form и статус уведомления (SQLite)
+
+CREATE TABLE IF NOT EXISTS contact_messages (
+ id           INTEGER PRIMARY KEY AUTOINCREMENT,
+ name         TEXT NOT NULL,
+ email        TEXT NOT NULL,
+ message      TEXT NOT NULL,
+ ip           TEXT NOT NULL DEFAULT '',
+ status       TEXT NOT NULL DEFAULT 'pending',
+ attempts     INTEGER NOT NULL DEFAULT 0,
+ last_error   TEXT NULL,
+ created_at   TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+ sent_at      TIMESTAMP NULL
+);
+CREATE INDEX idx_contact_messages_status ON contact_messages (status, id);
@@ -0,0 +1,18 @@
+-- 013_create_orders.up.sql — заказы (total — сумма снимков цен из order_items) (SQLite)
+-- NUMERIC: в SQLite нет точного DECIMAL, суммы считаются в SQL так же, как в MySQL.
+
+CREATE TABLE IF NOT EXISTS orders (
+ id          INTEGER PRIMARY KEY AUTOINCREMENT,
+ token       TEXT NOT NULL,
+ user_id     INTEGER NULL REFERENCES users (id) ON DELETE SET NULL,
+ name        TEXT NOT NULL,
+ email       TEXT NOT NULL,
+ phone       TEXT NOT NULL DEFAULT '',
+ address     TEXT NOT NULL,
+ comment     TEXT NULL,
+ status      TEXT NOT NULL DEFAULT 'new',
+ total       NUMERIC(12,2) NOT NULL DEFAULT 0,
+ created_at  TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+CREATE UNIQUE INDEX uq_orders_token ON orders (token);
+CREATE INDEX idx_orders_user ON orders (user_id, id);
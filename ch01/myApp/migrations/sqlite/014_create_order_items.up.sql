@@ -0,0 +1,12 @@
+-- 014_create_order_items.up.sql — позиции заказа: снимок названия и цены на момент заказа (SQLite)
+
+CREATE TABLE IF NOT EXISTS order_items (
+ id          INTEGER PRIMARY KEY AUTOINCREMENT,
+ order_id    INTEGER NOT NULL REFERENCES orders (id) ON DELETE CASCADE,
+ product_id  INTEGER NULL REFERENCES products (id) ON DELETE SET NULL,
+ name        TEXT NOT NULL,
+ article     TEXT NOT NULL,
+ price       NUMERIC(10,2) NOT NULL,
+ qty         INTEGER NOT NULL
+);
+CREATE INDEX idx_order_items_order ON order_items (order_id);
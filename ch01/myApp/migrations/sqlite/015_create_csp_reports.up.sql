@@ -0,0 +1,15 @@
+-- 015_create_csp_reports.up.sql — отчёты о нарушениях CSP (/csp-report) (SQLite)
+
+CREATE TABLE IF NOT EXISTS csp_reports (
+ id           INTEGER PRIMARY KEY AUTOINCREMENT,
+ directive    TEXT NOT NULL,
+ blocked_uri  TEXT NOT NULL DEFAULT '',
+ document_uri TEXT NOT NULL DEFAULT '',
+ source_file  TEXT NOT NULL DEFAULT '',
+ line_number  INTEGER NOT NULL DEFAULT 0,
+ disposition  TEXT NOT NULL DEFAULT 'enforce',
+ sample       TEXT NOT NULL DEFAULT '',
+ user_agent   TEXT NOT NULL DEFAULT '',
+ ip           TEXT NOT NULL DEFAULT '',
+ created_at   TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
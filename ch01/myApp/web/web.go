@@ -0,0 +1,28 @@
+// Package web — HTML-шаблоны и статика, встроенные в бинарник.
+//
+// templates/: layouts/*.html и partials/*.html подключаются к каждой странице,
+// pages/<имя>.html — страница <имя> (tpl.Render(c, "<имя>", ...)).
+// Новая страница — новый файл в pages/, регистрировать её не нужно.
+//
+// assets/manifest.json — имена статики с хэшем (см. internal/assets);
+// после изменения файлов в assets/ выполните go generate ./web.
+package web
+
+//go:generate go run ../cmd/assetmanifest -dir assets
+
+import (
+	"embed"
+	"io/fs"
+)
+
+//go:embed templates assets
+var files embed.FS
+
+// Dir — каталог web/ на диске (относительно рабочей директории); в dev
+// шаблоны и статика читаются оттуда, чтобы правки были видны без пересборки.
+const Dir = "web"
+
+// Embedded — встроенные templates/ и assets/.
+func Embedded() fs.FS {
+	return files
+}